@@ -0,0 +1,83 @@
+// cmd/config.go
+// config命令定义
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"multi-database-validator-optimization/internal/config"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+var showResolved bool
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "查看配置",
+}
+
+// configShowCmd represents the config show command
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "显示配置来源和合并后的配置",
+	Long: `显示配置来源和合并后的配置，密码等敏感值显示为******
+
+配置按以下顺序合并，后面的覆盖前面的:
+  1. 默认值
+  2. 基础配置文件 (--config，使用--profile时默认为configs/base.yaml)
+  3. profile配置文件 (与基础配置同目录的<profile>.yaml)
+  4. 环境变量 (MDV_MAX_WORKERS、MDV_AZURE_0_HOST等)
+  5. 命令行参数
+
+不带--resolved时只显示配置文件合并后的内容；带--resolved时显示
+全部来源合并并按配置结构解码后的结果。
+
+使用示例:
+  multi-database-validator config show --profile prod              # 显示base.yaml与prod.yaml合并后的内容
+  multi-database-validator config show --profile prod --resolved   # 显示最终生效的配置`,
+	RunE:         runConfigShow,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+
+	configShowCmd.Flags().BoolVar(&showResolved, "resolved", false, "显示合并环境变量和命令行参数并解码后的完整配置")
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	layers := config.LoadedLayers()
+	out := os.Stdout
+
+	fmt.Fprintln(out, "# 配置来源 (优先级从低到高):")
+	fmt.Fprintln(out, "#   默认值")
+	for _, file := range layers.Files {
+		fmt.Fprintf(out, "#   %s\n", file)
+	}
+	if len(layers.Env) > 0 {
+		fmt.Fprintf(out, "#   环境变量: %s\n", strings.Join(layers.Env, ", "))
+	}
+
+	settings := config.Redact(layers.Merged)
+	if showResolved {
+		fmt.Fprintln(out, "#   其他MDV_环境变量和命令行参数")
+		var err error
+		if settings, err = config.Resolved(); err != nil {
+			return err
+		}
+	}
+
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(settings); err != nil {
+		return fmt.Errorf("输出配置失败: %v", err)
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,120 @@
+// cmd/doctor.go
+// doctor命令定义
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"multi-database-validator-optimization/internal/config"
+	"multi-database-validator-optimization/internal/doctor"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorTimeout time.Duration
+	doctorJSON    bool
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "检查数据库连接与权限配置",
+	Long: `在正式验证前检查配置中的每个数据库实例
+
+检查项:
+- DNS解析、TCP连接、TLS握手
+- 身份认证
+- SELECT、PROCESS、REPLICATION CLIENT权限
+- 服务器版本、sql_mode、时区、字符集
+- Azure/AWS实例对之间的版本、sql_mode、时区和字符集一致性
+
+存在失败项时命令以非零状态退出，每个问题都会给出修复建议。
+
+使用示例:
+  multi-database-validator doctor                         # 检查配置文件中的所有实例
+  multi-database-validator doctor --config prod.yaml      # 检查指定配置文件
+  multi-database-validator doctor --timeout 10s --json    # 以JSON格式输出`,
+	RunE:         runDoctor,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 5*time.Second, "每项网络检查的超时时间")
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "以JSON格式输出检查结果")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	if len(cfg.Azure) == 0 && len(cfg.AWS) == 0 {
+		return fmt.Errorf("配置中没有数据库实例")
+	}
+
+	report := doctor.Run(context.Background(), cfg, doctorTimeout)
+
+	if doctorJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("输出检查结果失败: %v", err)
+		}
+	} else {
+		printDoctorReport(report)
+	}
+
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("%d 个实例检查未通过", failed)
+	}
+	return nil
+}
+
+// printDoctorReport 打印检查结果
+func printDoctorReport(report *doctor.Report) {
+	for _, inst := range report.Instances {
+		fmt.Printf("\n🩺 [%s] %s (%s/%s)\n", inst.Side, inst.Name, inst.Address, inst.Database)
+		printChecks(inst.Checks)
+	}
+
+	for _, pair := range report.Pairs {
+		fmt.Printf("\n🔗 %s vs %s\n", pair.AzureInstance, pair.AWSInstance)
+		printChecks(pair.Checks)
+	}
+
+	fmt.Println()
+	passed := len(report.Instances) - report.Failed()
+	fmt.Printf("📊 检查完成: %d/%d 个实例通过，%d 个警告\n", passed, len(report.Instances), report.Warnings())
+}
+
+// printChecks 打印检查项列表
+func printChecks(checks []doctor.CheckResult) {
+	for _, c := range checks {
+		fmt.Printf("  %s %s  %s\n", statusIcon(c.Status), c.Name, c.Detail)
+		if c.Hint != "" && (c.Status == doctor.StatusFail || c.Status == doctor.StatusWarn) {
+			fmt.Printf("     💡 %s\n", c.Hint)
+		}
+	}
+}
+
+// statusIcon 检查状态对应的图标
+func statusIcon(status string) string {
+	switch status {
+	case doctor.StatusPass:
+		return "✅"
+	case doctor.StatusWarn:
+		return "⚠️ "
+	case doctor.StatusFail:
+		return "❌"
+	default:
+		return "⏭️ "
+	}
+}
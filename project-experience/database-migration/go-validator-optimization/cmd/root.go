@@ -13,7 +13,10 @@ import (
 	"github.com/spf13/viper"
 )
 
-var cfgFile string
+var (
+	cfgFile string
+	profile string
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -35,6 +38,8 @@ var rootCmd = &cobra.Command{
 使用示例:
   multi-database-validator validate                    # 使用默认配置验证
   multi-database-validator validate --config config.yaml  # 指定配置文件
+  multi-database-validator validate --profile prod    # 合并configs/base.yaml和configs/prod.yaml
+  multi-database-validator config show --resolved     # 显示合并后的完整配置
   multi-database-validator init --format yaml         # 创建YAML配置文件
   multi-database-validator validate --max-workers 5   # 设置并发数`,
 	Version: "2.0.0",
@@ -54,6 +59,7 @@ func init() {
 
 	// 全局标志
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "配置文件路径 (默认: config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", os.Getenv("MDV_PROFILE"), "环境profile，在基础配置(base.yaml或--config)之上合并同目录下的<profile>.yaml")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "详细输出")
 	rootCmd.PersistentFlags().String("log-level", "info", "日志级别 (debug, info, warn, error)")
 
@@ -66,20 +72,11 @@ func init() {
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	// 初始化配置
-	if err := config.InitViper(); err != nil {
+	if err := config.InitViper(config.LoadOptions{File: cfgFile, Profile: profile}); err != nil {
 		fmt.Fprintf(os.Stderr, "配置初始化失败: %v\n", err)
 		os.Exit(1)
 	}
 
-	// 如果指定了配置文件，使用指定的文件
-	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
-		if err := viper.ReadInConfig(); err != nil {
-			fmt.Fprintf(os.Stderr, "读取指定配置文件失败: %v\n", err)
-			os.Exit(1)
-		}
-	}
-
 	// 初始化输出目录
 	if err := config.InitOutputDirs(); err != nil {
 		fmt.Fprintf(os.Stderr, "输出目录初始化失败: %v\n", err)
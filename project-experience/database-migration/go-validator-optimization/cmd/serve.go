@@ -0,0 +1,130 @@
+// cmd/serve.go
+// serve命令定义
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"multi-database-validator-optimization/internal/config"
+	"multi-database-validator-optimization/internal/server"
+	"multi-database-validator-optimization/internal/types"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "以守护进程模式运行，定时验证并提供HTTP API",
+	Long: `以守护进程模式运行验证服务
+
+按配置文件中serve.schedules定义的cron表达式定时执行验证，
+配置文件修改后自动重新加载，并提供HTTP API和状态页面:
+
+  GET  /                       状态页面
+  GET  /api/schedules          定时任务列表
+  GET  /api/runs               运行记录列表
+  POST /api/runs               立即触发一次验证
+  GET  /api/runs/{id}          运行详情
+  GET  /api/runs/{id}/events   运行进度 (Server-Sent Events)
+  GET  /api/runs/{id}/report   验证报告
+
+配置示例:
+  serve:
+    addr: ":8080"
+    max_history: 50
+    schedules:
+      - name: nightly
+        cron: "0 2 * * *"
+      - name: frequent
+        cron: "@every 6h"
+
+使用示例:
+  multi-database-validator serve                         # 使用配置文件中的监听地址
+  multi-database-validator serve --addr 127.0.0.1:9090   # 指定监听地址`,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("addr", ":8080", "HTTP监听地址")
+	viper.BindPFlag("serve.addr", serveCmd.Flags().Lookup("addr"))
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	srv := server.New(loadServeConfig)
+	if err := srv.Start(); err != nil {
+		log.Printf("⚠️  部分定时任务加载失败: %v", err)
+	}
+
+	// 配置文件变化时重新加载定时任务
+	config.OnConfigChange(func(e fsnotify.Event) {
+		log.Printf("检测到配置文件变化: %s，重新加载", e.Name)
+		if err := srv.Reload(); err != nil {
+			log.Printf("⚠️  重新加载配置失败: %v", err)
+		}
+	})
+	config.WatchConfig()
+
+	addr := viper.GetString("serve.addr")
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		fmt.Printf("🌐 验证服务已启动: http://%s\n", addr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			srv.Stop()
+			return fmt.Errorf("HTTP服务启动失败: %v", err)
+		}
+	case <-ctx.Done():
+	}
+
+	fmt.Println("🛑 正在停止验证服务，取消进行中的验证...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP服务关闭失败: %v", err)
+	}
+	srv.Stop()
+
+	return nil
+}
+
+// loadServeConfig 从Viper读取当前配置
+func loadServeConfig() (*types.Config, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxWorkers <= 0 {
+		cfg.MaxWorkers = 3
+	}
+	return cfg, nil
+}
@@ -4,29 +4,51 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"multi-database-validator-optimization/internal/config"
+	"multi-database-validator-optimization/internal/notify"
+	"multi-database-validator-optimization/internal/progress"
 	"multi-database-validator-optimization/internal/types"
-	"multi-database-validator-optimization/internal/validator"
+	"multi-database-validator-optimization/pkg/validator"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	maxWorkers int
-	outputFile string
-	dryRun     bool
-	azureHost  string
-	azureUser  string
-	azurePass  string
-	azureDB    string
-	awsHost    string
-	awsUser    string
-	awsPass    string
-	awsDB      string
+	maxWorkers  int
+	outputFile  string
+	dryRun      bool
+	azureHost   string
+	azureUser   string
+	azurePass   string
+	azureDB     string
+	awsHost     string
+	awsUser     string
+	awsPass     string
+	awsDB       string
+	planMode    bool
+	planOutput  string
+	planFile    string
+	noCalibrate bool
+	eventsFile  string
+	noProgress  bool
+	noColDiff   bool
+	noObjects   bool
+	checkText   bool
+	failOnText  bool
+	noNotify    bool
 )
 
 // validateCmd represents the validate command
@@ -35,6 +57,9 @@ var validateCmd = &cobra.Command{
 	Short: "验证数据库一致性",
 	Long: `验证Azure和AWS数据库的一致性
 
+在终端中运行时显示实时进度视图（每个对比对的当前表、已完成表数、
+已发现的不一致、吞吐量和剩余时间），输出被重定向时自动关闭。
+
 支持多种配置方式:
 1. 配置文件 (推荐)
 2. 命令行参数
@@ -46,6 +71,13 @@ var validateCmd = &cobra.Command{
   multi-database-validator validate                           # 使用配置文件验证
   multi-database-validator validate --max-workers 5          # 设置并发数
   multi-database-validator validate --dry-run                # 试运行模式
+  multi-database-validator validate --hash sha256            # 使用SHA-256计算校验和
+  multi-database-validator validate --plan                   # 生成验证计划并估算耗时，不执行验证
+  multi-database-validator validate --plan-file plan.json    # 按已保存的验证计划执行
+  multi-database-validator validate --events events.ndjson   # 将进度事件以NDJSON格式写入文件
+  multi-database-validator validate --events - | jq .        # 将进度事件写入标准输出，供其他程序消费
+  multi-database-validator validate --check-text             # 检查字符集转换造成的乱码
+  multi-database-validator validate --no-notify              # 不发送配置的通知
   multi-database-validator validate --azure-host azure.com   # 命令行指定Azure主机`,
 	RunE: runValidate,
 }
@@ -57,6 +89,18 @@ func init() {
 	validateCmd.Flags().IntVarP(&maxWorkers, "max-workers", "w", 3, "最大并发数")
 	validateCmd.Flags().StringVarP(&outputFile, "output", "o", "consistency_report.json", "输出报告文件")
 	validateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "试运行模式，不执行实际验证")
+	validateCmd.Flags().String("hash", validator.HashXXHash, "校验和哈希算法: xxhash、md5、sha256")
+	validateCmd.Flags().BoolVar(&planMode, "plan", false, "计划模式，列出每个表的规模、校验策略和预估耗时，不执行验证")
+	validateCmd.Flags().StringVar(&planOutput, "plan-output", "validation_plan.json", "计划模式下保存验证计划的JSON文件")
+	validateCmd.Flags().StringVar(&planFile, "plan-file", "", "按指定的验证计划JSON文件执行验证")
+	validateCmd.Flags().BoolVar(&noCalibrate, "no-calibrate", false, "计划模式下跳过校准基准测试，使用默认吞吐量估算")
+	validateCmd.Flags().StringVar(&eventsFile, "events", "", "将进度事件以NDJSON格式写入指定文件，\"-\"表示标准输出")
+	validateCmd.Flags().BoolVar(&noProgress, "no-progress", false, "关闭终端实时进度视图")
+	validateCmd.Flags().BoolVar(&noColDiff, "no-column-diff", false, "不计算每列指纹，校验和不一致时不定位差异列")
+	validateCmd.Flags().BoolVar(&noObjects, "no-objects", false, "不对比视图、存储过程、函数、触发器和事件的定义")
+	validateCmd.Flags().BoolVar(&checkText, "check-text", false, "检查文本列中的非法UTF-8、双重编码和丢失的4字节字符")
+	validateCmd.Flags().BoolVar(&failOnText, "fail-on-text-issues", false, "AWS端有文本问题时将该表记为不一致 (隐含--check-text)")
+	validateCmd.Flags().BoolVar(&noNotify, "no-notify", false, "不发送配置的验证结果通知")
 
 	// Azure配置标志
 	validateCmd.Flags().StringVar(&azureHost, "azure-host", "", "Azure数据库主机")
@@ -74,6 +118,7 @@ func init() {
 	// 注意：workers参数不绑定到Viper，只用于命令行参数
 	viper.BindPFlag("output", validateCmd.Flags().Lookup("output"))
 	viper.BindPFlag("dry_run", validateCmd.Flags().Lookup("dry-run"))
+	viper.BindPFlag("hash", validateCmd.Flags().Lookup("hash"))
 
 	// 注意：Azure和AWS参数不绑定到Viper，只用于命令行参数覆盖
 }
@@ -84,94 +129,214 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("初始化配置失败: %v", err)
 	}
 
+	// 事件写入标准输出时，其余输出改为标准错误，保证标准输出只有NDJSON
+	var out io.Writer = os.Stdout
+	if eventsFile == "-" {
+		out = os.Stderr
+	}
+
+	// 实例列表按配置结构严格解码，files类型实例没有host等连接字段
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	// 并发数只在命令行显式指定时覆盖配置
+	if cmd.Flags().Changed("max-workers") {
+		cfg.MaxWorkers = maxWorkers
+	}
+
 	// 显示配置信息
-	if viper.GetBool("verbose") {
-		showConfig()
+	if cfg.Verbose {
+		showConfig(out, cfg)
 	}
 
 	// 试运行模式
-	if viper.GetBool("dry_run") {
-		fmt.Println("🔍 试运行模式 - 显示配置信息，不执行实际验证")
-		showConfig()
+	if cfg.DryRun {
+		fmt.Fprintln(out, "🔍 试运行模式 - 显示配置信息，不执行实际验证")
+		showConfig(out, cfg)
 		return nil
 	}
 
-	// 开始验证
-	fmt.Println("🚀 开始数据库一致性验证...")
 	startTime := time.Now()
 
-	// 创建配置对象
-	// 优先使用命令行参数，然后使用配置文件中的max_workers
-	actualMaxWorkers := maxWorkers
-	if actualMaxWorkers == 3 { // 如果使用默认值，尝试从配置文件获取
-		if configMaxWorkers := viper.GetInt("max_workers"); configMaxWorkers > 0 {
-			actualMaxWorkers = configMaxWorkers
-		}
-	}
-	cfg := &types.Config{
-		MaxWorkers: actualMaxWorkers,
-	}
-
-	// 解析Azure和AWS配置
-	if azureConfig := viper.Get("azure"); azureConfig != nil {
-		azureInstances := azureConfig.([]interface{})
-		cfg.Azure = make([]types.DatabaseInstance, len(azureInstances))
-		for i, instance := range azureInstances {
-			inst := instance.(map[string]interface{})
-			cfg.Azure[i] = types.DatabaseInstance{
-				Name:     inst["name"].(string),
-				Host:     inst["host"].(string),
-				User:     inst["user"].(string),
-				Password: inst["password"].(string),
-				Database: inst["database"].(string),
-				Charset:  inst["charset"].(string),
-			}
+	// 中断时取消验证，已完成的结果仍会写入报告
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts := []validator.Option{
+		validator.WithInstances(cfg.Azure, cfg.AWS),
+		validator.WithMaxWorkers(cfg.MaxWorkers),
+		validator.WithHashAlgorithm(cfg.Hash),
+		validator.WithColumnDiff(!noColDiff),
+		validator.WithObjects(!noObjects),
+		validator.WithGrantUsers(cfg.GrantUsers...),
+		validator.WithMasking(cfg.Masking...),
+	}
+	if cfg.Discover != nil {
+		opts = append(opts, validator.WithDiscovery(*cfg.Discover))
+	}
+	if cfg.Sharding != nil {
+		opts = append(opts, validator.WithSharding(*cfg.Sharding))
+	}
+	if checkText || failOnText {
+		if cfg.TextCheck == nil {
+			cfg.TextCheck = &validator.TextCheckConfig{}
 		}
+		cfg.TextCheck.FailOnIssues = cfg.TextCheck.FailOnIssues || failOnText
+	}
+	if cfg.TextCheck != nil {
+		opts = append(opts, validator.WithTextCheck(*cfg.TextCheck))
 	}
 
-	if awsConfig := viper.Get("aws"); awsConfig != nil {
-		awsInstances := awsConfig.([]interface{})
-		cfg.AWS = make([]types.DatabaseInstance, len(awsInstances))
-		for i, instance := range awsInstances {
-			inst := instance.(map[string]interface{})
-			cfg.AWS[i] = types.DatabaseInstance{
-				Name:     inst["name"].(string),
-				Host:     inst["host"].(string),
-				User:     inst["user"].(string),
-				Password: inst["password"].(string),
-				Database: inst["database"].(string),
-				Charset:  inst["charset"].(string),
-			}
+	// 计划模式只生成验证计划
+	if planMode {
+		return runPlan(ctx, validator.New(opts...))
+	}
+
+	// 进度事件处理
+	var handlers []progress.Handler
+	var events *progress.EventWriter
+	if eventsFile != "" {
+		var err error
+		events, err = progress.OpenEventWriter(eventsFile)
+		if err != nil {
+			return err
+		}
+		defer events.Close()
+		handlers = append(handlers, events.Handle)
+	}
+
+	var terminal *progress.Terminal
+	if !noProgress && eventsFile != "-" && progress.IsTerminal(os.Stdout) {
+		// 实时视图期间日志写入日志文件，避免与进度视图交错
+		logPath := filepath.Join(config.GetLogsDir(), fmt.Sprintf("validate_%s.log", startTime.Format("20060102_150405")))
+		logFile, err := os.Create(logPath)
+		if err != nil {
+			return fmt.Errorf("创建日志文件失败: %v", err)
 		}
+		defer logFile.Close()
+		log.SetOutput(logFile)
+		defer log.SetOutput(os.Stderr)
+		fmt.Fprintf(out, "📝 详细日志: %s\n", logPath)
+
+		tracker := progress.NewTracker()
+		handlers = append(handlers, tracker.Handle)
+		terminal = progress.NewTerminal(os.Stdout, tracker)
+	}
+	if len(handlers) > 0 {
+		opts = append(opts, validator.WithProgressHook(progress.Fanout(handlers...)))
 	}
+	validatorInstance := validator.New(opts...)
 
-	// 创建验证器并执行验证
-	validatorInstance := validator.NewMultiDatabaseValidator(cfg)
-	if err := validatorInstance.ValidateAllDatabases(); err != nil {
-		return fmt.Errorf("验证失败: %v", err)
+	// 开始验证
+	fmt.Fprintln(out, "🚀 开始数据库一致性验证...")
+	var plan *validator.Plan
+	if planFile != "" {
+		var err error
+		plan, err = validator.LoadPlan(planFile)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "📋 按验证计划执行: %s (%d 个对比对，%d 个表)\n", planFile, len(plan.Pairs), plan.TotalTables)
+	}
+
+	if terminal != nil {
+		terminal.Start()
+	}
+	summary, validateErr := validatorInstance.Run(ctx, plan)
+	if terminal != nil {
+		terminal.Stop()
+	}
+	if summary == nil {
+		sendNotifications(out, cfg, nil, "", validateErr)
+		return fmt.Errorf("验证失败: %v", validateErr)
+	}
+	if events != nil {
+		if err := events.Close(); err != nil {
+			return fmt.Errorf("写入事件失败: %v", err)
+		}
 	}
 
 	// 生成报告
-	outputFile := config.GetReportPath(viper.GetString("output"))
-	summary, err := validatorInstance.GenerateReport(outputFile)
-	if err != nil {
+	outputFile := config.GetReportPath(cfg.Output)
+	if err := validator.SaveReport(summary, outputFile); err != nil {
 		return fmt.Errorf("生成报告失败: %v", err)
 	}
+	log.Printf("验证报告已生成: %s", outputFile)
+	if validateErr != nil {
+		return fmt.Errorf("验证已中断，部分结果已写入 %s: %v", outputFile, validateErr)
+	}
 
 	duration := time.Since(startTime)
 
 	// 显示验证结果
-	fmt.Printf("✅ 验证完成，耗时: %v\n", duration)
-	fmt.Printf("📊 验证结果:\n")
-	fmt.Printf("  - 总数据库数: %d\n", summary.TotalDatabases)
-	fmt.Printf("  - 验证成功: %d\n", summary.SuccessfulValidations)
-	fmt.Printf("  - 数据不一致: %d\n", summary.InconsistentDatabases)
-	fmt.Printf("  - 验证错误: %d\n", summary.ErrorDatabases)
-	fmt.Printf("  - 成功率: %s\n", summary.SuccessRate)
+	fmt.Fprintf(out, "✅ 验证完成，耗时: %v\n", duration)
+	fmt.Fprintf(out, "📊 验证结果:\n")
+	fmt.Fprintf(out, "  - 总数据库数: %d\n", summary.TotalDatabases)
+	fmt.Fprintf(out, "  - 验证成功: %d\n", summary.SuccessfulValidations)
+	fmt.Fprintf(out, "  - 数据不一致: %d\n", summary.InconsistentDatabases)
+	fmt.Fprintf(out, "  - 验证错误: %d\n", summary.ErrorDatabases)
+	fmt.Fprintf(out, "  - 成功率: %s\n", summary.SuccessRate)
+	if len(summary.MissingDatabases) > 0 {
+		fmt.Fprintf(out, "  - ⚠️  AWS中缺少的数据库: %v\n", summary.MissingDatabases)
+	}
+	if len(summary.ExtraDatabases) > 0 {
+		fmt.Fprintf(out, "  - ⚠️  仅AWS中存在的数据库: %v\n", summary.ExtraDatabases)
+	}
+	for _, g := range summary.Grants {
+		switch {
+		case g.Error != "":
+			fmt.Fprintf(out, "  - 用户 %s 权限 (%s vs %s): ERROR - %s\n", g.User, g.AzureInstance, g.AWSInstance, g.Error)
+		case !g.Match:
+			fmt.Fprintf(out, "  - 用户 %s 权限 (%s vs %s): AWS缺少 %v，多出 %v\n", g.User, g.AzureInstance, g.AWSInstance, g.MissingInAWS, g.ExtraInAWS)
+		}
+	}
+	databases := make([]string, 0, len(summary.Results))
+	for name := range summary.Results {
+		databases = append(databases, name)
+	}
+	sort.Strings(databases)
+	for _, name := range databases {
+		for _, c := range summary.Results[name].TableComparisons {
+			if len(c.TextIssues) > 0 {
+				fmt.Fprintf(out, "  - 表 %s.%s 文本问题: %s\n", name, c.Table, validator.TextSummary(c.TextIssues))
+			}
+		}
+	}
+	for _, sharded := range summary.ShardedTables {
+		fmt.Fprintf(out, "  - 分片表 %s (%d 个分片): %s", sharded.Name, len(sharded.Shards), sharded.Status)
+		if detail := sharded.Summary + sharded.Error; detail != "" {
+			fmt.Fprintf(out, " - %s", detail)
+		}
+		fmt.Fprintln(out)
+	}
 
+	sendNotifications(out, cfg, summary, outputFile, nil)
 	return nil
 }
 
+// sendNotifications 发送验证结果通知，通知失败只输出警告，不影响验证结果
+func sendNotifications(out io.Writer, cfg *types.Config, summary *validator.Report, reportFile string, runErr error) {
+	if cfg.Notify == nil || len(cfg.Notify.Sinks) == 0 || noNotify {
+		return
+	}
+	notifier, err := notify.New(*cfg.Notify, config.GetNotifyStateFile())
+	if err != nil {
+		fmt.Fprintf(out, "⚠️  创建通知失败: %v\n", err)
+		return
+	}
+	outcome := notify.FromReport(summary, runErr)
+	outcome.Trigger = "cli"
+	outcome.ReportFile = reportFile
+	sent, err := notifier.Notify(context.Background(), outcome)
+	if len(sent) > 0 {
+		fmt.Fprintf(out, "📣 已发送通知: %s\n", strings.Join(sent, ", "))
+	}
+	if err != nil {
+		fmt.Fprintf(out, "⚠️  发送通知失败: %v\n", err)
+	}
+}
+
 // initValidationConfig 初始化验证配置
 func initValidationConfig() error {
 	// 设置默认值
@@ -208,43 +373,173 @@ func initValidationConfig() error {
 }
 
 // showConfig 显示当前配置
-func showConfig() {
-	// 计算实际使用的并发数
-	actualMaxWorkers := maxWorkers
-	if actualMaxWorkers == 3 { // 如果使用默认值，尝试从配置文件获取
-		if configMaxWorkers := viper.GetInt("max_workers"); configMaxWorkers > 0 {
-			actualMaxWorkers = configMaxWorkers
-		}
-	}
-
-	fmt.Println("📋 当前配置:")
-	fmt.Printf("  - 配置文件: %s\n", viper.ConfigFileUsed())
-	fmt.Printf("  - 并发数: %d\n", actualMaxWorkers)
-	fmt.Printf("  - 输出文件: %s\n", viper.GetString("output"))
-	fmt.Printf("  - 详细模式: %t\n", viper.GetBool("verbose"))
-	fmt.Printf("  - 试运行: %t\n", viper.GetBool("dry_run"))
-
-	// 显示Azure配置
-	if azureInstances := viper.Get("azure"); azureInstances != nil {
-		if instances, ok := azureInstances.([]interface{}); ok {
-			fmt.Printf("  - Azure实例数: %d\n", len(instances))
-			for i, instance := range instances {
-				if inst, ok := instance.(map[string]interface{}); ok {
-					fmt.Printf("    [%d] %s: %s/%s\n", i+1, inst["name"], inst["host"], inst["database"])
-				}
+func showConfig(out io.Writer, cfg *types.Config) {
+	layers := config.LoadedLayers()
+	fmt.Fprintln(out, "📋 当前配置:")
+	fmt.Fprintf(out, "  - 配置文件: %s\n", strings.Join(layers.Files, " + "))
+	if layers.Profile != "" {
+		fmt.Fprintf(out, "  - Profile: %s\n", layers.Profile)
+	}
+	fmt.Fprintf(out, "  - 并发数: %d\n", cfg.MaxWorkers)
+	fmt.Fprintf(out, "  - 哈希算法: %s\n", cfg.Hash)
+	fmt.Fprintf(out, "  - 输出文件: %s\n", cfg.Output)
+	fmt.Fprintf(out, "  - 详细模式: %t\n", cfg.Verbose)
+	fmt.Fprintf(out, "  - 试运行: %t\n", cfg.DryRun)
+
+	// 显示实例配置
+	sides := []struct {
+		label     string
+		instances []validator.DatabaseInstance
+	}{{"Azure", cfg.Azure}, {"AWS", cfg.AWS}}
+	for _, side := range sides {
+		if len(side.instances) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "  - %s实例数: %d\n", side.label, len(side.instances))
+		for i, inst := range side.instances {
+			if inst.IsFiles() {
+				fmt.Fprintf(out, "    [%d] %s: files %s\n", i+1, inst.Name, inst.Path)
+				continue
 			}
+			fmt.Fprintf(out, "    [%d] %s: %s/%s\n", i+1, inst.Name, inst.Host, inst.Database)
+		}
+	}
+
+	// 显示分片表配置
+	if cfg.Sharding != nil {
+		fmt.Fprintf(out, "  - 分片表数: %d\n", len(cfg.Sharding.Tables))
+		for i, m := range cfg.Sharding.Tables {
+			fmt.Fprintf(out, "    [%d] %s/%s.%s -> %d 个分片 (%s %s)\n",
+				i+1, m.Source.Instance, m.Source.Database, m.Source.Table, len(m.Targets), m.Function, m.Key)
 		}
 	}
 
-	// 显示AWS配置
-	if awsInstances := viper.Get("aws"); awsInstances != nil {
-		if instances, ok := awsInstances.([]interface{}); ok {
-			fmt.Printf("  - AWS实例数: %d\n", len(instances))
-			for i, instance := range instances {
-				if inst, ok := instance.(map[string]interface{}); ok {
-					fmt.Printf("    [%d] %s: %s/%s\n", i+1, inst["name"], inst["host"], inst["database"])
-				}
+	// 显示脱敏规则，不输出密钥
+	if len(cfg.Masking) > 0 {
+		fmt.Fprintf(out, "  - 脱敏规则数: %d\n", len(cfg.Masking))
+		for i, r := range cfg.Masking {
+			fmt.Fprintf(out, "    [%d] %s.%s: %s\n", i+1, r.Table, r.Column, r.Transform)
+		}
+	}
+
+	// 显示文本完整性检查配置
+	if cfg.TextCheck != nil {
+		fmt.Fprintf(out, "  - 文本检查: 开启 (有问题时记为不一致: %v)\n", cfg.TextCheck.FailOnIssues)
+	}
+
+	// 显示通知渠道，不输出url等敏感信息
+	if cfg.Notify != nil && len(cfg.Notify.Sinks) > 0 {
+		fmt.Fprintf(out, "  - 通知渠道数: %d\n", len(cfg.Notify.Sinks))
+		for i, sink := range cfg.Notify.Sinks {
+			severities := "全部"
+			if len(sink.Severities) > 0 {
+				severities = strings.Join(sink.Severities, ",")
 			}
+			fmt.Fprintf(out, "    [%d] %s (%s): 级别 %s\n", i+1, sink.Name, sink.Type, severities)
+		}
+	}
+
+	// 显示自动发现配置
+	if cfg.Discover != nil {
+		fmt.Fprintf(out, "  - 自动发现: %s vs %s\n", cfg.Discover.Azure.Name, cfg.Discover.AWS.Name)
+		if len(cfg.Discover.Exclude) > 0 {
+			fmt.Fprintf(out, "    排除: %v\n", cfg.Discover.Exclude)
+		}
+	}
+}
+
+// runPlan 生成并输出验证计划
+func runPlan(ctx context.Context, validatorInstance *validator.Validator) error {
+	fmt.Println("📐 正在生成验证计划...")
+	plan, err := validatorInstance.BuildPlan(ctx, !noCalibrate)
+	if err != nil {
+		return fmt.Errorf("生成验证计划失败: %v", err)
+	}
+
+	printPlan(plan)
+
+	planPath := config.GetReportPath(planOutput)
+	if err := validator.SavePlan(plan, planPath); err != nil {
+		return err
+	}
+	fmt.Printf("💾 验证计划已保存: %s\n", planPath)
+	fmt.Printf("   使用 validate --plan-file %s 按此计划执行验证\n", planPath)
+
+	failed := 0
+	for _, pair := range plan.Pairs {
+		if pair.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个对比对生成计划失败", failed)
+	}
+	return nil
+}
+
+// printPlan 打印验证计划
+func printPlan(plan *validator.Plan) {
+	for _, pair := range plan.Pairs {
+		fmt.Printf("\n🗂  %s/%s vs %s/%s\n", pair.AzureInstance, pair.AzureDatabase, pair.AWSInstance, pair.AWSDatabase)
+		if pair.Error != "" {
+			fmt.Printf("  ❌ %s\n", pair.Error)
+			continue
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  TABLE\tROWS(EST)\tDATA\tSTRATEGY\tCHUNKS\tETA")
+		for _, t := range pair.Tables {
+			fmt.Fprintf(w, "  %s\t%d\t%s\t%s\t%d\t%s\n",
+				t.Table, t.EstimatedRows, formatBytes(t.DataBytes), t.Strategy, t.Chunks, formatSeconds(t.EstimatedSeconds))
+		}
+		w.Flush()
+
+		if len(pair.MissingInAWS) > 0 {
+			fmt.Printf("  ⚠️  AWS中缺少的表: %v\n", pair.MissingInAWS)
+		}
+		if len(pair.ExtraInAWS) > 0 {
+			fmt.Printf("  ⚠️  仅AWS中存在的表: %v\n", pair.ExtraInAWS)
 		}
+		fmt.Printf("  吞吐量: Azure %.0f 行/秒，AWS %.0f 行/秒，预估耗时: %s\n",
+			pair.AzureRowsPerSecond, pair.AWSRowsPerSecond, formatSeconds(pair.EstimatedSeconds))
 	}
+
+	if len(plan.MissingDatabases) > 0 {
+		fmt.Printf("\n⚠️  AWS中缺少的数据库: %v\n", plan.MissingDatabases)
+	}
+	if len(plan.ExtraDatabases) > 0 {
+		fmt.Printf("\n⚠️  仅AWS中存在的数据库: %v\n", plan.ExtraDatabases)
+	}
+
+	fmt.Println()
+	fmt.Printf("📊 计划汇总:\n")
+	fmt.Printf("  - 对比对: %d\n", len(plan.Pairs))
+	fmt.Printf("  - 表数量: %d\n", plan.TotalTables)
+	fmt.Printf("  - 估算行数: %d\n", plan.TotalRows)
+	fmt.Printf("  - 数据大小: %s\n", formatBytes(plan.TotalDataBytes))
+	fmt.Printf("  - 并发数: %d\n", plan.MaxWorkers)
+	if plan.Calibrated {
+		fmt.Printf("  - 预估总耗时: %s (基于校准基准测试)\n", plan.EstimatedDuration)
+	} else {
+		fmt.Printf("  - 预估总耗时: %s (未校准，使用默认吞吐量)\n", plan.EstimatedDuration)
+	}
+}
+
+// formatBytes 格式化字节数
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatSeconds 格式化秒数为可读时长
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
 }
@@ -11,49 +11,46 @@ import (
 	"time"
 
 	"multi-database-validator-optimization/internal/types"
+	"multi-database-validator-optimization/pkg/validator"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 )
 
 // InitViper 初始化Viper配置
-func InitViper() error {
-	// 设置配置文件名称（不包含扩展名）
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml") // 默认类型
-
-	// 添加配置文件搜索路径
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("./configs")
-	viper.AddConfigPath("./examples")
-	viper.AddConfigPath("$HOME/.multi-database-validator")
-	viper.AddConfigPath("/etc/multi-database-validator")
+// 优先级从低到高: 默认值、基础配置文件、profile配置文件、环境变量、命令行参数
+func InitViper(opts LoadOptions) error {
+	loadOptions = opts
 
 	// 绑定环境变量
 	bindEnvVars()
+	viper.SetDefault("max_workers", 3)
+	viper.SetDefault("hash", validator.HashXXHash)
+	viper.SetDefault("output_dir", "output")
+	viper.SetDefault("output", "consistency_report.json")
 
-	// 读取配置文件
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// 配置文件未找到，使用默认配置
-			fmt.Println("⚠️  未找到配置文件，使用默认配置")
-			setDefaults()
-		} else {
-			return fmt.Errorf("读取配置文件失败: %v", err)
-		}
-	} else {
-		fmt.Printf("使用配置文件: %s\n", viper.ConfigFileUsed())
+	// 读取并合并配置文件
+	found, err := loadLayers()
+	if err != nil {
+		return err
+	}
+	if !found {
+		// 配置文件未找到，使用默认配置
+		fmt.Fprintln(os.Stderr, "⚠️  未找到配置文件，使用默认配置")
+		setDefaults()
+	} else if len(loaded.Files) > 0 {
+		fmt.Fprintf(os.Stderr, "使用配置文件: %s\n", strings.Join(loaded.Files, " + "))
 	}
 
-	// 解析配置到结构体
-	var globalConfig types.Config
-	if err := viper.Unmarshal(&globalConfig); err != nil {
-		return fmt.Errorf("解析配置失败: %v", err)
+	// 严格解析配置到结构体，未知的配置项和类型错误会报告字段路径
+	globalConfig, err := decodeConfig()
+	if err != nil {
+		return err
 	}
 
 	// 验证配置（只在有实际配置时验证）
-	if len(globalConfig.Azure) > 0 || len(globalConfig.AWS) > 0 {
-		if err := validateConfig(globalConfig); err != nil {
+	if len(globalConfig.Azure) > 0 || len(globalConfig.AWS) > 0 || globalConfig.Discover != nil || globalConfig.Sharding != nil {
+		if err := validateConfig(*globalConfig); err != nil {
 			return fmt.Errorf("配置验证失败: %v", err)
 		}
 	}
@@ -62,24 +59,18 @@ func InitViper() error {
 }
 
 // bindEnvVars 绑定环境变量
+// 标量配置项使用 MDV_<配置项>，嵌套的配置项用下划线连接，如 MDV_SERVE_ADDR；
+// 实例列表使用带下标的 MDV_AZURE_0_HOST，由loadLayers合并到配置文件的列表中
 func bindEnvVars() {
 	// 设置环境变量前缀
 	viper.SetEnvPrefix("MDV")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
-	// 注释掉环境变量绑定，避免干扰YAML数组解析
-	// 如果需要使用环境变量，请使用命令行参数替代
-	// viper.BindEnv("azure.0.host", "MDV_AZURE_HOST")
-	// viper.BindEnv("azure.0.user", "MDV_AZURE_USER")
-	// viper.BindEnv("azure.0.password", "MDV_AZURE_PASSWORD")
-	// viper.BindEnv("azure.0.database", "MDV_AZURE_DATABASE")
-
-	// viper.BindEnv("aws.0.host", "MDV_AWS_HOST")
-	// viper.BindEnv("aws.0.user", "MDV_AWS_USER")
-	// viper.BindEnv("aws.0.password", "MDV_AWS_PASSWORD")
-	// viper.BindEnv("aws.0.database", "MDV_AWS_DATABASE")
-
 	viper.BindEnv("max_workers", "MDV_MAX_WORKERS")
+	viper.BindEnv("hash", "MDV_HASH")
+	viper.BindEnv("output", "MDV_OUTPUT")
+	viper.BindEnv("output_dir", "MDV_OUTPUT_DIR")
 }
 
 // setDefaults 设置默认配置值
@@ -125,18 +116,43 @@ func setDefaults() {
 	})
 
 	viper.SetDefault("max_workers", 3)
+	viper.SetDefault("hash", validator.HashXXHash)
 	viper.SetDefault("output_dir", "output")
 	viper.SetDefault("output", "consistency_report.json")
 }
 
 // validateConfig 验证配置
 func validateConfig(config types.Config) error {
-	if len(config.Azure) == 0 {
-		return fmt.Errorf("Azure实例列表不能为空")
+	if config.Discover != nil {
+		if err := validateDiscover(config.Discover); err != nil {
+			return err
+		}
 	}
+	if config.Sharding != nil {
+		for _, mapping := range config.Sharding.Tables {
+			if err := mapping.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	if config.Notify != nil {
+		if err := config.Notify.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, rule := range config.Masking {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.Discover == nil && config.Sharding == nil {
+		if len(config.Azure) == 0 {
+			return fmt.Errorf("Azure实例列表不能为空")
+		}
 
-	if len(config.AWS) == 0 {
-		return fmt.Errorf("AWS实例列表不能为空")
+		if len(config.AWS) == 0 {
+			return fmt.Errorf("AWS实例列表不能为空")
+		}
 	}
 
 	if len(config.Azure) != len(config.AWS) {
@@ -147,6 +163,35 @@ func validateConfig(config types.Config) error {
 		return fmt.Errorf("最大并发数必须大于0")
 	}
 
+	if _, err := validator.NewHash(config.Hash); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateDiscover 验证自动发现配置
+func validateDiscover(discover *validator.DiscoveryConfig) error {
+	servers := []struct {
+		side     string
+		instance validator.DatabaseInstance
+	}{{"Azure", discover.Azure}, {"AWS", discover.AWS}}
+	for _, s := range servers {
+		if s.instance.Name == "" {
+			return fmt.Errorf("自动发现的%s服务器缺少name", s.side)
+		}
+		if s.instance.IsFiles() && s.instance.Path == "" {
+			return fmt.Errorf("自动发现的%s服务器 %s 缺少path", s.side, s.instance.Name)
+		}
+		if !s.instance.IsFiles() && s.instance.Host == "" {
+			return fmt.Errorf("自动发现的%s服务器 %s 缺少host", s.side, s.instance.Name)
+		}
+	}
+	for _, r := range discover.Rename {
+		if r.Azure == "" || r.AWS == "" {
+			return fmt.Errorf("自动发现的改名规则必须同时指定azure和aws")
+		}
+	}
 	return nil
 }
 
@@ -188,11 +233,7 @@ func SetConfigValue(key string, value interface{}) {
 
 // GetConfig 获取完整配置
 func GetConfig() (*types.Config, error) {
-	var config types.Config
-	if err := viper.Unmarshal(&config); err != nil {
-		return nil, fmt.Errorf("解析配置失败: %v", err)
-	}
-	return &config, nil
+	return decodeConfig()
 }
 
 // WatchConfig 监听配置文件变化
@@ -200,9 +241,14 @@ func WatchConfig() {
 	viper.WatchConfig()
 }
 
-// OnConfigChange 配置文件变化回调
+// OnConfigChange 配置文件变化回调，回调前重新合并各层配置
 func OnConfigChange(fn func(in fsnotify.Event)) {
-	viper.OnConfigChange(fn)
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		if _, err := loadLayers(); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  重新载入配置失败: %v\n", err)
+		}
+		fn(e)
+	})
 }
 
 // GetOutputDir 获取输出目录
@@ -235,6 +281,11 @@ func GetTempDir() string {
 	return tempDir
 }
 
+// GetNotifyStateFile 获取通知去重状态文件路径
+func GetNotifyStateFile() string {
+	return filepath.Join(GetOutputDir(), "notify_state.json")
+}
+
 // GetReportPath 获取报告文件路径
 func GetReportPath(filename string) string {
 	if filename == "" {
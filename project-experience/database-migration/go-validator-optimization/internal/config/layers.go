@@ -0,0 +1,396 @@
+// internal/config/layers.go
+// 分层配置：基础配置 + 环境profile覆盖 + 带下标的环境变量，严格解码与脱敏输出
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"multi-database-validator-optimization/internal/types"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// LoadOptions 配置加载选项
+type LoadOptions struct {
+	File    string // --config指定的配置文件，使用profile时作为基础配置
+	Profile string // --profile指定的环境，在基础配置之上合并同目录下的<profile>.yaml
+}
+
+// Layers 已载入的配置来源，按优先级从低到高
+type Layers struct {
+	Files   []string       // 配置文件，后面的覆盖前面的
+	Env     []string       // 生效的带下标环境变量名
+	Profile string         // 使用的profile
+	Merged  map[string]any // 合并后的配置文件内容，不含环境变量和命令行参数
+}
+
+var (
+	loadOptions LoadOptions
+	loaded      Layers
+)
+
+// searchPaths 配置文件搜索路径
+var searchPaths = []string{".", "./configs", "./examples", "$HOME/.multi-database-validator", "/etc/multi-database-validator"}
+
+// configExts 支持的配置文件扩展名
+var configExts = []string{".yaml", ".yml", ".json", ".toml"}
+
+// envRef 配置文件中的环境变量引用，如 ${AZURE_PASSWORD}
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// indexedEnv 带下标的实例环境变量，如 MDV_AZURE_0_HOST、MDV_SOURCE_1_PASSWORD
+var indexedEnv = regexp.MustCompile(`^MDV_(AZURE|SOURCE|AWS|TARGET)_(\d+)_([A-Z_]+)$`)
+
+// envSides 环境变量中的实例列表名，SOURCE/TARGET是AZURE/AWS的别名
+var envSides = map[string]string{"AZURE": "azure", "SOURCE": "azure", "AWS": "aws", "TARGET": "aws"}
+
+// envFields 环境变量中的字段名对应的实例配置路径
+var envFields = map[string][]string{
+	"NAME":           {"name"},
+	"TYPE":           {"type"},
+	"HOST":           {"host"},
+	"USER":           {"user"},
+	"PASSWORD":       {"password"},
+	"DATABASE":       {"database"},
+	"CHARSET":        {"charset"},
+	"PATH":           {"path"},
+	"FILE_FORMAT":    {"file", "format"},
+	"FILE_NO_HEADER": {"file", "no_header"},
+	"FILE_NULL":      {"file", "null"},
+}
+
+// loadLayers 读取并合并各层配置文件和带下标的环境变量，结果一次性载入viper
+// 返回false表示没有找到配置文件
+func loadLayers() (bool, error) {
+	opts := loadOptions
+	base := opts.File
+	if base == "" {
+		name := "config"
+		if opts.Profile != "" {
+			name = "base"
+		}
+		base = findConfig(name)
+	}
+	if base == "" && opts.Profile != "" {
+		return false, fmt.Errorf("使用profile %s 时未找到基础配置文件 base.yaml", opts.Profile)
+	}
+
+	// 没有配置文件时实例列表可以完全由环境变量给出
+	var files []string
+	if base != "" {
+		files = append(files, base)
+	}
+	if opts.Profile != "" {
+		overlay := findIn(filepath.Dir(base), opts.Profile)
+		if overlay == "" {
+			return false, fmt.Errorf("未找到profile配置文件: %s/%s.yaml", filepath.Dir(base), opts.Profile)
+		}
+		files = append(files, overlay)
+	}
+
+	merged := map[string]any{}
+	for _, file := range files {
+		settings, err := readLayer(file)
+		if err != nil {
+			return false, err
+		}
+		mergeSettings(merged, settings)
+	}
+
+	// 配置文件中的内容在合并环境变量前保存一份，供config show显示
+	fileSettings := map[string]any{}
+	mergeSettings(fileSettings, merged)
+	env, err := applyIndexedEnv(merged, os.Environ())
+	if err != nil {
+		return false, err
+	}
+
+	if len(files) == 0 && len(env) == 0 {
+		return false, nil
+	}
+
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return false, fmt.Errorf("合并配置失败: %v", err)
+	}
+	if len(files) > 0 {
+		viper.SetConfigFile(files[len(files)-1])
+	}
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+		return false, fmt.Errorf("载入合并后的配置失败: %v", err)
+	}
+
+	loaded = Layers{Files: files, Env: env, Profile: opts.Profile, Merged: fileSettings}
+	return true, nil
+}
+
+// findConfig 在搜索路径中查找配置文件
+func findConfig(name string) string {
+	for _, dir := range searchPaths {
+		if path := findIn(os.ExpandEnv(dir), name); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// findIn 在目录中查找任一支持扩展名的配置文件
+func findIn(dir, name string) string {
+	for _, ext := range configExts {
+		path := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// readLayer 读取一个配置文件，并替换字符串值中的${VAR}
+func readLayer(path string) (map[string]any, error) {
+	layer := viper.New()
+	layer.SetConfigFile(path)
+	if err := layer.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取配置文件 %s 失败: %v", path, err)
+	}
+	return expandEnv(layer.AllSettings()).(map[string]any), nil
+}
+
+// expandEnv 替换字符串值中的环境变量引用，未设置的变量替换为空
+func expandEnv(value any) any {
+	switch v := value.(type) {
+	case string:
+		return envRef.ReplaceAllStringFunc(v, func(ref string) string {
+			return os.Getenv(envRef.FindStringSubmatch(ref)[1])
+		})
+	case map[string]any:
+		for key, item := range v {
+			v[key] = expandEnv(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = expandEnv(item)
+		}
+	}
+	return value
+}
+
+// mergeSettings 将src合并到dst：映射逐键合并，列表和标量整体替换
+func mergeSettings(dst, src map[string]any) {
+	for key, value := range src {
+		if srcMap, ok := value.(map[string]any); ok {
+			dstMap, ok := dst[key].(map[string]any)
+			if !ok {
+				dstMap = map[string]any{}
+				dst[key] = dstMap
+			}
+			mergeSettings(dstMap, srcMap)
+			continue
+		}
+		if list, ok := value.([]any); ok {
+			value = copyList(list)
+		}
+		dst[key] = value
+	}
+}
+
+// copyList 复制列表，其中的映射也复制一份
+func copyList(list []any) []any {
+	out := make([]any, len(list))
+	for i, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			c := map[string]any{}
+			mergeSettings(c, m)
+			item = c
+		}
+		out[i] = item
+	}
+	return out
+}
+
+// applyIndexedEnv 将 MDV_AZURE_0_HOST 这类环境变量写入实例列表的对应元素
+// 下标等于列表长度时追加一个实例，更大的下标报错；返回生效的环境变量名
+func applyIndexedEnv(settings map[string]any, environ []string) ([]string, error) {
+	var applied []string
+	sort.Strings(environ)
+	type entry struct {
+		name, side string
+		index      int
+		path       []string
+		value      string
+	}
+	var entries []entry
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		m := indexedEnv.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		path, ok := envFields[m[3]]
+		if !ok {
+			return nil, fmt.Errorf("环境变量 %s: 未知的实例字段 %s", name, m[3])
+		}
+		index, err := strconv.Atoi(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("环境变量 %s: 下标无效", name)
+		}
+		entries = append(entries, entry{name, envSides[m[1]], index, path, value})
+	}
+	// 按下标顺序处理，追加实例时下标必须连续
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].index < entries[j].index })
+
+	for _, e := range entries {
+		list, _ := settings[e.side].([]any)
+		if e.index > len(list) {
+			return nil, fmt.Errorf("环境变量 %s: %s列表只有 %d 个实例，下标最大为 %d", e.name, e.side, len(list), len(list))
+		}
+		if e.index == len(list) {
+			list = append(list, map[string]any{})
+			settings[e.side] = list
+		}
+		instance, ok := list[e.index].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("环境变量 %s: %s[%d] 不是实例配置", e.name, e.side, e.index)
+		}
+		for _, key := range e.path[:len(e.path)-1] {
+			key = fieldKey(instance, key)
+			child, ok := instance[key].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				instance[key] = child
+			}
+			instance = child
+		}
+		instance[fieldKey(instance, e.path[len(e.path)-1])] = e.value
+		applied = append(applied, e.name)
+	}
+	return applied, nil
+}
+
+// fieldKey 返回实例配置中与字段名大小写不敏感匹配的已有键，没有时返回字段名
+func fieldKey(instance map[string]any, field string) string {
+	for key := range instance {
+		if strings.EqualFold(key, field) {
+			return key
+		}
+	}
+	return field
+}
+
+// decodeConfig 严格解码当前配置：未知的配置项和类型错误都会报告字段路径
+func decodeConfig() (*types.Config, error) {
+	var config types.Config
+	if err := viper.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %s", describeDecodeError(err))
+	}
+	return &config, nil
+}
+
+// describeDecodeError 将解码错误整理为每个字段一行
+func describeDecodeError(err error) string {
+	var lines []string
+	var walk func(error)
+	walk = func(err error) {
+		de, ok := err.(*mapstructure.DecodeError)
+		if !ok {
+			switch e := err.(type) {
+			case interface{ Unwrap() []error }:
+				for _, inner := range e.Unwrap() {
+					walk(inner)
+				}
+			case interface{ Unwrap() error }:
+				walk(e.Unwrap())
+			default:
+				lines = append(lines, err.Error())
+			}
+			return
+		}
+		field := de.Name()
+		if field == "" {
+			field = "(顶层)"
+		}
+		cause := de.Unwrap().Error()
+		if keys, ok := strings.CutPrefix(cause, "has invalid keys: "); ok {
+			lines = append(lines, fmt.Sprintf("%s: 未知的配置项 %s", field, keys))
+			return
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", field, cause))
+	}
+	walk(err)
+	return "\n  " + strings.Join(lines, "\n  ")
+}
+
+// redacted 表示已隐藏的敏感值
+const redacted = "******"
+
+// sensitiveKeys 需要隐藏的配置项名
+var sensitiveKeys = map[string]bool{"password": true, "secret": true, "token": true}
+
+// isSensitive 配置项是否需要隐藏；脱敏规则中的key是hmac密钥，
+// 通知渠道的url通常包含访问令牌，请求头通常包含认证信息
+func isSensitive(parent, key string) bool {
+	if sensitiveKeys[key] || strings.HasSuffix(key, "_password") || strings.HasSuffix(key, "_secret") || strings.HasSuffix(key, "_token") {
+		return true
+	}
+	return (parent == "masking" && key == "key") || (parent == "sinks" && key == "url") || parent == "headers"
+}
+
+// Redact 返回隐藏敏感值后的副本
+func Redact(settings map[string]any) map[string]any {
+	return redactValue("", settings).(map[string]any)
+}
+
+func redactValue(parent string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			if s, ok := item.(string); ok && s != "" && isSensitive(parent, key) {
+				out[key] = redacted
+				continue
+			}
+			out[key] = redactValue(key, item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			// 列表元素的父级是列表本身的配置项名
+			out[i] = redactValue(parent, item)
+		}
+		return out
+	}
+	return value
+}
+
+// Resolved 解析后的完整配置（默认值、配置文件、环境变量和命令行参数合并后），敏感值已隐藏
+func Resolved() (map[string]any, error) {
+	config, err := decodeConfig()
+	if err != nil {
+		return nil, err
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var settings map[string]any
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, err
+	}
+	return Redact(settings), nil
+}
+
+// LoadedLayers 返回已载入的配置来源
+func LoadedLayers() Layers {
+	return loaded
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestMergeAndIndexedEnv(t *testing.T) {
+	base := map[string]any{
+		"max_workers": 3,
+		"serve":       map[string]any{"addr": ":8080", "max_history": 50},
+		"azure":       []any{map[string]any{"name": "base-a"}},
+	}
+	overlay := map[string]any{
+		"serve": map[string]any{"addr": ":9090"},
+		"azure": []any{map[string]any{"name": "prod-a", "Host": "a.example.com"}},
+		"aws":   []any{map[string]any{"name": "prod-b"}},
+	}
+	merged := map[string]any{}
+	mergeSettings(merged, base)
+	mergeSettings(merged, overlay)
+	if serve := merged["serve"].(map[string]any); serve["addr"] != ":9090" || serve["max_history"] != 50 {
+		t.Errorf("serve = %v", serve)
+	}
+
+	applied, err := applyIndexedEnv(merged, []string{
+		"MDV_AZURE_0_HOST=override.example.com",
+		"MDV_SOURCE_1_NAME=extra",
+		"MDV_TARGET_0_FILE_FORMAT=csv",
+		"MDV_MAX_WORKERS=5",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 3 {
+		t.Errorf("applied = %v", applied)
+	}
+	want := "[map[Host:override.example.com name:prod-a] map[name:extra]]"
+	if got := fmt.Sprint(merged["azure"]); got != want {
+		t.Errorf("azure = %s, want %s", got, want)
+	}
+	if got := fmt.Sprint(merged["aws"]); got != "[map[file:map[format:csv] name:prod-b]]" {
+		t.Errorf("aws = %s", got)
+	}
+	// 覆盖层的列表是副本，不影响原配置
+	if overlay["azure"].([]any)[0].(map[string]any)["Host"] != "a.example.com" {
+		t.Error("overlay was modified")
+	}
+
+	if _, err := applyIndexedEnv(merged, []string{"MDV_AWS_3_HOST=x"}); err == nil {
+		t.Error("expected error for index gap")
+	}
+	if _, err := applyIndexedEnv(merged, []string{"MDV_AWS_0_PORT=3306"}); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	settings := map[string]any{
+		"azure":   []any{map[string]any{"host": "h", "password": "secret"}},
+		"masking": []any{map[string]any{"column": "phone", "key": "k"}},
+		"sharding": map[string]any{
+			"tables": []any{map[string]any{"key": "customer_id"}},
+		},
+		"notify": map[string]any{
+			"sinks": []any{map[string]any{"name": "ops", "url": "https://hooks/x", "headers": map[string]any{"authorization": "Bearer t"}}},
+		},
+	}
+	got := fmt.Sprint(Redact(settings))
+	want := "map[azure:[map[host:h password:******]] masking:[map[column:phone key:******]] notify:map[sinks:[map[headers:map[authorization:******] name:ops url:******]]] sharding:map[tables:[map[key:customer_id]]]]"
+	if got != want {
+		t.Errorf("Redact = %s\nwant %s", got, want)
+	}
+	if settings["azure"].([]any)[0].(map[string]any)["password"] != "secret" {
+		t.Error("original settings were modified")
+	}
+}
+
+func TestLoadRepoProfiles(t *testing.T) {
+	// 使用仓库自带的configs/目录，确认base.yaml已随profile一起提交
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer viper.Reset()
+
+	tests := []struct {
+		profile  string
+		workers  int
+		verbose  bool
+		logLevel string
+	}{
+		{"dev", 2, true, "debug"},
+		{"test", 1, true, "debug"},
+		{"prod", 5, false, "info"},
+	}
+	for _, tt := range tests {
+		viper.Reset()
+		if err := InitViper(LoadOptions{Profile: tt.profile}); err != nil {
+			t.Fatalf("profile %s: %v", tt.profile, err)
+		}
+		want := []string{filepath.Join("configs", "base.yaml"), filepath.Join("configs", tt.profile+".yaml")}
+		if got := LoadedLayers().Files; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("profile %s: files = %v, want %v", tt.profile, got, want)
+		}
+		cfg, err := GetConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.MaxWorkers != tt.workers || cfg.Verbose != tt.verbose || cfg.LogLevel != tt.logLevel {
+			t.Errorf("profile %s: max_workers=%d verbose=%v log_level=%q", tt.profile, cfg.MaxWorkers, cfg.Verbose, cfg.LogLevel)
+		}
+		if cfg.Serve.Addr != ":8080" {
+			t.Errorf("profile %s: serve.addr = %q, want base value", tt.profile, cfg.Serve.Addr)
+		}
+	}
+}
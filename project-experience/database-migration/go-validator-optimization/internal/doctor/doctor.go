@@ -0,0 +1,679 @@
+// internal/doctor/doctor.go
+// 连接与权限诊断
+
+package doctor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"multi-database-validator-optimization/internal/types"
+	"multi-database-validator-optimization/pkg/validator"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// 检查结果状态
+const (
+	StatusPass = "PASS"
+	StatusWarn = "WARN"
+	StatusFail = "FAIL"
+	StatusSkip = "SKIP"
+)
+
+// 检查项名称
+const (
+	CheckDNS               = "DNS解析"
+	CheckTCP               = "TCP连接"
+	CheckTLS               = "TLS握手"
+	CheckAuth              = "身份认证"
+	CheckSelect            = "SELECT权限"
+	CheckProcess           = "PROCESS权限"
+	CheckReplicationClient = "REPLICATION CLIENT权限"
+	CheckVersion           = "服务器版本"
+	CheckSQLMode           = "sql_mode"
+	CheckTimeZone          = "时区"
+	CheckCharset           = "字符集"
+	CheckFiles             = "数据文件"
+)
+
+// instanceChecks 单实例检查项，按执行顺序排列
+var instanceChecks = []string{
+	CheckDNS, CheckTCP, CheckTLS, CheckAuth,
+	CheckSelect, CheckProcess, CheckReplicationClient,
+	CheckVersion, CheckSQLMode, CheckTimeZone, CheckCharset,
+}
+
+// defaultPort MySQL默认端口
+const defaultPort = "3306"
+
+// CheckResult 单项检查结果
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"` // 修复建议
+}
+
+// InstanceReport 单个实例的检查报告
+type InstanceReport struct {
+	Side     string        `json:"side"` // azure 或 aws
+	Name     string        `json:"name"`
+	Address  string        `json:"address"`
+	Database string        `json:"database"`
+	Checks   []CheckResult `json:"checks"`
+
+	facts serverFacts
+	files bool // 文件数据源，没有服务器信息
+}
+
+// PairReport Azure与AWS实例之间的对比检查报告
+type PairReport struct {
+	AzureInstance string        `json:"azure_instance"`
+	AWSInstance   string        `json:"aws_instance"`
+	Checks        []CheckResult `json:"checks"`
+}
+
+// Report 诊断报告
+type Report struct {
+	Instances []InstanceReport `json:"instances"`
+	Pairs     []PairReport     `json:"pairs"`
+}
+
+// serverFacts 检查过程中收集的服务器信息，用于对比检查
+type serverFacts struct {
+	version        string
+	sqlMode        string
+	timeZoneOffset int // 相对UTC的分钟数
+	timeZone       string
+	charset        string
+	collation      string
+}
+
+// Passed 实例是否没有失败的检查项
+func (r InstanceReport) Passed() bool {
+	return !hasStatus(r.Checks, StatusFail)
+}
+
+// Failed 返回存在失败检查项的实例数
+func (r *Report) Failed() int {
+	failed := 0
+	for _, inst := range r.Instances {
+		if !inst.Passed() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Warnings 返回所有警告数量（包括对比检查）
+func (r *Report) Warnings() int {
+	warnings := 0
+	for _, inst := range r.Instances {
+		warnings += countStatus(inst.Checks, StatusWarn)
+	}
+	for _, pair := range r.Pairs {
+		warnings += countStatus(pair.Checks, StatusWarn)
+	}
+	return warnings
+}
+
+// Run 并发检查配置中的所有实例，并对比每个Azure/AWS实例对
+func Run(ctx context.Context, cfg *types.Config, timeout time.Duration) *Report {
+	report := &Report{
+		Instances: make([]InstanceReport, len(cfg.Azure)+len(cfg.AWS)),
+	}
+
+	var wg sync.WaitGroup
+	check := func(idx int, side string, inst validator.DatabaseInstance) {
+		defer wg.Done()
+		report.Instances[idx] = CheckInstance(ctx, side, inst, timeout)
+	}
+	for i, inst := range cfg.Azure {
+		wg.Add(1)
+		go check(i, "azure", inst)
+	}
+	for i, inst := range cfg.AWS {
+		wg.Add(1)
+		go check(len(cfg.Azure)+i, "aws", inst)
+	}
+	wg.Wait()
+
+	for i := 0; i < len(cfg.Azure) && i < len(cfg.AWS); i++ {
+		report.Pairs = append(report.Pairs, comparePair(report.Instances[i], report.Instances[len(cfg.Azure)+i]))
+	}
+
+	return report
+}
+
+// CheckInstance 依次检查单个实例，前置检查失败时跳过后续检查
+func CheckInstance(ctx context.Context, side string, inst validator.DatabaseInstance, timeout time.Duration) InstanceReport {
+	if inst.IsFiles() {
+		return checkFilesInstance(ctx, side, inst)
+	}
+
+	host, port := splitHostPort(inst.Host)
+	addr := net.JoinHostPort(host, port)
+	r := &InstanceReport{
+		Side:     side,
+		Name:     inst.Name,
+		Address:  addr,
+		Database: inst.Database,
+	}
+
+	if !r.checkDNS(ctx, host, timeout) || !r.checkTCP(ctx, addr, timeout) {
+		r.skipRemaining()
+		return *r
+	}
+
+	r.checkTLS(ctx, inst, addr, timeout)
+
+	db, ok := r.checkAuth(ctx, inst, addr, timeout)
+	if !ok {
+		r.skipRemaining()
+		return *r
+	}
+	defer db.Close()
+
+	r.checkPrivileges(ctx, db, inst)
+	r.checkVersion(ctx, db)
+	r.checkSQLMode(ctx, db)
+	r.checkTimeZone(ctx, db)
+	r.checkCharset(ctx, db, inst)
+
+	return *r
+}
+
+// checkFilesInstance 检查文件数据源：目录可读，每个表的数据文件可以解析出列名
+func checkFilesInstance(ctx context.Context, side string, inst validator.DatabaseInstance) InstanceReport {
+	r := &InstanceReport{Side: side, Name: inst.Name, Address: inst.Path, Database: inst.Database, files: true}
+
+	src, err := validator.OpenFiles(ctx, inst)
+	if err != nil {
+		r.add(CheckFiles, StatusFail, err.Error(), "检查path配置及目录读取权限")
+		return *r
+	}
+	defer src.Close()
+
+	tables, _ := src.Tables(ctx)
+	if len(tables) == 0 {
+		r.add(CheckFiles, StatusWarn, "目录中没有.csv/.tsv/.txt/.jsonl/.ndjson数据文件", "确认导出目录或在file.format中指定格式")
+		return *r
+	}
+
+	// 读取每个表的第一行，检查表头、schema文件和格式
+	for _, table := range tables {
+		rows, err := src.ReadRows(ctx, table, 0, 1)
+		if err == nil {
+			for rows.Next() {
+			}
+			err = rows.Err()
+			rows.Close()
+		}
+		if err != nil {
+			r.add(CheckFiles, StatusFail, fmt.Sprintf("表 %s: %v", table, err), "检查文件格式、表头或<表名>.schema.json")
+			return *r
+		}
+	}
+	r.add(CheckFiles, StatusPass, fmt.Sprintf("%d个表", len(tables)), "")
+	return *r
+}
+
+// add 添加检查结果
+func (r *InstanceReport) add(name, status, detail, hint string) {
+	r.Checks = append(r.Checks, CheckResult{Name: name, Status: status, Detail: detail, Hint: hint})
+}
+
+// skipRemaining 将尚未执行的检查项标记为跳过
+func (r *InstanceReport) skipRemaining() {
+	done := make(map[string]bool, len(r.Checks))
+	for _, c := range r.Checks {
+		done[c.Name] = true
+	}
+	for _, name := range instanceChecks {
+		if !done[name] {
+			r.add(name, StatusSkip, "前置检查未通过", "")
+		}
+	}
+}
+
+// checkDNS 解析主机名
+func (r *InstanceReport) checkDNS(ctx context.Context, host string, timeout time.Duration) bool {
+	if net.ParseIP(host) != nil {
+		r.add(CheckDNS, StatusPass, "IP地址，无需解析", "")
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		r.add(CheckDNS, StatusFail, err.Error(), "检查主机名拼写；私有终结点需在VPC/VNet内解析，必要时配置私有DNS区域")
+		return false
+	}
+	r.add(CheckDNS, StatusPass, strings.Join(addrs, ", "), "")
+	return true
+}
+
+// checkTCP 建立TCP连接
+func (r *InstanceReport) checkTCP(ctx context.Context, addr string, timeout time.Duration) bool {
+	dialer := net.Dialer{Timeout: timeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		r.add(CheckTCP, StatusFail, err.Error(), "检查安全组/防火墙规则是否放行当前客户端IP访问该端口，以及实例是否允许公网访问")
+		return false
+	}
+	conn.Close()
+	r.add(CheckTCP, StatusPass, fmt.Sprintf("连接耗时 %v", time.Since(start).Round(time.Millisecond)), "")
+	return true
+}
+
+// checkTLS 检查TLS握手，证书无法验证或服务器不支持TLS时给出警告
+func (r *InstanceReport) checkTLS(ctx context.Context, inst validator.DatabaseInstance, addr string, timeout time.Duration) {
+	version, err := tlsVersion(ctx, inst, addr, timeout, "true")
+	if err == nil {
+		r.add(CheckTLS, StatusPass, version, "")
+		return
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// 服务端在TLS握手完成后才会返回认证等错误
+		r.add(CheckTLS, StatusPass, "握手成功（后续认证失败，见身份认证检查）", "")
+		return
+	}
+	if errors.Is(err, mysql.ErrNoTLS) {
+		r.add(CheckTLS, StatusWarn, "服务器未启用TLS", "迁移链路经过公网时建议在服务器端启用TLS(require_secure_transport=ON)")
+		return
+	}
+
+	if version, skipErr := tlsVersion(ctx, inst, addr, timeout, "skip-verify"); skipErr == nil {
+		r.add(CheckTLS, StatusWarn, fmt.Sprintf("%s，但证书校验失败: %v", version, err),
+			"导入云厂商CA证书（Azure: DigiCert Global Root G2，AWS: RDS global bundle）到系统信任链")
+		return
+	}
+	r.add(CheckTLS, StatusWarn, err.Error(), "检查服务器TLS配置及客户端与服务器支持的TLS版本")
+}
+
+// tlsVersion 使用TLS建立连接并返回协商的TLS版本
+func tlsVersion(ctx context.Context, inst validator.DatabaseInstance, addr string, timeout time.Duration, tlsMode string) (string, error) {
+	db, err := openDB(inst, addr, timeout, tlsMode)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var name, version string
+	if err := db.QueryRowContext(ctx, "SHOW SESSION STATUS LIKE 'Ssl_version'").Scan(&name, &version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// checkAuth 使用与验证器一致的连接参数（不启用TLS）进行认证
+func (r *InstanceReport) checkAuth(ctx context.Context, inst validator.DatabaseInstance, addr string, timeout time.Duration) (*sql.DB, bool) {
+	db, err := openDB(inst, addr, timeout, "")
+	if err == nil {
+		err = db.PingContext(ctx)
+	}
+	if err != nil {
+		if db != nil {
+			db.Close()
+		}
+		r.add(CheckAuth, StatusFail, err.Error(), authHint(err))
+		return nil, false
+	}
+
+	var currentUser string
+	if err := db.QueryRowContext(ctx, "SELECT CURRENT_USER()").Scan(&currentUser); err != nil {
+		currentUser = inst.User
+	}
+	r.add(CheckAuth, StatusPass, "当前用户 "+currentUser, "")
+	return db, true
+}
+
+// authHint 根据MySQL错误码给出修复建议
+func authHint(err error) string {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return "检查网络连通性与连接参数"
+	}
+	switch mysqlErr.Number {
+	case 1045:
+		return "检查用户名和密码；Azure单服务器需使用 user@servername 格式的用户名；确认用户的host部分允许当前客户端IP"
+	case 1044:
+		return "用户没有访问该数据库的权限，执行 GRANT SELECT ON `db`.* TO 'user'@'host'"
+	case 1049:
+		return "数据库不存在，检查配置中的database名称"
+	case 3159:
+		return "服务器要求TLS连接(require_secure_transport=ON)，验证器连接需启用TLS"
+	case 1129:
+		return "客户端IP因连接错误过多被封禁，在服务器执行 FLUSH HOSTS 或调大max_connect_errors"
+	case 1040:
+		return "服务器连接数已满，调小max_workers或调大max_connections"
+	default:
+		return fmt.Sprintf("MySQL错误码 %d", mysqlErr.Number)
+	}
+}
+
+// grantee 当前用户在information_schema中的GRANTEE表示
+const grantee = `CONCAT('''', SUBSTRING_INDEX(CURRENT_USER(), '@', 1), '''@''', SUBSTRING_INDEX(CURRENT_USER(), '@', -1), '''')`
+
+// checkPrivileges 检查SELECT/PROCESS/REPLICATION CLIENT权限
+func (r *InstanceReport) checkPrivileges(ctx context.Context, db *sql.DB, inst validator.DatabaseInstance) {
+	global, err := queryStrings(ctx, db,
+		"SELECT PRIVILEGE_TYPE FROM information_schema.USER_PRIVILEGES WHERE GRANTEE = "+grantee)
+	if err != nil {
+		for _, name := range []string{CheckSelect, CheckProcess, CheckReplicationClient} {
+			r.add(name, StatusWarn, fmt.Sprintf("查询权限失败: %v", err), "")
+		}
+		return
+	}
+	schema, err := queryStrings(ctx, db,
+		"SELECT PRIVILEGE_TYPE FROM information_schema.SCHEMA_PRIVILEGES WHERE GRANTEE = "+grantee+" AND ? LIKE TABLE_SCHEMA",
+		inst.Database)
+	if err != nil {
+		schema = nil
+	}
+	tables, err := queryStrings(ctx, db,
+		"SELECT DISTINCT TABLE_NAME FROM information_schema.TABLE_PRIVILEGES WHERE GRANTEE = "+grantee+" AND TABLE_SCHEMA = ? AND PRIVILEGE_TYPE = 'SELECT'",
+		inst.Database)
+	if err != nil {
+		tables = nil
+	}
+	// MySQL 8的角色权限不出现在上面的视图中，5.7没有APPLICABLE_ROLES，查询失败时忽略
+	roles, err := queryStrings(ctx, db,
+		"SELECT CONCAT(ROLE_NAME, '@', ROLE_HOST) FROM information_schema.APPLICABLE_ROLES")
+	if err != nil {
+		roles = nil
+	}
+
+	grants := selectGrants{
+		global: containsFold(global, "SELECT"),
+		schema: containsFold(schema, "SELECT"),
+		tables: len(tables),
+		roles:  roles,
+	}
+	r.Checks = append(r.Checks, grants.result(inst.Database, inst.User))
+
+	if containsFold(global, "PROCESS") {
+		r.add(CheckProcess, StatusPass, "", "")
+	} else {
+		r.add(CheckProcess, StatusWarn, "缺少PROCESS权限，无法查看会话和InnoDB统计信息",
+			fmt.Sprintf("GRANT PROCESS ON *.* TO %s;", quoteUser(inst.User)))
+	}
+
+	if containsFold(global, "REPLICATION CLIENT") {
+		r.add(CheckReplicationClient, StatusPass, "", "")
+	} else {
+		r.add(CheckReplicationClient, StatusWarn, "缺少REPLICATION CLIENT权限，无法读取binlog位点和复制延迟",
+			fmt.Sprintf("GRANT REPLICATION CLIENT ON *.* TO %s;", quoteUser(inst.User)))
+	}
+}
+
+// selectGrants 在information_schema中找到的SELECT权限来源
+type selectGrants struct {
+	global bool
+	schema bool
+	tables int      // 有表级SELECT权限的表数
+	roles  []string // 授予当前用户的角色
+}
+
+// result 生成SELECT权限检查结果；表级权限和角色权限无法确认覆盖所有表，给出警告而不是失败
+func (g selectGrants) result(database, user string) CheckResult {
+	grant := fmt.Sprintf("GRANT SELECT ON `%s`.* TO %s;", database, quoteUser(user))
+	switch {
+	case g.global:
+		return CheckResult{Name: CheckSelect, Status: StatusPass, Detail: "全局SELECT权限"}
+	case g.schema:
+		return CheckResult{Name: CheckSelect, Status: StatusPass, Detail: fmt.Sprintf("数据库 %s 的SELECT权限", database)}
+	case g.tables > 0:
+		return CheckResult{Name: CheckSelect, Status: StatusWarn,
+			Detail: fmt.Sprintf("只有 %d 个表的表级SELECT权限，没有权限的表不会被验证", g.tables),
+			Hint:   "确认表级授权覆盖所有待验证表，或改为数据库级授权: " + grant}
+	case len(g.roles) > 0:
+		return CheckResult{Name: CheckSelect, Status: StatusWarn,
+			Detail: fmt.Sprintf("未找到直接授予的SELECT权限，已授予角色 %s", strings.Join(g.roles, ", ")),
+			Hint:   "确认角色包含SELECT权限且为默认角色(SET DEFAULT ROLE ALL TO 用户)，验证器连接不会执行SET ROLE"}
+	default:
+		return CheckResult{Name: CheckSelect, Status: StatusFail, Detail: "未找到全局、数据库级、表级或角色授予的SELECT权限", Hint: grant}
+	}
+}
+
+// checkVersion 检查服务器版本
+func (r *InstanceReport) checkVersion(ctx context.Context, db *sql.DB) {
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		r.add(CheckVersion, StatusWarn, err.Error(), "")
+		return
+	}
+	r.facts.version = version
+
+	major, minor := parseVersion(version)
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		r.add(CheckVersion, StatusWarn, version, "MariaDB与MySQL在information_schema和校验和函数上存在差异，结果需人工复核")
+		return
+	}
+	if major < 5 || (major == 5 && minor < 7) {
+		r.add(CheckVersion, StatusWarn, version, "MySQL 5.7以下版本未经验证，建议升级后再进行迁移验证")
+		return
+	}
+	r.add(CheckVersion, StatusPass, version, "")
+}
+
+// checkSQLMode 检查sql_mode是否为严格模式
+func (r *InstanceReport) checkSQLMode(ctx context.Context, db *sql.DB) {
+	var sqlMode string
+	if err := db.QueryRowContext(ctx, "SELECT @@GLOBAL.sql_mode").Scan(&sqlMode); err != nil {
+		r.add(CheckSQLMode, StatusWarn, err.Error(), "")
+		return
+	}
+	r.facts.sqlMode = sqlMode
+
+	detail := sqlMode
+	if detail == "" {
+		detail = "(空)"
+	}
+	if !strings.Contains(sqlMode, "STRICT_TRANS_TABLES") && !strings.Contains(sqlMode, "STRICT_ALL_TABLES") {
+		r.add(CheckSQLMode, StatusWarn, detail, "非严格模式下写入会静默截断或转换数据，迁移后容易出现校验和差异，建议启用STRICT_TRANS_TABLES")
+		return
+	}
+	r.add(CheckSQLMode, StatusPass, detail, "")
+}
+
+// checkTimeZone 检查时区设置
+func (r *InstanceReport) checkTimeZone(ctx context.Context, db *sql.DB) {
+	var timeZone, systemTimeZone string
+	var offset int
+	query := "SELECT @@GLOBAL.time_zone, @@system_time_zone, TIMESTAMPDIFF(MINUTE, UTC_TIMESTAMP(), NOW())"
+	if err := db.QueryRowContext(ctx, query).Scan(&timeZone, &systemTimeZone, &offset); err != nil {
+		r.add(CheckTimeZone, StatusWarn, err.Error(), "")
+		return
+	}
+	r.facts.timeZone = timeZone
+	r.facts.timeZoneOffset = offset
+
+	if timeZone == "SYSTEM" {
+		timeZone = "SYSTEM(" + systemTimeZone + ")"
+	}
+	r.add(CheckTimeZone, StatusPass, fmt.Sprintf("%s，UTC%+d分钟", timeZone, offset), "")
+}
+
+// checkCharset 检查服务器与数据库默认字符集
+func (r *InstanceReport) checkCharset(ctx context.Context, db *sql.DB, inst validator.DatabaseInstance) {
+	var charset, collation string
+	query := "SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?"
+	err := db.QueryRowContext(ctx, query, inst.Database).Scan(&charset, &collation)
+	if err == sql.ErrNoRows {
+		r.add(CheckCharset, StatusFail, fmt.Sprintf("数据库 %s 不存在或不可见", inst.Database), "检查配置中的database名称及用户权限")
+		return
+	}
+	if err != nil {
+		r.add(CheckCharset, StatusWarn, err.Error(), "")
+		return
+	}
+	r.facts.charset = charset
+	r.facts.collation = collation
+
+	detail := fmt.Sprintf("数据库默认 %s/%s", charset, collation)
+	switch {
+	case charset == "utf8" || charset == "utf8mb3":
+		r.add(CheckCharset, StatusWarn, detail, "utf8(utf8mb3)无法存储4字节字符，迁移到utf8mb4时需确认数据未被截断")
+	case inst.Charset != "" && !strings.EqualFold(inst.Charset, charset):
+		r.add(CheckCharset, StatusWarn, detail+"，连接字符集为 "+inst.Charset, "连接字符集与数据库字符集不一致可能导致字符转换，建议配置charset与数据库一致")
+	default:
+		r.add(CheckCharset, StatusPass, detail, "")
+	}
+}
+
+// comparePair 对比一对实例的服务器设置
+func comparePair(azure, aws InstanceReport) PairReport {
+	pair := PairReport{AzureInstance: azure.Name, AWSInstance: aws.Name}
+	add := func(name, status, detail, hint string) {
+		pair.Checks = append(pair.Checks, CheckResult{Name: name, Status: status, Detail: detail, Hint: hint})
+	}
+
+	a, b := azure.facts, aws.facts
+	if azure.files || aws.files {
+		add(CheckVersion, StatusSkip, "文件数据源没有服务器配置可对比", "")
+		return pair
+	}
+	if a.version == "" || b.version == "" {
+		add(CheckVersion, StatusSkip, "实例检查未完成", "")
+		return pair
+	}
+
+	aMajor, aMinor := parseVersion(a.version)
+	bMajor, bMinor := parseVersion(b.version)
+	if aMajor != bMajor || aMinor != bMinor {
+		add(CheckVersion, StatusWarn, fmt.Sprintf("%s vs %s", a.version, b.version), "主版本不同时默认排序规则和浮点格式可能不同，校验和差异需结合版本复核")
+	} else {
+		add(CheckVersion, StatusPass, fmt.Sprintf("%s vs %s", a.version, b.version), "")
+	}
+
+	if a.sqlMode != b.sqlMode {
+		add(CheckSQLMode, StatusWarn, fmt.Sprintf("%q vs %q", a.sqlMode, b.sqlMode), "两端sql_mode不一致，可能导致迁移写入行为不同")
+	} else {
+		add(CheckSQLMode, StatusPass, "一致", "")
+	}
+
+	if a.timeZoneOffset != b.timeZoneOffset {
+		add(CheckTimeZone, StatusWarn, fmt.Sprintf("UTC%+d分钟 vs UTC%+d分钟", a.timeZoneOffset, b.timeZoneOffset),
+			"时区不一致会导致TIMESTAMP列读出的值不同而产生误报，建议两端统一time_zone")
+	} else {
+		add(CheckTimeZone, StatusPass, "一致", "")
+	}
+
+	if a.charset != b.charset || a.collation != b.collation {
+		add(CheckCharset, StatusWarn, fmt.Sprintf("%s/%s vs %s/%s", a.charset, a.collation, b.charset, b.collation),
+			"数据库默认字符集或排序规则不一致，ORDER BY结果和字符比较可能不同")
+	} else {
+		add(CheckCharset, StatusPass, "一致", "")
+	}
+
+	return pair
+}
+
+// openDB 根据实例配置打开连接，tlsMode为空时不启用TLS
+func openDB(inst validator.DatabaseInstance, addr string, timeout time.Duration, tlsMode string) (*sql.DB, error) {
+	cfg := mysql.NewConfig()
+	cfg.User = inst.User
+	cfg.Passwd = inst.Password
+	cfg.Net = "tcp"
+	cfg.Addr = addr
+	cfg.DBName = inst.Database
+	cfg.Timeout = timeout
+	cfg.ReadTimeout = timeout
+	cfg.WriteTimeout = timeout
+	cfg.TLSConfig = tlsMode
+	if inst.Charset != "" {
+		cfg.Params = map[string]string{"charset": inst.Charset}
+	}
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// splitHostPort 拆分主机和端口，未指定端口时使用3306
+func splitHostPort(hostport string) (string, string) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return strings.Trim(hostport, "[]"), defaultPort
+	}
+	return host, port
+}
+
+// parseVersion 解析主次版本号，如 "8.0.35-log" 返回 8, 0
+func parseVersion(version string) (int, int) {
+	parts := strings.SplitN(version, ".", 3)
+	major, _ := strconv.Atoi(parts[0])
+	minor := 0
+	if len(parts) > 1 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	return major, minor
+}
+
+// queryStrings 执行查询并返回第一列的所有值
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// quoteUser 生成GRANT语句中的用户名，Azure单服务器的user@server格式只取用户部分
+func quoteUser(user string) string {
+	name, _, _ := strings.Cut(user, "@")
+	return fmt.Sprintf("'%s'@'%%'", name)
+}
+
+// containsFold 忽略大小写判断切片是否包含指定元素
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasStatus 判断检查结果中是否存在指定状态
+func hasStatus(checks []CheckResult, status string) bool {
+	return countStatus(checks, status) > 0
+}
+
+// countStatus 统计指定状态的检查项数量
+func countStatus(checks []CheckResult, status string) int {
+	n := 0
+	for _, c := range checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
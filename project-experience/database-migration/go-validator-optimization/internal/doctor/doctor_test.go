@@ -0,0 +1,180 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version      string
+		major, minor int
+	}{
+		{"8.0.35-log", 8, 0},
+		{"5.7.44", 5, 7},
+		{"10.11.6-MariaDB", 10, 11},
+		{"8", 8, 0},
+		{"", 0, 0},
+		{"abc", 0, 0},
+	}
+	for _, tt := range tests {
+		major, minor := parseVersion(tt.version)
+		if major != tt.major || minor != tt.minor {
+			t.Errorf("parseVersion(%q) = %d, %d, 期望 %d, %d", tt.version, major, minor, tt.major, tt.minor)
+		}
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+	tests := []struct {
+		in, host, port string
+	}{
+		{"db.example.com", "db.example.com", "3306"},
+		{"db.example.com:3307", "db.example.com", "3307"},
+		{"10.0.0.1", "10.0.0.1", "3306"},
+		{"[::1]:3308", "::1", "3308"},
+		{"[::1]", "::1", "3306"},
+		{"mysql.mysql.database.azure.com:3306", "mysql.mysql.database.azure.com", "3306"},
+	}
+	for _, tt := range tests {
+		host, port := splitHostPort(tt.in)
+		if host != tt.host || port != tt.port {
+			t.Errorf("splitHostPort(%q) = %q, %q, 期望 %q, %q", tt.in, host, port, tt.host, tt.port)
+		}
+	}
+}
+
+func TestAuthHint(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&mysql.MySQLError{Number: 1045}, "user@servername"},
+		{&mysql.MySQLError{Number: 1044}, "GRANT SELECT"},
+		{&mysql.MySQLError{Number: 1049}, "数据库不存在"},
+		{&mysql.MySQLError{Number: 3159}, "require_secure_transport"},
+		{&mysql.MySQLError{Number: 1129}, "FLUSH HOSTS"},
+		{&mysql.MySQLError{Number: 1040}, "max_connections"},
+		{&mysql.MySQLError{Number: 2013}, "MySQL错误码 2013"},
+		{fmt.Errorf("连接失败: %w", &mysql.MySQLError{Number: 1045}), "user@servername"},
+		{errors.New("i/o timeout"), "检查网络连通性"},
+	}
+	for _, tt := range tests {
+		if got := authHint(tt.err); !strings.Contains(got, tt.want) {
+			t.Errorf("authHint(%v) = %q, 期望包含 %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteUser(t *testing.T) {
+	tests := map[string]string{
+		"validator":            "'validator'@'%'",
+		"validator@myserver":   "'validator'@'%'",
+		"validator@host@extra": "'validator'@'%'",
+		"":                     "''@'%'",
+	}
+	for in, want := range tests {
+		if got := quoteUser(in); got != want {
+			t.Errorf("quoteUser(%q) = %s, 期望 %s", in, got, want)
+		}
+	}
+}
+
+func TestSkipRemaining(t *testing.T) {
+	r := &InstanceReport{}
+	r.add(CheckDNS, StatusPass, "", "")
+	r.add(CheckTCP, StatusFail, "connection refused", "")
+	r.skipRemaining()
+
+	if len(r.Checks) != len(instanceChecks) {
+		t.Fatalf("检查项数量 = %d, 期望 %d", len(r.Checks), len(instanceChecks))
+	}
+	for i, c := range r.Checks {
+		if c.Name != instanceChecks[i] {
+			t.Errorf("第%d项 = %s, 期望 %s", i, c.Name, instanceChecks[i])
+		}
+		if i >= 2 && c.Status != StatusSkip {
+			t.Errorf("%s 状态 = %s, 期望SKIP", c.Name, c.Status)
+		}
+	}
+	if r.Passed() {
+		t.Error("TCP失败的实例不应通过")
+	}
+}
+
+func TestSelectGrants(t *testing.T) {
+	tests := []struct {
+		name   string
+		grants selectGrants
+		status string
+	}{
+		{"全局", selectGrants{global: true}, StatusPass},
+		{"数据库级", selectGrants{schema: true, tables: 3}, StatusPass},
+		{"表级", selectGrants{tables: 3}, StatusWarn},
+		{"角色", selectGrants{roles: []string{"readonly@%"}}, StatusWarn},
+		{"无权限", selectGrants{}, StatusFail},
+	}
+	for _, tt := range tests {
+		got := tt.grants.result("app", "validator@myserver")
+		if got.Name != CheckSelect || got.Status != tt.status {
+			t.Errorf("%s: %+v, 期望 %s", tt.name, got, tt.status)
+		}
+		if got.Status == StatusFail && got.Hint != "GRANT SELECT ON `app`.* TO 'validator'@'%';" {
+			t.Errorf("%s: 修复建议 = %q", tt.name, got.Hint)
+		}
+	}
+}
+
+func TestComparePair(t *testing.T) {
+	base := serverFacts{
+		version:        "8.0.35",
+		sqlMode:        "STRICT_TRANS_TABLES",
+		timeZoneOffset: 0,
+		timeZone:       "+00:00",
+		charset:        "utf8mb4",
+		collation:      "utf8mb4_0900_ai_ci",
+	}
+	statuses := func(p PairReport) string {
+		var parts []string
+		for _, c := range p.Checks {
+			parts = append(parts, c.Name+"="+c.Status)
+		}
+		return strings.Join(parts, ",")
+	}
+
+	tests := []struct {
+		name   string
+		modify func(f *serverFacts)
+		files  bool
+		want   string
+	}{
+		{"一致", func(f *serverFacts) { f.version = "8.0.36" }, false,
+			"服务器版本=PASS,sql_mode=PASS,时区=PASS,字符集=PASS"},
+		{"全部不同", func(f *serverFacts) {
+			f.version = "5.7.44"
+			f.sqlMode = ""
+			f.timeZoneOffset = 480
+			f.collation = "utf8mb4_general_ci"
+		}, false, "服务器版本=WARN,sql_mode=WARN,时区=WARN,字符集=WARN"},
+		{"检查未完成", func(f *serverFacts) { f.version = "" }, false, "服务器版本=SKIP"},
+		{"文件数据源", func(f *serverFacts) {}, true, "服务器版本=SKIP"},
+	}
+	for _, tt := range tests {
+		aws := base
+		tt.modify(&aws)
+		pair := comparePair(
+			InstanceReport{Name: "azure-1", facts: base},
+			InstanceReport{Name: "aws-1", facts: aws, files: tt.files},
+		)
+		if pair.AzureInstance != "azure-1" || pair.AWSInstance != "aws-1" {
+			t.Errorf("%s: 实例名称 = %s/%s", tt.name, pair.AzureInstance, pair.AWSInstance)
+		}
+		if got := statuses(pair); got != tt.want {
+			t.Errorf("%s: %s, 期望 %s", tt.name, got, tt.want)
+		}
+	}
+}
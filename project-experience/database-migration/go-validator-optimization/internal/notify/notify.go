@@ -0,0 +1,362 @@
+// internal/notify/notify.go
+// 验证结果通知：按严重级别路由到各通知渠道，只在状态变化时通知
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"multi-database-validator-optimization/pkg/validator"
+)
+
+// 严重级别
+const (
+	SeverityOK       = "ok"       // 全部一致
+	SeverityWarning  = "warning"  // 有警告，如仅AWS中存在的数据库
+	SeverityCritical = "critical" // 数据不一致、缺少数据库或验证出错
+)
+
+// 通知渠道类型
+const (
+	TypeWebhook = "webhook" // 通用webhook，请求体由模板生成
+	TypeChat    = "chat"    // 聊天机器人webhook
+	TypeSMTP    = "smtp"    // 邮件
+	TypeCommand = "command" // 本地命令
+)
+
+// defaultTimeout 单次通知的默认超时时间
+const defaultTimeout = 10 * time.Second
+
+// Config 通知配置
+type Config struct {
+	StateFile string       `json:"state_file,omitempty" yaml:"state_file,omitempty" mapstructure:"state_file"` // 去重状态文件，默认为输出目录下的notify_state.json
+	Sinks     []SinkConfig `json:"sinks" yaml:"sinks" mapstructure:"sinks"`
+}
+
+// SinkConfig 通知渠道配置
+type SinkConfig struct {
+	Name       string   `json:"name" yaml:"name" mapstructure:"name"`
+	Type       string   `json:"type" yaml:"type" mapstructure:"type"`                                       // webhook、chat、smtp、command
+	Severities []string `json:"severities,omitempty" yaml:"severities,omitempty" mapstructure:"severities"` // 需要通知的级别，默认全部
+	Always     bool     `json:"always,omitempty" yaml:"always,omitempty" mapstructure:"always"`             // 每次运行都通知，不去重
+	Timeout    string   `json:"timeout,omitempty" yaml:"timeout,omitempty" mapstructure:"timeout"`          // 超时时间，默认10s
+
+	// webhook和chat
+	URL     string            `json:"url,omitempty" yaml:"url,omitempty" mapstructure:"url"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty" mapstructure:"headers"`
+	Body    string            `json:"body,omitempty" yaml:"body,omitempty" mapstructure:"body"`       // webhook请求体模板(text/template)，默认为通知内容的JSON
+	Format  string            `json:"format,omitempty" yaml:"format,omitempty" mapstructure:"format"` // chat消息格式: slack(默认)、dingtalk、wecom、feishu
+
+	// smtp
+	Host     string   `json:"host,omitempty" yaml:"host,omitempty" mapstructure:"host"`
+	Port     int      `json:"port,omitempty" yaml:"port,omitempty" mapstructure:"port"` // 默认25
+	Username string   `json:"username,omitempty" yaml:"username,omitempty" mapstructure:"username"`
+	Password string   `json:"password,omitempty" yaml:"password,omitempty" mapstructure:"password"`
+	From     string   `json:"from,omitempty" yaml:"from,omitempty" mapstructure:"from"`
+	To       []string `json:"to,omitempty" yaml:"to,omitempty" mapstructure:"to"`
+
+	// command，通知内容的JSON写入标准输入
+	Command []string `json:"command,omitempty" yaml:"command,omitempty" mapstructure:"command"`
+}
+
+// Validate 检查通知配置
+func (c Config) Validate() error {
+	names := make(map[string]bool, len(c.Sinks))
+	for _, s := range c.Sinks {
+		if s.Name == "" {
+			return fmt.Errorf("通知渠道缺少name")
+		}
+		if names[s.Name] {
+			return fmt.Errorf("通知渠道 %s 重复", s.Name)
+		}
+		names[s.Name] = true
+		if _, err := newSink(s); err != nil {
+			return fmt.Errorf("通知渠道 %s: %v", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// routes 渠道是否需要通知该级别
+func (s SinkConfig) routes(severity string) bool {
+	return len(s.Severities) == 0 || contains(s.Severities, severity)
+}
+
+// timeout 解析超时时间
+func (s SinkConfig) timeout() (time.Duration, error) {
+	if s.Timeout == "" {
+		return defaultTimeout, nil
+	}
+	d, err := time.ParseDuration(s.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("无效的timeout: %s", s.Timeout)
+	}
+	return d, nil
+}
+
+// Outcome 一次验证运行的结果，是各渠道通知内容和模板的数据
+type Outcome struct {
+	RunID                 string   `json:"run_id,omitempty"`
+	Trigger               string   `json:"trigger,omitempty"` // 触发方式，如 cli、manual、schedule:nightly
+	Severity              string   `json:"severity"`
+	PreviousSeverity      string   `json:"previous_severity,omitempty"` // 上一次通知时的级别，首次通知为空
+	Title                 string   `json:"title"`
+	Time                  string   `json:"time"`
+	TotalDatabases        int      `json:"total_databases"`
+	InconsistentDatabases int      `json:"inconsistent_databases"`
+	ErrorDatabases        int      `json:"error_databases"`
+	Problems              []string `json:"problems,omitempty"` // 不一致或出错的对象，如 db1.orders
+	Warnings              []string `json:"warnings,omitempty"`
+	ReportFile            string   `json:"report_file,omitempty"`
+	Error                 string   `json:"error,omitempty"` // 验证运行本身失败的原因
+}
+
+// FromReport 根据验证报告生成通知内容，report为nil时表示运行失败
+func FromReport(report *validator.Report, runErr error) Outcome {
+	o := Outcome{Time: time.Now().Format(time.RFC3339)}
+	if runErr != nil {
+		o.Error = runErr.Error()
+		o.Problems = append(o.Problems, "验证运行失败: "+o.Error)
+	}
+	if report != nil {
+		o.TotalDatabases = report.TotalDatabases
+		o.InconsistentDatabases = report.InconsistentDatabases
+		o.ErrorDatabases = report.ErrorDatabases
+
+		databases := make([]string, 0, len(report.Results))
+		for name := range report.Results {
+			databases = append(databases, name)
+		}
+		sort.Strings(databases)
+		for _, name := range databases {
+			result := report.Results[name]
+			switch result.Status {
+			case validator.StatusInconsistent:
+				tables := 0
+				for _, c := range result.TableComparisons {
+					if !c.Match {
+						o.Problems = append(o.Problems, name+"."+c.Table)
+						tables++
+					}
+				}
+				if tables == 0 {
+					o.Problems = append(o.Problems, name)
+				}
+			case validator.StatusError:
+				o.Problems = append(o.Problems, name+" (验证错误)")
+			case validator.StatusWarning:
+				o.Warnings = append(o.Warnings, name)
+			}
+		}
+		for _, db := range report.MissingDatabases {
+			o.Problems = append(o.Problems, db+" (AWS中缺少)")
+		}
+		for _, db := range report.ExtraDatabases {
+			o.Warnings = append(o.Warnings, db+" (仅AWS中存在)")
+		}
+		for _, s := range report.ShardedTables {
+			if s.Status != validator.TableMatch {
+				o.Problems = append(o.Problems, "分片表 "+s.Name)
+			}
+		}
+		for _, g := range report.Grants {
+			if g.Error != "" || !g.Match {
+				o.Problems = append(o.Problems, fmt.Sprintf("用户 %s 权限 (%s)", g.User, g.AWSInstance))
+			}
+		}
+	}
+
+	switch {
+	case len(o.Problems) > 0:
+		o.Severity = SeverityCritical
+	case len(o.Warnings) > 0:
+		o.Severity = SeverityWarning
+	default:
+		o.Severity = SeverityOK
+	}
+	return o
+}
+
+// fingerprint 结果的状态标识，级别和问题对象都相同时视为状态未变化
+func (o Outcome) fingerprint() string {
+	return o.Severity + "|" + strings.Join(o.Problems, ",") + "|" + strings.Join(o.Warnings, ",")
+}
+
+// withTitle 填充标题
+func (o Outcome) withTitle() Outcome {
+	switch {
+	case o.Error != "":
+		o.Title = "数据一致性验证失败"
+	case o.Severity == SeverityCritical:
+		o.Title = fmt.Sprintf("数据一致性验证发现问题: %d 个数据库不一致，%d 个验证错误", o.InconsistentDatabases, o.ErrorDatabases)
+	case o.Severity == SeverityWarning:
+		o.Title = "数据一致性验证有警告"
+	case o.PreviousSeverity != "" && o.PreviousSeverity != SeverityOK:
+		o.Title = "数据一致性已恢复"
+	default:
+		o.Title = "数据一致性验证通过"
+	}
+	o.Title = "[" + o.Severity + "] " + o.Title
+	return o
+}
+
+// Text 通知的纯文本内容
+func (o Outcome) Text() string {
+	var b strings.Builder
+	b.WriteString(o.Title)
+	fmt.Fprintf(&b, "\n时间: %s", o.Time)
+	if o.Trigger != "" {
+		fmt.Fprintf(&b, "\n触发方式: %s", o.Trigger)
+	}
+	fmt.Fprintf(&b, "\n数据库: %d，不一致: %d，错误: %d", o.TotalDatabases, o.InconsistentDatabases, o.ErrorDatabases)
+	for _, p := range o.Problems {
+		fmt.Fprintf(&b, "\n- %s", p)
+	}
+	for _, w := range o.Warnings {
+		fmt.Fprintf(&b, "\n- ⚠️ %s", w)
+	}
+	if o.ReportFile != "" {
+		fmt.Fprintf(&b, "\n报告: %s", o.ReportFile)
+	}
+	return b.String()
+}
+
+// sinkState 渠道上一次通知的状态
+type sinkState struct {
+	Fingerprint string `json:"fingerprint"`
+	Severity    string `json:"severity"`
+	Time        string `json:"time"`
+}
+
+// Notifier 将验证结果发送到配置的通知渠道
+type Notifier struct {
+	mu        sync.Mutex
+	sinks     []SinkConfig
+	senders   map[string]sender
+	stateFile string
+}
+
+// New 创建通知器，stateFile为空时不持久化去重状态
+func New(cfg Config, stateFile string) (*Notifier, error) {
+	if cfg.StateFile != "" {
+		stateFile = cfg.StateFile
+	}
+	n := &Notifier{sinks: cfg.Sinks, senders: make(map[string]sender, len(cfg.Sinks)), stateFile: stateFile}
+	for _, s := range cfg.Sinks {
+		sender, err := newSink(s)
+		if err != nil {
+			return nil, fmt.Errorf("通知渠道 %s: %v", s.Name, err)
+		}
+		n.senders[s.Name] = sender
+	}
+	return n, nil
+}
+
+// Notify 发送通知，返回已通知的渠道
+// 每个渠道独立记录状态：状态未变化或级别未路由到该渠道时不通知，发送失败时不更新状态，下次运行重试
+func (n *Notifier) Notify(ctx context.Context, o Outcome) ([]string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	states, err := n.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	var sent []string
+	var errs []error
+	fingerprint := o.fingerprint()
+	for _, s := range n.sinks {
+		prev, seen := states[s.Name]
+		if !seen {
+			// 首次运行时视为之前一切正常，一致的结果不通知
+			prev = sinkState{Fingerprint: Outcome{Severity: SeverityOK}.fingerprint()}
+		}
+		changed := prev.Fingerprint != fingerprint
+		current := sinkState{Fingerprint: fingerprint, Severity: o.Severity, Time: o.Time}
+		if !s.routes(o.Severity) || (!changed && !s.Always) {
+			states[s.Name] = current
+			continue
+		}
+
+		out := o
+		out.PreviousSeverity = prev.Severity
+		if err := n.send(ctx, s, out.withTitle()); err != nil {
+			errs = append(errs, fmt.Errorf("通知渠道 %s: %v", s.Name, err))
+			continue
+		}
+		states[s.Name] = current
+		sent = append(sent, s.Name)
+	}
+
+	if err := n.saveState(states); err != nil {
+		errs = append(errs, err)
+	}
+	return sent, errors.Join(errs...)
+}
+
+// send 在渠道超时时间内发送一条通知
+func (n *Notifier) send(ctx context.Context, s SinkConfig, o Outcome) error {
+	timeout, err := s.timeout()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return n.senders[s.Name].send(ctx, o)
+}
+
+// loadState 读取去重状态，文件不存在时返回空状态
+func (n *Notifier) loadState() (map[string]sinkState, error) {
+	states := make(map[string]sinkState)
+	if n.stateFile == "" {
+		return states, nil
+	}
+	data, err := os.ReadFile(n.stateFile)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取通知状态失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("解析通知状态失败 %s: %v", n.stateFile, err)
+	}
+	return states, nil
+}
+
+// saveState 保存去重状态
+func (n *Notifier) saveState(states map[string]sinkState) error {
+	if n.stateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化通知状态失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(n.stateFile), 0755); err != nil {
+		return fmt.Errorf("保存通知状态失败: %v", err)
+	}
+	if err := os.WriteFile(n.stateFile, data, 0644); err != nil {
+		return fmt.Errorf("保存通知状态失败: %v", err)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
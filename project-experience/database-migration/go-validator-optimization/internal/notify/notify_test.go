@@ -0,0 +1,303 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"multi-database-validator-optimization/pkg/validator"
+)
+
+// report 生成测试用的验证报告，mismatched为不一致的表
+func report(mismatched ...string) *validator.Report {
+	result := validator.DatabaseResult{Database: "db1", Status: validator.StatusSuccess}
+	for _, table := range mismatched {
+		result.Status = validator.StatusInconsistent
+		result.TableComparisons = append(result.TableComparisons, validator.TableComparison{Table: table})
+	}
+	r := &validator.Report{TotalDatabases: 1, Results: map[string]validator.DatabaseResult{"db1": result}}
+	if len(mismatched) > 0 {
+		r.InconsistentDatabases = 1
+	}
+	return r
+}
+
+// recorder 记录收到的HTTP请求体
+type recorder struct {
+	mu     sync.Mutex
+	bodies []string
+	status int
+}
+
+func (rec *recorder) server(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		if rec.status != 0 {
+			w.WriteHeader(rec.status)
+			return
+		}
+		rec.bodies = append(rec.bodies, r.Header.Get("X-Token")+" "+string(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (rec *recorder) setStatus(status int) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.status = status
+}
+
+func (rec *recorder) take() []string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	bodies := rec.bodies
+	rec.bodies = nil
+	return bodies
+}
+
+func TestNotifyRoutingAndDedup(t *testing.T) {
+	webhook, chat := &recorder{}, &recorder{}
+	webhookURL, chatURL := webhook.server(t).URL, chat.server(t).URL
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	cfg := Config{Sinks: []SinkConfig{
+		{
+			Name:    "hook",
+			Type:    TypeWebhook,
+			URL:     webhookURL,
+			Headers: map[string]string{"X-Token": "t1"},
+			Body:    `{"level": {{json .Severity}}, "problems": {{json .Problems}}}`,
+		},
+		{Name: "ding", Type: TypeChat, URL: chatURL, Format: "dingtalk", Severities: []string{SeverityCritical}},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	notify := func(r *validator.Report) []string {
+		// 每次运行重新创建，去重状态通过文件保存
+		n, err := New(cfg, stateFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sent, err := n.Notify(context.Background(), FromReport(r, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sent
+	}
+
+	// 首次运行一致时不通知
+	if sent := notify(report()); sent != nil {
+		t.Errorf("sent = %v", sent)
+	}
+
+	if sent := notify(report("orders")); fmt.Sprint(sent) != "[hook ding]" {
+		t.Errorf("sent = %v", sent)
+	}
+	if got := fmt.Sprint(webhook.take()); got != `[t1 {"level": "critical", "problems": ["db1.orders"]}]` {
+		t.Errorf("webhook = %s", got)
+	}
+	var msg struct {
+		MsgType string `json:"msgtype"`
+		Text    struct{ Content string }
+	}
+	bodies := chat.take()
+	if len(bodies) != 1 || json.Unmarshal([]byte(strings.TrimSpace(bodies[0])), &msg) != nil {
+		t.Fatalf("chat = %v", bodies)
+	}
+	if msg.MsgType != "text" || !strings.HasPrefix(msg.Text.Content, "[critical] ") || !strings.Contains(msg.Text.Content, "- db1.orders") {
+		t.Errorf("chat message = %+v", msg)
+	}
+
+	// 状态未变化时不重复通知，出现新的不一致表时再次通知
+	if sent := notify(report("orders")); sent != nil {
+		t.Errorf("repeat sent = %v", sent)
+	}
+	if sent := notify(report("orders", "users")); fmt.Sprint(sent) != "[hook ding]" {
+		t.Errorf("sent = %v", sent)
+	}
+
+	// 恢复时只通知订阅了ok级别的渠道
+	if sent := notify(report()); fmt.Sprint(sent) != "[hook]" {
+		t.Errorf("recovery sent = %v", sent)
+	}
+	chat.take()
+	var recovery map[string]any
+	if bodies := webhook.take(); len(bodies) != 2 || json.Unmarshal([]byte(strings.TrimPrefix(bodies[1], "t1 ")), &recovery) != nil || recovery["level"] != "ok" {
+		t.Errorf("webhook = %v", bodies)
+	}
+
+	// 发送失败不更新状态，下次运行重试
+	chat.setStatus(http.StatusInternalServerError)
+	n, _ := New(cfg, stateFile)
+	if _, err := n.Notify(context.Background(), FromReport(report("orders"), nil)); err == nil || !strings.Contains(err.Error(), "响应状态 500") {
+		t.Errorf("err = %v", err)
+	}
+	chat.setStatus(0)
+	if sent := notify(report("orders")); fmt.Sprint(sent) != "[ding]" {
+		t.Errorf("retry sent = %v", sent)
+	}
+}
+
+func TestNotifyRunFailure(t *testing.T) {
+	o := FromReport(nil, errors.New("连接失败"))
+	if o.Severity != SeverityCritical || o.withTitle().Title != "[critical] 数据一致性验证失败" {
+		t.Errorf("outcome = %+v", o)
+	}
+	if o := FromReport(&validator.Report{ExtraDatabases: []string{"tmp"}}, nil); o.Severity != SeverityWarning {
+		t.Errorf("severity = %s", o.Severity)
+	}
+}
+
+// smtpServer 测试用的SMTP服务器，记录收到的邮件
+func smtpServer(t *testing.T) (string, chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+		reply("220 localhost ESMTP")
+		var envelope []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				envelope = append(envelope, strings.TrimSpace(line))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				messages <- strings.Join(envelope, "\n") + "\n" + data.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), messages
+}
+
+func TestSMTPSink(t *testing.T) {
+	addr, messages := smtpServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+	var portNum int
+	fmt.Sscan(port, &portNum)
+
+	n, err := New(Config{Sinks: []SinkConfig{{
+		Name: "mail",
+		Type: TypeSMTP,
+		Host: host,
+		Port: portNum,
+		From: "validator@example.com",
+		To:   []string{"dba@example.com", "ops@example.com"},
+	}}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Notify(context.Background(), FromReport(report("orders"), nil)); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-messages
+	for _, want := range []string{
+		"MAIL FROM:<validator@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"Subject: =?UTF-8?b?",
+		"Content-Type: text/plain; charset=UTF-8",
+		"- db1.orders",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestCommandSink(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	n, err := New(Config{Sinks: []SinkConfig{{
+		Name:    "hook",
+		Type:    TypeCommand,
+		Command: []string{"sh", "-c", `echo "$MDV_NOTIFY_SEVERITY" > "$0"; cat >> "$0"`, out},
+		Always:  true,
+	}}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := FromReport(report("orders"), nil)
+	o.RunID = "run-1"
+	if _, err := n.Notify(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	severity, body, _ := strings.Cut(string(data), "\n")
+	var got Outcome
+	if severity != SeverityCritical || json.Unmarshal([]byte(body), &got) != nil || got.RunID != "run-1" {
+		t.Errorf("command output = %s", data)
+	}
+
+	failing, _ := New(Config{Sinks: []SinkConfig{{Name: "bad", Type: TypeCommand, Command: []string{"sh", "-c", "echo boom >&2; exit 3"}}}}, "")
+	if _, err := failing.Notify(context.Background(), o); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		sink SinkConfig
+		want string
+	}{
+		{SinkConfig{Name: "a", Type: "pager"}, "未知的type"},
+		{SinkConfig{Name: "a", Type: TypeWebhook}, "缺少url"},
+		{SinkConfig{Name: "a", Type: TypeWebhook, URL: "http://x", Body: "{{.Nope"}, "解析body模板失败"},
+		{SinkConfig{Name: "a", Type: TypeChat, URL: "http://x", Format: "irc"}, "未知的format"},
+		{SinkConfig{Name: "a", Type: TypeSMTP, Host: "h"}, "smtp需要"},
+		{SinkConfig{Name: "a", Type: TypeCommand, Severities: []string{"info"}}, "未知的级别"},
+		{SinkConfig{Name: "a", Type: TypeCommand, Command: []string{"true"}, Timeout: "soon"}, "无效的timeout"},
+	}
+	for _, tt := range tests {
+		err := Config{Sinks: []SinkConfig{tt.sink}}.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.sink, err, tt.want)
+		}
+	}
+}
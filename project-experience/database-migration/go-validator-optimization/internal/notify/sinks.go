@@ -0,0 +1,263 @@
+// internal/notify/sinks.go
+// 通知渠道：webhook、聊天机器人、邮件和本地命令
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// sender 发送一条通知
+type sender interface {
+	send(ctx context.Context, o Outcome) error
+}
+
+// newSink 根据配置创建通知渠道
+func newSink(s SinkConfig) (sender, error) {
+	for _, severity := range s.Severities {
+		if severity != SeverityOK && severity != SeverityWarning && severity != SeverityCritical {
+			return nil, fmt.Errorf("未知的级别 %s，可选: ok、warning、critical", severity)
+		}
+	}
+	if _, err := s.timeout(); err != nil {
+		return nil, err
+	}
+
+	switch s.Type {
+	case TypeWebhook:
+		if s.URL == "" {
+			return nil, fmt.Errorf("缺少url")
+		}
+		body := defaultBody
+		if s.Body != "" {
+			var err error
+			if body, err = template.New(s.Name).Funcs(template.FuncMap{"json": toJSON}).Parse(s.Body); err != nil {
+				return nil, fmt.Errorf("解析body模板失败: %v", err)
+			}
+		}
+		return &webhookSink{url: s.URL, headers: s.Headers, body: body}, nil
+	case TypeChat:
+		if s.URL == "" {
+			return nil, fmt.Errorf("缺少url")
+		}
+		if _, ok := chatFormats[s.formatOrDefault()]; !ok {
+			return nil, fmt.Errorf("未知的format %s，可选: slack、dingtalk、wecom、feishu", s.Format)
+		}
+		return &chatSink{url: s.URL, headers: s.Headers, format: s.formatOrDefault()}, nil
+	case TypeSMTP:
+		if s.Host == "" || s.From == "" || len(s.To) == 0 {
+			return nil, fmt.Errorf("smtp需要host、from和to")
+		}
+		port := s.Port
+		if port == 0 {
+			port = 25
+		}
+		return &smtpSink{addr: net.JoinHostPort(s.Host, strconv.Itoa(port)), host: s.Host, username: s.Username, password: s.Password, from: s.From, to: s.To}, nil
+	case TypeCommand:
+		if len(s.Command) == 0 {
+			return nil, fmt.Errorf("缺少command")
+		}
+		return &commandSink{command: s.Command}, nil
+	case "":
+		return nil, fmt.Errorf("缺少type")
+	}
+	return nil, fmt.Errorf("未知的type %s，可选: webhook、chat、smtp、command", s.Type)
+}
+
+// defaultBody webhook默认请求体：通知内容的JSON
+var defaultBody = template.Must(template.New("default").Funcs(template.FuncMap{"json": toJSON}).Parse("{{json .}}"))
+
+// toJSON 模板函数，将值编码为JSON，用于在模板中安全地嵌入字符串
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// postJSON 发送JSON请求，非2xx响应视为失败
+func postJSON(ctx context.Context, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("响应状态 %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// webhookSink 通用webhook
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	body    *template.Template
+}
+
+func (s *webhookSink) send(ctx context.Context, o Outcome) error {
+	var body bytes.Buffer
+	if err := s.body.Execute(&body, o); err != nil {
+		return fmt.Errorf("生成请求体失败: %v", err)
+	}
+	if !json.Valid(body.Bytes()) {
+		return fmt.Errorf("body模板生成的请求体不是合法的JSON: %s", body.String())
+	}
+	return postJSON(ctx, s.url, s.headers, body.Bytes())
+}
+
+// chatFormats 聊天机器人的文本消息格式
+var chatFormats = map[string]func(text string) any{
+	"slack": func(text string) any {
+		return map[string]any{"text": text}
+	},
+	"dingtalk": func(text string) any {
+		return map[string]any{"msgtype": "text", "text": map[string]any{"content": text}}
+	},
+	"wecom": func(text string) any {
+		return map[string]any{"msgtype": "text", "text": map[string]any{"content": text}}
+	},
+	"feishu": func(text string) any {
+		return map[string]any{"msg_type": "text", "content": map[string]any{"text": text}}
+	},
+}
+
+// formatOrDefault chat消息格式，默认slack
+func (s SinkConfig) formatOrDefault() string {
+	if s.Format == "" {
+		return "slack"
+	}
+	return s.Format
+}
+
+// chatSink 聊天机器人webhook
+type chatSink struct {
+	url     string
+	headers map[string]string
+	format  string
+}
+
+func (s *chatSink) send(ctx context.Context, o Outcome) error {
+	body, err := json.Marshal(chatFormats[s.format](o.Text()))
+	if err != nil {
+		return fmt.Errorf("生成消息失败: %v", err)
+	}
+	return postJSON(ctx, s.url, s.headers, body)
+}
+
+// smtpSink 邮件，服务器支持时使用STARTTLS
+type smtpSink struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (s *smtpSink) send(ctx context.Context, o Outcome) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return fmt.Errorf("SMTP握手失败: %v", err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(nil); err != nil {
+			return fmt.Errorf("STARTTLS失败: %v", err)
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("SMTP认证失败: %v", err)
+		}
+	}
+	if err := client.Mail(s.from); err != nil {
+		return fmt.Errorf("MAIL FROM失败: %v", err)
+	}
+	for _, to := range s.to {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("RCPT TO %s失败: %v", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA失败: %v", err)
+	}
+	if _, err := w.Write(s.message(o)); err != nil {
+		return fmt.Errorf("写入邮件失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	return client.Quit()
+}
+
+// message 生成邮件内容
+func (s *smtpSink) message(o Outcome) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", o.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(o.Text(), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// commandSink 本地命令，通知内容的JSON写入标准输入，级别和标题通过环境变量传递
+type commandSink struct {
+	command []string
+}
+
+func (s *commandSink) send(ctx context.Context, o Outcome) error {
+	input, err := json.Marshal(o)
+	if err != nil {
+		return fmt.Errorf("序列化通知失败: %v", err)
+	}
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"MDV_NOTIFY_SEVERITY="+o.Severity,
+		"MDV_NOTIFY_PREVIOUS_SEVERITY="+o.PreviousSeverity,
+		"MDV_NOTIFY_TITLE="+o.Title,
+		"MDV_NOTIFY_REPORT="+o.ReportFile,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("执行命令失败: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// internal/progress/events.go
+// NDJSON事件流输出
+
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"multi-database-validator-optimization/pkg/validator"
+)
+
+// EventWriter 将验证事件逐行写为JSON(NDJSON)，可并发调用
+type EventWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	closer  io.Closer
+	encoder *json.Encoder
+	err     error
+}
+
+// NewEventWriter 创建写入w的事件输出
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{w: w, encoder: json.NewEncoder(w)}
+}
+
+// OpenEventWriter 打开事件输出文件，path为"-"时写入标准输出
+func OpenEventWriter(path string) (*EventWriter, error) {
+	if path == "-" {
+		return NewEventWriter(os.Stdout), nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建事件文件失败: %v", err)
+	}
+	ew := NewEventWriter(file)
+	ew.closer = file
+	return ew, nil
+}
+
+// Handle 写入一个事件，写入失败后不再继续写入，错误由Close返回
+func (ew *EventWriter) Handle(event validator.Event) {
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	if ew.err != nil {
+		return
+	}
+	ew.err = ew.encoder.Encode(event)
+}
+
+// Close 关闭事件输出并返回写入过程中的第一个错误
+func (ew *EventWriter) Close() error {
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	if ew.closer != nil {
+		if err := ew.closer.Close(); err != nil && ew.err == nil {
+			ew.err = err
+		}
+		ew.closer = nil
+	}
+	return ew.err
+}
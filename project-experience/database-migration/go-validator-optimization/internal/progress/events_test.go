@@ -0,0 +1,114 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"multi-database-validator-optimization/pkg/validator"
+)
+
+func TestEventWriterNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	ew := NewEventWriter(&buf)
+
+	const ts = "2024-01-01T00:00:00Z"
+	events := []validator.Event{
+		{Type: validator.EventTableStarted, Time: ts, Database: "db1", Table: "users"},
+		{Type: validator.EventChunkDone, Time: ts, Database: "db1", Table: "users", Side: validator.SideAzure, Chunk: 1, Chunks: 2, Rows: 500},
+		{Type: validator.EventTableMismatch, Time: ts, Database: "db1", Table: "users", Status: "MISMATCH", Message: "行数不一致"},
+		{Type: validator.EventRunFinished, Time: ts, Databases: 1, Tables: 3},
+	}
+	for _, e := range events {
+		ew.Handle(e)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("Close 失败: %v", err)
+	}
+
+	// 每个事件一行，字段名固定，空字段省略
+	wantKeys := [][]string{
+		{"database", "table", "time", "type"},
+		{"chunk", "chunks", "database", "rows", "side", "table", "time", "type"},
+		{"database", "message", "status", "table", "time", "type"},
+		{"databases", "tables", "time", "type"},
+	}
+	wantTypes := []string{"table_started", "chunk_done", "table_mismatch", "run_finished"}
+
+	scanner := bufio.NewScanner(&buf)
+	var i int
+	for ; scanner.Scan(); i++ {
+		if i >= len(events) {
+			t.Fatalf("多余的行: %s", scanner.Text())
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			t.Fatalf("第%d行不是JSON: %v", i+1, err)
+		}
+		var keys []string
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, wantKeys[i]) {
+			t.Errorf("第%d行字段 = %v, 期望 %v", i+1, keys, wantKeys[i])
+		}
+		if fields["type"] != wantTypes[i] {
+			t.Errorf("第%d行 type = %v, 期望 %s", i+1, fields["type"], wantTypes[i])
+		}
+
+		var got validator.Event
+		if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != events[i] {
+			t.Errorf("第%d行 = %+v, 期望 %+v", i+1, got, events[i])
+		}
+	}
+	if i != len(events) {
+		t.Errorf("行数 = %d, 期望 %d", i, len(events))
+	}
+}
+
+// failWriter 第n次写入后失败
+type failWriter struct {
+	n      int
+	writes int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes > w.n {
+		return 0, errors.New("磁盘已满")
+	}
+	return len(p), nil
+}
+
+func TestEventWriterStopsAfterError(t *testing.T) {
+	w := &failWriter{n: 1}
+	ew := NewEventWriter(w)
+	for i := 0; i < 3; i++ {
+		ew.Handle(validator.Event{Type: validator.EventChunkDone})
+	}
+	if err := ew.Close(); err == nil {
+		t.Error("期望 Close 返回写入错误")
+	}
+	if w.writes != 2 {
+		t.Errorf("写入次数 = %d, 期望 2", w.writes)
+	}
+}
+
+func TestFanoutSkipsNil(t *testing.T) {
+	var got []string
+	h := Fanout(nil, func(e validator.Event) { got = append(got, "a:"+e.Type) }, nil,
+		func(e validator.Event) { got = append(got, "b:"+e.Type) })
+	h(validator.Event{Type: validator.EventRunStarted})
+	want := []string{"a:run_started", "b:run_started"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, 期望 %v", got, want)
+	}
+}
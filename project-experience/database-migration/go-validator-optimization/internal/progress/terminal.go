@@ -0,0 +1,144 @@
+// internal/progress/terminal.go
+// 终端实时进度视图
+
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认刷新间隔
+const defaultRefreshInterval = 500 * time.Millisecond
+
+// IsTerminal 判断文件是否为终端
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Terminal 在终端中定时重绘进度视图
+type Terminal struct {
+	out      io.Writer
+	tracker  *Tracker
+	interval time.Duration
+	lines    int
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewTerminal 创建终端进度视图
+func NewTerminal(out io.Writer, tracker *Tracker) *Terminal {
+	return &Terminal{
+		out:      out,
+		tracker:  tracker,
+		interval: defaultRefreshInterval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start 开始定时刷新
+func (t *Terminal) Start() {
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.render()
+			case <-t.stop:
+				t.render()
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止刷新并绘制最终状态
+func (t *Terminal) Stop() {
+	t.once.Do(func() {
+		close(t.stop)
+		<-t.done
+	})
+}
+
+// render 清除上一次输出并重绘
+func (t *Terminal) render() {
+	s := t.tracker.Snapshot()
+
+	var b strings.Builder
+	// 光标上移到上一次输出的起始位置并清除到屏幕末尾
+	if t.lines > 0 {
+		fmt.Fprintf(&b, "\033[%dA\r\033[J", t.lines)
+	}
+
+	fmt.Fprintf(&b, "⏱  已用 %s  剩余 %s  数据库 %d/%d  表 %d/%d  不一致 %d  %.0f 行/秒\n",
+		s.Elapsed.Round(time.Second), formatETA(s.ETA),
+		s.DatabasesDone, s.DatabasesTotal, s.TablesDone, s.TablesTotal, s.Mismatches, s.RowsPerSecond)
+	lines := 1
+
+	for _, p := range s.Pairs {
+		fmt.Fprintf(&b, "  %s %-20s %s %3d/%-3d 不一致 %-3d %8.0f 行/秒 剩余 %-8s %s\n",
+			pairIcon(p), p.Database, bar(p), p.TablesDone, p.TablesTotal,
+			p.Mismatches, p.RowsPerSecond, formatETA(p.ETA), p.CurrentTable)
+		lines++
+	}
+
+	t.lines = lines
+	io.WriteString(t.out, b.String())
+}
+
+// bar 对比对进度条
+func bar(p PairSnapshot) string {
+	const width = 20
+	var f float64
+	switch {
+	case p.Status == PairFinished:
+		f = 1
+	case p.RowsTotal > 0:
+		f = float64(p.RowsHashed) / float64(p.RowsTotal)
+	case p.TablesTotal > 0:
+		f = float64(p.TablesDone) / float64(p.TablesTotal)
+	}
+	if f > 1 {
+		f = 1
+	}
+	filled := int(f * width)
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+// pairIcon 对比对状态图标
+func pairIcon(p PairSnapshot) string {
+	switch p.Status {
+	case PairRunning:
+		return "🔄"
+	case PairFinished:
+		switch p.Result {
+		case "SUCCESS":
+			return "✅"
+		case "WARNING":
+			return "⚠️ "
+		}
+		return "❌"
+	default:
+		return "⏳"
+	}
+}
+
+// formatETA 格式化剩余时间，未知时显示"--"
+func formatETA(d time.Duration) string {
+	if d < 0 {
+		return "--"
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,266 @@
+// internal/progress/tracker.go
+// 验证进度统计
+
+package progress
+
+import (
+	"sync"
+	"time"
+
+	"multi-database-validator-optimization/pkg/validator"
+)
+
+// 对比对状态
+const (
+	PairPending  = "PENDING"
+	PairRunning  = "RUNNING"
+	PairFinished = "FINISHED"
+)
+
+// Handler 事件处理函数
+type Handler func(validator.Event)
+
+// Fanout 将事件依次分发给多个处理函数，忽略nil
+func Fanout(handlers ...Handler) Handler {
+	var active []Handler
+	for _, h := range handlers {
+		if h != nil {
+			active = append(active, h)
+		}
+	}
+	return func(event validator.Event) {
+		for _, h := range active {
+			h(event)
+		}
+	}
+}
+
+// PairSnapshot 单个对比对的进度快照
+type PairSnapshot struct {
+	Database      string        `json:"database"`
+	Status        string        `json:"status"`
+	Result        string        `json:"result,omitempty"` // 对比对完成后的验证状态
+	CurrentTable  string        `json:"current_table,omitempty"`
+	TablesTotal   int           `json:"tables_total"`
+	TablesDone    int           `json:"tables_done"`
+	Mismatches    int           `json:"mismatches"`
+	RowsHashed    int64         `json:"rows_hashed"`
+	RowsTotal     int64         `json:"rows_total,omitempty"` // 两侧合计的计划行数，未知时为0
+	RowsPerSecond float64       `json:"rows_per_second"`
+	Elapsed       time.Duration `json:"elapsed"`
+	ETA           time.Duration `json:"eta"` // 未知时为-1
+}
+
+// Snapshot 整体进度快照
+type Snapshot struct {
+	Elapsed        time.Duration  `json:"elapsed"`
+	ETA            time.Duration  `json:"eta"` // 未知时为-1
+	DatabasesTotal int            `json:"databases_total"`
+	DatabasesDone  int            `json:"databases_done"`
+	TablesTotal    int            `json:"tables_total"`
+	TablesDone     int            `json:"tables_done"`
+	Mismatches     int            `json:"mismatches"`
+	RowsHashed     int64          `json:"rows_hashed"`
+	RowsPerSecond  float64        `json:"rows_per_second"`
+	Finished       bool           `json:"finished"`
+	Pairs          []PairSnapshot `json:"pairs"`
+}
+
+// pairState 对比对进度状态
+type pairState struct {
+	database     string
+	status       string
+	result       string
+	currentTable string
+	tablesTotal  int
+	tablesDone   int
+	mismatches   int
+	rowsHashed   int64
+	rowsTotal    int64
+	start        time.Time
+	end          time.Time
+}
+
+// Tracker 根据验证事件统计进度，可并发调用
+type Tracker struct {
+	mu             sync.Mutex
+	now            func() time.Time
+	start          time.Time
+	end            time.Time
+	databasesTotal int
+	pairs          map[string]*pairState
+	order          []string
+}
+
+// NewTracker 创建进度统计器
+func NewTracker() *Tracker {
+	return &Tracker{
+		now:   time.Now,
+		pairs: make(map[string]*pairState),
+	}
+}
+
+// Handle 处理一个验证事件
+func (t *Tracker) Handle(event validator.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if t.start.IsZero() {
+		t.start = now
+	}
+
+	switch event.Type {
+	case validator.EventRunStarted:
+		t.start = now
+		t.databasesTotal = event.Databases
+		return
+	case validator.EventRunFinished:
+		t.end = now
+		return
+	}
+
+	if event.Database == "" {
+		return
+	}
+	p := t.pair(event.Database)
+
+	switch event.Type {
+	case validator.EventDatabaseStarted:
+		p.status = PairRunning
+		p.start = now
+	case validator.EventDatabaseTables:
+		p.tablesTotal = event.Tables
+		p.rowsTotal = event.Rows * 2
+	case validator.EventTableStarted:
+		p.currentTable = event.Table
+	case validator.EventChunkDone:
+		p.rowsHashed += event.Rows
+	case validator.EventTableChecked:
+		p.tablesDone++
+		if p.currentTable == event.Table {
+			p.currentTable = ""
+		}
+	case validator.EventTableMismatch:
+		p.mismatches++
+	case validator.EventDatabaseFinished:
+		p.status = PairFinished
+		p.result = event.Status
+		p.currentTable = ""
+		p.end = now
+	}
+}
+
+// pair 获取或创建对比对状态，调用方需持有锁
+func (t *Tracker) pair(database string) *pairState {
+	p, ok := t.pairs[database]
+	if !ok {
+		p = &pairState{database: database, status: PairPending}
+		t.pairs[database] = p
+		t.order = append(t.order, database)
+	}
+	return p
+}
+
+// Snapshot 返回当前进度快照
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if !t.end.IsZero() {
+		now = t.end
+	}
+
+	s := Snapshot{
+		DatabasesTotal: t.databasesTotal,
+		Finished:       !t.end.IsZero(),
+		ETA:            -1,
+	}
+	if !t.start.IsZero() {
+		s.Elapsed = now.Sub(t.start)
+	}
+	if s.DatabasesTotal < len(t.pairs) {
+		s.DatabasesTotal = len(t.pairs)
+	}
+
+	// 已完成对比对贡献1，进行中的对比对按表完成比例计入
+	var fraction float64
+	for _, name := range t.order {
+		p := t.pairs[name]
+		ps := p.snapshot(now)
+		s.Pairs = append(s.Pairs, ps)
+
+		s.TablesTotal += ps.TablesTotal
+		s.TablesDone += ps.TablesDone
+		s.Mismatches += ps.Mismatches
+		s.RowsHashed += ps.RowsHashed
+		switch p.status {
+		case PairFinished:
+			s.DatabasesDone++
+			fraction++
+		case PairRunning:
+			fraction += p.fraction()
+		}
+	}
+
+	if secs := s.Elapsed.Seconds(); secs > 0 {
+		s.RowsPerSecond = float64(s.RowsHashed) / secs
+	}
+
+	switch {
+	case s.Finished:
+		s.ETA = 0
+	case s.DatabasesTotal > 0 && fraction > 0:
+		done := fraction / float64(s.DatabasesTotal)
+		s.ETA = time.Duration(float64(s.Elapsed) * (1 - done) / done).Round(time.Second)
+	}
+
+	return s
+}
+
+// fraction 对比对完成比例，有计划行数时按行数计算，否则按表数计算
+func (p *pairState) fraction() float64 {
+	if p.rowsTotal > 0 {
+		f := float64(p.rowsHashed) / float64(p.rowsTotal)
+		if f > 1 {
+			f = 1
+		}
+		return f
+	}
+	if p.tablesTotal > 0 {
+		return float64(p.tablesDone) / float64(p.tablesTotal)
+	}
+	return 0
+}
+
+// snapshot 返回对比对进度快照
+func (p *pairState) snapshot(now time.Time) PairSnapshot {
+	s := PairSnapshot{
+		Database:     p.database,
+		Status:       p.status,
+		Result:       p.result,
+		CurrentTable: p.currentTable,
+		TablesTotal:  p.tablesTotal,
+		TablesDone:   p.tablesDone,
+		Mismatches:   p.mismatches,
+		RowsHashed:   p.rowsHashed,
+		RowsTotal:    p.rowsTotal,
+		ETA:          -1,
+	}
+
+	switch p.status {
+	case PairFinished:
+		s.Elapsed = p.end.Sub(p.start)
+		s.ETA = 0
+	case PairRunning:
+		s.Elapsed = now.Sub(p.start)
+		if f := p.fraction(); f > 0 {
+			s.ETA = time.Duration(float64(s.Elapsed) * (1 - f) / f).Round(time.Second)
+		}
+	}
+	if secs := s.Elapsed.Seconds(); secs > 0 {
+		s.RowsPerSecond = float64(p.rowsHashed) / secs
+	}
+	return s
+}
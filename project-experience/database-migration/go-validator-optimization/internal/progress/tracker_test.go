@@ -0,0 +1,133 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"multi-database-validator-optimization/pkg/validator"
+)
+
+// fakeClock 手动推进的时钟
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+func newTestTracker() (*Tracker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	t := NewTracker()
+	t.now = clock.Now
+	return t, clock
+}
+
+func TestTrackerETAByRows(t *testing.T) {
+	tr, clock := newTestTracker()
+	tr.Handle(validator.Event{Type: validator.EventRunStarted, Databases: 2})
+
+	if s := tr.Snapshot(); s.ETA != -1 {
+		t.Errorf("无进度时 ETA = %v, 期望 -1", s.ETA)
+	}
+
+	tr.Handle(validator.Event{Type: validator.EventDatabaseStarted, Database: "a"})
+	tr.Handle(validator.Event{Type: validator.EventDatabaseTables, Database: "a", Tables: 1, Rows: 100})
+	tr.Handle(validator.Event{Type: validator.EventTableStarted, Database: "a", Table: "t1"})
+	tr.Handle(validator.Event{Type: validator.EventDatabaseStarted, Database: "b"})
+	tr.Handle(validator.Event{Type: validator.EventDatabaseTables, Database: "b", Tables: 1})
+
+	clock.Advance(10 * time.Second)
+	// a 两侧计划共200行，已完成一侧100行
+	tr.Handle(validator.Event{Type: validator.EventChunkDone, Database: "a", Table: "t1", Side: validator.SideAzure, Rows: 100})
+	tr.Handle(validator.Event{Type: validator.EventTableChecked, Database: "b", Table: "t1", Status: "MATCH"})
+	tr.Handle(validator.Event{Type: validator.EventDatabaseFinished, Database: "b", Status: "SUCCESS"})
+
+	s := tr.Snapshot()
+	if s.Elapsed != 10*time.Second {
+		t.Errorf("Elapsed = %v, 期望 10s", s.Elapsed)
+	}
+	// 完成比例 (0.5+1)/2 = 0.75，剩余 10s*0.25/0.75 ≈ 3.33s
+	if s.ETA != 3*time.Second {
+		t.Errorf("ETA = %v, 期望 3s", s.ETA)
+	}
+	if s.RowsHashed != 100 || s.RowsPerSecond != 10 {
+		t.Errorf("RowsHashed = %d, RowsPerSecond = %v, 期望 100, 10", s.RowsHashed, s.RowsPerSecond)
+	}
+	if s.DatabasesDone != 1 || s.DatabasesTotal != 2 || s.TablesDone != 1 || s.TablesTotal != 2 {
+		t.Errorf("数据库 %d/%d 表 %d/%d, 期望 1/2 1/2", s.DatabasesDone, s.DatabasesTotal, s.TablesDone, s.TablesTotal)
+	}
+
+	if len(s.Pairs) != 2 {
+		t.Fatalf("Pairs = %d, 期望 2", len(s.Pairs))
+	}
+	a, b := s.Pairs[0], s.Pairs[1]
+	if a.Database != "a" || a.Status != PairRunning || a.CurrentTable != "t1" || a.RowsTotal != 200 {
+		t.Errorf("a = %+v", a)
+	}
+	if a.ETA != 10*time.Second || a.RowsPerSecond != 10 {
+		t.Errorf("a ETA = %v, RowsPerSecond = %v, 期望 10s, 10", a.ETA, a.RowsPerSecond)
+	}
+	if b.Status != PairFinished || b.Result != "SUCCESS" || b.ETA != 0 || b.Elapsed != 10*time.Second {
+		t.Errorf("b = %+v", b)
+	}
+}
+
+func TestTrackerETAByTables(t *testing.T) {
+	tr, clock := newTestTracker()
+	tr.Handle(validator.Event{Type: validator.EventRunStarted, Databases: 1})
+	tr.Handle(validator.Event{Type: validator.EventDatabaseStarted, Database: "a"})
+	tr.Handle(validator.Event{Type: validator.EventDatabaseTables, Database: "a", Tables: 4})
+
+	clock.Advance(30 * time.Second)
+	tr.Handle(validator.Event{Type: validator.EventTableChecked, Database: "a", Table: "t1"})
+
+	// 没有计划行数时按表数计算：1/4完成，剩余 30s*0.75/0.25 = 90s
+	s := tr.Snapshot()
+	if s.ETA != 90*time.Second {
+		t.Errorf("ETA = %v, 期望 90s", s.ETA)
+	}
+	if s.Pairs[0].ETA != 90*time.Second {
+		t.Errorf("对比对 ETA = %v, 期望 90s", s.Pairs[0].ETA)
+	}
+	if s.RowsPerSecond != 0 {
+		t.Errorf("RowsPerSecond = %v, 期望 0", s.RowsPerSecond)
+	}
+}
+
+func TestTrackerFinishedFreezesElapsed(t *testing.T) {
+	tr, clock := newTestTracker()
+	tr.Handle(validator.Event{Type: validator.EventRunStarted, Databases: 1})
+	tr.Handle(validator.Event{Type: validator.EventDatabaseStarted, Database: "a"})
+	clock.Advance(4 * time.Second)
+	tr.Handle(validator.Event{Type: validator.EventChunkDone, Database: "a", Rows: 80})
+	tr.Handle(validator.Event{Type: validator.EventDatabaseFinished, Database: "a", Status: "SUCCESS"})
+	tr.Handle(validator.Event{Type: validator.EventRunFinished})
+
+	clock.Advance(time.Minute)
+	s := tr.Snapshot()
+	if !s.Finished || s.ETA != 0 {
+		t.Errorf("Finished = %v, ETA = %v, 期望 true, 0", s.Finished, s.ETA)
+	}
+	// 完成后耗时和速率固定在结束时刻
+	if s.Elapsed != 4*time.Second || s.RowsPerSecond != 20 {
+		t.Errorf("Elapsed = %v, RowsPerSecond = %v, 期望 4s, 20", s.Elapsed, s.RowsPerSecond)
+	}
+}
+
+func TestTrackerRowsFractionCapped(t *testing.T) {
+	tr, clock := newTestTracker()
+	tr.Handle(validator.Event{Type: validator.EventRunStarted, Databases: 2})
+	tr.Handle(validator.Event{Type: validator.EventDatabaseStarted, Database: "a"})
+	tr.Handle(validator.Event{Type: validator.EventDatabaseTables, Database: "a", Tables: 1, Rows: 10})
+	clock.Advance(10 * time.Second)
+	// 计划行数是估算值，实际行数超出时完成比例不超过1
+	tr.Handle(validator.Event{Type: validator.EventChunkDone, Database: "a", Rows: 50})
+
+	s := tr.Snapshot()
+	if s.Pairs[0].ETA != 0 {
+		t.Errorf("对比对 ETA = %v, 期望 0", s.Pairs[0].ETA)
+	}
+	// 完成比例 1/2，剩余 10s
+	if s.ETA != 10*time.Second {
+		t.Errorf("ETA = %v, 期望 10s", s.ETA)
+	}
+}
@@ -0,0 +1,263 @@
+// internal/schedule/cron.go
+// cron表达式解析
+
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 计算任务的下一次触发时间
+type Schedule interface {
+	// Next 返回严格晚于t的下一次触发时间，无可用时间时返回零值
+	Next(t time.Time) time.Time
+}
+
+// field 单个cron字段的取值范围
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField = field{name: "分钟", min: 0, max: 59}
+	hourField   = field{name: "小时", min: 0, max: 23}
+	domField    = field{name: "日", min: 1, max: 31}
+	monthField  = field{name: "月", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = field{name: "星期", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// descriptors 预定义的cron描述符
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronSchedule 标准5字段cron表达式，每个字段用位图表示
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// everySchedule 固定间隔调度 (@every 1h30m)
+type everySchedule struct {
+	interval time.Duration
+}
+
+// Parse 解析cron表达式
+//
+// 支持标准5字段格式 "分 时 日 月 周"（支持 * , - / 以及月份/星期英文缩写），
+// 预定义描述符 @yearly/@monthly/@weekly/@daily/@hourly，以及 "@every <duration>"。
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("cron表达式不能为空")
+	}
+
+	if strings.HasPrefix(spec, "@every") {
+		durStr := strings.TrimSpace(strings.TrimPrefix(spec, "@every"))
+		interval, err := time.ParseDuration(durStr)
+		if err != nil {
+			return nil, fmt.Errorf("解析@every间隔失败 %q: %v", durStr, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("@every间隔不能小于1秒: %s", interval)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		expanded, ok := descriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("不支持的cron描述符: %s", spec)
+		}
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron表达式需要5个字段(分 时 日 月 周)，实际为%d个: %q", len(fields), spec)
+	}
+
+	s := &cronSchedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	// 星期中的7与0都表示周日
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	// 与Vixie cron一致：以*开头的字段（包括*/2）视为不限制，不参与日/星期的"或"语义
+	s.domStar = isStar(fields[2])
+	s.dowStar = isStar(fields[4])
+
+	return s, nil
+}
+
+// isStar 字段是否以*或?开头
+func isStar(expr string) bool {
+	return strings.HasPrefix(expr, "*") || strings.HasPrefix(expr, "?")
+}
+
+// parseField 将一个字段解析为位图
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		b, err := parseRange(part, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+// parseRange 解析单个范围，如 "*", "5", "1-5", "*/15", "10-50/10"
+func parseRange(expr string, f field) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(expr, "/")
+
+	start, end := f.min, f.max
+	switch {
+	case rangeExpr == "*" || rangeExpr == "?":
+	case strings.Contains(rangeExpr, "-"):
+		lo, hi, _ := strings.Cut(rangeExpr, "-")
+		var err error
+		if start, err = parseValue(lo, f); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(hi, f); err != nil {
+			return 0, err
+		}
+	default:
+		v, err := parseValue(rangeExpr, f)
+		if err != nil {
+			return 0, err
+		}
+		start = v
+		end = v
+		// "5/10" 表示从5开始每10个单位
+		if hasStep {
+			end = f.max
+		}
+	}
+
+	step := 1
+	if hasStep {
+		var err error
+		step, err = strconv.Atoi(stepExpr)
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("%s字段步长无效: %q", f.name, expr)
+		}
+	}
+
+	if start > end {
+		return 0, fmt.Errorf("%s字段范围无效: %q", f.name, expr)
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << uint(i)
+	}
+	return bits, nil
+}
+
+// parseValue 解析单个数值或英文缩写
+func parseValue(expr string, f field) (int, error) {
+	if v, ok := f.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, fmt.Errorf("%s字段值无效: %q", f.name, expr)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s字段值超出范围[%d,%d]: %d", f.name, f.min, f.max, v)
+	}
+	return v, nil
+}
+
+// Next 返回下一次触发时间
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+// dayMatches 判断日期是否满足日/星期字段
+// 与标准cron一致：两个字段都有限制时满足任意一个即可
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next 返回下一次触发时间
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
@@ -0,0 +1,193 @@
+// internal/schedule/scheduler.go
+// 定时任务调度器
+
+package schedule
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job 定时任务
+type Job struct {
+	Name     string
+	Spec     string
+	Schedule Schedule
+	Run      func()
+}
+
+// JobStatus 定时任务状态
+type JobStatus struct {
+	Name    string `json:"name"`
+	Spec    string `json:"spec"`
+	NextRun string `json:"next_run"`
+	LastRun string `json:"last_run,omitempty"`
+}
+
+// entry 调度器内部的任务条目
+type entry struct {
+	job  Job
+	next time.Time
+	prev time.Time
+}
+
+// Scheduler 按Schedule周期性执行任务，支持运行时替换任务列表；Stop之后可以再次Start
+type Scheduler struct {
+	mu      sync.Mutex
+	entries []*entry
+	now     func() time.Time
+	wake    chan struct{}
+	stop    chan struct{} // 每次Start时重新创建
+	done    chan struct{}
+	running bool
+}
+
+// NewScheduler 创建调度器
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		now:  time.Now,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Replace 替换全部任务，用于配置热加载
+func (s *Scheduler) Replace(jobs []Job) {
+	s.mu.Lock()
+	now := s.now()
+	prevRuns := make(map[string]time.Time, len(s.entries))
+	for _, e := range s.entries {
+		prevRuns[e.job.Name] = e.prev
+	}
+	s.entries = make([]*entry, 0, len(jobs))
+	for _, job := range jobs {
+		s.entries = append(s.entries, &entry{
+			job:  job,
+			next: job.Schedule.Next(now),
+			prev: prevRuns[job.Name],
+		})
+	}
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// Jobs 返回当前任务状态，按下一次触发时间排序
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.entries))
+	for _, e := range s.entries {
+		status := JobStatus{Name: e.job.Name, Spec: e.job.Spec}
+		if !e.next.IsZero() {
+			status.NextRun = e.next.Format(time.RFC3339)
+		}
+		if !e.prev.IsZero() {
+			status.LastRun = e.prev.Format(time.RFC3339)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NextRun < statuses[j].NextRun
+	})
+	return statuses
+}
+
+// Start 启动调度循环
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.loop(s.stop, s.done)
+}
+
+// Stop 停止调度循环并等待其退出，已经开始执行的任务不会被中断
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	stop, done := s.stop, s.done
+	s.mu.Unlock()
+
+	close(stop)
+	<-done
+}
+
+// notify 唤醒调度循环重新计算等待时间
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop 调度主循环
+func (s *Scheduler) loop(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	for {
+		timer := time.NewTimer(s.untilNext())
+		select {
+		case <-timer.C:
+			s.runDue()
+		case <-s.wake:
+			timer.Stop()
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// untilNext 计算距离最近一次触发的等待时间
+func (s *Scheduler) untilNext() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 没有任务时长时间休眠，等待Replace唤醒
+	wait := 24 * time.Hour
+	now := s.now()
+	for _, e := range s.entries {
+		if e.next.IsZero() {
+			continue
+		}
+		if d := e.next.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// runDue 执行所有已到期的任务
+func (s *Scheduler) runDue() {
+	s.mu.Lock()
+	now := s.now()
+	var due []Job
+	for _, e := range s.entries {
+		if e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+		due = append(due, e.job)
+		e.prev = now
+		e.next = e.job.Schedule.Next(now)
+	}
+	s.mu.Unlock()
+
+	for _, job := range due {
+		log.Printf("定时任务 %s 触发 (%s)", job.Name, job.Spec)
+		go job.Run()
+	}
+}
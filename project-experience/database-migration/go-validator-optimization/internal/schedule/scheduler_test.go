@@ -0,0 +1,82 @@
+package schedule
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// intervalSchedule 测试用的毫秒级固定间隔
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// waitRuns 等待任务至少执行n次
+func waitRuns(t *testing.T, runs *atomic.Int32, n int32) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for runs.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("任务只执行了%d次，期望至少%d次", runs.Load(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerStartTriggerStop(t *testing.T) {
+	var runs atomic.Int32
+	s := NewScheduler()
+	s.Replace([]Job{{
+		Name:     "nightly",
+		Spec:     "@every 5ms",
+		Schedule: intervalSchedule(5 * time.Millisecond),
+		Run:      func() { runs.Add(1) },
+	}})
+
+	jobs := s.Jobs()
+	if len(jobs) != 1 || jobs[0].NextRun == "" || jobs[0].LastRun != "" {
+		t.Fatalf("启动前的任务状态不正确: %+v", jobs)
+	}
+
+	s.Start()
+	s.Start() // 重复启动无效果
+	waitRuns(t, &runs, 3)
+	s.Stop()
+	s.Stop() // 重复停止无效果
+
+	if s.Jobs()[0].LastRun == "" {
+		t.Error("执行后LastRun为空")
+	}
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	if n := runs.Load(); n != stopped {
+		t.Errorf("停止后任务仍在执行: %d -> %d", stopped, n)
+	}
+
+	// 停止后可以再次启动
+	s.Start()
+	waitRuns(t, &runs, stopped+2)
+	s.Stop()
+}
+
+func TestSchedulerReplaceWakesLoop(t *testing.T) {
+	var runs atomic.Int32
+	s := NewScheduler()
+	s.Start()
+	defer s.Stop()
+
+	// 没有任务时调度循环长时间休眠，Replace需要唤醒它
+	s.Replace([]Job{{
+		Name:     "hourly",
+		Schedule: intervalSchedule(time.Millisecond),
+		Run:      func() { runs.Add(1) },
+	}})
+	waitRuns(t, &runs, 1)
+
+	s.Replace(nil)
+	if jobs := s.Jobs(); len(jobs) != 0 {
+		t.Errorf("替换后仍有任务: %+v", jobs)
+	}
+}
@@ -0,0 +1,262 @@
+// internal/server/runs.go
+// 验证运行记录管理
+
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"multi-database-validator-optimization/internal/progress"
+	"multi-database-validator-optimization/pkg/validator"
+)
+
+// 运行状态
+const (
+	RunStatusRunning  = "RUNNING"
+	RunStatusFinished = "FINISHED"
+	RunStatusFailed   = "FAILED"
+)
+
+// subscriberBuffer 每个事件订阅者的缓冲区大小
+const subscriberBuffer = 64
+
+// RunInfo 运行记录快照，用于API输出
+type RunInfo struct {
+	ID               string  `json:"id"`
+	Trigger          string  `json:"trigger"`
+	Status           string  `json:"status"`
+	StartTime        string  `json:"start_time"`
+	EndTime          string  `json:"end_time,omitempty"`
+	ReportFile       string  `json:"report_file,omitempty"`
+	Error            string  `json:"error,omitempty"`
+	DatabasesTotal   int     `json:"databases_total"`
+	DatabasesDone    int     `json:"databases_done"`
+	TablesTotal      int     `json:"tables_total"`
+	TablesChecked    int     `json:"tables_checked"`
+	TablesMismatched int     `json:"tables_mismatched"`
+	RowsHashed       int64   `json:"rows_hashed"`
+	RowsPerSecond    float64 `json:"rows_per_second"`
+	ETA              string  `json:"eta,omitempty"` // 预估剩余时间，未知时为空
+}
+
+// run 一次验证运行，保存事件历史并向订阅者广播
+type run struct {
+	mu          sync.Mutex
+	info        RunInfo
+	summary     *validator.Report
+	tracker     *progress.Tracker
+	events      []validator.Event
+	subscribers map[chan validator.Event]struct{}
+	finished    bool
+}
+
+// newRun 创建运行记录
+func newRun(id, trigger string, databases int) *run {
+	return &run{
+		info: RunInfo{
+			ID:             id,
+			Trigger:        trigger,
+			Status:         RunStatusRunning,
+			StartTime:      time.Now().Format(time.RFC3339),
+			DatabasesTotal: databases,
+		},
+		tracker:     progress.NewTracker(),
+		subscribers: make(map[chan validator.Event]struct{}),
+	}
+}
+
+// snapshot 返回运行记录快照
+func (r *run) snapshot() RunInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info := r.info
+	p := r.tracker.Snapshot()
+	info.DatabasesDone = p.DatabasesDone
+	info.TablesTotal = p.TablesTotal
+	info.TablesChecked = p.TablesDone
+	info.TablesMismatched = p.Mismatches
+	info.RowsHashed = p.RowsHashed
+	info.RowsPerSecond = p.RowsPerSecond
+	if p.ETA >= 0 {
+		info.ETA = p.ETA.String()
+	}
+	return info
+}
+
+// report 返回验证报告，运行未完成时返回nil
+func (r *run) report() *validator.Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.summary
+}
+
+// publish 记录事件并广播给订阅者
+// 验证器的run_finished由finish统一发送，chunk_done只广播不计入历史
+func (r *run) publish(event validator.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.finished || event.Type == validator.EventRunFinished {
+		return
+	}
+
+	r.tracker.Handle(event)
+	if event.Type == validator.EventRunStarted {
+		// 自动发现时对比对数量在开始运行后才确定
+		r.info.DatabasesTotal = event.Databases
+	}
+	if event.Type != validator.EventChunkDone {
+		r.events = append(r.events, event)
+	}
+	for ch := range r.subscribers {
+		// 订阅者消费过慢时丢弃事件，避免阻塞验证流程
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// finish 标记运行结束并关闭所有订阅
+func (r *run) finish(summary *validator.Report, reportFile string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.info.EndTime = time.Now().Format(time.RFC3339)
+	r.info.ReportFile = reportFile
+	r.summary = summary
+	if err != nil {
+		r.info.Status = RunStatusFailed
+		r.info.Error = err.Error()
+	} else {
+		r.info.Status = RunStatusFinished
+	}
+
+	end := validator.Event{Type: validator.EventRunFinished, Time: r.info.EndTime, Status: r.info.Status, Message: r.info.Error}
+	r.tracker.Handle(end)
+	r.events = append(r.events, end)
+	for ch := range r.subscribers {
+		select {
+		case ch <- end:
+		default:
+		}
+		close(ch)
+	}
+	r.subscribers = nil
+	r.finished = true
+}
+
+// subscribe 订阅事件，返回历史事件和后续事件通道
+// 运行已结束时返回的通道已关闭
+func (r *run) subscribe() ([]validator.Event, chan validator.Event, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	history := make([]validator.Event, len(r.events))
+	copy(history, r.events)
+
+	ch := make(chan validator.Event, subscriberBuffer)
+	if r.finished {
+		close(ch)
+		return history, ch, func() {}
+	}
+
+	r.subscribers[ch] = struct{}{}
+	unsubscribe := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if _, ok := r.subscribers[ch]; ok {
+			delete(r.subscribers, ch)
+			close(ch)
+		}
+	}
+	return history, ch, unsubscribe
+}
+
+// runStore 运行记录存储，只保留最近maxHistory条
+type runStore struct {
+	mu         sync.RWMutex
+	runs       []*run
+	byID       map[string]*run
+	active     *run
+	seq        int
+	maxHistory int
+}
+
+// newRunStore 创建运行记录存储
+func newRunStore(maxHistory int) *runStore {
+	if maxHistory <= 0 {
+		maxHistory = 50
+	}
+	return &runStore{
+		byID:       make(map[string]*run),
+		maxHistory: maxHistory,
+	}
+}
+
+// start 创建新的运行记录，已有运行进行中时返回ErrRunInProgress
+func (s *runStore) start(trigger string, databases int) (*run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active != nil {
+		return nil, ErrRunInProgress
+	}
+
+	s.seq++
+	id := fmt.Sprintf("%s-%03d", time.Now().Format("20060102150405"), s.seq)
+	r := newRun(id, trigger, databases)
+	s.runs = append(s.runs, r)
+	s.byID[id] = r
+	s.active = r
+
+	// 淘汰最旧的运行记录
+	for len(s.runs) > s.maxHistory {
+		oldest := s.runs[0]
+		s.runs = s.runs[1:]
+		delete(s.byID, oldest.info.ID)
+	}
+
+	return r, nil
+}
+
+// release 释放当前活动运行
+func (s *runStore) release(r *run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == r {
+		s.active = nil
+	}
+}
+
+// get 根据ID查找运行记录
+func (s *runStore) get(id string) (*run, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.byID[id]
+	return r, ok
+}
+
+// list 返回所有运行记录快照，最新的在前
+func (s *runStore) list() []RunInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]RunInfo, 0, len(s.runs))
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		infos = append(infos, s.runs[i].snapshot())
+	}
+	return infos
+}
+
+// setMaxHistory 更新保留的运行记录数
+func (s *runStore) setMaxHistory(n int) {
+	if n <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxHistory = n
+}
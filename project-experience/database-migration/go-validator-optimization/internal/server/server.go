@@ -0,0 +1,376 @@
+// internal/server/server.go
+// 守护进程模式：定时验证与HTTP API
+
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"multi-database-validator-optimization/internal/config"
+	"multi-database-validator-optimization/internal/notify"
+	"multi-database-validator-optimization/internal/schedule"
+	"multi-database-validator-optimization/internal/types"
+	"multi-database-validator-optimization/pkg/validator"
+)
+
+// ErrRunInProgress 已有验证正在运行
+var ErrRunInProgress = errors.New("已有验证任务正在运行")
+
+// ErrStopped 服务正在停止，不再接受新的验证
+var ErrStopped = errors.New("验证服务正在停止")
+
+//go:embed static/index.html
+var indexHTML []byte
+
+// ConfigLoader 加载当前生效的配置
+type ConfigLoader func() (*types.Config, error)
+
+// Server 验证服务，负责定时触发验证并提供HTTP API
+type Server struct {
+	loadConfig ConfigLoader
+	runs       *runStore
+	scheduler  *schedule.Scheduler
+
+	// ctx 在Stop时取消，正在运行的验证随之中止
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex // 保护stopping，并保证Stop开始后不再有wg.Add
+	stopping bool
+	wg       sync.WaitGroup
+
+	// notifier 所有运行共用一个通知器，去重状态文件只由它读写
+	notifyMu  sync.Mutex
+	notifier  *notify.Notifier
+	notifyCfg notify.Config
+}
+
+// New 创建验证服务
+func New(loadConfig ConfigLoader) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		loadConfig: loadConfig,
+		runs:       newRunStore(0),
+		scheduler:  schedule.NewScheduler(),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start 加载定时任务并启动调度器
+func (s *Server) Start() error {
+	err := s.Reload()
+	s.scheduler.Start()
+	return err
+}
+
+// Stop 停止调度器，取消正在运行的验证并等待其结束，之后的Trigger返回ErrStopped
+func (s *Server) Stop() {
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+
+	s.cancel()
+	s.scheduler.Stop()
+	s.wg.Wait()
+}
+
+// Reload 重新加载配置中的定时任务，无效的cron表达式会被跳过并返回错误
+func (s *Server) Reload() error {
+	cfg, err := s.loadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+	s.runs.setMaxHistory(cfg.Serve.MaxHistory)
+
+	var jobs []schedule.Job
+	var errs []string
+	for _, sc := range cfg.Serve.Schedules {
+		sched, err := schedule.Parse(sc.Cron)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("定时任务 %s: %v", sc.Name, err))
+			continue
+		}
+		trigger := "schedule:" + sc.Name
+		jobs = append(jobs, schedule.Job{
+			Name:     sc.Name,
+			Spec:     sc.Cron,
+			Schedule: sched,
+			Run: func() {
+				if _, err := s.Trigger(trigger); err != nil {
+					log.Printf("定时任务 %s 跳过: %v", trigger, err)
+				}
+			},
+		})
+	}
+	s.scheduler.Replace(jobs)
+	log.Printf("已加载 %d 个定时验证任务", len(jobs))
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Trigger 触发一次验证，验证在后台执行
+func (s *Server) Trigger(trigger string) (RunInfo, error) {
+	cfg, err := s.loadConfig()
+	if err != nil {
+		return RunInfo{}, fmt.Errorf("加载配置失败: %v", err)
+	}
+	if (len(cfg.Azure) == 0 && cfg.Discover == nil && cfg.Sharding == nil) || len(cfg.Azure) != len(cfg.AWS) {
+		return RunInfo{}, fmt.Errorf("配置无效: Azure=%d, AWS=%d", len(cfg.Azure), len(cfg.AWS))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopping {
+		return RunInfo{}, ErrStopped
+	}
+	r, err := s.runs.start(trigger, len(cfg.Azure))
+	if err != nil {
+		return RunInfo{}, err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.runs.release(r)
+		s.execute(r, cfg)
+	}()
+
+	log.Printf("验证任务 %s 已启动 (触发方式: %s)", r.info.ID, trigger)
+	return r.snapshot(), nil
+}
+
+// execute 执行验证并生成报告
+func (s *Server) execute(r *run, cfg *types.Config) {
+	opts := []validator.Option{
+		validator.WithInstances(cfg.Azure, cfg.AWS),
+		validator.WithMaxWorkers(cfg.MaxWorkers),
+		validator.WithHashAlgorithm(cfg.Hash),
+		validator.WithGrantUsers(cfg.GrantUsers...),
+		validator.WithMasking(cfg.Masking...),
+		validator.WithProgressHook(r.publish),
+	}
+	if cfg.Discover != nil {
+		opts = append(opts, validator.WithDiscovery(*cfg.Discover))
+	}
+	if cfg.Sharding != nil {
+		opts = append(opts, validator.WithSharding(*cfg.Sharding))
+	}
+	if cfg.TextCheck != nil {
+		opts = append(opts, validator.WithTextCheck(*cfg.TextCheck))
+	}
+	v := validator.New(opts...)
+
+	summary, err := v.Run(s.ctx, nil)
+	if err != nil {
+		err = fmt.Errorf("验证失败: %v", err)
+		r.finish(nil, "", err)
+		s.notify(r, cfg, nil, "", err)
+		return
+	}
+
+	reportFile := config.GetReportPath(fmt.Sprintf("consistency_report_%s.json", r.info.ID))
+	if err := validator.SaveReport(summary, reportFile); err != nil {
+		err = fmt.Errorf("生成报告失败: %v", err)
+		r.finish(nil, "", err)
+		s.notify(r, cfg, summary, "", err)
+		return
+	}
+
+	r.finish(summary, reportFile, nil)
+	log.Printf("验证任务 %s 完成，报告: %s", r.info.ID, reportFile)
+	s.notify(r, cfg, summary, reportFile, nil)
+}
+
+// notify 按配置发送验证结果通知
+func (s *Server) notify(r *run, cfg *types.Config, summary *validator.Report, reportFile string, runErr error) {
+	if cfg.Notify == nil || len(cfg.Notify.Sinks) == 0 {
+		return
+	}
+	s.notifyMu.Lock()
+	defer s.notifyMu.Unlock()
+	notifier, err := s.notifierFor(*cfg.Notify)
+	if err != nil {
+		log.Printf("验证任务 %s 创建通知失败: %v", r.info.ID, err)
+		return
+	}
+	outcome := notify.FromReport(summary, runErr)
+	outcome.RunID = r.info.ID
+	outcome.Trigger = r.info.Trigger
+	outcome.ReportFile = reportFile
+	sent, err := notifier.Notify(context.Background(), outcome)
+	if len(sent) > 0 {
+		log.Printf("验证任务 %s 已发送通知: %s", r.info.ID, strings.Join(sent, ", "))
+	}
+	if err != nil {
+		log.Printf("验证任务 %s 发送通知失败: %v", r.info.ID, err)
+	}
+}
+
+// notifierFor 返回共用的通知器，通知配置变化时重新创建，调用方需持有notifyMu
+func (s *Server) notifierFor(cfg notify.Config) (*notify.Notifier, error) {
+	if s.notifier != nil && reflect.DeepEqual(s.notifyCfg, cfg) {
+		return s.notifier, nil
+	}
+	notifier, err := notify.New(cfg, config.GetNotifyStateFile())
+	if err != nil {
+		return nil, err
+	}
+	s.notifier, s.notifyCfg = notifier, cfg
+	return notifier, nil
+}
+
+// Handler 返回HTTP路由
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /api/schedules", s.handleSchedules)
+	mux.HandleFunc("GET /api/runs", s.handleListRuns)
+	mux.HandleFunc("POST /api/runs", s.handleTriggerRun)
+	mux.HandleFunc("GET /api/runs/{id}", s.handleGetRun)
+	mux.HandleFunc("GET /api/runs/{id}/events", s.handleRunEvents)
+	mux.HandleFunc("GET /api/runs/{id}/report", s.handleRunReport)
+	return mux
+}
+
+// handleIndex 状态页面
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+// handleHealth 健康检查
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleSchedules 列出定时任务
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.scheduler.Jobs())
+}
+
+// handleListRuns 列出运行记录
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.runs.list())
+}
+
+// handleTriggerRun 手动触发验证
+func (s *Server) handleTriggerRun(w http.ResponseWriter, r *http.Request) {
+	info, err := s.Trigger("manual")
+	if errors.Is(err, ErrRunInProgress) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, ErrStopped) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", "/api/runs/"+info.ID)
+	writeJSON(w, http.StatusAccepted, info)
+}
+
+// handleGetRun 查询单个运行记录
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.runs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("运行记录不存在: %s", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, run.snapshot())
+}
+
+// handleRunReport 获取验证报告
+func (s *Server) handleRunReport(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.runs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("运行记录不存在: %s", r.PathValue("id")))
+		return
+	}
+	summary := run.report()
+	if summary == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("运行 %s 尚无报告", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// handleRunEvents 以Server-Sent Events推送运行进度，先回放历史事件
+func (s *Server) handleRunEvents(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.runs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("运行记录不存在: %s", r.PathValue("id")))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("不支持流式响应"))
+		return
+	}
+
+	history, events, unsubscribe := run.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	for _, event := range history {
+		writeEvent(w, event)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(w, event)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent 写入一条SSE事件
+func writeEvent(w http.ResponseWriter, event validator.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
+
+// writeJSON 输出JSON响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Printf("写入响应失败: %v", err)
+	}
+}
+
+// writeError 输出JSON错误响应
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"multi-database-validator-optimization/internal/notify"
+	"multi-database-validator-optimization/internal/types"
+	"multi-database-validator-optimization/pkg/validator"
+)
+
+// validConfig 能通过Trigger配置检查的最小配置，测试中不会真正连接
+func validConfig() (*types.Config, error) {
+	return &types.Config{
+		Azure: []validator.DatabaseInstance{{Name: "azure-1", Database: "db1"}},
+		AWS:   []validator.DatabaseInstance{{Name: "aws-1", Database: "db1"}},
+	}, nil
+}
+
+// newTestServer 创建只提供HTTP API的测试服务
+func newTestServer(t *testing.T, loader ConfigLoader) (*Server, *httptest.Server) {
+	s := New(loader)
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+// getJSON 发送GET请求并解析JSON响应
+func getJSON(t *testing.T, url string, v any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("解析 %s 响应失败: %v", url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// event 构造验证事件
+func event(typ, database, table string, rows int64) validator.Event {
+	return validator.Event{Type: typ, Time: time.Now().Format(time.RFC3339), Database: database, Table: table, Rows: rows}
+}
+
+func TestRunsAPI(t *testing.T) {
+	s, srv := newTestServer(t, validConfig)
+
+	if code := getJSON(t, srv.URL+"/healthz", nil); code != http.StatusOK {
+		t.Errorf("healthz = %d", code)
+	}
+	var runs []RunInfo
+	if getJSON(t, srv.URL+"/api/runs", &runs); len(runs) != 0 {
+		t.Fatalf("初始运行记录 = %+v", runs)
+	}
+
+	r, err := s.runs.start("manual", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.publish(validator.Event{Type: validator.EventRunStarted, Databases: 2})
+	r.publish(event(validator.EventDatabaseStarted, "db1", "", 0))
+	r.publish(event(validator.EventTableStarted, "db1", "users", 0))
+	r.publish(event(validator.EventChunkDone, "db1", "users", 500))
+	r.publish(event(validator.EventTableChecked, "db1", "users", 0))
+	id := r.info.ID
+
+	var info RunInfo
+	if code := getJSON(t, srv.URL+"/api/runs/"+id, &info); code != http.StatusOK {
+		t.Fatalf("GET run = %d", code)
+	}
+	if info.Status != RunStatusRunning || info.DatabasesTotal != 2 || info.TablesChecked != 1 || info.RowsHashed != 500 {
+		t.Errorf("运行中快照 = %+v", info)
+	}
+	if code := getJSON(t, srv.URL+"/api/runs/"+id+"/report", nil); code != http.StatusNotFound {
+		t.Errorf("运行中获取报告 = %d, 期望404", code)
+	}
+	if code := getJSON(t, srv.URL+"/api/runs/no-such-run", nil); code != http.StatusNotFound {
+		t.Errorf("不存在的运行 = %d", code)
+	}
+
+	// 已有运行进行中时拒绝新的触发
+	resp, err := http.Post(srv.URL+"/api/runs", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("重复触发 = %d, 期望409", resp.StatusCode)
+	}
+
+	r.finish(&validator.Report{TotalDatabases: 2, SuccessfulValidations: 2}, "report.json", nil)
+	s.runs.release(r)
+
+	if getJSON(t, srv.URL+"/api/runs/"+id, &info); info.Status != RunStatusFinished || info.ReportFile != "report.json" || info.ETA != "0s" {
+		t.Errorf("结束后快照 = %+v", info)
+	}
+	var report validator.Report
+	if code := getJSON(t, srv.URL+"/api/runs/"+id+"/report", &report); code != http.StatusOK || report.SuccessfulValidations != 2 {
+		t.Errorf("报告 = %d %+v", code, report)
+	}
+	if getJSON(t, srv.URL+"/api/runs", &runs); len(runs) != 1 || runs[0].ID != id {
+		t.Errorf("运行记录列表 = %+v", runs)
+	}
+}
+
+func TestTriggerRejectsInvalidConfig(t *testing.T) {
+	tests := map[string]ConfigLoader{
+		"加载失败": func() (*types.Config, error) { return nil, errors.New("文件不存在") },
+		"实例为空": func() (*types.Config, error) { return &types.Config{}, nil },
+		"实例数不同": func() (*types.Config, error) {
+			cfg, _ := validConfig()
+			cfg.AWS = nil
+			return cfg, nil
+		},
+	}
+	for name, loader := range tests {
+		s, srv := newTestServer(t, loader)
+		resp, err := http.Post(srv.URL+"/api/runs", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("%s: 状态码 = %d", name, resp.StatusCode)
+		}
+		if runs := s.runs.list(); len(runs) != 0 {
+			t.Errorf("%s: 不应创建运行记录: %+v", name, runs)
+		}
+	}
+}
+
+// readEvents 读取SSE流中的事件类型，直到流结束
+func readEvents(t *testing.T, resp *http.Response) []string {
+	t.Helper()
+	var names []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestRunEventsStream(t *testing.T) {
+	s, srv := newTestServer(t, validConfig)
+	r, _ := s.runs.start("manual", 1)
+	r.publish(validator.Event{Type: validator.EventRunStarted, Databases: 1})
+	r.publish(event(validator.EventTableStarted, "db1", "users", 0))
+
+	resp, err := http.Get(srv.URL + "/api/runs/" + r.info.ID + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	// 等待订阅建立后再发布实时事件
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		r.mu.Lock()
+		n := len(r.subscribers)
+		r.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("订阅未建立")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	r.publish(event(validator.EventChunkDone, "db1", "users", 100))
+	r.publish(event(validator.EventTableMismatch, "db1", "users", 0))
+	r.publish(validator.Event{Type: validator.EventRunFinished}) // 验证器的run_finished被忽略，由finish发送
+	r.finish(nil, "", errors.New("连接中断"))
+
+	got := strings.Join(readEvents(t, resp), ",")
+	want := "run_started,table_started,chunk_done,table_mismatch,run_finished"
+	if got != want {
+		t.Errorf("实时事件 = %s, 期望 %s", got, want)
+	}
+
+	// 结束后订阅：只回放历史，chunk_done不计入历史
+	replay, err := http.Get(srv.URL + "/api/runs/" + r.info.ID + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Body.Close()
+	if got := strings.Join(readEvents(t, replay), ","); got != "run_started,table_started,table_mismatch,run_finished" {
+		t.Errorf("回放事件 = %s", got)
+	}
+	if info := r.snapshot(); info.Status != RunStatusFailed || info.Error != "连接中断" {
+		t.Errorf("失败运行快照 = %+v", info)
+	}
+}
+
+func TestRunStoreHistory(t *testing.T) {
+	store := newRunStore(2)
+	var ids []string
+	for range 3 {
+		r, err := store.start("schedule:nightly", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.start("manual", 1); !errors.Is(err, ErrRunInProgress) {
+			t.Errorf("并发运行 err = %v", err)
+		}
+		r.finish(nil, "", nil)
+		store.release(r)
+		ids = append(ids, r.info.ID)
+	}
+
+	list := store.list()
+	if len(list) != 2 || list[0].ID != ids[2] || list[1].ID != ids[1] {
+		t.Errorf("保留的运行记录 = %+v, 期望 %v", list, ids[1:])
+	}
+	if _, ok := store.get(ids[0]); ok {
+		t.Error("最旧的运行记录未被淘汰")
+	}
+}
+
+func TestStopRejectsTrigger(t *testing.T) {
+	s, srv := newTestServer(t, validConfig)
+	s.Stop()
+
+	// Stop取消传给验证的ctx，之后的触发（包括定时任务）被拒绝
+	if s.ctx.Err() == nil {
+		t.Error("Stop后验证ctx未取消")
+	}
+	if _, err := s.Trigger("schedule:nightly"); !errors.Is(err, ErrStopped) {
+		t.Errorf("Stop后触发 err = %v", err)
+	}
+	resp, err := http.Post(srv.URL+"/api/runs", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Stop后手动触发 = %d, 期望503", resp.StatusCode)
+	}
+	if runs := s.runs.list(); len(runs) != 0 {
+		t.Errorf("不应创建运行记录: %+v", runs)
+	}
+}
+
+func TestNotifierShared(t *testing.T) {
+	s := New(validConfig)
+	cfg := notify.Config{
+		StateFile: filepath.Join(t.TempDir(), "notify_state.json"),
+		Sinks:     []notify.SinkConfig{{Name: "ops", Type: "command", Command: []string{"true"}}},
+	}
+	first, err := s.notifierFor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 配置不变时各次运行共用同一个通知器，去重状态文件只有一个写入者
+	same := cfg
+	same.Sinks = []notify.SinkConfig{{Name: "ops", Type: "command", Command: []string{"true"}}}
+	if n, _ := s.notifierFor(same); n != first {
+		t.Error("相同配置创建了新的通知器")
+	}
+	changed := cfg
+	changed.Sinks = []notify.SinkConfig{{Name: "ops", Type: "command", Command: []string{"true"}, Always: true}}
+	if n, _ := s.notifierFor(changed); n == first {
+		t.Error("配置变化后仍使用旧的通知器")
+	}
+}
@@ -1,8 +1,13 @@
 // internal/types/types.go
-// 共享类型定义
+// 共享类型定义，验证相关的类型见公开包pkg/validator
 
 package types
 
+import (
+	"multi-database-validator-optimization/internal/notify"
+	"multi-database-validator-optimization/pkg/validator"
+)
+
 // DatabaseConfig 数据库连接配置
 type DatabaseConfig struct {
 	Host     string `json:"host" yaml:"host" mapstructure:"host"`
@@ -11,64 +16,37 @@ type DatabaseConfig struct {
 	Charset  string `json:"charset" yaml:"charset" mapstructure:"charset"`
 }
 
-// DatabaseInstance 数据库实例配置
-type DatabaseInstance struct {
-	Name     string `json:"name" yaml:"name" mapstructure:"name"`             // 实例名称
-	Host     string `json:"host" yaml:"host" mapstructure:"host"`             // 实例主机地址
-	User     string `json:"user" yaml:"user" mapstructure:"user"`             // 用户名
-	Password string `json:"password" yaml:"password" mapstructure:"password"` // 密码
-	Database string `json:"database" yaml:"database" mapstructure:"database"` // 数据库名称
-	Charset  string `json:"charset" yaml:"charset" mapstructure:"charset"`    // 字符集
-}
-
-// TableComparison 表对比结果
-type TableComparison struct {
-	Table         string `json:"table" yaml:"table" mapstructure:"table"`
-	AzureChecksum string `json:"azure_checksum" yaml:"azure_checksum" mapstructure:"azure_checksum"`
-	AWSChecksum   string `json:"aws_checksum" yaml:"aws_checksum" mapstructure:"aws_checksum"`
-	Match         bool   `json:"match" yaml:"match" mapstructure:"match"`
-	AzureInstance string `json:"azure_instance" yaml:"azure_instance" mapstructure:"azure_instance"`
-	AWSInstance   string `json:"aws_instance" yaml:"aws_instance" mapstructure:"aws_instance"`
-	AzureDatabase string `json:"azure_database" yaml:"azure_database" mapstructure:"azure_database"`
-	AWSDatabase   string `json:"aws_database" yaml:"aws_database" mapstructure:"aws_database"`
-}
-
-// DatabaseResult 数据库验证结果
-type DatabaseResult struct {
-	Database         string            `json:"database" yaml:"database" mapstructure:"database"`
-	AzureInstance    string            `json:"azure_instance" yaml:"azure_instance" mapstructure:"azure_instance"` // Azure实例名称
-	AWSInstance      string            `json:"aws_instance" yaml:"aws_instance" mapstructure:"aws_instance"`       // AWS实例名称
-	AzureTables      int               `json:"azure_tables" yaml:"azure_tables" mapstructure:"azure_tables"`
-	AWSTables        int               `json:"aws_tables" yaml:"aws_tables" mapstructure:"aws_tables"`
-	TableComparisons []TableComparison `json:"table_comparisons" yaml:"table_comparisons" mapstructure:"table_comparisons"`
-	Status           string            `json:"status" yaml:"status" mapstructure:"status"`
-	Errors           []string          `json:"errors" yaml:"errors" mapstructure:"errors"`
-	StartTime        string            `json:"start_time" yaml:"start_time" mapstructure:"start_time"`
-	EndTime          string            `json:"end_time" yaml:"end_time" mapstructure:"end_time"`
-}
-
-// ValidationSummary 验证摘要
-type ValidationSummary struct {
-	Timestamp             string                    `json:"timestamp" yaml:"timestamp" mapstructure:"timestamp"`
-	TotalDatabases        int                       `json:"total_databases" yaml:"total_databases" mapstructure:"total_databases"`
-	SuccessfulValidations int                       `json:"successful_validations" yaml:"successful_validations" mapstructure:"successful_validations"`
-	InconsistentDatabases int                       `json:"inconsistent_databases" yaml:"inconsistent_databases" mapstructure:"inconsistent_databases"`
-	ErrorDatabases        int                       `json:"error_databases" yaml:"error_databases" mapstructure:"error_databases"`
-	SuccessRate           string                    `json:"success_rate" yaml:"success_rate" mapstructure:"success_rate"`
-	Results               map[string]DatabaseResult `json:"results" yaml:"results" mapstructure:"results"`
-}
-
 // Config 配置文件结构
 type Config struct {
-	Azure      []DatabaseInstance `json:"azure" yaml:"azure" mapstructure:"azure"`                   // Azure实例列表
-	AWS        []DatabaseInstance `json:"aws" yaml:"aws" mapstructure:"aws"`                         // AWS实例列表
-	MaxWorkers int                `json:"max_workers" yaml:"max_workers" mapstructure:"max_workers"` // 最大并发数
+	Azure      []validator.DatabaseInstance `json:"azure" yaml:"azure" mapstructure:"azure"`                                       // Azure实例列表
+	AWS        []validator.DatabaseInstance `json:"aws" yaml:"aws" mapstructure:"aws"`                                             // AWS实例列表
+	Discover   *validator.DiscoveryConfig   `json:"discover,omitempty" yaml:"discover,omitempty" mapstructure:"discover"`          // 自动发现数据库，与实例列表同时配置时两者都验证
+	Sharding   *validator.ShardingConfig    `json:"sharding,omitempty" yaml:"sharding,omitempty" mapstructure:"sharding"`          // 分片表映射：源表与多个实例上的分片表对比
+	GrantUsers []string                     `json:"grant_users,omitempty" yaml:"grant_users,omitempty" mapstructure:"grant_users"` // 需要对比有效权限的用户，如 app@%
+	Masking    []validator.MaskRule         `json:"masking,omitempty" yaml:"masking,omitempty" mapstructure:"masking"`             // 脱敏规则：目标端为脱敏数据时，源端的列先做相同变换
+	TextCheck  *validator.TextCheckConfig   `json:"text_check,omitempty" yaml:"text_check,omitempty" mapstructure:"text_check"`    // 文本完整性检查：非法UTF-8、双重编码和丢失的4字节字符
+	MaxWorkers int                          `json:"max_workers" yaml:"max_workers" mapstructure:"max_workers"`                     // 最大并发数
+	Hash       string                       `json:"hash" yaml:"hash" mapstructure:"hash"`                                          // 校验和哈希算法: xxhash(默认)、md5、sha256
+	Output     string                       `json:"output,omitempty" yaml:"output,omitempty" mapstructure:"output"`                // 报告文件名
+	OutputDir  string                       `json:"output_dir,omitempty" yaml:"output_dir,omitempty" mapstructure:"output_dir"`    // 输出目录
+	Verbose    bool                         `json:"verbose,omitempty" yaml:"verbose,omitempty" mapstructure:"verbose"`             // 详细输出
+	LogLevel   string                       `json:"log_level,omitempty" yaml:"log_level,omitempty" mapstructure:"log_level"`       // 日志级别
+	DryRun     bool                         `json:"dry_run,omitempty" yaml:"dry_run,omitempty" mapstructure:"dry_run"`             // 试运行模式
+	Serve      ServeConfig                  `json:"serve" yaml:"serve" mapstructure:"serve"`                                       // 守护进程配置
+	Notify     *notify.Config               `json:"notify,omitempty" yaml:"notify,omitempty" mapstructure:"notify"`                // 验证结果通知
+}
+
+// ServeConfig 守护进程(serve命令)配置
+type ServeConfig struct {
+	Addr       string           `json:"addr" yaml:"addr" mapstructure:"addr"`                      // HTTP监听地址
+	MaxHistory int              `json:"max_history" yaml:"max_history" mapstructure:"max_history"` // 保留的运行记录数
+	Schedules  []ScheduleConfig `json:"schedules" yaml:"schedules" mapstructure:"schedules"`       // 定时验证任务
 }
 
-// DatabasePair 数据库对比对
-type DatabasePair struct {
-	AzureInstance DatabaseInstance `json:"azure_instance" yaml:"azure_instance" mapstructure:"azure_instance"`
-	AWSInstance   DatabaseInstance `json:"aws_instance" yaml:"aws_instance" mapstructure:"aws_instance"`
+// ScheduleConfig 定时验证任务配置
+type ScheduleConfig struct {
+	Name string `json:"name" yaml:"name" mapstructure:"name"` // 任务名称
+	Cron string `json:"cron" yaml:"cron" mapstructure:"cron"` // cron表达式，如 "0 2 * * *" 或 "@every 6h"
 }
 
 // ValidationOptions 验证选项
@@ -0,0 +1,363 @@
+// pkg/validator/charset.go
+// 文本完整性检查：发现字符集转换造成的非法UTF-8、双重编码以及被截断或替换为'?'的4字节字符
+
+package validator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 文本问题类型
+const (
+	TextInvalidUTF8   = "invalid_utf8"    // 不是合法的UTF-8
+	TextDoubleEncoded = "double_encoded"  // UTF-8字节被当作latin1再次编码，如"Ã©"
+	TextTruncated4B   = "truncated_4byte" // 值在4字节字符（如emoji）处被截断
+	TextReplaced4B    = "replaced_4byte"  // 4字节字符被替换为'?'
+)
+
+// defaultTextSampleKeys 每类问题默认记录的示例主键数
+const defaultTextSampleKeys = 5
+
+// TextCheckConfig 文本完整性检查配置
+type TextCheckConfig struct {
+	Tables       []string `json:"tables,omitempty" yaml:"tables,omitempty" mapstructure:"tables"`                         // 只检查这些表，为空时检查所有表
+	SampleKeys   int      `json:"sample_keys,omitempty" yaml:"sample_keys,omitempty" mapstructure:"sample_keys"`          // 每类问题记录的示例主键数，默认5
+	FailOnIssues bool     `json:"fail_on_issues,omitempty" yaml:"fail_on_issues,omitempty" mapstructure:"fail_on_issues"` // AWS端有问题时该表记为不一致
+}
+
+// TextColumnSource 可列出表中文本列的数据源
+// 未实现时所有列都按文本检查，二进制列可能被误报为非法UTF-8
+type TextColumnSource interface {
+	TextColumns(ctx context.Context, table string) ([]string, error)
+}
+
+// TextIssue 一列中同一类文本问题
+type TextIssue struct {
+	Column     string   `json:"column" yaml:"column" mapstructure:"column"`
+	Side       string   `json:"side" yaml:"side" mapstructure:"side"` // 问题所在的一端，azure的问题在迁移前已存在
+	Kind       string   `json:"kind" yaml:"kind" mapstructure:"kind"`
+	Rows       int64    `json:"rows" yaml:"rows" mapstructure:"rows"`
+	SampleKeys []string `json:"sample_keys" yaml:"sample_keys" mapstructure:"sample_keys"` // 问题行第一列的值
+}
+
+// covers 是否检查表
+func (c *TextCheckConfig) covers(table string) bool {
+	return len(c.Tables) == 0 || contains(c.Tables, table)
+}
+
+// samples 每类问题记录的示例主键数
+func (c *TextCheckConfig) samples() int {
+	if c.SampleKeys > 0 {
+		return c.SampleKeys
+	}
+	return defaultTextSampleKeys
+}
+
+// textFailed 是否有AWS端的问题
+func textFailed(issues []TextIssue) bool {
+	for _, issue := range issues {
+		if issue.Side == SideAWS {
+			return true
+		}
+	}
+	return false
+}
+
+// TextSummary 文本问题摘要，如"AWS name 列双重编码 3 行"
+func TextSummary(issues []TextIssue) string {
+	names := map[string]string{
+		TextInvalidUTF8:   "非法UTF-8",
+		TextDoubleEncoded: "双重编码",
+		TextTruncated4B:   "4字节字符处截断",
+		TextReplaced4B:    "4字节字符被替换为?",
+	}
+	sides := map[string]string{SideAzure: "Azure", SideAWS: "AWS"}
+	parts := make([]string, len(issues))
+	for i, issue := range issues {
+		parts[i] = fmt.Sprintf("%s %s 列%s %d 行", sides[issue.Side], issue.Column, names[issue.Kind], issue.Rows)
+	}
+	return strings.Join(parts, "；")
+}
+
+// checkText 检查两端表中文本列的完整性
+// Azure端包含4字节字符的值按第一列暂存，与AWS端同一行对比，超过maxPendingRows后不再暂存
+func checkText(ctx context.Context, azureSrc, awsSrc Source, table string, samples int) ([]TextIssue, error) {
+	found := &textIssues{byKey: map[[3]string]*TextIssue{}, samples: samples}
+	pending := map[string]map[string]string{}
+	overflow := false
+
+	err := scanText(ctx, azureSrc, table, func(id []byte, column string, value []byte) {
+		kind := textKind(value)
+		if kind != "" {
+			found.add(SideAzure, column, kind, id)
+			return
+		}
+		if !hasFourByte(value) {
+			return
+		}
+		key := string(id)
+		if pending[key] == nil {
+			if len(pending) >= maxPendingRows {
+				overflow = true
+				return
+			}
+			pending[key] = map[string]string{}
+		}
+		pending[key][strings.ToLower(column)] = string(value)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Azure: %v", err)
+	}
+
+	err = scanText(ctx, awsSrc, table, func(id []byte, column string, value []byte) {
+		if kind := textKind(value); kind != "" {
+			found.add(SideAWS, column, kind, id)
+			return
+		}
+		src, ok := pending[string(id)][strings.ToLower(column)]
+		if !ok || value == nil || string(value) == src {
+			return
+		}
+		if kind := fourByteLoss(src, value); kind != "" {
+			found.add(SideAWS, column, kind, id)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AWS: %v", err)
+	}
+	if overflow {
+		return found.list(), fmt.Errorf("包含4字节字符的行超过 %d 行，只检查了部分行的4字节字符", maxPendingRows)
+	}
+	return found.list(), nil
+}
+
+// scanText 不排序读取表，对每一行中每个非NULL文本列调用onValue，参数在回调返回后被复用
+func scanText(ctx context.Context, src Source, table string, onValue func(id []byte, column string, value []byte)) error {
+	var textColumns []string
+	if s, ok := unwrapSource(src).(TextColumnSource); ok {
+		var err error
+		if textColumns, err = s.TextColumns(ctx, table); err != nil {
+			return fmt.Errorf("获取文本列失败: %v", err)
+		}
+		if len(textColumns) == 0 {
+			return nil
+		}
+	}
+
+	rows, err := openForDiff(ctx, src, table, true)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	raw, isRaw := rows.(rawRows)
+	var values valueBuffer
+	var columns []string
+	var check []int
+	for rows.Next() {
+		if columns == nil {
+			columns = rows.Columns()
+			for i, c := range columns {
+				if textColumns == nil || containsFold(textColumns, c) {
+					check = append(check, i)
+				}
+			}
+		}
+		var row [][]byte
+		if isRaw {
+			row = raw.rawValues()
+		} else {
+			row = values.from(rows.Values())
+		}
+		for _, i := range check {
+			if row[i] != nil {
+				onValue(row[0], columns[i], row[i])
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// containsFold 忽略大小写判断切片中是否包含字符串
+func containsFold(items []string, s string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// textIssues 按列、端、问题类型汇总
+type textIssues struct {
+	byKey   map[[3]string]*TextIssue
+	samples int
+}
+
+func (t *textIssues) add(side, column, kind string, id []byte) {
+	key := [3]string{column, side, kind}
+	issue := t.byKey[key]
+	if issue == nil {
+		issue = &TextIssue{Column: column, Side: side, Kind: kind}
+		t.byKey[key] = issue
+	}
+	issue.Rows++
+	if len(issue.SampleKeys) < t.samples {
+		issue.SampleKeys = append(issue.SampleKeys, string(id))
+	}
+}
+
+// list 按列、端（Azure在前）、问题类型排序
+func (t *textIssues) list() []TextIssue {
+	issues := make([]TextIssue, 0, len(t.byKey))
+	for _, issue := range t.byKey {
+		issues = append(issues, *issue)
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		if a.Side != b.Side {
+			return a.Side == SideAzure
+		}
+		return a.Kind < b.Kind
+	})
+	return issues
+}
+
+// textKind 不依赖另一端即可判断的问题：非法UTF-8或双重编码
+func textKind(value []byte) string {
+	if !utf8.Valid(value) {
+		return TextInvalidUTF8
+	}
+	if doubleEncoded(value) {
+		return TextDoubleEncoded
+	}
+	return ""
+}
+
+// doubleEncoded 是否包含被当作latin1（MySQL的latin1即cp1252）读取后再次编码的UTF-8多字节序列
+// 将每个字符还原为latin1字节后，以0xC2..0xF4开头、后跟续字节并能解码为非ASCII字符时判定为双重编码
+func doubleEncoded(value []byte) bool {
+	var seq [4]byte
+	for i := 0; i < len(value); {
+		r, size := utf8.DecodeRune(value[i:])
+		i += size
+		lead, ok := latin1Byte(r)
+		if !ok || lead < 0xC2 || lead > 0xF4 {
+			continue
+		}
+		n := 2
+		switch {
+		case lead >= 0xF0:
+			n = 4
+		case lead >= 0xE0:
+			n = 3
+		}
+		seq[0] = lead
+		j, k := i, 1
+		for ; k < n && j < len(value); k++ {
+			r, size := utf8.DecodeRune(value[j:])
+			b, ok := latin1Byte(r)
+			if !ok || b < 0x80 || b > 0xBF {
+				break
+			}
+			seq[k] = b
+			j += size
+		}
+		if k == n {
+			if r, size := utf8.DecodeRune(seq[:n]); r != utf8.RuneError && size == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cp1252 0x80..0x9F中有定义的字符对应的字节
+var cp1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// latin1Byte 字符在latin1（cp1252）中的字节
+func latin1Byte(r rune) (byte, bool) {
+	if r < 0x100 {
+		return byte(r), true
+	}
+	b, ok := cp1252[r]
+	return b, ok
+}
+
+// hasFourByte 是否包含4字节UTF-8字符（U+10000及以上，如emoji）
+func hasFourByte(value []byte) bool {
+	for _, b := range value {
+		if b >= 0xF0 {
+			return true
+		}
+	}
+	return false
+}
+
+// fourByteLoss 判断AWS端的值是否由Azure端的值丢失4字节字符得到
+// utf8（utf8mb3）列在非严格模式下会在第一个4字节字符处截断，字符集转换则将其替换为'?'
+func fourByteLoss(src string, dst []byte) string {
+	if bytes.HasPrefix([]byte(src), dst) {
+		if r, _ := utf8.DecodeRuneInString(src[len(dst):]); r >= 0x10000 {
+			return TextTruncated4B
+		}
+	}
+	for _, repl := range []string{"?", "????"} {
+		if replaceFourByte(src, repl) == string(dst) {
+			return TextReplaced4B
+		}
+	}
+	return ""
+}
+
+// replaceFourByte 将每个4字节字符替换为repl
+func replaceFourByte(s, repl string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 0x10000 {
+			b.WriteString(repl)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// TextColumns 列出表中的字符串列（CHAR、VARCHAR、TEXT、ENUM、SET）
+func (s *mysqlSource) TextColumns(ctx context.Context, table string) ([]string, error) {
+	query := `SELECT COLUMN_NAME FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+			AND DATA_TYPE IN ('char', 'varchar', 'tinytext', 'text', 'mediumtext', 'longtext', 'enum', 'set')
+		ORDER BY ORDINAL_POSITION`
+	rows, err := s.db.QueryContext(ctx, query, s.database, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
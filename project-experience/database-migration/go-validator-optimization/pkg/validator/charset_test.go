@@ -0,0 +1,95 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestDoubleEncoded(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"café", false},
+		{"cafÃ©", true},         // é 的UTF-8字节 C3 A9 按latin1读取
+		{"â€™s", true},          // ’ 的UTF-8字节 E2 80 99，0x80和0x99在cp1252中为€和™
+		{"ä¸­æ–‡", true},        // 中文
+		{"Â", false},            // 没有续字节
+		{"naïve résumé", false}, // 正常的latin1字符
+		{"数据库", false},
+	}
+	for _, tt := range tests {
+		if got := doubleEncoded([]byte(tt.value)); got != tt.want {
+			t.Errorf("doubleEncoded(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRunTextCheck(t *testing.T) {
+	azure := &memSource{tables: map[string][][]any{
+		"comments": {
+			{1, "café", "ok"},
+			{2, "good 👍 job", "ok"},
+			{3, "🎉 party", "ok"},
+			{4, "a 😀 b 😀", "ok"},
+			{5, "bad \xff", "ok"},
+		},
+	}}
+	aws := &memSource{tables: map[string][][]any{
+		"comments": {
+			{1, "cafÃ©", "ok"},
+			{2, "good ", "ok"},
+			{3, "", "ok"},
+			{4, "a ? b ?", "ok"},
+			{5, "bad \xff", "ok"},
+		},
+	}}
+	run := func(cfg TextCheckConfig) DatabaseResult {
+		report, err := New(
+			WithInstances(
+				[]DatabaseInstance{{Name: "azure-1", Database: "db1"}},
+				[]DatabaseInstance{{Name: "aws-1", Database: "db1"}},
+			),
+			WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+				if inst.Name == "azure-1" {
+					return azure, nil
+				}
+				return aws, nil
+			}),
+			WithTextCheck(cfg),
+			WithLogger(nil),
+		).Run(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		return report.Results[ResultKey("azure-1", "aws-1", "db1")]
+	}
+
+	result := run(TextCheckConfig{})
+	if len(result.TableComparisons) != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	var got []string
+	for _, issue := range result.TableComparisons[0].TextIssues {
+		got = append(got, fmt.Sprintf("%s/%s/%s/%d/%v", issue.Column, issue.Side, issue.Kind, issue.Rows, issue.SampleKeys))
+	}
+	want := "[c2/azure/invalid_utf8/1/[5] c2/aws/double_encoded/1/[1] c2/aws/invalid_utf8/1/[5] c2/aws/replaced_4byte/1/[4] c2/aws/truncated_4byte/2/[2 3]]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("issues = %v\nwant %s", got, want)
+	}
+
+	// 只检查指定的表
+	if result := run(TextCheckConfig{Tables: []string{"users"}}); result.TableComparisons[0].TextIssues != nil {
+		t.Errorf("unexpected issues: %+v", result.TableComparisons[0].TextIssues)
+	}
+
+	// AWS端的值与Azure端相同时不影响一致性，开启fail_on_issues后仍记为不一致
+	azure.tables["comments"] = aws.tables["comments"]
+	if result := run(TextCheckConfig{}); result.Status != StatusSuccess {
+		t.Errorf("status = %s", result.Status)
+	}
+	if result := run(TextCheckConfig{FailOnIssues: true}); result.Status != StatusInconsistent {
+		t.Errorf("fail_on_issues: status = %s", result.Status)
+	}
+}
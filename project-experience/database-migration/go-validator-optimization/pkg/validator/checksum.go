@@ -0,0 +1,326 @@
+// pkg/validator/checksum.go
+// 校验和策略
+
+package validator
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+	"time"
+)
+
+// 校验策略名称
+const (
+	StrategyFull     = "full"     // 单次全表读取
+	StrategyBatched  = "batched"  // 按批次读取
+	StrategyMultiset = "multiset" // 与行顺序无关的多重集指纹，用于没有唯一键的表
+)
+
+const (
+	// largeTableThreshold 超过该行数的表使用分批策略
+	largeTableThreshold = 100000
+	// defaultBatchSize 分批策略默认每批行数
+	defaultBatchSize = 10000
+	// emptyTableChecksum 空表的校验和
+	emptyTableChecksum = "empty_table"
+	// mysqlDateTime MySQL DATETIME的文本格式，小数秒为0时省略
+	mysqlDateTime = "2006-01-02 15:04:05.999999"
+)
+
+// ChunkFunc 分块完成回调，chunk从1开始，rows为该分块的行数
+type ChunkFunc func(chunk, chunks int, rows int64)
+
+// ChecksumStrategy 表校验和计算策略
+type ChecksumStrategy interface {
+	// Name 策略名称，记录在验证计划中
+	Name() string
+	// Chunks 给定行数时的分块数，用于进度和耗时估算
+	Chunks(rows int64) int
+	// Checksum 计算表的校验和，rows为表的精确行数，每个分块完成后调用onChunk
+	Checksum(ctx context.Context, src Source, table string, rows int64, onChunk ChunkFunc) (string, error)
+}
+
+// StrategySelector 根据行数选择校验策略名称
+type StrategySelector func(rows int64) string
+
+// ChooseStrategy 默认的策略选择：超过10万行的表分批计算
+func ChooseStrategy(rows int64) string {
+	if rows > largeTableThreshold {
+		return StrategyBatched
+	}
+	return StrategyFull
+}
+
+// FullStrategy 一次读取全表，逐行写入哈希
+type FullStrategy struct {
+	Hash HashFunc // 哈希算法，为空时使用xxhash
+}
+
+// Name 策略名称
+func (FullStrategy) Name() string { return StrategyFull }
+
+// Chunks 全表策略只有一个分块
+func (FullStrategy) Chunks(rows int64) int { return 1 }
+
+// Checksum 计算全表校验和
+func (s FullStrategy) Checksum(ctx context.Context, src Source, table string, rows int64, onChunk ChunkFunc) (string, error) {
+	h := s.Hash.orDefault()()
+	var rh rowHasher
+	n, err := rh.hashRows(ctx, src, table, 0, 0, h)
+	if err != nil {
+		return "", err
+	}
+
+	onChunk(1, 1, n)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// BatchedStrategy 按批次读取，分别计算每批哈希后再合并
+type BatchedStrategy struct {
+	BatchSize int64    // 每批行数，<=0时使用10000
+	Hash      HashFunc // 哈希算法，为空时使用xxhash
+}
+
+// Name 策略名称
+func (BatchedStrategy) Name() string { return StrategyBatched }
+
+// size 实际使用的批大小
+func (s BatchedStrategy) size() int64 {
+	if s.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+	return s.BatchSize
+}
+
+// Chunks 分批数
+func (s BatchedStrategy) Chunks(rows int64) int {
+	if rows <= 0 {
+		return 1
+	}
+	size := s.size()
+	return int((rows + size - 1) / size)
+}
+
+// Checksum 分批计算校验和，合并哈希的输入为各批次哈希的十六进制文本
+func (s BatchedStrategy) Checksum(ctx context.Context, src Source, table string, rows int64, onChunk ChunkFunc) (string, error) {
+	newHash := s.Hash.orDefault()
+	size := s.size()
+	chunks := s.Chunks(rows)
+
+	combined := newHash()
+	batch := newHash()
+	var rh rowHasher
+	var sum, digest []byte
+	chunk := 0
+	for offset := int64(0); offset < rows; offset += size {
+		batch.Reset()
+		n, err := rh.hashRows(ctx, src, table, offset, size, batch)
+		if err != nil {
+			return "", err
+		}
+
+		sum = batch.Sum(sum[:0])
+		digest = hex.AppendEncode(digest[:0], sum)
+		combined.Write(digest)
+		chunk++
+		onChunk(chunk, chunks, n)
+	}
+
+	return hex.EncodeToString(combined.Sum(nil)), nil
+}
+
+// MultisetStrategy 与行顺序无关的表指纹
+// 每行的哈希按64位分段累加（模2^64），加法满足交换律，重复行会被重复累加，
+// 因此行顺序不影响结果，而重复行数量不同会得到不同的指纹。结果同时包含行数。
+// 适用于没有唯一键、ORDER BY结果不确定的表
+type MultisetStrategy struct {
+	BatchSize int64    // 每处理多少行报告一次进度，<=0时使用10000
+	Hash      HashFunc // 每行使用的哈希算法，为空时使用xxhash
+}
+
+// Name 策略名称
+func (MultisetStrategy) Name() string { return StrategyMultiset }
+
+// Chunks 进度报告次数
+func (s MultisetStrategy) Chunks(rows int64) int {
+	return BatchedStrategy{BatchSize: s.BatchSize}.Chunks(rows)
+}
+
+// Checksum 一次读取全表，优先使用不排序的读取方式
+func (s MultisetStrategy) Checksum(ctx context.Context, src Source, table string, rows int64, onChunk ChunkFunc) (string, error) {
+	size := BatchedStrategy{BatchSize: s.BatchSize}.size()
+	chunks := s.Chunks(rows)
+
+	var it Rows
+	var err error
+	if u, ok := src.(UnorderedSource); ok {
+		it, err = u.ReadRowsUnordered(ctx, table)
+	} else {
+		it, err = src.ReadRows(ctx, table, 0, 0)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	h := s.Hash.orDefault()()
+	raw, isRaw := it.(rawRows)
+	var buf, sum []byte
+	var count, inChunk int64
+	var lanes [2]uint64
+	chunk := 0
+	for it.Next() {
+		buf = buf[:0]
+		if isRaw {
+			buf = appendRawRow(buf, raw.rawValues())
+		} else {
+			buf = AppendRow(buf, it.Values())
+		}
+		h.Reset()
+		h.Write(buf)
+		sum = h.Sum(sum[:0])
+		first, second := digestLanes(sum)
+		lanes[0] += first
+		lanes[1] += second
+
+		count++
+		inChunk++
+		if inChunk == size {
+			chunk++
+			onChunk(chunk, chunks, inChunk)
+			inChunk = 0
+		}
+	}
+	if err := it.Err(); err != nil {
+		return "", err
+	}
+	if inChunk > 0 || chunk == 0 {
+		chunk++
+		onChunk(chunk, chunks, inChunk)
+	}
+
+	return fmt.Sprintf("%d-%016x%016x", count, lanes[0], lanes[1]), nil
+}
+
+// digestLanes 将行哈希拆成两个64位分段，只有64位时第二段由第一段混合得到
+func digestLanes(sum []byte) (uint64, uint64) {
+	var first uint64
+	if len(sum) < 8 {
+		for _, b := range sum {
+			first = first<<8 | uint64(b)
+		}
+		return first, mix64(first)
+	}
+	first = binary.BigEndian.Uint64(sum)
+	if len(sum) < 16 {
+		return first, mix64(first)
+	}
+	return first, binary.BigEndian.Uint64(sum[8:])
+}
+
+// mix64 splitmix64的最终混合函数
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// rawRows 可直接提供列原始字节的行迭代器，编码时无需装箱为any
+type rawRows interface {
+	// rawValues 返回当前行各列的字节，NULL为nil，在下一次Next后失效
+	rawValues() [][]byte
+}
+
+// rowHasher 将行编码后写入哈希，编码缓冲区在各行和各批次之间复用
+type rowHasher struct {
+	buf []byte
+}
+
+// hashRows 读取行并逐行写入h，行之间以换行分隔，返回读取的行数
+func (rh *rowHasher) hashRows(ctx context.Context, src Source, table string, offset, limit int64, h hash.Hash) (int64, error) {
+	rows, err := src.ReadRows(ctx, table, offset, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	raw, isRaw := rows.(rawRows)
+	buf := rh.buf
+	defer func() { rh.buf = buf }()
+
+	var n int64
+	for rows.Next() {
+		buf = buf[:0]
+		if n > 0 {
+			buf = append(buf, '\n')
+		}
+		if isRaw {
+			buf = appendRawRow(buf, raw.rawValues())
+		} else {
+			buf = AppendRow(buf, rows.Values())
+		}
+		h.Write(buf)
+		n++
+	}
+	return n, rows.Err()
+}
+
+// EncodeRow 行的规范编码，各列以"|"连接
+// NULL编码为"NULL"，[]byte按文本编码，time.Time按MySQL的DATETIME文本格式编码，其余按%v格式化
+// MySQL数据源与文件数据源产生相同的值文本时得到相同的编码
+func EncodeRow(values []any) string {
+	return string(AppendRow(nil, values))
+}
+
+// AppendRow 将行的规范编码追加到dst，与EncodeRow相同
+func AppendRow(dst []byte, values []any) []byte {
+	for i, val := range values {
+		if i > 0 {
+			dst = append(dst, '|')
+		}
+		dst = appendValue(dst, val)
+	}
+	return dst
+}
+
+// appendRawRow 追加原始字节行的规范编码
+func appendRawRow(dst []byte, values [][]byte) []byte {
+	for i, val := range values {
+		if i > 0 {
+			dst = append(dst, '|')
+		}
+		if val == nil {
+			dst = append(dst, "NULL"...)
+		} else {
+			dst = append(dst, val...)
+		}
+	}
+	return dst
+}
+
+// appendValue 追加单个值的规范编码
+func appendValue(dst []byte, val any) []byte {
+	switch v := val.(type) {
+	case nil:
+		return append(dst, "NULL"...)
+	case []byte:
+		return append(dst, v...)
+	case string:
+		return append(dst, v...)
+	case int64:
+		return strconv.AppendInt(dst, v, 10)
+	case int:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case time.Time:
+		return v.AppendFormat(dst, mysqlDateTime)
+	default:
+		return fmt.Appendf(dst, "%v", v)
+	}
+}
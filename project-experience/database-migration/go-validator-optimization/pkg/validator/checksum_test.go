@@ -0,0 +1,113 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// genSource 按需生成行的数据源，不在内存中保存表数据
+type genSource struct {
+	memSource
+	rows int64
+}
+
+func (s *genSource) CountRows(ctx context.Context, table string) (int64, error) { return s.rows, nil }
+
+func (s *genSource) ReadRows(ctx context.Context, table string, offset, limit int64) (Rows, error) {
+	end := s.rows
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return &genRows{next: offset, end: end, id: make([]byte, 0, 20), name: make([]byte, 0, 64), raw: make([][]byte, 3)}, nil
+}
+
+// genRows 与sqlRows相同，复用每列的缓冲区
+type genRows struct {
+	next, end int64
+	id, name  []byte
+	raw       [][]byte
+}
+
+func (r *genRows) Columns() []string { return []string{"id", "name", "note"} }
+
+func (r *genRows) Next() bool {
+	if r.next >= r.end {
+		return false
+	}
+	r.id = strconv.AppendInt(r.id[:0], r.next, 10)
+	r.name = append(strconv.AppendInt(append(r.name[:0], "name-"...), r.next, 10), " with some padding text"...)
+	r.raw[0], r.raw[1], r.raw[2] = r.id, r.name, nil
+	r.next++
+	return true
+}
+
+func (r *genRows) Values() []any       { return []any{r.raw[0], r.raw[1], nil} }
+func (r *genRows) rawValues() [][]byte { return r.raw }
+func (r *genRows) Err() error          { return nil }
+func (r *genRows) Close() error        { return nil }
+
+func checksumOnce(tb testing.TB, s ChecksumStrategy, src Source, rows int64) {
+	if _, err := s.Checksum(context.Background(), src, "t", rows, func(int, int, int64) {}); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// 分配次数与表的行数无关，内存占用不随表大小增长
+// 行数增加100倍时，编码缓冲区只会因值变长多扩容一两次
+func TestChecksumAllocationsIndependentOfRows(t *testing.T) {
+	allocs := func(s ChecksumStrategy, rows int64) float64 {
+		src := &genSource{rows: rows}
+		return testing.AllocsPerRun(5, func() { checksumOnce(t, s, src, rows) })
+	}
+
+	if small, large := allocs(FullStrategy{}, 1000), allocs(FullStrategy{}, 100000); large > small+2 {
+		t.Errorf("full: %v allocs for 100k rows, %v for 1k rows", large, small)
+	}
+	// 分批策略每批打开一次Rows，批数相同时分配次数应相同
+	if small, large := allocs(BatchedStrategy{BatchSize: 100}, 1000), allocs(BatchedStrategy{BatchSize: 10000}, 100000); large > small+2 {
+		t.Errorf("batched: %v allocs for 100k rows, %v for 1k rows", large, small)
+	}
+}
+
+func TestHashAlgorithmsDiffer(t *testing.T) {
+	src := &genSource{rows: 10}
+	seen := map[string]string{}
+	for _, name := range []string{HashXXHash, HashMD5, HashSHA256} {
+		newHash, err := NewHash(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := FullStrategy{Hash: newHash}.Checksum(context.Background(), src, "t", 10, func(int, int, int64) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := seen[sum]; ok {
+			t.Errorf("%s and %s produced the same checksum", name, other)
+		}
+		seen[sum] = name
+	}
+	if _, err := NewHash("crc32"); err == nil {
+		t.Error("expected error for unknown hash")
+	}
+}
+
+func BenchmarkChecksum(b *testing.B) {
+	for _, name := range []string{HashXXHash, HashMD5, HashSHA256} {
+		newHash, _ := NewHash(name)
+		strategies := []ChecksumStrategy{FullStrategy{Hash: newHash}, BatchedStrategy{Hash: newHash}}
+		for _, s := range strategies {
+			for _, rows := range []int64{1000, 100000, 1000000} {
+				b.Run(fmt.Sprintf("%s/%s/rows=%d", name, s.Name(), rows), func(b *testing.B) {
+					src := &genSource{rows: rows}
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						checksumOnce(b, s, src, rows)
+					}
+					b.ReportMetric(float64(rows)*float64(b.N)/b.Elapsed().Seconds(), "rows/s")
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,471 @@
+// pkg/validator/columns.go
+// 列级差异定位：每列指纹在计算校验和时累加，只对校验和不一致的表比较，不输出原始数据
+
+package validator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const (
+	// maxPendingRows 行级对比时暂存的未配对行数上限，超过后只统计分块级差异
+	maxPendingRows = 200000
+	// nullHash NULL值的列哈希，与任何非NULL值（包括文本"NULL"）不同
+	nullHash = 0x9e3779b97f4a7c15
+)
+
+// TableDiff 不一致表的列级差异
+type TableDiff struct {
+	Summary          string       `json:"summary" yaml:"summary" mapstructure:"summary"`                                                      // 差异摘要，如"仅 price 列不一致，涉及 1,204 行"
+	Columns          []ColumnDiff `json:"columns,omitempty" yaml:"columns,omitempty" mapstructure:"columns"`                                  // 值不一致的列
+	AzureOnlyColumns []string     `json:"azure_only_columns,omitempty" yaml:"azure_only_columns,omitempty" mapstructure:"azure_only_columns"` // 只在Azure表中存在的列
+	AWSOnlyColumns   []string     `json:"aws_only_columns,omitempty" yaml:"aws_only_columns,omitempty" mapstructure:"aws_only_columns"`       // 只在AWS表中存在的列
+	AzureOnlyRows    int64        `json:"azure_only_rows,omitempty" yaml:"azure_only_rows,omitempty" mapstructure:"azure_only_rows"`          // 唯一键列的值只在Azure中出现的行数
+	AWSOnlyRows      int64        `json:"aws_only_rows,omitempty" yaml:"aws_only_rows,omitempty" mapstructure:"aws_only_rows"`                // 唯一键列的值只在AWS中出现的行数
+	ChunkSize        int64        `json:"chunk_size,omitempty" yaml:"chunk_size,omitempty" mapstructure:"chunk_size"`                         // 分块行数，有序表按该大小累加各列的分块指纹
+	MismatchedChunks []int        `json:"mismatched_chunks,omitempty" yaml:"mismatched_chunks,omitempty" mapstructure:"mismatched_chunks"`    // 存在列指纹不一致的分块，从1开始
+	RowsCounted      bool         `json:"rows_counted" yaml:"rows_counted" mapstructure:"rows_counted"`                                       // 是否按唯一键列配对统计了行数；无唯一键或差异过多时为false
+}
+
+// ColumnDiff 单列的差异
+type ColumnDiff struct {
+	Column string `json:"column" yaml:"column" mapstructure:"column"`
+	Rows   int64  `json:"rows,omitempty" yaml:"rows,omitempty" mapstructure:"rows"`       // 唯一键相同但该列值不同的行数
+	Chunks []int  `json:"chunks,omitempty" yaml:"chunks,omitempty" mapstructure:"chunks"` // 该列指纹不一致的分块
+}
+
+// diffColumns 根据第一遍计算校验和时累加的每列指纹，定位不一致的列
+// 有序表逐个分块比较每列的指纹，只重新读取不一致的分块，按唯一键列的值配对统计每列不一致的行数；
+// 无唯一键的表（multiset策略）只比较每列在全表上的指纹
+func diffColumns(ctx context.Context, azureSrc, awsSrc Source, table, strategy string, azureSums, awsSums *columnSums) (*TableDiff, error) {
+	azure := &diffSide{name: "Azure", src: azureSrc, sums: azureSums}
+	aws := &diffSide{name: "AWS", src: awsSrc, sums: awsSums}
+	for _, side := range []*diffSide{azure, aws} {
+		if err := side.loadColumns(ctx, table); err != nil {
+			return nil, err
+		}
+	}
+
+	// 按列名对齐两端的列
+	diff := &TableDiff{}
+	awsIndex := make(map[string]int, len(aws.columns))
+	for i, col := range aws.columns {
+		awsIndex[col] = i
+	}
+	var common []string
+	for i, col := range azure.columns {
+		if j, ok := awsIndex[col]; ok {
+			common = append(common, col)
+			azure.pos = append(azure.pos, i)
+			aws.pos = append(aws.pos, j)
+			delete(awsIndex, col)
+		} else {
+			diff.AzureOnlyColumns = append(diff.AzureOnlyColumns, col)
+		}
+	}
+	for col := range awsIndex {
+		diff.AWSOnlyColumns = append(diff.AWSOnlyColumns, col)
+	}
+	sort.Strings(diff.AWSOnlyColumns)
+
+	rowDiffs := make([]int64, len(common))
+	chunkDiffs := make([][]int, len(common))
+	unordered := strategy == StrategyMultiset
+	azureTotals, awsTotals := azureSums.totals(), awsSums.totals()
+
+	if !unordered {
+		chunkSize := azureSums.chunkSize
+		diff.ChunkSize = chunkSize
+		var mismatched []int64
+		for k := 0; k < max(len(azureSums.chunks), len(awsSums.chunks)); k++ {
+			a, b := azureSums.chunk(k), awsSums.chunk(k)
+			bad := false
+			for c := range common {
+				if sumAt(a, azure.pos[c]) != sumAt(b, aws.pos[c]) {
+					chunkDiffs[c] = append(chunkDiffs[c], k+1)
+					bad = true
+				}
+			}
+			if bad {
+				diff.MismatchedChunks = append(diff.MismatchedChunks, k+1)
+				mismatched = append(mismatched, int64(k))
+			}
+		}
+
+		// 唯一键列即Azure端的第一列，按列名在AWS端定位，两端列顺序不同时也能配对
+		azure.key, aws.key = -1, -1
+		if len(azure.columns) > 0 {
+			azure.key = 0
+			if j, ok := indexOf(aws.columns, azure.columns[0]); ok {
+				aws.key = j
+			}
+		}
+		if aws.key >= 0 {
+			// 指纹相同的分块行集合相同，只需重新读取不一致的分块配对
+			matcher := newRowMatcher(rowDiffs)
+			hashes := make([]uint64, len(common))
+			for _, k := range mismatched {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				if err := azure.matchChunk(ctx, table, k*chunkSize, chunkSize, matcher, true, hashes); err != nil {
+					return nil, err
+				}
+				if err := aws.matchChunk(ctx, table, k*chunkSize, chunkSize, matcher, false, hashes); err != nil {
+					return nil, err
+				}
+				if matcher.overflow {
+					break
+				}
+			}
+			diff.RowsCounted = !matcher.overflow
+			if diff.RowsCounted {
+				diff.AzureOnlyRows = int64(len(matcher.pending[0]))
+				diff.AWSOnlyRows = int64(len(matcher.pending[1]))
+			}
+		}
+	}
+
+	for c, col := range common {
+		// 行数不同时之后的分块整体错位，能按唯一键配对时以逐行比较的结果为准
+		var differs bool
+		switch {
+		case unordered:
+			differs = sumAt(azureTotals, azure.pos[c]) != sumAt(awsTotals, aws.pos[c])
+		case diff.RowsCounted:
+			differs = rowDiffs[c] > 0
+		default:
+			differs = len(chunkDiffs[c]) > 0
+		}
+		if !differs {
+			continue
+		}
+		cd := ColumnDiff{Column: col, Chunks: chunkDiffs[c]}
+		if diff.RowsCounted {
+			cd.Rows = rowDiffs[c]
+		}
+		diff.Columns = append(diff.Columns, cd)
+	}
+	diff.Summary = diff.summarize()
+	return diff, nil
+}
+
+// sumAt 第c列的指纹，该端没有该分块时为0
+func sumAt(sums []uint64, c int) uint64 {
+	if c >= len(sums) {
+		return 0
+	}
+	return sums[c]
+}
+
+// indexOf 列名在列表中的下标
+func indexOf(columns []string, name string) (int, bool) {
+	for i, col := range columns {
+		if col == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// openForDiff 打开读取，无唯一键的表优先不排序读取
+func openForDiff(ctx context.Context, src Source, table string, unordered bool) (Rows, error) {
+	if u, ok := src.(UnorderedSource); ok && unordered {
+		return u.ReadRowsUnordered(ctx, table)
+	}
+	return src.ReadRows(ctx, table, 0, 0)
+}
+
+// diffSide 一端的差异定位状态
+type diffSide struct {
+	name    string // Azure 或 AWS，用于错误信息
+	src     Source
+	sums    *columnSums
+	columns []string
+	pos     []int // 公共列在该端的下标
+	key     int   // 唯一键列在该端的下标，不存在时为-1
+	convert valueBuffer
+}
+
+// loadColumns 获取该端的列名，第一遍没有读到行（如空表）时读取一行获取
+func (s *diffSide) loadColumns(ctx context.Context, table string) error {
+	if s.sums.columns != nil {
+		s.columns = s.sums.columns
+		return nil
+	}
+	rows, err := s.src.ReadRows(ctx, table, 0, 1)
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.name, err)
+	}
+	defer rows.Close()
+	// 部分数据源（如没有schema的JSONL）读到第一行后才知道列名
+	rows.Next()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %v", s.name, err)
+	}
+	s.columns = rows.Columns()
+	return nil
+}
+
+// matchChunk 重新读取一个分块，将每行公共列的哈希按唯一键加入配对器
+func (s *diffSide) matchChunk(ctx context.Context, table string, offset, limit int64, m *rowMatcher, azure bool, hashes []uint64) error {
+	rows, err := s.src.ReadRows(ctx, table, offset, limit)
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.name, err)
+	}
+	defer rows.Close()
+	for !m.overflow && rows.Next() {
+		values := rowBytes(rows, &s.convert)
+		for c, i := range s.pos {
+			hashes[c] = hashBytes(values[i])
+		}
+		m.add(azure, string(values[s.key]), hashes)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %v", s.name, err)
+	}
+	return nil
+}
+
+// columnSums 计算校验和的同一遍读取中按分块累加的每列指纹
+// 第i行（从0开始）属于第i/chunkSize个分块，分块内每个值的哈希经mix64后按列累加（模2^64），
+// 与分块内的行顺序无关。只在一个goroutine中使用
+type columnSums struct {
+	chunkSize int64
+	columns   []string   // 该端的列名，读到第一行后确定
+	chunks    [][]uint64 // 每个分块按该端列顺序的指纹
+}
+
+// newColumnSums 创建每列指纹，chunkSize与分批策略的批大小一致
+func newColumnSums(chunkSize int64) *columnSums {
+	if chunkSize <= 0 {
+		chunkSize = defaultBatchSize
+	}
+	return &columnSums{chunkSize: chunkSize}
+}
+
+// wrap 包装数据源，经其读取的每一行都累加到每列指纹
+func (s *columnSums) wrap(src Source) Source {
+	return &columnSource{Source: src, sums: s}
+}
+
+// add 累加第row行各列的哈希
+func (s *columnSums) add(row int64, hashes []uint64) {
+	k := int(row / s.chunkSize)
+	for len(s.chunks) <= k {
+		s.chunks = append(s.chunks, nil)
+	}
+	if s.chunks[k] == nil {
+		s.chunks[k] = make([]uint64, len(hashes))
+	}
+	for c, h := range hashes {
+		s.chunks[k][c] += mix64(h)
+	}
+}
+
+// chunk 第k个分块的指纹，没有该分块时为nil
+func (s *columnSums) chunk(k int) []uint64 {
+	if k >= len(s.chunks) {
+		return nil
+	}
+	return s.chunks[k]
+}
+
+// totals 全表每列的指纹，用于无唯一键的表
+func (s *columnSums) totals() []uint64 {
+	totals := make([]uint64, len(s.columns))
+	for _, sums := range s.chunks {
+		for c, sum := range sums {
+			totals[c] += sum
+		}
+	}
+	return totals
+}
+
+// columnSource 读取时累加每列指纹的数据源
+// 实现KeyChecker和UnorderedSource，底层数据源未实现时与未实现该接口的行为相同
+type columnSource struct {
+	Source
+	sums *columnSums
+}
+
+// ReadRows 按第一列排序读取，offset用于确定行所在的分块
+func (s *columnSource) ReadRows(ctx context.Context, table string, offset, limit int64) (Rows, error) {
+	rows, err := s.Source.ReadRows(ctx, table, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &columnRows{Rows: rows, sums: s.sums, row: offset}, nil
+}
+
+// ReadRowsUnordered 不排序读取，底层不支持时按第一列排序读取
+func (s *columnSource) ReadRowsUnordered(ctx context.Context, table string) (Rows, error) {
+	var rows Rows
+	var err error
+	if u, ok := s.Source.(UnorderedSource); ok {
+		rows, err = u.ReadRowsUnordered(ctx, table)
+	} else {
+		rows, err = s.Source.ReadRows(ctx, table, 0, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &columnRows{Rows: rows, sums: s.sums}, nil
+}
+
+// HasUniqueKey 转发给底层数据源，未实现时按有唯一键处理
+func (s *columnSource) HasUniqueKey(ctx context.Context, table string) (bool, error) {
+	if checker, ok := s.Source.(KeyChecker); ok {
+		return checker.HasUniqueKey(ctx, table)
+	}
+	return true, nil
+}
+
+// unwrap 返回底层数据源
+func (s *columnSource) unwrap() Source { return s.Source }
+
+// columnRows 读取每一行时计算各列哈希并累加的行迭代器
+type columnRows struct {
+	Rows
+	sums    *columnSums
+	row     int64 // 下一行在表中的行号
+	values  [][]byte
+	hashes  []uint64
+	convert valueBuffer
+}
+
+// Next 读取下一行并累加各列哈希
+func (r *columnRows) Next() bool {
+	if !r.Rows.Next() {
+		return false
+	}
+	r.values = rowBytes(r.Rows, &r.convert)
+	if len(r.hashes) != len(r.values) {
+		r.hashes = make([]uint64, len(r.values))
+	}
+	if r.sums.columns == nil {
+		r.sums.columns = r.Rows.Columns()
+	}
+	for c, v := range r.values {
+		r.hashes[c] = hashBytes(v)
+	}
+	r.sums.add(r.row, r.hashes)
+	r.row++
+	return true
+}
+
+func (r *columnRows) rawValues() [][]byte { return r.values }
+
+// rowBytes 当前行各列的字节，NULL为nil
+func rowBytes(rows Rows, convert *valueBuffer) [][]byte {
+	if raw, ok := rows.(rawRows); ok {
+		return raw.rawValues()
+	}
+	return convert.from(rows.Values())
+}
+
+// hashBytes 单个值的哈希，nil为NULL
+func hashBytes(b []byte) uint64 {
+	if b == nil {
+		return nullHash
+	}
+	return xxhash.Sum64(b)
+}
+
+// rowMatcher 按唯一键列的值配对两端的行，统计每列不一致的行数
+// 两端按相同顺序读取，未配对的行只在差异附近短暂保留
+type rowMatcher struct {
+	pending  [2]map[string][]uint64 // 0为Azure，1为AWS
+	rowDiffs []int64
+	overflow bool
+}
+
+// newRowMatcher 创建行配对器
+func newRowMatcher(rowDiffs []int64) *rowMatcher {
+	return &rowMatcher{
+		pending:  [2]map[string][]uint64{make(map[string][]uint64), make(map[string][]uint64)},
+		rowDiffs: rowDiffs,
+	}
+}
+
+// add 加入一行，另一端已有相同键的行时比较各列
+func (m *rowMatcher) add(azure bool, key string, hashes []uint64) {
+	if m.overflow {
+		return
+	}
+	self, other := 0, 1
+	if !azure {
+		self, other = 1, 0
+	}
+	if peer, ok := m.pending[other][key]; ok {
+		delete(m.pending[other], key)
+		for c, h := range hashes {
+			if peer[c] != h {
+				m.rowDiffs[c]++
+			}
+		}
+		return
+	}
+	if _, dup := m.pending[self][key]; dup || len(m.pending[0])+len(m.pending[1]) >= maxPendingRows {
+		// 唯一键有重复值或差异过多，无法可靠配对
+		m.overflow = true
+		m.pending = [2]map[string][]uint64{}
+		return
+	}
+	m.pending[self][key] = append([]uint64(nil), hashes...)
+}
+
+// summarize 生成差异摘要
+func (d *TableDiff) summarize() string {
+	var parts []string
+	if len(d.AzureOnlyColumns) > 0 {
+		parts = append(parts, "仅Azure存在的列: "+strings.Join(d.AzureOnlyColumns, ", "))
+	}
+	if len(d.AWSOnlyColumns) > 0 {
+		parts = append(parts, "仅AWS存在的列: "+strings.Join(d.AWSOnlyColumns, ", "))
+	}
+
+	if len(d.Columns) > 0 {
+		names := make([]string, len(d.Columns))
+		for i, c := range d.Columns {
+			names[i] = c.Column
+			if d.RowsCounted {
+				names[i] += fmt.Sprintf("(%s行)", formatCount(c.Rows))
+			}
+		}
+		if len(d.Columns) == 1 && d.RowsCounted {
+			parts = append(parts, fmt.Sprintf("仅 %s 列不一致，涉及 %s 行", d.Columns[0].Column, formatCount(d.Columns[0].Rows)))
+		} else {
+			parts = append(parts, "不一致的列: "+strings.Join(names, ", "))
+		}
+	}
+
+	if d.AzureOnlyRows > 0 {
+		parts = append(parts, fmt.Sprintf("Azure多 %s 行", formatCount(d.AzureOnlyRows)))
+	}
+	if d.AWSOnlyRows > 0 {
+		parts = append(parts, fmt.Sprintf("AWS多 %s 行", formatCount(d.AWSOnlyRows)))
+	}
+	if len(parts) == 0 {
+		return "各列值一致，差异来自行顺序或NULL与文本\"NULL\"的编码"
+	}
+	return strings.Join(parts, "；")
+}
+
+// formatCount 千位分隔的整数
+func formatCount(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	if n < 0 {
+		return s
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
@@ -0,0 +1,183 @@
+// pkg/validator/discover.go
+// 自动发现两端服务器上的数据库并组成对比对
+
+package validator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// systemSchemas MySQL系统库，自动发现时始终排除
+var systemSchemas = []string{"information_schema", "mysql", "performance_schema", "sys"}
+
+// DiscoveryConfig 自动发现配置
+type DiscoveryConfig struct {
+	Azure   DatabaseInstance `json:"azure" yaml:"azure" mapstructure:"azure"`                           // Azure服务器，database留空
+	AWS     DatabaseInstance `json:"aws" yaml:"aws" mapstructure:"aws"`                                 // AWS服务器，database留空
+	Exclude []string         `json:"exclude,omitempty" yaml:"exclude,omitempty" mapstructure:"exclude"` // 排除的数据库名，支持通配符，如 "test_*"
+	Rename  []DatabaseRename `json:"rename,omitempty" yaml:"rename,omitempty" mapstructure:"rename"`    // 迁移时改名的数据库
+}
+
+// DatabaseRename 迁移时改名的数据库
+type DatabaseRename struct {
+	Azure string `json:"azure" yaml:"azure" mapstructure:"azure"` // Azure端的数据库名
+	AWS   string `json:"aws" yaml:"aws" mapstructure:"aws"`       // AWS端的数据库名
+}
+
+// DatabaseLister 列出实例上的所有数据库
+type DatabaseLister func(ctx context.Context, instance DatabaseInstance) ([]string, error)
+
+// Discovery 自动发现的结果
+type Discovery struct {
+	Pairs        []DatabasePair
+	MissingInAWS []string // 只在Azure中存在的数据库
+	ExtraInAWS   []string // 只在AWS中存在的数据库
+}
+
+// ListDatabases 列出实例上的数据库，默认的DatabaseLister
+// MySQL实例执行SHOW DATABASES，files实例的每个子目录为一个数据库
+func ListDatabases(ctx context.Context, instance DatabaseInstance) ([]string, error) {
+	if instance.IsFiles() {
+		return listDirectories(instance.Path)
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/?charset=%s", instance.User, instance.Password, instance.Host, instance.Charset)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("连接失败: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "SHOW DATABASES")
+	if err != nil {
+		return nil, fmt.Errorf("SHOW DATABASES失败: %v", err)
+	}
+	defer rows.Close()
+
+	var databases []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		databases = append(databases, name)
+	}
+	return databases, rows.Err()
+}
+
+// listDirectories 列出目录下的子目录
+func listDirectories(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("连接失败: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name()[0] != '.' {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// Discover 列出两端的数据库，按名称或改名规则配对
+// 系统库和匹配Exclude的数据库不参与配对
+func Discover(ctx context.Context, cfg DiscoveryConfig, list DatabaseLister) (*Discovery, error) {
+	if list == nil {
+		list = ListDatabases
+	}
+	for _, pattern := range cfg.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("排除规则 %q 无效: %v", pattern, err)
+		}
+	}
+
+	azureDatabases, err := list(ctx, cfg.Azure)
+	if err != nil {
+		return nil, fmt.Errorf("列出Azure服务器 %s 的数据库失败: %v", cfg.Azure.Name, err)
+	}
+	awsDatabases, err := list(ctx, cfg.AWS)
+	if err != nil {
+		return nil, fmt.Errorf("列出AWS服务器 %s 的数据库失败: %v", cfg.AWS.Name, err)
+	}
+
+	rename := make(map[string]string, len(cfg.Rename))
+	for _, r := range cfg.Rename {
+		rename[r.Azure] = r.AWS
+	}
+
+	awsSet := make(map[string]bool, len(awsDatabases))
+	for _, name := range awsDatabases {
+		if !cfg.excluded(name) {
+			awsSet[name] = true
+		}
+	}
+
+	result := &Discovery{}
+	sort.Strings(azureDatabases)
+	for _, name := range azureDatabases {
+		if cfg.excluded(name) {
+			continue
+		}
+		target := name
+		if renamed, ok := rename[name]; ok {
+			target = renamed
+		}
+		if !awsSet[target] {
+			result.MissingInAWS = append(result.MissingInAWS, name)
+			continue
+		}
+		delete(awsSet, target)
+		result.Pairs = append(result.Pairs, DatabasePair{
+			AzureInstance: cfg.Azure.forDatabase(name),
+			AWSInstance:   cfg.AWS.forDatabase(target),
+		})
+	}
+	for name := range awsSet {
+		result.ExtraInAWS = append(result.ExtraInAWS, name)
+	}
+	sort.Strings(result.ExtraInAWS)
+	return result, nil
+}
+
+// excluded 是否为系统库或匹配排除规则
+func (cfg DiscoveryConfig) excluded(name string) bool {
+	for _, schema := range systemSchemas {
+		if name == schema {
+			return true
+		}
+	}
+	for _, pattern := range cfg.Exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// instance 按计划中的实例名称和数据库名称还原自动发现的实例
+func (cfg DiscoveryConfig) instance(side, name, database string) (DatabaseInstance, bool) {
+	server := cfg.Azure
+	if side == SideAWS {
+		server = cfg.AWS
+	}
+	if server.Name != name {
+		return DatabaseInstance{}, false
+	}
+	return server.forDatabase(database), true
+}
+
+// forDatabase 返回指向服务器上指定数据库的实例，files实例的路径为子目录
+func (i DatabaseInstance) forDatabase(database string) DatabaseInstance {
+	i.Database = database
+	if i.IsFiles() {
+		i.Path = filepath.Join(i.Path, database)
+	}
+	return i
+}
@@ -0,0 +1,691 @@
+// pkg/validator/files.go
+// 文件数据源：CSV、TSV、JSONL导出文件
+
+package validator
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 导出文件格式
+const (
+	FormatCSV   = "csv"
+	FormatTSV   = "tsv"
+	FormatJSONL = "jsonl"
+)
+
+const (
+	// schemaSuffix 列定义文件后缀，如 users.schema.json
+	schemaSuffix = ".schema.json"
+	// defaultNull CSV中默认的NULL表示，与mysqldump --tab和SELECT INTO OUTFILE一致
+	defaultNull = `\N`
+)
+
+// formatByExt 扩展名对应的文件格式
+var formatByExt = map[string]string{
+	".csv":    FormatCSV,
+	".tsv":    FormatTSV,
+	".txt":    FormatTSV, // mysqldump --tab 默认输出
+	".jsonl":  FormatJSONL,
+	".ndjson": FormatJSONL,
+}
+
+// tableFile 单个表的数据文件
+type tableFile struct {
+	path   string
+	format string
+}
+
+// fileSource 目录中每个表一个文件的数据源
+// 文件中的行需按第一列排序（mysqldump和按主键导出的默认顺序）
+type fileSource struct {
+	dir     string
+	options FileOptions
+	files   map[string]tableFile
+	counts  map[string]int64
+	cursor  *fileCursor
+}
+
+// OpenFiles 打开导出文件目录作为数据源
+// 表名为文件名去掉扩展名（支持.gz压缩），列名来自表头或<表名>.schema.json
+func OpenFiles(ctx context.Context, instance DatabaseInstance) (Source, error) {
+	if instance.Path == "" {
+		return nil, fmt.Errorf("连接失败: files类型实例 %s 未配置path", instance.Name)
+	}
+	format := strings.ToLower(instance.File.Format)
+	if format != "" && format != FormatCSV && format != FormatTSV && format != FormatJSONL {
+		return nil, fmt.Errorf("连接失败: 不支持的文件格式 %q", instance.File.Format)
+	}
+
+	entries, err := os.ReadDir(instance.Path)
+	if err != nil {
+		return nil, fmt.Errorf("连接失败: %v", err)
+	}
+
+	s := &fileSource{
+		dir:     instance.Path,
+		options: instance.File,
+		files:   make(map[string]tableFile),
+		counts:  make(map[string]int64),
+	}
+	if s.options.Null == "" {
+		s.options.Null = defaultNull
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasSuffix(name, schemaSuffix) || strings.HasPrefix(name, ".") {
+			continue
+		}
+		base := strings.TrimSuffix(name, ".gz")
+		ext := strings.ToLower(filepath.Ext(base))
+		fileFormat := format
+		if fileFormat == "" {
+			fileFormat = formatByExt[ext]
+		}
+		if fileFormat == "" {
+			continue
+		}
+		table := strings.TrimSuffix(base, filepath.Ext(base))
+		if existing, ok := s.files[table]; ok {
+			return nil, fmt.Errorf("连接失败: 表 %s 有多个数据文件: %s, %s", table, filepath.Base(existing.path), name)
+		}
+		s.files[table] = tableFile{path: filepath.Join(instance.Path, name), format: fileFormat}
+	}
+	return s, nil
+}
+
+// Tables 返回所有表名
+func (s *fileSource) Tables(ctx context.Context) ([]string, error) {
+	tables := make([]string, 0, len(s.files))
+	for table := range s.files {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+// TableStats 返回每个表的行数和文件大小，行数需要完整读取一遍文件
+func (s *fileSource) TableStats(ctx context.Context) ([]TableStat, error) {
+	tables, _ := s.Tables(ctx)
+	stats := make([]TableStat, 0, len(tables))
+	for _, table := range tables {
+		rows, err := s.CountRows(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		st := TableStat{Name: table, Rows: rows}
+		if info, err := os.Stat(s.files[table].path); err == nil {
+			st.DataBytes = info.Size()
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
+
+// CountRows 读取文件统计行数，结果会被缓存
+func (s *fileSource) CountRows(ctx context.Context, table string) (int64, error) {
+	if n, ok := s.counts[table]; ok {
+		return n, nil
+	}
+	c, err := s.openCursor(table)
+	if err != nil {
+		return 0, err
+	}
+	defer c.close()
+
+	// 计数与行顺序无关，没有唯一键的表文件可以是任意顺序
+	c.unordered = true
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if _, err := c.next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	s.counts[table] = c.pos
+	return c.pos, nil
+}
+
+// ReadRows 从offset开始读取行，紧接上次读取位置时继续使用已打开的文件
+func (s *fileSource) ReadRows(ctx context.Context, table string, offset, limit int64) (Rows, error) {
+	c := s.cursor
+	if c == nil || c.table != table || c.pos != offset {
+		if c != nil {
+			c.close()
+			s.cursor = nil
+		}
+		var err error
+		c, err = s.openCursor(table)
+		if err != nil {
+			return nil, err
+		}
+		for c.pos < offset {
+			if _, err := c.next(); err == io.EOF {
+				break
+			} else if err != nil {
+				c.close()
+				return nil, err
+			}
+		}
+		s.cursor = c
+	}
+	return &fileRows{ctx: ctx, source: s, cursor: c, limit: limit}, nil
+}
+
+// ReadRowsUnordered 按文件中的顺序读取全部行，不检查第一列的顺序
+func (s *fileSource) ReadRowsUnordered(ctx context.Context, table string) (Rows, error) {
+	c, err := s.openCursor(table)
+	if err != nil {
+		return nil, err
+	}
+	c.unordered = true
+	return &fileRows{ctx: ctx, source: s, cursor: c, owned: true}, nil
+}
+
+// Ping 检查目录是否可访问
+func (s *fileSource) Ping(ctx context.Context) error {
+	_, err := os.Stat(s.dir)
+	return err
+}
+
+// Close 关闭打开的文件
+func (s *fileSource) Close() error {
+	if s.cursor != nil {
+		s.cursor.close()
+		s.cursor = nil
+	}
+	return nil
+}
+
+// openCursor 打开表的数据文件
+func (s *fileSource) openCursor(table string) (*fileCursor, error) {
+	tf, ok := s.files[table]
+	if !ok {
+		return nil, fmt.Errorf("表 %s 没有数据文件", table)
+	}
+
+	file, err := os.Open(tf.path)
+	if err != nil {
+		return nil, err
+	}
+	c := &fileCursor{table: table, path: tf.path, closers: []io.Closer{file}}
+
+	var r io.Reader = file
+	if strings.HasSuffix(tf.path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			c.close()
+			return nil, fmt.Errorf("%s: %v", tf.path, err)
+		}
+		c.closers = append(c.closers, gz)
+		r = gz
+	}
+
+	schema, err := s.readSchema(table)
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+
+	switch tf.format {
+	case FormatJSONL:
+		c.reader = newJSONLReader(r, schema)
+	case FormatTSV:
+		c.reader, err = newTSVReader(r, schema, !s.options.NoHeader)
+	default:
+		c.reader, err = newCSVReader(r, schema, !s.options.NoHeader, s.options.Null)
+	}
+	if err != nil {
+		c.close()
+		return nil, fmt.Errorf("%s: %v", tf.path, err)
+	}
+	return c, nil
+}
+
+// readSchema 读取<表名>.schema.json中的列名，文件不存在时返回nil
+// 格式: {"columns": ["id", "name", ...]}
+func (s *fileSource) readSchema(table string) ([]string, error) {
+	path := filepath.Join(s.dir, table+schemaSuffix)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if s.options.NoHeader && s.files[table].format != FormatJSONL {
+			return nil, fmt.Errorf("表 %s 的数据文件没有表头，需要提供 %s", table, filepath.Base(path))
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var schema struct {
+		Columns []string `json:"columns"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", path, err)
+	}
+	if len(schema.Columns) == 0 {
+		return nil, fmt.Errorf("%s 中没有列定义", path)
+	}
+	return schema.Columns, nil
+}
+
+// recordReader 逐行读取导出文件，NULL为nil
+type recordReader interface {
+	columns() []string
+	read() ([]any, error)
+}
+
+// fileCursor 一个已打开的表数据文件及当前读取位置
+type fileCursor struct {
+	table     string
+	path      string
+	reader    recordReader
+	closers   []io.Closer
+	pos       int64 // 已读取的行数
+	lastKey   string
+	hasKey    bool
+	unordered bool // 不检查第一列的顺序
+}
+
+// next 读取下一行并检查第一列的顺序
+func (c *fileCursor) next() ([]any, error) {
+	values, err := c.reader.read()
+	if err != nil {
+		if err != io.EOF {
+			err = fmt.Errorf("%s 第%d行: %v", c.path, c.pos+1, err)
+		}
+		return nil, err
+	}
+	if err := c.checkOrder(values); err != nil {
+		return nil, err
+	}
+	c.pos++
+	return values, nil
+}
+
+// checkOrder 第一列为数字时检查文件按第一列升序排列
+// 字符串列的顺序取决于MySQL排序规则，无法在文件侧可靠判断，按导出顺序使用
+func (c *fileCursor) checkOrder(values []any) error {
+	if c.unordered || len(values) == 0 {
+		return nil
+	}
+	key, ok := values[0].(string)
+	if !ok {
+		return nil
+	}
+	if c.hasKey && compareNumeric(c.lastKey, key) > 0 {
+		return fmt.Errorf("%s 第%d行: 第一列 %s 小于上一行的 %s，文件需要按第一列排序", c.path, c.pos+1, key, c.lastKey)
+	}
+	c.lastKey, c.hasKey = key, true
+	return nil
+}
+
+// compareNumeric 两个值都是数字时按数值比较，否则返回0
+func compareNumeric(a, b string) int {
+	x, err1 := strconv.ParseFloat(a, 64)
+	y, err2 := strconv.ParseFloat(b, 64)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// close 关闭文件
+func (c *fileCursor) close() {
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		c.closers[i].Close()
+	}
+	c.closers = nil
+}
+
+// fileRows 从游标读取最多limit行
+type fileRows struct {
+	ctx    context.Context
+	source *fileSource
+	cursor *fileCursor
+	limit  int64
+	read   int64
+	values []any
+	err    error
+	owned  bool // 游标只属于该迭代器，关闭时一并关闭
+}
+
+func (r *fileRows) Columns() []string { return r.cursor.reader.columns() }
+
+func (r *fileRows) Next() bool {
+	if r.err != nil || (r.limit > 0 && r.read >= r.limit) {
+		return false
+	}
+	if err := r.ctx.Err(); err != nil {
+		r.err = err
+		return false
+	}
+	values, err := r.cursor.next()
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		r.err = err
+		return false
+	}
+	r.values = values
+	r.read++
+	return true
+}
+
+func (r *fileRows) Values() []any { return r.values }
+
+func (r *fileRows) Err() error { return r.err }
+
+// Close 出错时关闭游标，否则保留游标供下一批次继续读取
+func (r *fileRows) Close() error {
+	if r.owned {
+		r.cursor.close()
+		return nil
+	}
+	if r.err != nil && r.source.cursor == r.cursor {
+		r.cursor.close()
+		r.source.cursor = nil
+	}
+	return nil
+}
+
+// csvReader 读取RFC 4180格式的CSV文件
+type csvReader struct {
+	r      *csv.Reader
+	cols   []string
+	null   string
+	values []any
+}
+
+// newCSVReader 创建CSV读取器，header为true时第一行为表头
+func newCSVReader(r io.Reader, schema []string, header bool, null string) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	c := &csvReader{r: cr, cols: schema, null: null}
+	if header {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return c, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c.cols == nil {
+			c.cols = append([]string(nil), record...)
+		}
+	}
+	return c, nil
+}
+
+func (c *csvReader) columns() []string { return c.cols }
+
+func (c *csvReader) read() ([]any, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	if c.cols != nil && len(record) != len(c.cols) {
+		return nil, fmt.Errorf("列数 %d 与表头的 %d 列不一致", len(record), len(c.cols))
+	}
+	values := resize(c.values, len(record))
+	c.values = values
+	for i, field := range record {
+		if field != c.null {
+			values[i] = field
+		}
+	}
+	return values, nil
+}
+
+// tsvReader 读取MySQL风格的制表符分隔文件（mysqldump --tab、SELECT INTO OUTFILE）
+// 字段中的特殊字符使用反斜杠转义，\N表示NULL
+type tsvReader struct {
+	r      *bufio.Reader
+	cols   []string
+	values []any
+}
+
+// newTSVReader 创建TSV读取器，header为true时第一行为表头
+func newTSVReader(r io.Reader, schema []string, header bool) (*tsvReader, error) {
+	t := &tsvReader{r: bufio.NewReaderSize(r, 64*1024), cols: schema}
+	if header {
+		values, err := t.read()
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if t.cols == nil {
+			for _, v := range values {
+				name, _ := v.(string)
+				t.cols = append(t.cols, name)
+			}
+		}
+	}
+	return t, nil
+}
+
+func (t *tsvReader) columns() []string { return t.cols }
+
+func (t *tsvReader) read() ([]any, error) {
+	line, err := t.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := bytes.Split(line, []byte{'\t'})
+	if t.cols != nil && len(fields) != len(t.cols) {
+		return nil, fmt.Errorf("列数 %d 与表头的 %d 列不一致", len(fields), len(t.cols))
+	}
+	values := resize(t.values, len(fields))
+	t.values = values
+	for i, field := range fields {
+		if string(field) == defaultNull {
+			continue
+		}
+		values[i] = unescapeTSV(field)
+	}
+	return values, nil
+}
+
+// readLine 读取一行，反斜杠转义的换行属于字段内容
+func (t *tsvReader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		part, err := t.r.ReadBytes('\n')
+		line = append(line, part...)
+		if err == io.EOF {
+			if len(line) == 0 {
+				return nil, io.EOF
+			}
+			return bytes.TrimSuffix(line, []byte{'\r'}), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// 行尾换行前有奇数个反斜杠时换行被转义
+		body := line[:len(line)-1]
+		n := 0
+		for i := len(body) - 1; i >= 0 && body[i] == '\\'; i-- {
+			n++
+		}
+		if n%2 == 0 {
+			return bytes.TrimSuffix(body, []byte{'\r'}), nil
+		}
+	}
+}
+
+// unescapeTSV 还原MySQL的反斜杠转义
+func unescapeTSV(field []byte) string {
+	if bytes.IndexByte(field, '\\') < 0 {
+		return string(field)
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		ch := field[i]
+		if ch != '\\' || i+1 == len(field) {
+			b.WriteByte(ch)
+			continue
+		}
+		i++
+		switch field[i] {
+		case '0':
+			b.WriteByte(0)
+		case 'b':
+			b.WriteByte('\b')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'Z':
+			b.WriteByte(26)
+		default:
+			b.WriteByte(field[i])
+		}
+	}
+	return b.String()
+}
+
+// jsonlReader 读取每行一个JSON对象的文件
+// 列顺序来自schema文件，没有schema时使用第一个对象的键顺序
+type jsonlReader struct {
+	dec    *json.Decoder
+	cols   []string
+	index  map[string]int
+	values []any
+}
+
+// newJSONLReader 创建JSONL读取器
+func newJSONLReader(r io.Reader, schema []string) *jsonlReader {
+	dec := json.NewDecoder(bufio.NewReaderSize(r, 64*1024))
+	dec.UseNumber()
+	return &jsonlReader{dec: dec, cols: schema}
+}
+
+func (j *jsonlReader) columns() []string { return j.cols }
+
+func (j *jsonlReader) read() ([]any, error) {
+	keys, raw, err := j.readObject()
+	if err != nil {
+		return nil, err
+	}
+	if j.cols == nil {
+		j.cols = keys
+	}
+
+	if j.index == nil {
+		j.index = make(map[string]int, len(j.cols))
+		for i, col := range j.cols {
+			j.index[col] = i
+		}
+	}
+
+	values := resize(j.values, len(j.cols))
+	j.values = values
+	for k, key := range keys {
+		i, ok := j.index[key]
+		if !ok {
+			return nil, fmt.Errorf("包含未定义的列 %s", key)
+		}
+		values[i], err = jsonValue(raw[k])
+		if err != nil {
+			return nil, fmt.Errorf("列 %s: %v", key, err)
+		}
+	}
+	return values, nil
+}
+
+// readObject 读取一个JSON对象，保留键的顺序
+func (j *jsonlReader) readObject() ([]string, []json.RawMessage, error) {
+	tok, err := j.dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, nil, fmt.Errorf("期望JSON对象，实际为 %v", tok)
+	}
+
+	var keys []string
+	var raw []json.RawMessage
+	for j.dec.More() {
+		tok, err := j.dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := j.dec.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		raw = append(raw, value)
+	}
+	if _, err := j.dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	return keys, raw, nil
+}
+
+// resize 复用values的底层数组，返回长度为n且元素均为nil的切片
+func resize(values []any, n int) []any {
+	if cap(values) < n {
+		return make([]any, n)
+	}
+	values = values[:n]
+	clear(values)
+	return values
+}
+
+// jsonValue 将JSON值转换为与MySQL文本一致的表示
+// 字符串取其内容，数字保留原文，布尔值为1/0，对象和数组保留紧凑的JSON文本
+func jsonValue(raw json.RawMessage) (any, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	switch raw[0] {
+	case 'n':
+		return nil, nil
+	case 't':
+		return "1", nil
+	case 'f':
+		return "0", nil
+	case '"':
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	case '{', '[':
+		var b bytes.Buffer
+		err := json.Compact(&b, raw)
+		return b.String(), err
+	default:
+		return string(raw), nil
+	}
+}
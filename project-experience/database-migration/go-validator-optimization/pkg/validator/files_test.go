@@ -0,0 +1,101 @@
+package validator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func tableChecksum(t *testing.T, src Source, table string, strategy ChecksumStrategy) string {
+	t.Helper()
+	ctx := context.Background()
+	rows, err := src.CountRows(ctx, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := strategy.Checksum(ctx, src, table, rows, func(int, int, int64) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sum
+}
+
+func TestFileFormatsMatchMySQLText(t *testing.T) {
+	// MySQL文本协议返回的值：整数、含换行的字符串、NULL、DATETIME、JSON
+	mem := &memSource{tables: map[string][][]any{"t": {
+		{"1", "a|b", nil, "2024-01-02 03:04:05", `{"k":[1,2]}`},
+		{"2", "line\nbreak", "x", "2024-01-02 03:04:05.5", nil},
+		{"10", "tab\there", "1", "2024-12-31 00:00:00", "[]"},
+	}}}
+
+	dirs := map[string]string{
+		"csv": writeFiles(t, map[string]string{"t.csv": "id,s,n,d,j\n" +
+			"1,a|b,\\N,2024-01-02 03:04:05,\"{\"\"k\"\":[1,2]}\"\n" +
+			"2,\"line\nbreak\",x,2024-01-02 03:04:05.5,\\N\n" +
+			"10,tab\there,1,2024-12-31 00:00:00,[]\n"}),
+		"tsv": writeFiles(t, map[string]string{"t.txt": "" +
+			"1\ta|b\t\\N\t2024-01-02 03:04:05\t{\"k\":[1,2]}\n" +
+			"2\tline\\\nbreak\tx\t2024-01-02 03:04:05.5\t\\N\n" +
+			"10\ttab\\there\t1\t2024-12-31 00:00:00\t[]\n",
+			"t.schema.json": `{"columns": ["id", "s", "n", "d", "j"]}`}),
+		"jsonl": writeFiles(t, map[string]string{"t.jsonl": "" +
+			`{"id": 1, "s": "a|b", "n": null, "d": "2024-01-02 03:04:05", "j": {"k": [1, 2]}}` + "\n" +
+			`{"id": 2, "s": "line\nbreak", "n": "x", "d": "2024-01-02 03:04:05.5"}` + "\n" +
+			`{"id": 10, "s": "tab\there", "n": true, "d": "2024-12-31 00:00:00", "j": []}` + "\n"}),
+	}
+
+	for _, strategy := range []ChecksumStrategy{FullStrategy{}, BatchedStrategy{BatchSize: 2}} {
+		want := tableChecksum(t, mem, "t", strategy)
+		for format, dir := range dirs {
+			inst := DatabaseInstance{Name: format, Type: SourceFiles, Path: dir}
+			if format == "tsv" {
+				inst.File.NoHeader = true
+			}
+			src, err := OpenFiles(context.Background(), inst)
+			if err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			if got := tableChecksum(t, src, "t", strategy); got != want {
+				t.Errorf("%s/%s: checksum %s, want %s", format, strategy.Name(), got, want)
+			}
+			src.Close()
+		}
+	}
+}
+
+func TestFileSourceRejectsUnorderedKeys(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"t.csv": "id,v\n1,a\n3,b\n2,c\n"})
+	src, err := OpenFiles(context.Background(), DatabaseInstance{Type: SourceFiles, Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	// 计数和不排序读取不检查顺序
+	if n, err := src.CountRows(context.Background(), "t"); err != nil || n != 3 {
+		t.Fatalf("CountRows = %d, %v", n, err)
+	}
+	rows, err := src.ReadRows(context.Background(), "t", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if err := rows.Err(); err == nil || !strings.Contains(err.Error(), "排序") {
+		t.Fatalf("expected ordering error, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+// pkg/validator/hash.go
+// 校验和哈希算法
+
+package validator
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// 哈希算法名称
+const (
+	HashXXHash = "xxhash" // 默认，64位非加密哈希，速度最快
+	HashMD5    = "md5"
+	HashSHA256 = "sha256"
+)
+
+// HashFunc 创建哈希对象
+type HashFunc func() hash.Hash
+
+// NewHash 按名称返回哈希算法，名称为空时使用xxhash
+func NewHash(name string) (HashFunc, error) {
+	switch name {
+	case "", HashXXHash:
+		return newXXHash, nil
+	case HashMD5:
+		return md5.New, nil
+	case HashSHA256:
+		return sha256.New, nil
+	default:
+		return nil, fmt.Errorf("未知的哈希算法: %s (可选 %s、%s、%s)", name, HashXXHash, HashMD5, HashSHA256)
+	}
+}
+
+// newXXHash 创建xxhash对象
+func newXXHash() hash.Hash { return xxhash.New() }
+
+// orDefault 未设置时使用xxhash
+func (f HashFunc) orDefault() HashFunc {
+	if f == nil {
+		return newXXHash
+	}
+	return f
+}
@@ -0,0 +1,292 @@
+// pkg/validator/masking.go
+// 脱敏对比：对源端的列套用与目标端相同的脱敏变换后再计算校验和
+
+package validator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// 脱敏变换
+const (
+	MaskSHA256   = "sha256"   // 小写十六进制SHA-256，与MySQL的SHA2(col, 256)一致
+	MaskHMAC     = "hmac"     // 小写十六进制HMAC-SHA256
+	MaskConstant = "constant" // 替换为固定值，NULL也会被替换
+	MaskNull     = "null"     // 替换为NULL
+	MaskTruncate = "truncate" // 保留前Length个字符，与MySQL的LEFT(col, n)一致
+)
+
+// MaskRule 一列的脱敏规则，只作用于Azure（源）端
+type MaskRule struct {
+	Database  string `json:"database,omitempty" yaml:"database,omitempty" mapstructure:"database"` // Azure端数据库名，为空时作用于所有数据库
+	Table     string `json:"table" yaml:"table" mapstructure:"table"`
+	Column    string `json:"column" yaml:"column" mapstructure:"column"`
+	Transform string `json:"transform" yaml:"transform" mapstructure:"transform"`               // sha256、hmac、constant、null 或 truncate
+	Key       string `json:"key,omitempty" yaml:"key,omitempty" mapstructure:"key"`             // hmac的密钥
+	KeyEnv    string `json:"key_env,omitempty" yaml:"key_env,omitempty" mapstructure:"key_env"` // 从环境变量读取hmac密钥，优先于key
+	Value     string `json:"value,omitempty" yaml:"value,omitempty" mapstructure:"value"`       // constant的替换值
+	Length    int    `json:"length,omitempty" yaml:"length,omitempty" mapstructure:"length"`    // truncate保留的字符数
+}
+
+// maskFunc 将一个值变换后追加到dst，value为nil表示NULL；返回nil表示结果为NULL
+type maskFunc func(dst, value []byte) []byte
+
+// Validate 检查规则配置
+func (r MaskRule) Validate() error {
+	_, err := r.compile()
+	return err
+}
+
+// compile 返回规则的变换函数
+func (r MaskRule) compile() (maskFunc, error) {
+	if r.Table == "" || r.Column == "" {
+		return nil, fmt.Errorf("脱敏规则必须指定table和column")
+	}
+	name := r.Table + "." + r.Column
+
+	switch r.Transform {
+	case MaskSHA256:
+		return hexDigest(sha256.New()), nil
+	case MaskHMAC:
+		key := r.Key
+		if r.KeyEnv != "" {
+			key = os.Getenv(r.KeyEnv)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("脱敏规则 %s 缺少hmac密钥 (key 或 key_env)", name)
+		}
+		return hexDigest(hmac.New(sha256.New, []byte(key))), nil
+	case MaskConstant:
+		value := []byte(r.Value)
+		return func(dst, _ []byte) []byte { return value }, nil
+	case MaskNull:
+		return func(dst, _ []byte) []byte { return nil }, nil
+	case MaskTruncate:
+		if r.Length <= 0 {
+			return nil, fmt.Errorf("脱敏规则 %s 的truncate长度必须大于0", name)
+		}
+		return func(dst, value []byte) []byte {
+			if value == nil {
+				return nil
+			}
+			cut, chars := 0, 0
+			for cut < len(value) && chars < r.Length {
+				_, size := utf8.DecodeRune(value[cut:])
+				cut += size
+				chars++
+			}
+			return append(dst[:0], value[:cut]...)
+		}, nil
+	default:
+		return nil, fmt.Errorf("脱敏规则 %s 的变换无效: %q (可选: sha256, hmac, constant, null, truncate)", name, r.Transform)
+	}
+}
+
+// hexDigest 返回计算小写十六进制摘要的变换，NULL保持为NULL
+// 每个maskedRows使用独立的变换函数，哈希状态不会被并发使用
+func hexDigest(h hash.Hash) maskFunc {
+	var sum []byte
+	return func(dst, value []byte) []byte {
+		if value == nil {
+			return nil
+		}
+		h.Reset()
+		h.Write(value)
+		sum = h.Sum(sum[:0])
+		return hex.AppendEncode(dst[:0], sum)
+	}
+}
+
+// maskRules 验证器的脱敏规则，按数据库过滤后按表、列名（小写）索引
+type maskRules []MaskRule
+
+// forDatabase 返回作用于数据库的规则，没有规则时返回nil
+func (rules maskRules) forDatabase(database string) map[string][]MaskRule {
+	var byTable map[string][]MaskRule
+	for _, r := range rules {
+		if r.Database != "" && r.Database != database {
+			continue
+		}
+		if byTable == nil {
+			byTable = map[string][]MaskRule{}
+		}
+		byTable[r.Table] = append(byTable[r.Table], r)
+	}
+	return byTable
+}
+
+// columns 表中被脱敏的列名
+func (rules maskRules) columns(database, table string) []string {
+	var columns []string
+	for _, r := range rules.forDatabase(database)[table] {
+		columns = append(columns, r.Column)
+	}
+	return columns
+}
+
+// maskSource 对数据库有脱敏规则时包装源端数据源
+func (v *Validator) maskSource(src Source, database string) Source {
+	byTable := v.masking.forDatabase(database)
+	if byTable == nil {
+		return src
+	}
+	return &maskedSource{Source: src, rules: byTable}
+}
+
+// maskedSource 读取时对规则中的列做脱敏变换的数据源
+// 实现KeyChecker和UnorderedSource，底层数据源未实现时与未实现该接口的行为相同
+type maskedSource struct {
+	Source
+	rules map[string][]MaskRule
+}
+
+// ReadRows 按第一列排序读取并脱敏
+func (s *maskedSource) ReadRows(ctx context.Context, table string, offset, limit int64) (Rows, error) {
+	rows, err := s.Source.ReadRows(ctx, table, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.wrap(table, rows)
+}
+
+// ReadRowsUnordered 不排序读取并脱敏，底层不支持时按第一列排序读取
+func (s *maskedSource) ReadRowsUnordered(ctx context.Context, table string) (Rows, error) {
+	var rows Rows
+	var err error
+	if u, ok := s.Source.(UnorderedSource); ok {
+		rows, err = u.ReadRowsUnordered(ctx, table)
+	} else {
+		rows, err = s.Source.ReadRows(ctx, table, 0, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.wrap(table, rows)
+}
+
+// HasUniqueKey 转发给底层数据源，未实现时按有唯一键处理
+func (s *maskedSource) HasUniqueKey(ctx context.Context, table string) (bool, error) {
+	if checker, ok := s.Source.(KeyChecker); ok {
+		return checker.HasUniqueKey(ctx, table)
+	}
+	return true, nil
+}
+
+// unwrap 返回底层数据源
+func (s *maskedSource) unwrap() Source { return s.Source }
+
+// wrap 表有脱敏规则时包装行迭代器
+func (s *maskedSource) wrap(table string, rows Rows) (Rows, error) {
+	rules := s.rules[table]
+	if len(rules) == 0 {
+		return rows, nil
+	}
+	funcs := make(map[string]maskFunc, len(rules))
+	for _, r := range rules {
+		fn, err := r.compile()
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		funcs[strings.ToLower(r.Column)] = fn
+	}
+	return &maskedRows{Rows: rows, funcs: funcs}, nil
+}
+
+// unwrapSource 去掉脱敏等包装，返回原始数据源
+func unwrapSource(src Source) Source {
+	for {
+		w, ok := src.(interface{ unwrap() Source })
+		if !ok {
+			return src
+		}
+		src = w.unwrap()
+	}
+}
+
+// maskedRows 对指定列做脱敏变换的行迭代器，缓冲区在各行之间复用
+type maskedRows struct {
+	Rows
+	funcs   map[string]maskFunc
+	masks   []maskFunc // 按列下标，不脱敏的列为nil
+	out     [][]byte
+	bufs    [][]byte
+	values  []any
+	convert valueBuffer
+	err     error
+}
+
+// Next 读取下一行并脱敏
+func (r *maskedRows) Next() bool {
+	if r.err != nil || !r.Rows.Next() {
+		return false
+	}
+	if r.masks == nil {
+		columns := r.Rows.Columns()
+		r.masks = make([]maskFunc, len(columns))
+		found := 0
+		for i, c := range columns {
+			if fn, ok := r.funcs[strings.ToLower(c)]; ok {
+				r.masks[i] = fn
+				found++
+			}
+		}
+		if found < len(r.funcs) {
+			r.err = fmt.Errorf("脱敏规则中的列不存在: 表的列为 %s", strings.Join(columns, ","))
+			return false
+		}
+		r.out = make([][]byte, len(columns))
+		r.bufs = make([][]byte, len(columns))
+	}
+
+	var row [][]byte
+	if raw, ok := r.Rows.(rawRows); ok {
+		row = raw.rawValues()
+	} else {
+		row = r.convert.from(r.Rows.Values())
+	}
+	copy(r.out, row)
+	for i, fn := range r.masks {
+		if fn == nil {
+			continue
+		}
+		masked := fn(r.bufs[i], row[i])
+		if masked != nil {
+			r.bufs[i] = masked
+		}
+		r.out[i] = masked
+	}
+	r.values = r.values[:0]
+	return true
+}
+
+// Values 返回脱敏后的值，非NULL的值为[]byte
+func (r *maskedRows) Values() []any {
+	if len(r.values) == 0 {
+		for _, b := range r.out {
+			if b == nil {
+				r.values = append(r.values, nil)
+			} else {
+				r.values = append(r.values, b)
+			}
+		}
+	}
+	return r.values
+}
+
+func (r *maskedRows) rawValues() [][]byte { return r.out }
+
+func (r *maskedRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Rows.Err()
+}
@@ -0,0 +1,100 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestRunMasking(t *testing.T) {
+	t.Setenv("MDV_TEST_HMAC_KEY", "key")
+	fox := "The quick brown fox jumps over the lazy dog"
+	azure := &memSource{tables: map[string][][]any{
+		"users": {
+			{1, "abc", fox, "张三", nil},
+			{2, nil, nil, "李四", "x"},
+		},
+		"orders": numberedRows(3),
+	}}
+	// 目标端: SHA2(c2, 256)、HMAC-SHA256(c3)、LEFT(c4, 1)、c5 = '***'
+	aws := &memSource{tables: map[string][][]any{
+		"users": {
+			{1, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+				"f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", "张", "***"},
+			{2, nil, nil, "李", "***"},
+		},
+		"orders": numberedRows(3),
+	}}
+	rules := []MaskRule{
+		{Table: "users", Column: "c2", Transform: MaskSHA256},
+		{Table: "users", Column: "C3", Transform: MaskHMAC, KeyEnv: "MDV_TEST_HMAC_KEY"},
+		{Table: "users", Column: "c4", Transform: MaskTruncate, Length: 1},
+		{Table: "users", Column: "c5", Transform: MaskConstant, Value: "***"},
+	}
+	run := func(rules ...MaskRule) DatabaseResult {
+		report, err := New(
+			WithInstances(
+				[]DatabaseInstance{{Name: "azure-1", Database: "db1"}},
+				[]DatabaseInstance{{Name: "aws-1", Database: "db1"}},
+			),
+			WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+				if inst.Name == "azure-1" {
+					return azure, nil
+				}
+				return aws, nil
+			}),
+			WithMasking(rules...),
+			WithLogger(nil),
+		).Run(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		return report.Results[ResultKey("azure-1", "aws-1", "db1")]
+	}
+
+	result := run(rules...)
+	if result.Status != StatusSuccess {
+		t.Fatalf("result = %+v", result)
+	}
+	for _, c := range result.TableComparisons {
+		want := "[]"
+		if c.Table == "users" {
+			want = "[c2 C3 c4 c5]"
+		}
+		if got := fmt.Sprint(c.MaskedColumns); got != want {
+			t.Errorf("%s masked columns = %s, want %s", c.Table, got, want)
+		}
+	}
+
+	// 规则只作用于指定的数据库
+	other := rules[0]
+	other.Database = "db2"
+	if result := run(other, rules[1], rules[2], rules[3]); result.Status != StatusInconsistent {
+		t.Errorf("rule for another database should not apply: %+v", result)
+	}
+
+	// 列不存在时该表记为错误
+	if result := run(MaskRule{Table: "users", Column: "email", Transform: MaskNull}); result.Status != StatusError {
+		t.Errorf("unknown column: %+v", result)
+	}
+}
+
+func TestMaskRuleValidate(t *testing.T) {
+	tests := []struct {
+		rule MaskRule
+		ok   bool
+	}{
+		{MaskRule{Table: "t", Column: "c", Transform: MaskSHA256}, true},
+		{MaskRule{Table: "t", Column: "c", Transform: MaskNull}, true},
+		{MaskRule{Table: "t", Column: "c", Transform: MaskHMAC}, false},
+		{MaskRule{Table: "t", Column: "c", Transform: MaskHMAC, KeyEnv: "MDV_TEST_UNSET_KEY"}, false},
+		{MaskRule{Table: "t", Column: "c", Transform: MaskTruncate}, false},
+		{MaskRule{Table: "t", Column: "c", Transform: "md5"}, false},
+		{MaskRule{Table: "t", Transform: MaskSHA256}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.rule.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v) = %v", tt.rule, err)
+		}
+	}
+}
@@ -0,0 +1,520 @@
+// pkg/validator/objects.go
+// 非表对象验证：视图、存储过程、函数、触发器、事件的定义和用户权限
+
+package validator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 非表对象类型
+const (
+	ObjectView      = "VIEW"
+	ObjectProcedure = "PROCEDURE"
+	ObjectFunction  = "FUNCTION"
+	ObjectTrigger   = "TRIGGER"
+	ObjectEvent     = "EVENT"
+	ObjectGrant     = "GRANT" // 用户权限，只用于Mismatch.Object
+)
+
+// objectTypes 报告中各类对象的顺序
+var objectTypes = []string{ObjectView, ObjectProcedure, ObjectFunction, ObjectTrigger, ObjectEvent}
+
+// SchemaObject 数据库中的非表对象
+type SchemaObject struct {
+	Type       string // VIEW、PROCEDURE、FUNCTION、TRIGGER 或 EVENT
+	Name       string
+	Definition string // SHOW CREATE返回的定义
+	SQLMode    string // 创建时的sql_mode，视图为空
+}
+
+// ObjectSource 可以列出非表对象的数据源
+type ObjectSource interface {
+	// Objects 返回数据库中的视图、存储过程、函数、触发器和事件
+	Objects(ctx context.Context) ([]SchemaObject, error)
+}
+
+// GrantSource 可以查询用户权限的数据源
+type GrantSource interface {
+	// Grants 返回用户的有效权限，展开授予的角色，每项形如"SELECT ON `db`.*"，已排序去重
+	Grants(ctx context.Context, user string) ([]string, error)
+}
+
+// ObjectSection 一类非表对象的对比结果
+type ObjectSection struct {
+	Type         string       `json:"type" yaml:"type" mapstructure:"type"`
+	AzureCount   int          `json:"azure_count" yaml:"azure_count" mapstructure:"azure_count"`
+	AWSCount     int          `json:"aws_count" yaml:"aws_count" mapstructure:"aws_count"`
+	Match        bool         `json:"match" yaml:"match" mapstructure:"match"`
+	MissingInAWS []string     `json:"missing_in_aws,omitempty" yaml:"missing_in_aws,omitempty" mapstructure:"missing_in_aws"`
+	ExtraInAWS   []string     `json:"extra_in_aws,omitempty" yaml:"extra_in_aws,omitempty" mapstructure:"extra_in_aws"`
+	Different    []ObjectDiff `json:"different,omitempty" yaml:"different,omitempty" mapstructure:"different"` // 两端都存在但定义不一致的对象
+}
+
+// ObjectDiff 定义不一致的对象，定义已去掉DEFINER子句并合并空白
+type ObjectDiff struct {
+	Name  string `json:"name" yaml:"name" mapstructure:"name"`
+	Azure string `json:"azure" yaml:"azure" mapstructure:"azure"`
+	AWS   string `json:"aws" yaml:"aws" mapstructure:"aws"`
+}
+
+// GrantComparison 一个用户在一对服务器上的权限对比结果
+type GrantComparison struct {
+	User          string   `json:"user" yaml:"user" mapstructure:"user"`
+	AzureInstance string   `json:"azure_instance" yaml:"azure_instance" mapstructure:"azure_instance"`
+	AWSInstance   string   `json:"aws_instance" yaml:"aws_instance" mapstructure:"aws_instance"`
+	Match         bool     `json:"match" yaml:"match" mapstructure:"match"`
+	MissingInAWS  []string `json:"missing_in_aws,omitempty" yaml:"missing_in_aws,omitempty" mapstructure:"missing_in_aws"` // 只在Azure中拥有的权限
+	ExtraInAWS    []string `json:"extra_in_aws,omitempty" yaml:"extra_in_aws,omitempty" mapstructure:"extra_in_aws"`       // 只在AWS中拥有的权限
+	Error         string   `json:"error,omitempty" yaml:"error,omitempty" mapstructure:"error"`
+}
+
+var (
+	definerClause = regexp.MustCompile("(?i)\\s*DEFINER\\s*=\\s*(`[^`]*`|'[^']*'|[^\\s@]+)@(`[^`]*`|'[^']*'|\\S+)")
+	whitespace    = regexp.MustCompile(`\s+`)
+	grantOn       = regexp.MustCompile(`(?is)^(GRANT|REVOKE)\s+(.+?)\s+ON\s+(.+?)\s+(?:TO|FROM)\s+\S+(\s+WITH GRANT OPTION)?`)
+	grantRoles    = regexp.MustCompile(`(?is)^GRANT\s+(.+?)\s+TO\s+\S+`)
+)
+
+// NormalizeDefinition 规范化对象定义：去掉DEFINER子句和对所在数据库的限定，合并空白
+// 两端数据库名不同（迁移时改名）时，视图中的`库名`.`表名`也能一致
+func NormalizeDefinition(definition, database string) string {
+	s := definerClause.ReplaceAllString(definition, "")
+	if database != "" {
+		s = strings.ReplaceAll(s, "`"+database+"`.", "")
+	}
+	return strings.TrimSpace(whitespace.ReplaceAllString(s, " "))
+}
+
+// compareObjects 按类型比较两端的非表对象
+func compareObjects(azure, aws []SchemaObject, azureDatabase, awsDatabase string) []ObjectSection {
+	index := func(objects []SchemaObject, database string) map[string]map[string]string {
+		byType := map[string]map[string]string{}
+		for _, o := range objects {
+			if byType[o.Type] == nil {
+				byType[o.Type] = map[string]string{}
+			}
+			definition := NormalizeDefinition(o.Definition, database)
+			if o.SQLMode != "" {
+				definition += " /* sql_mode=" + o.SQLMode + " */"
+			}
+			byType[o.Type][o.Name] = definition
+		}
+		return byType
+	}
+	azureByType := index(azure, azureDatabase)
+	awsByType := index(aws, awsDatabase)
+
+	var sections []ObjectSection
+	for _, objectType := range objectTypes {
+		azureObjects, awsObjects := azureByType[objectType], awsByType[objectType]
+		if len(azureObjects) == 0 && len(awsObjects) == 0 {
+			continue
+		}
+		section := ObjectSection{Type: objectType, AzureCount: len(azureObjects), AWSCount: len(awsObjects)}
+		for _, name := range sortedKeys(azureObjects) {
+			awsDefinition, ok := awsObjects[name]
+			switch {
+			case !ok:
+				section.MissingInAWS = append(section.MissingInAWS, name)
+			case awsDefinition != azureObjects[name]:
+				section.Different = append(section.Different, ObjectDiff{Name: name, Azure: azureObjects[name], AWS: awsDefinition})
+			}
+		}
+		for _, name := range sortedKeys(awsObjects) {
+			if _, ok := azureObjects[name]; !ok {
+				section.ExtraInAWS = append(section.ExtraInAWS, name)
+			}
+		}
+		section.Match = len(section.MissingInAWS) == 0 && len(section.ExtraInAWS) == 0 && len(section.Different) == 0
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// sortedKeys 按名称排序的键
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateObjects 比较对比对两端的非表对象，任一端不支持时跳过
+func (v *Validator) validateObjects(ctx context.Context, result *DatabaseResult, pair DatabasePair, azureSrc, awsSrc Source) {
+	azureObjects, ok1 := unwrapSource(azureSrc).(ObjectSource)
+	awsObjects, ok2 := awsSrc.(ObjectSource)
+	if !v.objects || !ok1 || !ok2 {
+		return
+	}
+	database := result.Database
+
+	azure, err := azureObjects.Objects(ctx)
+	if err != nil {
+		v.objectError(result, SideAzure, fmt.Errorf("获取Azure非表对象失败: %v", err))
+		return
+	}
+	aws, err := awsObjects.Objects(ctx)
+	if err != nil {
+		v.objectError(result, SideAWS, fmt.Errorf("获取AWS非表对象失败: %v", err))
+		return
+	}
+
+	result.Objects = compareObjects(azure, aws, pair.AzureInstance.Database, pair.AWSInstance.Database)
+	for _, section := range result.Objects {
+		if section.Match {
+			v.logf("数据库 %s: %d 个%s一致", database, section.AzureCount, section.Type)
+			continue
+		}
+		if result.Status != StatusError {
+			result.Status = StatusInconsistent
+		}
+		for _, name := range section.MissingInAWS {
+			v.objectMismatch(result, section.Type, name, TableMissing, fmt.Sprintf("%s %s 在AWS中不存在", section.Type, name))
+		}
+		for _, name := range section.ExtraInAWS {
+			v.objectMismatch(result, section.Type, name, TableMismatch, fmt.Sprintf("%s %s 只在AWS中存在", section.Type, name))
+		}
+		for _, diff := range section.Different {
+			v.objectMismatch(result, section.Type, diff.Name, TableMismatch, fmt.Sprintf("%s %s 定义不一致", section.Type, diff.Name))
+		}
+	}
+}
+
+// objectError 记录非表对象对比的错误
+func (v *Validator) objectError(result *DatabaseResult, side string, err error) {
+	result.Errors = append(result.Errors, err.Error())
+	result.Status = StatusError
+	v.logf("数据库 %s: %v", result.Database, err)
+	v.reportError(&ValidationError{Database: result.Database, Side: side, Err: err})
+}
+
+// objectMismatch 记录非表对象的差异并调用不一致回调
+func (v *Validator) objectMismatch(result *DatabaseResult, objectType, name, status, message string) {
+	result.Errors = append(result.Errors, message)
+	v.logf("数据库 %s: %s", result.Database, message)
+	if v.onMismatch != nil {
+		v.onMismatch(Mismatch{Database: result.Database, Table: name, Object: objectType, Status: status, Message: message})
+	}
+}
+
+// validateGrants 对每对服务器比较配置用户的有效权限，同一对服务器只比较一次
+func (v *Validator) validateGrants(ctx context.Context, pairs []DatabasePair) []GrantComparison {
+	if len(v.grantUsers) == 0 {
+		return nil
+	}
+
+	var comparisons []GrantComparison
+	seen := map[[2]string]bool{}
+	for _, pair := range pairs {
+		servers := [2]string{pair.AzureInstance.Name, pair.AWSInstance.Name}
+		if seen[servers] || pair.AzureInstance.IsFiles() || pair.AWSInstance.IsFiles() {
+			continue
+		}
+		seen[servers] = true
+		comparisons = append(comparisons, v.compareGrants(ctx, pair)...)
+	}
+	return comparisons
+}
+
+// compareGrants 比较一对服务器上所有配置用户的权限
+func (v *Validator) compareGrants(ctx context.Context, pair DatabasePair) []GrantComparison {
+	comparisons := make([]GrantComparison, len(v.grantUsers))
+	for i, user := range v.grantUsers {
+		comparisons[i] = GrantComparison{User: user, AzureInstance: pair.AzureInstance.Name, AWSInstance: pair.AWSInstance.Name}
+	}
+	fail := func(side string, err error) []GrantComparison {
+		for i := range comparisons {
+			comparisons[i].Error = err.Error()
+		}
+		v.logf("权限对比 %s vs %s: %v", pair.AzureInstance.Name, pair.AWSInstance.Name, err)
+		v.reportError(&ValidationError{Side: side, Err: err})
+		return comparisons
+	}
+
+	azureSrc, err := v.openSource(ctx, pair.AzureInstance)
+	if err != nil {
+		return fail(SideAzure, fmt.Errorf("Azure数据库%v", err))
+	}
+	defer azureSrc.Close()
+	awsSrc, err := v.openSource(ctx, pair.AWSInstance)
+	if err != nil {
+		return fail(SideAWS, fmt.Errorf("AWS数据库%v", err))
+	}
+	defer awsSrc.Close()
+
+	azureGrants, ok1 := azureSrc.(GrantSource)
+	awsGrants, ok2 := awsSrc.(GrantSource)
+	if !ok1 || !ok2 {
+		return fail("", fmt.Errorf("数据源不支持权限查询"))
+	}
+
+	for i := range comparisons {
+		c := &comparisons[i]
+		azure, err := azureGrants.Grants(ctx, c.User)
+		if err != nil {
+			c.Error = fmt.Sprintf("查询Azure权限失败: %v", err)
+			v.reportError(&ValidationError{Side: SideAzure, Err: fmt.Errorf("用户 %s: %s", c.User, c.Error)})
+			continue
+		}
+		aws, err := awsGrants.Grants(ctx, c.User)
+		if err != nil {
+			c.Error = fmt.Sprintf("查询AWS权限失败: %v", err)
+			v.reportError(&ValidationError{Side: SideAWS, Err: fmt.Errorf("用户 %s: %s", c.User, c.Error)})
+			continue
+		}
+		c.MissingInAWS, c.ExtraInAWS = diffSorted(azure, aws)
+		c.Match = len(c.MissingInAWS) == 0 && len(c.ExtraInAWS) == 0
+		if c.Match {
+			v.logf("用户 %s 的权限一致 (%s vs %s)", c.User, c.AzureInstance, c.AWSInstance)
+			continue
+		}
+		message := fmt.Sprintf("用户 %s 的权限不一致 (%s vs %s): AWS缺少 %d 项，多出 %d 项",
+			c.User, c.AzureInstance, c.AWSInstance, len(c.MissingInAWS), len(c.ExtraInAWS))
+		v.logf("%s", message)
+		if v.onMismatch != nil {
+			v.onMismatch(Mismatch{Table: c.User, Object: ObjectGrant, Status: TableMismatch, Message: message})
+		}
+	}
+	return comparisons
+}
+
+// diffSorted 比较两个已排序的切片，返回只在a中和只在b中的元素
+func diffSorted(a, b []string) (onlyA, onlyB []string) {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j >= len(b) || (i < len(a) && a[i] < b[j]):
+			onlyA = append(onlyA, a[i])
+			i++
+		case i >= len(a) || b[j] < a[i]:
+			onlyB = append(onlyB, b[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return onlyA, onlyB
+}
+
+// Objects 从information_schema列出非表对象，并用SHOW CREATE获取定义
+func (s *mysqlSource) Objects(ctx context.Context) ([]SchemaObject, error) {
+	lists := []struct {
+		query string
+		show  string // SHOW CREATE语句，%s为类型
+		col   int    // 定义所在的列
+	}{
+		{"SELECT 'VIEW', TABLE_NAME, '' FROM information_schema.VIEWS WHERE TABLE_SCHEMA = ?", "SHOW CREATE VIEW", 1},
+		{"SELECT ROUTINE_TYPE, ROUTINE_NAME, SQL_MODE FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ?", "SHOW CREATE %s", 2},
+		{"SELECT 'TRIGGER', TRIGGER_NAME, SQL_MODE FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = ?", "SHOW CREATE TRIGGER", 2},
+		{"SELECT 'EVENT', EVENT_NAME, SQL_MODE FROM information_schema.EVENTS WHERE EVENT_SCHEMA = ?", "SHOW CREATE EVENT", 3},
+	}
+
+	var objects []SchemaObject
+	for _, list := range lists {
+		rows, err := s.db.QueryContext(ctx, list.query, s.database)
+		if err != nil {
+			return nil, err
+		}
+		var found []SchemaObject
+		for rows.Next() {
+			var o SchemaObject
+			if err := rows.Scan(&o.Type, &o.Name, &o.SQLMode); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			found = append(found, o)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, o := range found {
+			show := list.show
+			if strings.Contains(show, "%s") {
+				show = fmt.Sprintf(show, o.Type)
+			}
+			definition, err := s.showCreate(ctx, fmt.Sprintf("%s `%s`.`%s`", show, s.database, o.Name), list.col)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %v", o.Type, o.Name, err)
+			}
+			o.Definition = definition
+			objects = append(objects, o)
+		}
+	}
+	return objects, nil
+}
+
+// showCreate 执行SHOW CREATE语句，返回第col列
+func (s *mysqlSource) showCreate(ctx context.Context, query string, col int) (string, error) {
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("对象不存在")
+	}
+	values := make([]sql.NullString, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return "", err
+	}
+	if col >= len(values) || !values[col].Valid {
+		// 没有SHOW_ROUTINE等权限时定义为NULL
+		return "", fmt.Errorf("无权读取定义")
+	}
+	return values[col].String, nil
+}
+
+// Grants 执行SHOW GRANTS并展开授予的角色
+func (s *mysqlSource) Grants(ctx context.Context, user string) ([]string, error) {
+	account, err := quoteAccount(user)
+	if err != nil {
+		return nil, err
+	}
+	items := map[string]bool{}
+	if err := s.collectGrants(ctx, account, items, map[string]bool{}); err != nil {
+		return nil, err
+	}
+	grants := make([]string, 0, len(items))
+	for item := range items {
+		grants = append(grants, item)
+	}
+	sort.Strings(grants)
+	return grants, nil
+}
+
+// collectGrants 收集账号的权限项，递归展开角色
+func (s *mysqlSource) collectGrants(ctx context.Context, account string, items, visited map[string]bool) error {
+	if visited[account] {
+		return nil
+	}
+	visited[account] = true
+
+	rows, err := s.db.QueryContext(ctx, "SHOW GRANTS FOR "+account)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			rows.Close()
+			return err
+		}
+		lines = append(lines, line)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		privileges, roles := parseGrant(line)
+		for _, p := range privileges {
+			items[p] = true
+		}
+		for _, role := range roles {
+			items["ROLE "+role] = true
+			if err := s.collectGrants(ctx, role, items, visited); err != nil {
+				return fmt.Errorf("角色 %s: %v", role, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseGrant 将一条GRANT/REVOKE语句拆分为权限项，角色授权返回角色列表
+// 权限名转为大写，权限顺序和语句的拆分方式不影响结果；USAGE表示无权限，忽略
+func parseGrant(line string) (privileges, roles []string) {
+	line = strings.TrimSpace(whitespace.ReplaceAllString(line, " "))
+	if m := grantOn.FindStringSubmatch(line); m != nil {
+		verb, object := strings.ToUpper(m[1]), m[3]
+		prefix := ""
+		if verb == "REVOKE" {
+			prefix = "REVOKE "
+		}
+		for _, p := range splitTopLevel(m[2]) {
+			// 列权限只转换权限名，如"SELECT (`id`)"
+			if i := strings.IndexByte(p, '('); i > 0 {
+				p = strings.ToUpper(strings.TrimSpace(p[:i])) + " " + p[i:]
+			} else {
+				p = strings.ToUpper(p)
+			}
+			if p == "USAGE" {
+				continue
+			}
+			privileges = append(privileges, prefix+p+" ON "+object)
+		}
+		if m[4] != "" {
+			privileges = append(privileges, "GRANT OPTION ON "+object)
+		}
+		return privileges, nil
+	}
+	if m := grantRoles.FindStringSubmatch(line); m != nil {
+		return nil, splitTopLevel(m[1])
+	}
+	// PROXY等其他语句按规范化后的整行比较
+	return []string{line}, nil
+}
+
+// splitTopLevel 按括号外的逗号拆分，如"SELECT (`a`, `b`), INSERT"
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '`' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// quoteAccount 将 user@host 转为 'user'@'host'，已加引号时原样返回
+func quoteAccount(user string) (string, error) {
+	if strings.ContainsAny(user, "'`") {
+		return user, nil
+	}
+	name, host := user, "%"
+	if i := strings.LastIndex(user, "@"); i >= 0 {
+		name, host = user[:i], user[i+1:]
+	}
+	if name == "" {
+		return "", fmt.Errorf("用户名无效: %q", user)
+	}
+	return "'" + name + "'@'" + host + "'", nil
+}
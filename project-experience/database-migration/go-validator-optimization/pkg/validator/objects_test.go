@@ -0,0 +1,138 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// objectSource 带非表对象和权限的内存数据源
+type objectSource struct {
+	memSource
+	objects []SchemaObject
+	grants  map[string][]string
+}
+
+func (s *objectSource) Objects(ctx context.Context) ([]SchemaObject, error) { return s.objects, nil }
+
+func (s *objectSource) Grants(ctx context.Context, user string) ([]string, error) {
+	var items []string
+	for _, line := range s.grants[user] {
+		privileges, roles := parseGrant(line)
+		items = append(items, privileges...)
+		for _, role := range roles {
+			items = append(items, "ROLE "+role)
+		}
+	}
+	sort.Strings(items)
+	return items, nil
+}
+
+func TestNormalizeDefinition(t *testing.T) {
+	azure := "CREATE ALGORITHM=UNDEFINED DEFINER=`admin`@`%` SQL SECURITY DEFINER VIEW `shop`.`v_orders` AS\n  select `shop`.`orders`.`id` AS `id`   from `shop`.`orders`"
+	aws := "CREATE ALGORITHM=UNDEFINED DEFINER=`rdsadmin`@`localhost` SQL SECURITY DEFINER VIEW `shop_v2`.`v_orders` AS select `shop_v2`.`orders`.`id` AS `id` from `shop_v2`.`orders`"
+	if a, b := NormalizeDefinition(azure, "shop"), NormalizeDefinition(aws, "shop_v2"); a != b {
+		t.Errorf("normalized definitions differ:\n%s\n%s", a, b)
+	}
+	if got := NormalizeDefinition("CREATE DEFINER='app'@'10.%' TRIGGER t", ""); got != "CREATE TRIGGER t" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseGrant(t *testing.T) {
+	privileges, roles := parseGrant("GRANT SELECT (`id`, `name`), insert ON `shop`.`orders` TO `app`@`%` WITH GRANT OPTION")
+	want := []string{"SELECT (`id`, `name`) ON `shop`.`orders`", "INSERT ON `shop`.`orders`", "GRANT OPTION ON `shop`.`orders`"}
+	if fmt.Sprint(privileges) != fmt.Sprint(want) || roles != nil {
+		t.Errorf("privileges = %q, roles = %q", privileges, roles)
+	}
+	if privileges, _ := parseGrant("GRANT USAGE ON *.* TO `app`@`%`"); len(privileges) != 0 {
+		t.Errorf("USAGE should be ignored, got %q", privileges)
+	}
+	if _, roles := parseGrant("GRANT `reader`@`%`,`writer`@`%` TO `app`@`%`"); fmt.Sprint(roles) != "[`reader`@`%` `writer`@`%`]" {
+		t.Errorf("roles = %q", roles)
+	}
+	if got, _ := quoteAccount("app@10.0.%"); got != "'app'@'10.0.%'" {
+		t.Errorf("quoteAccount = %q", got)
+	}
+}
+
+func TestRunObjects(t *testing.T) {
+	view := "CREATE DEFINER=`%s`@`%%` VIEW `v` AS select 1"
+	azure := &objectSource{
+		memSource: memSource{tables: map[string][][]any{"t": numberedRows(2)}},
+		objects: []SchemaObject{
+			{Type: ObjectView, Name: "v", Definition: fmt.Sprintf(view, "admin")},
+			{Type: ObjectView, Name: "v_old", Definition: "CREATE VIEW `v_old` AS select 2"},
+			{Type: ObjectTrigger, Name: "trg", Definition: "CREATE TRIGGER trg BEFORE INSERT ON t FOR EACH ROW SET NEW.c2 = 1", SQLMode: "STRICT_TRANS_TABLES"},
+		},
+		grants: map[string][]string{"app@%": {"GRANT SELECT, INSERT ON `db1`.* TO `app`@`%`"}},
+	}
+	aws := &objectSource{
+		memSource: memSource{tables: map[string][][]any{"t": numberedRows(2)}},
+		objects: []SchemaObject{
+			{Type: ObjectView, Name: "v", Definition: fmt.Sprintf(view, "rdsadmin")},
+			{Type: ObjectTrigger, Name: "trg", Definition: "CREATE TRIGGER trg BEFORE INSERT ON t FOR EACH ROW SET NEW.c2 = 1", SQLMode: ""},
+		},
+		grants: map[string][]string{"app@%": {"GRANT INSERT ON `db1`.* TO `app`@`%`", "GRANT SELECT ON `db1`.* TO `app`@`%`", "GRANT DELETE ON `db1`.* TO `app`@`%`"}},
+	}
+	sources := map[string]Source{"azure-1": azure, "aws-1": aws}
+
+	var mismatches []string
+	v := New(
+		WithInstances(
+			[]DatabaseInstance{{Name: "azure-1", Database: "db1"}},
+			[]DatabaseInstance{{Name: "aws-1", Database: "db1"}},
+		),
+		WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+			return sources[inst.Name], nil
+		}),
+		WithGrantUsers("app@%"),
+		WithMismatchHook(func(m Mismatch) { mismatches = append(mismatches, m.Object+":"+m.Table+":"+m.Status) }),
+		WithLogger(nil),
+	)
+
+	report, err := v.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	result := report.Results[ResultKey("azure-1", "aws-1", "db1")]
+	if result.Status != StatusInconsistent || len(result.Objects) != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	views, triggers := result.Objects[0], result.Objects[1]
+	if views.Type != ObjectView || fmt.Sprint(views.MissingInAWS) != "[v_old]" || len(views.Different) != 0 {
+		t.Errorf("views = %+v", views)
+	}
+	// sql_mode不同的触发器视为定义不一致
+	if triggers.Type != ObjectTrigger || len(triggers.Different) != 1 || triggers.Different[0].Name != "trg" {
+		t.Errorf("triggers = %+v", triggers)
+	}
+
+	if len(report.Grants) != 1 {
+		t.Fatalf("grants = %+v", report.Grants)
+	}
+	if g := report.Grants[0]; g.Match || fmt.Sprint(g.ExtraInAWS) != "[DELETE ON `db1`.*]" || len(g.MissingInAWS) != 0 {
+		t.Errorf("grant comparison = %+v", g)
+	}
+	sort.Strings(mismatches)
+	if want := "[GRANT:app@%:MISMATCH TRIGGER:trg:MISMATCH VIEW:v_old:MISSING]"; fmt.Sprint(mismatches) != want {
+		t.Errorf("mismatches = %v, want %s", mismatches, want)
+	}
+
+	// 关闭后不再对比非表对象
+	report, _ = New(
+		WithInstances(
+			[]DatabaseInstance{{Name: "azure-1", Database: "db1"}},
+			[]DatabaseInstance{{Name: "aws-1", Database: "db1"}},
+		),
+		WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+			return sources[inst.Name], nil
+		}),
+		WithObjects(false),
+		WithLogger(nil),
+	).Run(context.Background(), nil)
+	if result := report.Results[ResultKey("azure-1", "aws-1", "db1")]; result.Status != StatusSuccess || result.Objects != nil {
+		t.Errorf("objects disabled: %+v", result)
+	}
+}
@@ -0,0 +1,150 @@
+// pkg/validator/options.go
+// 验证器选项
+
+package validator
+
+import (
+	"log"
+)
+
+// Option 验证器选项
+type Option func(*Validator)
+
+// WithInstances 设置Azure和AWS实例列表，按下标组成对比对
+func WithInstances(azure, aws []DatabaseInstance) Option {
+	return func(v *Validator) {
+		v.azure = azure
+		v.aws = aws
+	}
+}
+
+// WithDiscovery 启用自动发现：列出两端服务器上的数据库，按名称或改名规则组成对比对，
+// 追加在WithInstances配置的对比对之后
+func WithDiscovery(cfg DiscoveryConfig) Option {
+	return func(v *Validator) {
+		v.discovery = &cfg
+	}
+}
+
+// WithSharding 配置分片表：源表与按分片键拆分到多个实例上的分片表对比，
+// 这些表不再参与所在对比对的验证
+func WithSharding(cfg ShardingConfig) Option {
+	return func(v *Validator) {
+		v.sharding = &cfg
+	}
+}
+
+// WithObjects 设置是否对比视图、存储过程、函数、触发器和事件的定义，默认开启
+func WithObjects(enabled bool) Option {
+	return func(v *Validator) {
+		v.objects = enabled
+	}
+}
+
+// WithGrantUsers 设置需要对比有效权限的用户，格式为 user@host，host默认为%
+func WithGrantUsers(users ...string) Option {
+	return func(v *Validator) {
+		v.grantUsers = users
+	}
+}
+
+// WithMasking 设置脱敏规则，目标端的列是脱敏后的值时，源端的列先做相同变换再计算校验和
+func WithMasking(rules ...MaskRule) Option {
+	return func(v *Validator) {
+		v.masking = rules
+	}
+}
+
+// WithTextCheck 开启文本完整性检查：再读取一遍两端的表，发现非法UTF-8、双重编码和丢失的4字节字符
+func WithTextCheck(cfg TextCheckConfig) Option {
+	return func(v *Validator) {
+		v.textCheck = &cfg
+	}
+}
+
+// WithDatabaseLister 设置自动发现时列出数据库的方式，默认ListDatabases
+func WithDatabaseLister(list DatabaseLister) Option {
+	return func(v *Validator) {
+		if list != nil {
+			v.listDatabases = list
+		}
+	}
+}
+
+// WithMaxWorkers 设置同时验证的对比对数量，默认3
+func WithMaxWorkers(n int) Option {
+	return func(v *Validator) {
+		if n > 0 {
+			v.maxWorkers = n
+		}
+	}
+}
+
+// WithSourceOpener 设置数据源打开方式，默认OpenSource
+func WithSourceOpener(opener SourceOpener) Option {
+	return func(v *Validator) {
+		if opener != nil {
+			v.openSource = opener
+		}
+	}
+}
+
+// WithChecksumStrategy 注册校验策略，与已有策略同名时替换
+func WithChecksumStrategy(strategy ChecksumStrategy) Option {
+	return func(v *Validator) {
+		v.strategies[strategy.Name()] = strategy
+	}
+}
+
+// WithHashAlgorithm 设置内置校验策略的哈希算法：xxhash(默认)、md5 或 sha256
+// 名称无效时Run返回错误；已显式设置Hash的策略不受影响
+func WithHashAlgorithm(name string) Option {
+	return func(v *Validator) {
+		v.hashName = name
+	}
+}
+
+// WithStrategySelector 设置未指定策略时按行数选择策略的方式，默认ChooseStrategy
+func WithStrategySelector(selector StrategySelector) Option {
+	return func(v *Validator) {
+		if selector != nil {
+			v.selectStrategy = selector
+		}
+	}
+}
+
+// WithColumnDiff 设置校验和不一致时是否定位差异所在的列，默认开启
+// 开启时计算校验和的同一遍读取中累加每列指纹，不一致时只重新读取指纹不同的分块
+func WithColumnDiff(enabled bool) Option {
+	return func(v *Validator) {
+		v.columnDiff = enabled
+	}
+}
+
+// WithLogger 设置日志输出，默认log.Default()，传入nil关闭日志
+func WithLogger(logger *log.Logger) Option {
+	return func(v *Validator) {
+		v.logger = logger
+	}
+}
+
+// WithProgressHook 设置进度事件回调，可能在多个goroutine中并发调用
+func WithProgressHook(fn func(Event)) Option {
+	return func(v *Validator) {
+		v.onProgress = fn
+	}
+}
+
+// WithMismatchHook 设置表数据不一致或缺失时的回调，可能在多个goroutine中并发调用
+func WithMismatchHook(fn func(Mismatch)) Option {
+	return func(v *Validator) {
+		v.onMismatch = fn
+	}
+}
+
+// WithErrorHook 设置验证错误回调，可能在多个goroutine中并发调用
+func WithErrorHook(fn func(*ValidationError)) Option {
+	return func(v *Validator) {
+		v.onError = fn
+	}
+}
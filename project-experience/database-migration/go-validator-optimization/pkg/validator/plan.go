@@ -0,0 +1,323 @@
+// pkg/validator/plan.go
+// 验证计划：规模估算、策略选择与耗时预估
+
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	// calibrationRows 校准基准测试读取的行数
+	calibrationRows = 5000
+	// defaultRowsPerSecond 无法校准时使用的默认吞吐量
+	defaultRowsPerSecond = 20000.0
+)
+
+// BuildPlan 查询所有对比对的表统计信息生成验证计划
+// calibrate为true时在每个实例上执行一次短基准测试以估算吞吐量
+func (v *Validator) BuildPlan(ctx context.Context, calibrate bool) (*Plan, error) {
+	if v.hashErr != nil {
+		return nil, v.hashErr
+	}
+
+	pairs, discovery, err := v.resolvePairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("没有需要验证的数据库对比对")
+	}
+
+	plan := &Plan{
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		MaxWorkers: v.maxWorkers,
+		BatchSize:  defaultBatchSize,
+		Calibrated: calibrate,
+	}
+	if discovery != nil {
+		plan.MissingDatabases = discovery.MissingInAWS
+		plan.ExtraDatabases = discovery.ExtraInAWS
+	}
+	if batched, ok := v.strategies[StrategyBatched].(BatchedStrategy); ok {
+		plan.BatchSize = int(batched.size())
+	}
+
+	for _, pair := range pairs {
+		planPair := v.planPair(ctx, pair, calibrate)
+		plan.Pairs = append(plan.Pairs, planPair)
+
+		plan.TotalTables += len(planPair.Tables)
+		for _, t := range planPair.Tables {
+			plan.TotalRows += t.EstimatedRows
+			plan.TotalDataBytes += t.DataBytes
+		}
+	}
+
+	plan.EstimatedSeconds = estimateMakespan(plan.Pairs, plan.MaxWorkers)
+	plan.EstimatedDuration = (time.Duration(plan.EstimatedSeconds * float64(time.Second))).Round(time.Second).String()
+	return plan, ctx.Err()
+}
+
+// planPair 生成单个对比对的计划，错误记录在PlanPair.Error中
+func (v *Validator) planPair(ctx context.Context, pair DatabasePair, calibrate bool) PlanPair {
+	azureInstance, awsInstance := pair.AzureInstance, pair.AWSInstance
+	planPair := PlanPair{
+		AzureInstance:      azureInstance.Name,
+		AWSInstance:        awsInstance.Name,
+		AzureDatabase:      azureInstance.Database,
+		AWSDatabase:        awsInstance.Database,
+		Tables:             []PlanTable{},
+		AzureRowsPerSecond: defaultRowsPerSecond,
+		AWSRowsPerSecond:   defaultRowsPerSecond,
+	}
+
+	azureSrc, err := v.openSource(ctx, azureInstance)
+	if err != nil {
+		planPair.Error = fmt.Sprintf("Azure数据库%v", err)
+		return planPair
+	}
+	defer azureSrc.Close()
+
+	awsSrc, err := v.openSource(ctx, awsInstance)
+	if err != nil {
+		planPair.Error = fmt.Sprintf("AWS数据库%v", err)
+		return planPair
+	}
+	defer awsSrc.Close()
+
+	azureStats, err := azureSrc.TableStats(ctx)
+	if err != nil {
+		planPair.Error = fmt.Sprintf("获取Azure表统计信息失败: %v", err)
+		return planPair
+	}
+	awsStats, err := awsSrc.TableStats(ctx)
+	if err != nil {
+		planPair.Error = fmt.Sprintf("获取AWS表统计信息失败: %v", err)
+		return planPair
+	}
+
+	awsByName := make(map[string]TableStat, len(awsStats))
+	for _, s := range awsStats {
+		awsByName[s.Name] = s
+	}
+	azureByName := make(map[string]bool, len(azureStats))
+
+	for _, s := range azureStats {
+		azureByName[s.Name] = true
+		rows := s.Rows
+		sources := []Source{azureSrc}
+		if aws, ok := awsByName[s.Name]; ok {
+			if aws.Rows > rows {
+				rows = aws.Rows
+			}
+			sources = append(sources, awsSrc)
+		} else {
+			planPair.MissingInAWS = append(planPair.MissingInAWS, s.Name)
+		}
+
+		strategy := v.selectStrategy(rows)
+		if v.keyless(ctx, planPair.AzureDatabase, s.Name, sources...) {
+			strategy = StrategyMultiset
+		}
+		planPair.Tables = append(planPair.Tables, PlanTable{
+			Table:         s.Name,
+			EstimatedRows: rows,
+			DataBytes:     s.DataBytes,
+			Strategy:      strategy,
+			Chunks:        v.chunkCount(strategy, rows),
+		})
+	}
+	for _, s := range awsStats {
+		if !azureByName[s.Name] {
+			planPair.ExtraInAWS = append(planPair.ExtraInAWS, s.Name)
+		}
+	}
+
+	if calibrate {
+		sample := largestTable(planPair.Tables, planPair.MissingInAWS)
+		if sample != "" {
+			if rate, err := calibrateSource(ctx, azureSrc, sample, v.newHash); err == nil {
+				planPair.AzureRowsPerSecond = rate
+			} else {
+				v.logf("Azure实例 %s 校准失败，使用默认吞吐量: %v", azureInstance.Name, err)
+			}
+			if rate, err := calibrateSource(ctx, awsSrc, sample, v.newHash); err == nil {
+				planPair.AWSRowsPerSecond = rate
+			} else {
+				v.logf("AWS实例 %s 校准失败，使用默认吞吐量: %v", awsInstance.Name, err)
+			}
+		}
+	}
+
+	latency := roundTrip(ctx, azureSrc) + roundTrip(ctx, awsSrc)
+	for i := range planPair.Tables {
+		t := &planPair.Tables[i]
+		rows := float64(t.EstimatedRows)
+		// 两端依次计算校验和，每个分块另有一次查询往返，外加COUNT(*)查询
+		t.EstimatedSeconds = rows/planPair.AzureRowsPerSecond + rows/planPair.AWSRowsPerSecond +
+			float64(t.Chunks+1)*latency.Seconds()
+		planPair.EstimatedSeconds += t.EstimatedSeconds
+	}
+
+	return planPair
+}
+
+// chunkCount 计算校验策略对应的分块数
+func (v *Validator) chunkCount(strategy string, rows int64) int {
+	if s, ok := v.strategies[strategy]; ok {
+		return s.Chunks(rows)
+	}
+	return 1
+}
+
+// calibrateSource 读取并哈希一小段数据，测量每秒可处理的行数
+func calibrateSource(ctx context.Context, src Source, table string, newHash HashFunc) (float64, error) {
+	start := time.Now()
+
+	// 与校验和计算相同的处理路径，保证测得的吞吐量可比
+	var rh rowHasher
+	n, err := rh.hashRows(ctx, src, table, 0, calibrationRows, newHash.orDefault()())
+	if err != nil {
+		return 0, err
+	}
+
+	elapsed := time.Since(start).Seconds()
+	if n == 0 || elapsed <= 0 {
+		return 0, fmt.Errorf("表 %s 没有可用于校准的数据", table)
+	}
+	return float64(n) / elapsed, nil
+}
+
+// roundTrip 测量一次查询往返耗时
+func roundTrip(ctx context.Context, src Source) time.Duration {
+	start := time.Now()
+	if err := src.Ping(ctx); err != nil {
+		return 0
+	}
+	return time.Since(start)
+}
+
+// largestTable 返回两端都存在的表中估算行数最多的表
+func largestTable(tables []PlanTable, missing []string) string {
+	name := ""
+	var maxRows int64
+	for _, t := range tables {
+		if t.EstimatedRows > maxRows && !contains(missing, t.Table) {
+			name, maxRows = t.Table, t.EstimatedRows
+		}
+	}
+	return name
+}
+
+// estimateMakespan 按最长处理时间优先分配到workers个并发槽，估算总耗时
+func estimateMakespan(pairs []PlanPair, workers int) float64 {
+	if workers <= 0 {
+		workers = 1
+	}
+	durations := make([]float64, len(pairs))
+	for i, p := range pairs {
+		durations[i] = p.EstimatedSeconds
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(durations)))
+
+	loads := make([]float64, workers)
+	for _, d := range durations {
+		idle := 0
+		for i := range loads {
+			if loads[i] < loads[idle] {
+				idle = i
+			}
+		}
+		loads[idle] += d
+	}
+
+	makespan := 0.0
+	for _, l := range loads {
+		if l > makespan {
+			makespan = l
+		}
+	}
+	return makespan
+}
+
+// resolvePlan 将验证计划转换为对比对，实例连接信息按名称从实例列表中查找
+func (v *Validator) resolvePlan(plan *Plan) ([]DatabasePair, error) {
+	azureByName := make(map[string]DatabaseInstance, len(v.azure))
+	for _, inst := range v.azure {
+		azureByName[inst.Name] = inst
+	}
+	awsByName := make(map[string]DatabaseInstance, len(v.aws))
+	for _, inst := range v.aws {
+		awsByName[inst.Name] = inst
+	}
+
+	databasePairs := make([]DatabasePair, 0, len(plan.Pairs))
+	for i := range plan.Pairs {
+		planPair := &plan.Pairs[i]
+		if planPair.Error != "" {
+			return nil, fmt.Errorf("对比对 %s vs %s 在计划生成时失败(%s)，请重新生成计划", planPair.AzureInstance, planPair.AWSInstance, planPair.Error)
+		}
+		azureInstance, ok := v.planInstance(azureByName, SideAzure, planPair.AzureInstance, planPair.AzureDatabase)
+		if !ok {
+			return nil, fmt.Errorf("计划中的Azure实例 %s 不在配置中", planPair.AzureInstance)
+		}
+		awsInstance, ok := v.planInstance(awsByName, SideAWS, planPair.AWSInstance, planPair.AWSDatabase)
+		if !ok {
+			return nil, fmt.Errorf("计划中的AWS实例 %s 不在配置中", planPair.AWSInstance)
+		}
+		if azureInstance.Database != planPair.AzureDatabase || awsInstance.Database != planPair.AWSDatabase {
+			return nil, fmt.Errorf("实例 %s/%s 的数据库与计划不一致: 配置为 %s/%s，计划为 %s/%s",
+				planPair.AzureInstance, planPair.AWSInstance,
+				azureInstance.Database, awsInstance.Database,
+				planPair.AzureDatabase, planPair.AWSDatabase)
+		}
+		databasePairs = append(databasePairs, DatabasePair{
+			AzureInstance: azureInstance,
+			AWSInstance:   awsInstance,
+			Plan:          planPair,
+		})
+	}
+	return databasePairs, nil
+}
+
+// planInstance 按名称查找配置的实例，找不到时查找自动发现的服务器
+func (v *Validator) planInstance(byName map[string]DatabaseInstance, side, name, database string) (DatabaseInstance, bool) {
+	if inst, ok := byName[name]; ok {
+		return inst, true
+	}
+	if v.discovery == nil {
+		return DatabaseInstance{}, false
+	}
+	return v.discovery.instance(side, name, database)
+}
+
+// SavePlan 将验证计划保存为JSON文件
+func SavePlan(plan *Plan, filename string) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化验证计划失败: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("写入验证计划失败: %v", err)
+	}
+	return nil
+}
+
+// LoadPlan 从JSON文件加载验证计划，校验策略在Run时检查
+func LoadPlan(filename string) (*Plan, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("读取验证计划失败: %v", err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("解析验证计划失败: %v", err)
+	}
+	return &plan, nil
+}
@@ -0,0 +1,691 @@
+// pkg/validator/shard.go
+// 分片目标验证：一个源表对比分布在多个目标实例上的分片表
+
+package validator
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// 内置分片函数
+const (
+	ShardHash  = "hash"  // CRC32(分片键文本) % 分片数，与MySQL的CRC32()一致
+	ShardMod   = "mod"   // 整数分片键 % 分片数
+	ShardRange = "range" // 按Bounds划分的整数区间
+)
+
+// ShardingConfig 分片验证配置
+type ShardingConfig struct {
+	Instances []DatabaseInstance `json:"instances,omitempty" yaml:"instances,omitempty" mapstructure:"instances"` // 分片表所在的服务器，database由分片表指定
+	Tables    []ShardMapping     `json:"tables" yaml:"tables" mapstructure:"tables"`                              // 分片表映射
+}
+
+// ShardMapping 一个源表到N个分片表的映射
+type ShardMapping struct {
+	Name     string       `json:"name,omitempty" yaml:"name,omitempty" mapstructure:"name"`       // 名称，默认为 库名.表名
+	Source   ShardTable   `json:"source" yaml:"source" mapstructure:"source"`                     // 源表
+	Key      string       `json:"key" yaml:"key" mapstructure:"key"`                              // 分片键列名
+	Function string       `json:"function" yaml:"function" mapstructure:"function"`               // hash、mod 或 range
+	Bounds   []int64      `json:"bounds,omitempty" yaml:"bounds,omitempty" mapstructure:"bounds"` // range: 前N-1个分片的上界（不含），最后一个分片没有上界
+	Targets  []ShardTable `json:"targets" yaml:"targets" mapstructure:"targets"`                  // 按分片序号排列的分片表
+	Func     ShardFunc    `json:"-" yaml:"-" mapstructure:"-"`                                    // 自定义分片函数，设置时忽略Function
+}
+
+// ShardTable 分片映射中的一个表
+type ShardTable struct {
+	Instance string `json:"instance" yaml:"instance" mapstructure:"instance"`                     // 实例名称
+	Database string `json:"database,omitempty" yaml:"database,omitempty" mapstructure:"database"` // 数据库名，为空时使用实例配置的数据库
+	Table    string `json:"table,omitempty" yaml:"table,omitempty" mapstructure:"table"`          // 表名，分片表为空时与源表同名
+}
+
+// ShardFunc 根据分片键的文本值返回分片序号，key为nil表示NULL
+type ShardFunc func(key []byte) (int, error)
+
+// ShardedTableResult 分片表的验证结果
+type ShardedTableResult struct {
+	Name           string        `json:"name" yaml:"name" mapstructure:"name"`
+	SourceInstance string        `json:"source_instance" yaml:"source_instance" mapstructure:"source_instance"`
+	SourceDatabase string        `json:"source_database" yaml:"source_database" mapstructure:"source_database"`
+	SourceTable    string        `json:"source_table" yaml:"source_table" mapstructure:"source_table"`
+	Key            string        `json:"key" yaml:"key" mapstructure:"key"`
+	Function       string        `json:"function" yaml:"function" mapstructure:"function"`
+	Status         string        `json:"status" yaml:"status" mapstructure:"status"` // MATCH、MISMATCH 或 ERROR
+	SourceRows     int64         `json:"source_rows" yaml:"source_rows" mapstructure:"source_rows"`
+	TargetRows     int64         `json:"target_rows" yaml:"target_rows" mapstructure:"target_rows"` // 所有分片的行数之和
+	Shards         []ShardResult `json:"shards" yaml:"shards" mapstructure:"shards"`
+	MisroutedRows  int64         `json:"misrouted_rows" yaml:"misrouted_rows" mapstructure:"misrouted_rows"`    // 源表中存在、但位于错误分片上的行
+	MissingRows    int64         `json:"missing_rows" yaml:"missing_rows" mapstructure:"missing_rows"`          // 源表中存在、任何分片上都没有的行
+	ExtraRows      int64         `json:"extra_rows" yaml:"extra_rows" mapstructure:"extra_rows"`                // 分片上存在、源表中没有的行（含重复行）
+	MismatchedRows int64         `json:"mismatched_rows" yaml:"mismatched_rows" mapstructure:"mismatched_rows"` // 位于正确分片上但内容不一致的行
+	UnroutableRows int64         `json:"unroutable_rows" yaml:"unroutable_rows" mapstructure:"unroutable_rows"` // 源表中分片键为NULL或无法解析的行
+	RowsCounted    bool          `json:"rows_counted" yaml:"rows_counted" mapstructure:"rows_counted"`          // 是否按第一列统计了行级差异；差异过多或第一列重复时为false
+	Summary        string        `json:"summary,omitempty" yaml:"summary,omitempty" mapstructure:"summary"`     // 差异摘要
+	Error          string        `json:"error,omitempty" yaml:"error,omitempty" mapstructure:"error"`           // 验证错误
+	StartTime      string        `json:"start_time" yaml:"start_time" mapstructure:"start_time"`                // 开始时间
+	EndTime        string        `json:"end_time" yaml:"end_time" mapstructure:"end_time"`                      // 结束时间
+}
+
+// ShardResult 单个分片的验证结果
+type ShardResult struct {
+	Shard            int    `json:"shard" yaml:"shard" mapstructure:"shard"`
+	Instance         string `json:"instance" yaml:"instance" mapstructure:"instance"`
+	Database         string `json:"database" yaml:"database" mapstructure:"database"`
+	Table            string `json:"table" yaml:"table" mapstructure:"table"`
+	ExpectedRows     int64  `json:"expected_rows" yaml:"expected_rows" mapstructure:"expected_rows"`             // 源表中路由到该分片的行数
+	Rows             int64  `json:"rows" yaml:"rows" mapstructure:"rows"`                                        // 分片表的行数
+	ExpectedChecksum string `json:"expected_checksum" yaml:"expected_checksum" mapstructure:"expected_checksum"` // 源表中路由到该分片的行的指纹
+	Checksum         string `json:"checksum" yaml:"checksum" mapstructure:"checksum"`                            // 分片表的指纹
+	Match            bool   `json:"match" yaml:"match" mapstructure:"match"`
+	ForeignRows      int64  `json:"foreign_rows,omitempty" yaml:"foreign_rows,omitempty" mapstructure:"foreign_rows"` // 分片键按映射不属于该分片的行数
+	Misrouted        int64  `json:"misrouted,omitempty" yaml:"misrouted,omitempty" mapstructure:"misrouted"`          // 该分片上误路由的行数
+	Missing          int64  `json:"missing,omitempty" yaml:"missing,omitempty" mapstructure:"missing"`                // 应在该分片上、但任何分片上都没有的行数
+	Extra            int64  `json:"extra,omitempty" yaml:"extra,omitempty" mapstructure:"extra"`                      // 该分片上源表中没有的行数
+	Mismatched       int64  `json:"mismatched,omitempty" yaml:"mismatched,omitempty" mapstructure:"mismatched"`       // 该分片上内容不一致的行数
+}
+
+// Validate 检查映射配置
+func (m ShardMapping) Validate() error {
+	if m.Source.Instance == "" || m.Source.Table == "" {
+		return fmt.Errorf("分片映射 %s 必须指定源表的instance和table", m.name())
+	}
+	if m.Key == "" {
+		return fmt.Errorf("分片映射 %s 缺少分片键key", m.name())
+	}
+	if len(m.Targets) == 0 {
+		return fmt.Errorf("分片映射 %s 没有分片表targets", m.name())
+	}
+	for i, t := range m.Targets {
+		if t.Instance == "" {
+			return fmt.Errorf("分片映射 %s 的第 %d 个分片缺少instance", m.name(), i)
+		}
+	}
+	_, err := NewShardFunc(m)
+	return err
+}
+
+// name 映射名称
+func (m ShardMapping) name() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	if m.Source.Database != "" {
+		return m.Source.Database + "." + m.Source.Table
+	}
+	return m.Source.Table
+}
+
+// target 第i个分片表，表名为空时与源表同名
+func (m ShardMapping) target(i int) ShardTable {
+	t := m.Targets[i]
+	if t.Table == "" {
+		t.Table = m.Source.Table
+	}
+	return t
+}
+
+// NewShardFunc 返回映射的分片函数
+func NewShardFunc(m ShardMapping) (ShardFunc, error) {
+	if m.Func != nil {
+		return m.Func, nil
+	}
+	n := len(m.Targets)
+	switch m.Function {
+	case ShardHash:
+		return func(key []byte) (int, error) {
+			if key == nil {
+				return 0, fmt.Errorf("分片键为NULL")
+			}
+			return int(crc32.ChecksumIEEE(key) % uint32(n)), nil
+		}, nil
+	case ShardMod:
+		return func(key []byte) (int, error) {
+			k, err := parseShardKey(key)
+			if err != nil {
+				return 0, err
+			}
+			shard := k % int64(n)
+			if shard < 0 {
+				shard += int64(n)
+			}
+			return int(shard), nil
+		}, nil
+	case ShardRange:
+		if len(m.Bounds) != n-1 {
+			return nil, fmt.Errorf("分片映射 %s 有 %d 个分片，bounds应有 %d 个上界，实际为 %d 个", m.name(), n, n-1, len(m.Bounds))
+		}
+		for i := 1; i < len(m.Bounds); i++ {
+			if m.Bounds[i] <= m.Bounds[i-1] {
+				return nil, fmt.Errorf("分片映射 %s 的bounds必须严格递增", m.name())
+			}
+		}
+		bounds := m.Bounds
+		return func(key []byte) (int, error) {
+			k, err := parseShardKey(key)
+			if err != nil {
+				return 0, err
+			}
+			return sort.Search(len(bounds), func(i int) bool { return k < bounds[i] }), nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("分片映射 %s 的分片函数无效: %q (可选: hash, mod, range)", m.name(), m.Function)
+	}
+}
+
+// parseShardKey 解析整数分片键
+func parseShardKey(key []byte) (int64, error) {
+	if key == nil {
+		return 0, fmt.Errorf("分片键为NULL")
+	}
+	k, err := strconv.ParseInt(string(key), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("分片键 %q 不是整数", key)
+	}
+	return k, nil
+}
+
+// shardedTableCount 配置的分片表数量
+func (v *Validator) shardedTableCount() int {
+	if v.sharding == nil {
+		return 0
+	}
+	return len(v.sharding.Tables)
+}
+
+// shardedTables 返回实例上参与分片验证的表，对比对验证时跳过这些表
+func (v *Validator) shardedTables(side string, instance DatabaseInstance) map[string]bool {
+	if v.sharding == nil {
+		return nil
+	}
+	tables := map[string]bool{}
+	matches := func(t ShardTable) bool {
+		return t.Instance == instance.Name && (t.Database == "" || t.Database == instance.Database)
+	}
+	for _, m := range v.sharding.Tables {
+		if side == SideAzure && matches(m.Source) {
+			tables[m.Source.Table] = true
+		}
+		if side == SideAWS {
+			for i := range m.Targets {
+				if t := m.target(i); matches(t) {
+					tables[t.Table] = true
+				}
+			}
+		}
+	}
+	return tables
+}
+
+// shardInstance 按名称查找分片映射引用的实例
+// 依次查找分片服务器、对比对实例和自动发现的服务器
+func (v *Validator) shardInstance(side string, t ShardTable) (DatabaseInstance, error) {
+	for _, inst := range v.sharding.Instances {
+		if inst.Name == t.Instance {
+			if t.Database == "" {
+				return inst, nil
+			}
+			return inst.forDatabase(t.Database), nil
+		}
+	}
+	instances := v.azure
+	if side == SideAWS {
+		instances = v.aws
+	}
+	for _, inst := range instances {
+		if inst.Name == t.Instance {
+			if t.Database != "" {
+				inst.Database = t.Database
+			}
+			return inst, nil
+		}
+	}
+	if v.discovery != nil && t.Database != "" {
+		if inst, ok := v.discovery.instance(side, t.Instance, t.Database); ok {
+			return inst, nil
+		}
+	}
+	return DatabaseInstance{}, fmt.Errorf("分片映射引用的实例 %s 不存在", t.Instance)
+}
+
+// validateShards 依次验证所有分片表
+func (v *Validator) validateShards(ctx context.Context) []ShardedTableResult {
+	if v.shardedTableCount() == 0 {
+		return nil
+	}
+	v.logf("开始验证 %d 个分片表", len(v.sharding.Tables))
+	results := make([]ShardedTableResult, 0, len(v.sharding.Tables))
+	for _, m := range v.sharding.Tables {
+		results = append(results, v.validateShard(ctx, m))
+	}
+	return results
+}
+
+// validateShard 验证一个源表与其全部分片表
+// 先按分片键路由源表的每一行，比较每个分片与顺序无关的指纹；
+// 有分片不一致时再按第一列配对，区分误路由、缺失、多余和内容不一致的行
+func (v *Validator) validateShard(ctx context.Context, m ShardMapping) ShardedTableResult {
+	label := "分片 " + m.name()
+	result := ShardedTableResult{
+		Name:           m.name(),
+		SourceInstance: m.Source.Instance,
+		SourceDatabase: m.Source.Database,
+		SourceTable:    m.Source.Table,
+		Key:            m.Key,
+		Function:       m.Function,
+		Status:         TableMatch,
+		RowsCounted:    true,
+		StartTime:      time.Now().Format(time.RFC3339),
+	}
+	if m.Func != nil {
+		result.Function = "custom"
+	}
+
+	v.logf("开始验证分片表 %s: %s -> %d 个分片", m.name(), m.Source.Table, len(m.Targets))
+	v.emit(Event{Type: EventDatabaseStarted, Database: label})
+	v.emit(Event{Type: EventDatabaseTables, Database: label, Tables: 1})
+	v.emit(Event{Type: EventTableStarted, Database: label, Table: m.Source.Table})
+
+	finish := func() ShardedTableResult {
+		result.EndTime = time.Now().Format(time.RFC3339)
+		status := StatusSuccess
+		switch result.Status {
+		case TableMismatch:
+			status = StatusInconsistent
+			v.logf("分片表 %s 不一致: %s", m.name(), result.Summary)
+		case TableError:
+			status = StatusError
+		default:
+			v.logf("分片表 %s 数据一致", m.name())
+		}
+		v.tableResult(label, m.Source.Table, result.Status, result.Summary+result.Error, nil)
+		v.emit(Event{Type: EventDatabaseFinished, Database: label, Status: status, Message: result.Error})
+		return result
+	}
+	fail := func(side string, err error) ShardedTableResult {
+		result.Status = TableError
+		result.Error = err.Error()
+		v.logf("分片表 %s: %v", m.name(), err)
+		v.reportError(&ValidationError{Database: label, Table: m.Source.Table, Side: side, Err: err})
+		return finish()
+	}
+
+	if err := m.Validate(); err != nil {
+		return fail("", err)
+	}
+	route, _ := NewShardFunc(m)
+	n := len(m.Targets)
+
+	// 打开源表和全部分片表
+	srcInstance, err := v.shardInstance(SideAzure, m.Source)
+	if err != nil {
+		return fail(SideAzure, err)
+	}
+	src, err := v.openSource(ctx, srcInstance)
+	if err != nil {
+		return fail(SideAzure, fmt.Errorf("源表实例%v", err))
+	}
+	defer src.Close()
+	src = v.maskSource(src, srcInstance.Database)
+
+	targets := make([]Source, n)
+	defer func() {
+		for _, t := range targets {
+			if t != nil {
+				t.Close()
+			}
+		}
+	}()
+	result.Shards = make([]ShardResult, n)
+	for i := range targets {
+		t := m.target(i)
+		inst, err := v.shardInstance(SideAWS, t)
+		if err != nil {
+			return fail(SideAWS, err)
+		}
+		if targets[i], err = v.openSource(ctx, inst); err != nil {
+			return fail(SideAWS, fmt.Errorf("分片 %d 实例%v", i, err))
+		}
+		result.Shards[i] = ShardResult{Shard: i, Instance: inst.Name, Database: inst.Database, Table: t.Table}
+	}
+
+	// 源表每一行按分片键路由，累加每个分片应有的指纹
+	newHash := v.newHash.orDefault()
+	expected := make([]shardSum, n)
+	h := newHash()
+	var digest []byte
+	srcColumns, err := scanShardRows(ctx, src, m.Source.Table, m.Key, route, func(row, id []byte, shard int) {
+		result.SourceRows++
+		if shard < 0 || shard >= n {
+			result.UnroutableRows++
+			return
+		}
+		digest = expected[shard].add(h, row, digest)
+	})
+	if err != nil {
+		return fail(SideAzure, fmt.Errorf("读取源表 %s 失败: %v", m.Source.Table, err))
+	}
+	v.emit(Event{Type: EventChunkDone, Database: label, Table: m.Source.Table, Side: SideAzure, Chunk: 1, Chunks: 1, Rows: result.SourceRows})
+
+	// 各分片表并行计算指纹，并统计分片键不属于该分片的行
+	actual := make([]shardSum, n)
+	errs := make([]error, n)
+	semaphore := make(chan struct{}, v.maxWorkers)
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			h := newHash()
+			var digest []byte
+			columns, err := scanShardRows(ctx, targets[i], result.Shards[i].Table, m.Key, route, func(row, id []byte, shard int) {
+				digest = actual[i].add(h, row, digest)
+				if shard != i {
+					result.Shards[i].ForeignRows++
+				}
+			})
+			if err == nil && srcColumns != nil && columns != nil && !sameColumns(srcColumns, columns) {
+				err = fmt.Errorf("列与源表不一致: %s vs %s", strings.Join(columns, ","), strings.Join(srcColumns, ","))
+			}
+			errs[i] = err
+			v.emit(Event{Type: EventChunkDone, Database: label, Table: m.Source.Table, Side: SideAWS, Chunk: i + 1, Chunks: n, Rows: actual[i].rows})
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			s := result.Shards[i]
+			return fail(SideAWS, fmt.Errorf("分片 %d (%s/%s.%s): %v", i, s.Instance, s.Database, s.Table, err))
+		}
+	}
+
+	mismatched := make([]bool, n)
+	for i := range result.Shards {
+		s := &result.Shards[i]
+		s.ExpectedRows = expected[i].rows
+		s.Rows = actual[i].rows
+		s.ExpectedChecksum = expected[i].String()
+		s.Checksum = actual[i].String()
+		s.Match = s.ExpectedChecksum == s.Checksum
+		result.TargetRows += s.Rows
+		if !s.Match {
+			mismatched[i] = true
+			result.Status = TableMismatch
+		}
+	}
+	if result.UnroutableRows > 0 {
+		result.Status = TableMismatch
+	}
+	if result.Status == TableMatch {
+		return finish()
+	}
+
+	// 只对不一致的分片按第一列配对，统计各类差异行
+	if err := v.countShardRows(ctx, &result, m, route, src, targets, mismatched); err != nil {
+		return fail("", fmt.Errorf("行级差异统计失败: %v", err))
+	}
+	result.Summary = result.summarize()
+	return finish()
+}
+
+// shardEntry 源表中一行的哈希和应在的分片，-1表示无法路由
+type shardEntry struct {
+	digest uint64
+	shard  int
+}
+
+// misplacedRow 位于分片键对应分片之外的分片表行
+type misplacedRow struct {
+	key   string
+	shard int
+}
+
+// countShardRows 按第一列配对源表和不一致的分片表，统计误路由、缺失、多余和内容不一致的行
+// 暂存的行数超过maxPendingRows或第一列重复时放弃行级统计，RowsCounted为false
+func (v *Validator) countShardRows(ctx context.Context, result *ShardedTableResult, m ShardMapping, route ShardFunc, src Source, targets []Source, mismatched []bool) error {
+	pending := map[string]shardEntry{}
+	overflow := false
+	_, err := scanShardRows(ctx, src, m.Source.Table, m.Key, route, func(row, id []byte, shard int) {
+		if overflow || (shard >= 0 && shard < len(targets) && !mismatched[shard]) {
+			return
+		}
+		if shard >= len(targets) {
+			shard = -1
+		}
+		key := string(id)
+		if _, dup := pending[key]; dup || len(pending) >= maxPendingRows {
+			overflow = true
+			return
+		}
+		pending[key] = shardEntry{digest: xxhash.Sum64(row), shard: shard}
+	})
+	if err != nil {
+		return err
+	}
+
+	var misplaced []misplacedRow
+	for i, target := range targets {
+		if !mismatched[i] || overflow {
+			continue
+		}
+		s := &result.Shards[i]
+		_, err := scanShardRows(ctx, target, s.Table, m.Key, route, func(row, id []byte, shard int) {
+			if overflow {
+				return
+			}
+			key := string(id)
+			entry, ok := pending[key]
+			switch {
+			case !ok:
+				// 源表中没有，或已在分片键对应的分片上
+				s.Extra++
+			case entry.shard == i:
+				delete(pending, key)
+				if entry.digest != xxhash.Sum64(row) {
+					s.Mismatched++
+				}
+			default:
+				// 应在其他分片上，等所有分片读取完后再判断是误路由还是重复
+				if len(misplaced) >= maxPendingRows {
+					overflow = true
+					return
+				}
+				misplaced = append(misplaced, misplacedRow{key: key, shard: i})
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("分片 %d: %v", i, err)
+		}
+	}
+	if overflow {
+		result.RowsCounted = false
+		for i := range result.Shards {
+			result.Shards[i].Extra, result.Shards[i].Mismatched = 0, 0
+		}
+		return nil
+	}
+
+	for _, row := range misplaced {
+		if _, ok := pending[row.key]; ok {
+			delete(pending, row.key)
+			result.Shards[row.shard].Misrouted++
+		} else {
+			result.Shards[row.shard].Extra++
+		}
+	}
+	for _, entry := range pending {
+		if entry.shard >= 0 {
+			result.Shards[entry.shard].Missing++
+		}
+		result.MissingRows++
+	}
+	for _, s := range result.Shards {
+		result.MisroutedRows += s.Misrouted
+		result.ExtraRows += s.Extra
+		result.MismatchedRows += s.Mismatched
+	}
+	return nil
+}
+
+// summarize 生成分片表的差异摘要
+func (r *ShardedTableResult) summarize() string {
+	var parts []string
+	if !r.RowsCounted {
+		var shards []string
+		var foreign int64
+		for _, s := range r.Shards {
+			if !s.Match {
+				shards = append(shards, strconv.Itoa(s.Shard))
+			}
+			foreign += s.ForeignRows
+		}
+		parts = append(parts, "差异过多或第一列有重复值，只比较了分片指纹")
+		if len(shards) > 0 {
+			parts = append(parts, "不一致的分片: "+strings.Join(shards, ", "))
+		}
+		if foreign > 0 {
+			parts = append(parts, fmt.Sprintf("%s 行不在分片键对应的分片上", formatCount(foreign)))
+		}
+	} else {
+		counts := []struct {
+			label string
+			n     int64
+		}{
+			{"误路由", r.MisroutedRows},
+			{"缺失", r.MissingRows},
+			{"多余", r.ExtraRows},
+			{"内容不一致", r.MismatchedRows},
+		}
+		for _, c := range counts {
+			if c.n > 0 {
+				parts = append(parts, fmt.Sprintf("%s %s 行", c.label, formatCount(c.n)))
+			}
+		}
+	}
+	if r.UnroutableRows > 0 {
+		parts = append(parts, fmt.Sprintf("源表 %s 行的分片键无法路由", formatCount(r.UnroutableRows)))
+	}
+	if len(parts) == 0 {
+		return "各行一致，差异来自第一列相同的重复行"
+	}
+	return strings.Join(parts, "；")
+}
+
+// shardSum 与行顺序无关的指纹，与multiset策略的格式相同
+type shardSum struct {
+	rows  int64
+	lanes [2]uint64
+}
+
+// add 加入一行，返回复用的摘要缓冲区
+func (s *shardSum) add(h hash.Hash, row, digest []byte) []byte {
+	h.Reset()
+	h.Write(row)
+	digest = h.Sum(digest[:0])
+	first, second := digestLanes(digest)
+	s.lanes[0] += first
+	s.lanes[1] += second
+	s.rows++
+	return digest
+}
+
+// String 指纹文本
+func (s shardSum) String() string {
+	return fmt.Sprintf("%d-%016x%016x", s.rows, s.lanes[0], s.lanes[1])
+}
+
+// scanShardRows 不排序读取整个表，对每一行调用onRow
+// onRow收到行的编码、第一列的值和分片键路由到的分片，无法路由时为-1；参数在回调返回后被复用
+func scanShardRows(ctx context.Context, src Source, table, key string, route ShardFunc, onRow func(row, id []byte, shard int)) ([]string, error) {
+	rows, err := openForDiff(ctx, src, table, true)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	raw, isRaw := rows.(rawRows)
+	var columns []string
+	var values valueBuffer
+	var buf []byte
+	keyCol := -1
+	for rows.Next() {
+		if keyCol < 0 {
+			columns = rows.Columns()
+			for i, c := range columns {
+				if strings.EqualFold(c, key) {
+					keyCol = i
+				}
+			}
+			if keyCol < 0 {
+				return nil, fmt.Errorf("表 %s 没有分片键列 %s", table, key)
+			}
+		}
+
+		var row [][]byte
+		if isRaw {
+			row = raw.rawValues()
+		} else {
+			row = values.from(rows.Values())
+		}
+		buf = appendRawRow(buf[:0], row)
+		shard, err := route(row[keyCol])
+		if err != nil {
+			shard = -1
+		}
+		onRow(buf, row[0], shard)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return columns, ctx.Err()
+}
+
+// valueBuffer 将Values()转换为每列的文本，复用缓冲区，NULL为nil
+type valueBuffer struct {
+	values [][]byte
+	bufs   [][]byte
+}
+
+// from 转换一行的值
+func (b *valueBuffer) from(values []any) [][]byte {
+	if len(b.values) != len(values) {
+		b.values = make([][]byte, len(values))
+		b.bufs = make([][]byte, len(values))
+		for i := range b.bufs {
+			b.bufs[i] = make([]byte, 0, 16)
+		}
+	}
+	for i, val := range values {
+		if val == nil {
+			b.values[i] = nil
+			continue
+		}
+		b.bufs[i] = appendValue(b.bufs[i][:0], val)
+		b.values[i] = b.bufs[i]
+	}
+	return b.values
+}
+
+// sameColumns 列名和顺序是否一致，忽略大小写
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
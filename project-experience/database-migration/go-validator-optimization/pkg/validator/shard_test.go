@@ -0,0 +1,125 @@
+package validator
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunShardedTable(t *testing.T) {
+	// orders按c2 (customer_id) % 4 拆分到两个实例的orders_00..orders_03
+	orders := make([][]any, 40)
+	for i := range orders {
+		orders[i] = []any{int64(i + 1), int64(i % 7), "9.99"}
+	}
+	shards := make([][][]any, 4)
+	for _, row := range orders {
+		s := row[1].(int64) % 4
+		shards[s] = append(shards[s], row)
+	}
+
+	// 分片0的第一行被写到分片1，分片2少一行、内容改一行，分片3多一行
+	moved := shards[0][0]
+	shards[0] = shards[0][1:]
+	shards[1] = append(shards[1], moved)
+	shards[2] = shards[2][1:]
+	shards[2][0] = []any{shards[2][0][0], shards[2][0][1], "10.00"}
+	shards[3] = append(shards[3], []any{int64(99), int64(3), "1.00"})
+
+	sources := map[string]Source{
+		"azure-1/shop": &memSource{tables: map[string][][]any{"orders": orders, "users": numberedRows(3)}},
+		"aws-1/shop":   &memSource{tables: map[string][][]any{"users": numberedRows(3)}},
+		"shard-a/shop": &memSource{tables: map[string][][]any{"orders_00": shards[0], "orders_01": shards[1]}},
+		"shard-b/shop": &memSource{tables: map[string][][]any{"orders_02": shards[2], "orders_03": shards[3]}},
+	}
+
+	mapping := ShardMapping{
+		Source:   ShardTable{Instance: "azure-1", Database: "shop", Table: "orders"},
+		Key:      "c2",
+		Function: ShardMod,
+		Targets: []ShardTable{
+			{Instance: "shard-a", Database: "shop", Table: "orders_00"},
+			{Instance: "shard-a", Database: "shop", Table: "orders_01"},
+			{Instance: "shard-b", Database: "shop", Table: "orders_02"},
+			{Instance: "shard-b", Database: "shop", Table: "orders_03"},
+		},
+	}
+	var mismatches []Mismatch
+	v := New(
+		WithInstances(
+			[]DatabaseInstance{{Name: "azure-1", Database: "shop"}},
+			[]DatabaseInstance{{Name: "aws-1", Database: "shop"}},
+		),
+		WithSharding(ShardingConfig{
+			Instances: []DatabaseInstance{{Name: "shard-a"}, {Name: "shard-b"}},
+			Tables:    []ShardMapping{mapping},
+		}),
+		WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+			return sources[inst.Name+"/"+inst.Database], nil
+		}),
+		WithMismatchHook(func(m Mismatch) { mismatches = append(mismatches, m) }),
+		WithLogger(nil),
+	)
+
+	report, err := v.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// 源表不再作为对比对中缺失的表
+	if got := report.Results[ResultKey("azure-1", "aws-1", "shop")]; got.Status != StatusSuccess || got.AzureTables != 1 {
+		t.Errorf("pair result = %+v", got)
+	}
+	if len(report.ShardedTables) != 1 {
+		t.Fatalf("sharded tables = %+v", report.ShardedTables)
+	}
+	r := report.ShardedTables[0]
+	if r.Status != TableMismatch || !r.RowsCounted || r.SourceRows != 40 || r.TargetRows != 40 {
+		t.Fatalf("result = %+v", r)
+	}
+	if r.MisroutedRows != 1 || r.MissingRows != 1 || r.ExtraRows != 1 || r.MismatchedRows != 1 {
+		t.Errorf("misrouted=%d missing=%d extra=%d mismatched=%d", r.MisroutedRows, r.MissingRows, r.ExtraRows, r.MismatchedRows)
+	}
+	if s := r.Shards; s[1].Misrouted != 1 || s[1].ForeignRows != 1 || s[2].Missing != 1 || s[2].Mismatched != 1 || s[3].Extra != 1 {
+		t.Errorf("shards = %+v", s)
+	}
+	if want := "误路由 1 行；缺失 1 行；多余 1 行；内容不一致 1 行"; r.Summary != want {
+		t.Errorf("summary = %q, want %q", r.Summary, want)
+	}
+	if len(mismatches) != 1 || mismatches[0].Table != "orders" {
+		t.Errorf("mismatches = %+v", mismatches)
+	}
+}
+
+func TestShardFuncs(t *testing.T) {
+	targets := make([]ShardTable, 3)
+	tests := []struct {
+		mapping ShardMapping
+		key     string
+		want    int
+	}{
+		{ShardMapping{Function: ShardMod}, "7", 1},
+		{ShardMapping{Function: ShardMod}, "-7", 2},
+		{ShardMapping{Function: ShardRange, Bounds: []int64{100, 200}}, "99", 0},
+		{ShardMapping{Function: ShardRange, Bounds: []int64{100, 200}}, "100", 1},
+		{ShardMapping{Function: ShardRange, Bounds: []int64{100, 200}}, "5000", 2},
+		// MySQL: SELECT CRC32('customer-1') % 3 = 0
+		{ShardMapping{Function: ShardHash}, "customer-1", 0},
+	}
+	for _, tt := range tests {
+		tt.mapping.Targets = targets
+		route, err := NewShardFunc(tt.mapping)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := route([]byte(tt.key)); err != nil || got != tt.want {
+			t.Errorf("%s(%s) = %d, %v, want %d", tt.mapping.Function, tt.key, got, err, tt.want)
+		}
+	}
+
+	if _, err := NewShardFunc(ShardMapping{Function: ShardRange, Bounds: []int64{1}, Targets: targets}); err == nil {
+		t.Error("expected error for wrong number of bounds")
+	}
+	route, _ := NewShardFunc(ShardMapping{Function: ShardMod, Targets: targets})
+	if _, err := route(nil); err == nil {
+		t.Error("expected error for NULL key")
+	}
+}
@@ -0,0 +1,287 @@
+// pkg/validator/source.go
+// 数据源接口与MySQL实现
+
+package validator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// TableStat 表的估算行数和数据大小
+type TableStat struct {
+	Name      string
+	Rows      int64
+	DataBytes int64
+}
+
+// Rows 按固定顺序读取的行迭代器
+type Rows interface {
+	// Columns 返回列名
+	Columns() []string
+	// Next 前进到下一行，没有更多行或出错时返回false
+	Next() bool
+	// Values 返回当前行的值，NULL为nil；返回的切片在下一次Next后可能被复用
+	Values() []any
+	// Err 返回迭代过程中的错误
+	Err() error
+	// Close 释放资源
+	Close() error
+}
+
+// Source 数据源，绑定到一个数据库实例中的一个数据库
+type Source interface {
+	// Tables 返回所有表名，按名称排序
+	Tables(ctx context.Context) ([]string, error)
+	// TableStats 返回所有表的估算行数和数据大小，按名称排序
+	TableStats(ctx context.Context) ([]TableStat, error)
+	// CountRows 返回表的精确行数
+	CountRows(ctx context.Context, table string) (int64, error)
+	// ReadRows 按第一列排序读取表中的行，limit<=0时读取offset之后的全部行
+	ReadRows(ctx context.Context, table string, offset, limit int64) (Rows, error)
+	// Ping 执行一次往返，用于检测连接和测量延迟
+	Ping(ctx context.Context) error
+	// Close 关闭数据源
+	Close() error
+}
+
+// KeyChecker 可以判断表的行顺序是否确定的数据源
+// 没有实现该接口的数据源视为按第一列排序的结果是确定的
+type KeyChecker interface {
+	// HasUniqueKey 表的第一列是否为非空的单列唯一键（主键或唯一索引），
+	// 为false时ORDER BY 1的结果不确定，需要使用与顺序无关的校验策略
+	HasUniqueKey(ctx context.Context, table string) (bool, error)
+}
+
+// UnorderedSource 可以不排序读取全表的数据源，与顺序无关的校验策略优先使用
+type UnorderedSource interface {
+	// ReadRowsUnordered 按任意顺序读取表中的全部行
+	ReadRowsUnordered(ctx context.Context, table string) (Rows, error)
+}
+
+// SourceOpener 根据实例配置打开数据源
+type SourceOpener func(ctx context.Context, instance DatabaseInstance) (Source, error)
+
+// mysqlSource 基于database/sql的MySQL数据源
+type mysqlSource struct {
+	db       *sql.DB
+	database string
+}
+
+// OpenSource 按实例类型打开数据源，默认的SourceOpener
+func OpenSource(ctx context.Context, instance DatabaseInstance) (Source, error) {
+	switch instance.Type {
+	case "", SourceMySQL:
+		return OpenMySQL(ctx, instance)
+	case SourceFiles:
+		return OpenFiles(ctx, instance)
+	default:
+		return nil, fmt.Errorf("未知的数据源类型: %s", instance.Type)
+	}
+}
+
+// OpenMySQL 连接MySQL实例并返回数据源
+// 不启用parseTime，日期时间列保持服务器返回的文本，与导出文件中的表示一致
+func OpenMySQL(ctx context.Context, instance DatabaseInstance) (Source, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=%s",
+		instance.User, instance.Password, instance.Host, instance.Database, instance.Charset)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("连接失败: %v", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接测试失败: %v", err)
+	}
+	return NewSQLSource(db, instance.Database), nil
+}
+
+// NewSQLSource 使用已有的MySQL连接创建数据源，关闭数据源时会关闭db
+func NewSQLSource(db *sql.DB, database string) Source {
+	return &mysqlSource{db: db, database: database}
+}
+
+// Tables 返回所有基础表
+func (s *mysqlSource) Tables(ctx context.Context) ([]string, error) {
+	query := "SELECT table_name FROM information_schema.tables WHERE table_schema = ? AND table_type = 'BASE TABLE' ORDER BY table_name"
+	rows, err := s.db.QueryContext(ctx, query, s.database)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, err
+		}
+		tables = append(tables, tableName)
+	}
+	return tables, rows.Err()
+}
+
+// TableStats 从information_schema.TABLES获取表的估算行数和数据大小
+func (s *mysqlSource) TableStats(ctx context.Context) ([]TableStat, error) {
+	query := `SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME`
+	rows, err := s.db.QueryContext(ctx, query, s.database)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []TableStat
+	for rows.Next() {
+		var st TableStat
+		if err := rows.Scan(&st.Name, &st.Rows, &st.DataBytes); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// CountRows 返回表的精确行数
+func (s *mysqlSource) CountRows(ctx context.Context, table string) (int64, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`", s.database, table)
+	var count int64
+	err := s.db.QueryRowContext(ctx, query).Scan(&count)
+	return count, err
+}
+
+// ReadRows 按第一列排序读取表中的行
+func (s *mysqlSource) ReadRows(ctx context.Context, table string, offset, limit int64) (Rows, error) {
+	query := fmt.Sprintf("SELECT * FROM `%s`.`%s` ORDER BY 1", s.database, table)
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	} else if offset > 0 {
+		// MySQL的OFFSET必须配合LIMIT使用
+		query += fmt.Sprintf(" LIMIT 18446744073709551615 OFFSET %d", offset)
+	}
+	return s.query(ctx, query)
+}
+
+// ReadRowsUnordered 不排序读取全表，避免没有索引的表进行文件排序
+func (s *mysqlSource) ReadRowsUnordered(ctx context.Context, table string) (Rows, error) {
+	return s.query(ctx, fmt.Sprintf("SELECT * FROM `%s`.`%s`", s.database, table))
+}
+
+// HasUniqueKey 查询第一列上是否有只包含该列的唯一索引，且该列不允许NULL
+func (s *mysqlSource) HasUniqueKey(ctx context.Context, table string) (bool, error) {
+	query := `SELECT COUNT(*)
+		FROM information_schema.STATISTICS st
+		JOIN information_schema.COLUMNS c
+			ON c.TABLE_SCHEMA = st.TABLE_SCHEMA AND c.TABLE_NAME = st.TABLE_NAME AND c.COLUMN_NAME = st.COLUMN_NAME
+		WHERE st.TABLE_SCHEMA = ? AND st.TABLE_NAME = ? AND st.NON_UNIQUE = 0
+			AND c.ORDINAL_POSITION = 1 AND c.IS_NULLABLE = 'NO'
+			AND NOT EXISTS (
+				SELECT 1 FROM information_schema.STATISTICS other
+				WHERE other.TABLE_SCHEMA = st.TABLE_SCHEMA AND other.TABLE_NAME = st.TABLE_NAME
+					AND other.INDEX_NAME = st.INDEX_NAME AND other.SEQ_IN_INDEX > 1)`
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, s.database, table).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// query 执行查询并返回行迭代器
+func (s *mysqlSource) query(ctx context.Context, query string) (Rows, error) {
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return newSQLRows(rows, columns), nil
+}
+
+// Ping 执行一次查询往返
+func (s *mysqlSource) Ping(ctx context.Context) error {
+	var one int
+	return s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+// Close 关闭连接
+func (s *mysqlSource) Close() error {
+	return s.db.Close()
+}
+
+// sqlRows 将*sql.Rows适配为Rows
+// 各列扫描为sql.RawBytes，扫描目标和值切片在各行之间复用，读取时不为每行分配内存
+type sqlRows struct {
+	rows    *sql.Rows
+	columns []string
+	raw     []sql.RawBytes
+	rawPtrs []any
+	bytes   [][]byte
+	values  []any
+	err     error
+}
+
+// newSQLRows 创建行迭代器
+func newSQLRows(rows *sql.Rows, columns []string) *sqlRows {
+	r := &sqlRows{
+		rows:    rows,
+		columns: columns,
+		raw:     make([]sql.RawBytes, len(columns)),
+		rawPtrs: make([]any, len(columns)),
+		bytes:   make([][]byte, len(columns)),
+	}
+	for i := range r.raw {
+		r.rawPtrs[i] = &r.raw[i]
+	}
+	return r
+}
+
+func (r *sqlRows) Columns() []string { return r.columns }
+
+func (r *sqlRows) Next() bool {
+	if r.err != nil || !r.rows.Next() {
+		return false
+	}
+	if err := r.rows.Scan(r.rawPtrs...); err != nil {
+		r.err = err
+		return false
+	}
+	for i, b := range r.raw {
+		r.bytes[i] = b
+	}
+	r.values = r.values[:0]
+	return true
+}
+
+// Values 返回当前行的值，非NULL的值均为服务器返回的文本[]byte
+func (r *sqlRows) Values() []any {
+	if len(r.values) == 0 && len(r.bytes) > 0 {
+		for _, b := range r.bytes {
+			if b == nil {
+				r.values = append(r.values, nil)
+			} else {
+				r.values = append(r.values, b)
+			}
+		}
+	}
+	return r.values
+}
+
+func (r *sqlRows) rawValues() [][]byte { return r.bytes }
+
+func (r *sqlRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.rows.Err()
+}
+
+func (r *sqlRows) Close() error { return r.rows.Close() }
@@ -0,0 +1,215 @@
+// pkg/validator/types.go
+// 公开类型定义
+
+package validator
+
+// 验证状态
+const (
+	StatusSuccess      = "SUCCESS"
+	StatusWarning      = "WARNING"
+	StatusInconsistent = "INCONSISTENT"
+	StatusError        = "ERROR"
+)
+
+// 表验证状态
+const (
+	TableMatch    = "MATCH"
+	TableMismatch = "MISMATCH"
+	TableMissing  = "MISSING"
+	TableError    = "ERROR"
+)
+
+// 数据源所在侧
+const (
+	SideAzure = "azure"
+	SideAWS   = "aws"
+)
+
+// 数据源类型
+const (
+	SourceMySQL = "mysql" // MySQL实例，默认
+	SourceFiles = "files" // CSV/TSV/JSONL导出文件目录
+)
+
+// DatabaseInstance 数据库实例配置
+type DatabaseInstance struct {
+	Name     string      `json:"name" yaml:"name" mapstructure:"name"`                     // 实例名称
+	Type     string      `json:"type,omitempty" yaml:"type,omitempty" mapstructure:"type"` // 数据源类型: mysql(默认) 或 files
+	Host     string      `json:"host" yaml:"host" mapstructure:"host"`                     // 实例主机地址
+	User     string      `json:"user" yaml:"user" mapstructure:"user"`                     // 用户名
+	Password string      `json:"password" yaml:"password" mapstructure:"password"`         // 密码
+	Database string      `json:"database" yaml:"database" mapstructure:"database"`         // 数据库名称
+	Charset  string      `json:"charset" yaml:"charset" mapstructure:"charset"`            // 字符集
+	Path     string      `json:"path,omitempty" yaml:"path,omitempty" mapstructure:"path"` // files类型: 数据文件目录，每个表一个文件
+	File     FileOptions `json:"file,omitempty" yaml:"file,omitempty" mapstructure:"file"` // files类型: 文件格式选项
+}
+
+// FileOptions 导出文件格式选项
+type FileOptions struct {
+	Format   string `json:"format,omitempty" yaml:"format,omitempty" mapstructure:"format"`          // csv、tsv 或 jsonl，为空时按扩展名判断
+	NoHeader bool   `json:"no_header,omitempty" yaml:"no_header,omitempty" mapstructure:"no_header"` // CSV/TSV没有表头，列名从<表名>.schema.json读取
+	Null     string `json:"null,omitempty" yaml:"null,omitempty" mapstructure:"null"`                // CSV中表示NULL的字符串，默认\N
+}
+
+// IsFiles 是否为文件数据源
+func (i DatabaseInstance) IsFiles() bool {
+	return i.Type == SourceFiles
+}
+
+// DatabasePair 数据库对比对
+type DatabasePair struct {
+	AzureInstance DatabaseInstance `json:"azure_instance" yaml:"azure_instance" mapstructure:"azure_instance"`
+	AWSInstance   DatabaseInstance `json:"aws_instance" yaml:"aws_instance" mapstructure:"aws_instance"`
+	Plan          *PlanPair        `json:"plan,omitempty" yaml:"plan,omitempty" mapstructure:"plan"` // 验证计划，为空时自动发现表
+}
+
+// TableComparison 表对比结果
+type TableComparison struct {
+	Table         string      `json:"table" yaml:"table" mapstructure:"table"`
+	AzureChecksum string      `json:"azure_checksum" yaml:"azure_checksum" mapstructure:"azure_checksum"`
+	AWSChecksum   string      `json:"aws_checksum" yaml:"aws_checksum" mapstructure:"aws_checksum"`
+	Match         bool        `json:"match" yaml:"match" mapstructure:"match"`
+	Strategy      string      `json:"strategy,omitempty" yaml:"strategy,omitempty" mapstructure:"strategy"` // 使用的校验策略，空表为空
+	AzureInstance string      `json:"azure_instance" yaml:"azure_instance" mapstructure:"azure_instance"`
+	AWSInstance   string      `json:"aws_instance" yaml:"aws_instance" mapstructure:"aws_instance"`
+	AzureDatabase string      `json:"azure_database" yaml:"azure_database" mapstructure:"azure_database"`
+	AWSDatabase   string      `json:"aws_database" yaml:"aws_database" mapstructure:"aws_database"`
+	Diff          *TableDiff  `json:"diff,omitempty" yaml:"diff,omitempty" mapstructure:"diff"`                               // 不一致时的列级差异
+	TextIssues    []TextIssue `json:"text_issues,omitempty" yaml:"text_issues,omitempty" mapstructure:"text_issues"`          // 文本列的字符集问题
+	MaskedColumns []string    `json:"masked_columns,omitempty" yaml:"masked_columns,omitempty" mapstructure:"masked_columns"` // 对比前在源端做了脱敏变换的列
+}
+
+// DatabaseResult 数据库验证结果
+type DatabaseResult struct {
+	Database         string            `json:"database" yaml:"database" mapstructure:"database"`
+	AzureInstance    string            `json:"azure_instance" yaml:"azure_instance" mapstructure:"azure_instance"` // Azure实例名称
+	AWSInstance      string            `json:"aws_instance" yaml:"aws_instance" mapstructure:"aws_instance"`       // AWS实例名称
+	AzureTables      int               `json:"azure_tables" yaml:"azure_tables" mapstructure:"azure_tables"`
+	AWSTables        int               `json:"aws_tables" yaml:"aws_tables" mapstructure:"aws_tables"`
+	TableComparisons []TableComparison `json:"table_comparisons" yaml:"table_comparisons" mapstructure:"table_comparisons"`
+	Objects          []ObjectSection   `json:"objects,omitempty" yaml:"objects,omitempty" mapstructure:"objects"` // 视图、存储过程、函数、触发器、事件，每类一节
+	Status           string            `json:"status" yaml:"status" mapstructure:"status"`
+	Errors           []string          `json:"errors" yaml:"errors" mapstructure:"errors"`
+	StartTime        string            `json:"start_time" yaml:"start_time" mapstructure:"start_time"`
+	EndTime          string            `json:"end_time" yaml:"end_time" mapstructure:"end_time"`
+}
+
+// ResultKey 对比对结果在Report.Results中的键，格式为"Azure实例/AWS实例/数据库"
+// 不同实例上的同名数据库各有一个结果
+func ResultKey(azureInstance, awsInstance, database string) string {
+	return azureInstance + "/" + awsInstance + "/" + database
+}
+
+// Report 验证报告
+type Report struct {
+	Timestamp             string                    `json:"timestamp" yaml:"timestamp" mapstructure:"timestamp"`
+	TotalDatabases        int                       `json:"total_databases" yaml:"total_databases" mapstructure:"total_databases"`
+	SuccessfulValidations int                       `json:"successful_validations" yaml:"successful_validations" mapstructure:"successful_validations"`
+	InconsistentDatabases int                       `json:"inconsistent_databases" yaml:"inconsistent_databases" mapstructure:"inconsistent_databases"`
+	ErrorDatabases        int                       `json:"error_databases" yaml:"error_databases" mapstructure:"error_databases"`
+	SuccessRate           string                    `json:"success_rate" yaml:"success_rate" mapstructure:"success_rate"`
+	HashAlgorithm         string                    `json:"hash_algorithm,omitempty" yaml:"hash_algorithm,omitempty" mapstructure:"hash_algorithm"`          // 校验和使用的哈希算法，不同算法的校验和不可比较
+	MissingDatabases      []string                  `json:"missing_databases,omitempty" yaml:"missing_databases,omitempty" mapstructure:"missing_databases"` // 自动发现时只在Azure中存在的数据库
+	ExtraDatabases        []string                  `json:"extra_databases,omitempty" yaml:"extra_databases,omitempty" mapstructure:"extra_databases"`       // 自动发现时只在AWS中存在的数据库
+	Results               map[string]DatabaseResult `json:"results" yaml:"results" mapstructure:"results"`                                                   // 按ResultKey索引的对比对结果
+	ShardedTables         []ShardedTableResult      `json:"sharded_tables,omitempty" yaml:"sharded_tables,omitempty" mapstructure:"sharded_tables"`          // 分片表验证结果
+	Grants                []GrantComparison         `json:"grants,omitempty" yaml:"grants,omitempty" mapstructure:"grants"`                                  // 配置用户的权限对比结果
+}
+
+// 验证事件类型
+const (
+	EventRunStarted       = "run_started"       // 开始验证，Databases为对比对数量
+	EventDatabaseStarted  = "database_started"  // 开始验证一个对比对
+	EventDatabaseTables   = "database_tables"   // 获取表列表完成，Tables为待验证表数，Rows为计划估算行数
+	EventTableStarted     = "table_started"     // 开始验证一个表
+	EventChunkDone        = "chunk_done"        // 一侧完成一个分块的校验和计算，Rows为该分块行数
+	EventTableChecked     = "table_checked"     // 表验证完成，Status为MATCH/MISMATCH/MISSING/ERROR
+	EventTableMismatch    = "table_mismatch"    // 表数据不一致或缺失
+	EventDatabaseFinished = "database_finished" // 对比对验证完成
+	EventRunFinished      = "run_finished"      // 全部验证完成
+)
+
+// Event 验证过程中产生的事件
+type Event struct {
+	Type      string `json:"type" yaml:"type" mapstructure:"type"`                                    // 事件类型
+	Time      string `json:"time" yaml:"time" mapstructure:"time"`                                    // 事件时间
+	Database  string `json:"database,omitempty" yaml:"database,omitempty" mapstructure:"database"`    // 数据库名称
+	Table     string `json:"table,omitempty" yaml:"table,omitempty" mapstructure:"table"`             // 表名
+	Side      string `json:"side,omitempty" yaml:"side,omitempty" mapstructure:"side"`                // azure 或 aws
+	Status    string `json:"status,omitempty" yaml:"status,omitempty" mapstructure:"status"`          // 状态
+	Databases int    `json:"databases,omitempty" yaml:"databases,omitempty" mapstructure:"databases"` // 对比对数量
+	Tables    int    `json:"tables,omitempty" yaml:"tables,omitempty" mapstructure:"tables"`          // 表数量
+	Chunk     int    `json:"chunk,omitempty" yaml:"chunk,omitempty" mapstructure:"chunk"`             // 分块序号，从1开始
+	Chunks    int    `json:"chunks,omitempty" yaml:"chunks,omitempty" mapstructure:"chunks"`          // 分块总数
+	Rows      int64  `json:"rows,omitempty" yaml:"rows,omitempty" mapstructure:"rows"`                // 行数
+	Message   string `json:"message,omitempty" yaml:"message,omitempty" mapstructure:"message"`       // 附加信息
+}
+
+// Mismatch 表数据不一致或缺失，传给不一致回调
+type Mismatch struct {
+	Database   string           // 数据库名称
+	Table      string           // 表名；非表对象为对象名，权限为用户名
+	Object     string           // 非表对象的类型，如VIEW、TRIGGER、GRANT；表为空
+	Status     string           // MISMATCH 或 MISSING
+	Comparison *TableComparison // 表对比结果，MISSING时为nil
+	Message    string           // 附加信息
+}
+
+// ValidationError 验证过程中的错误，传给错误回调
+type ValidationError struct {
+	Database string // 数据库名称
+	Table    string // 表名，对比对级别的错误为空
+	Side     string // azure 或 aws，无法区分时为空
+	Err      error
+}
+
+// Error 实现error接口
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Plan 验证计划，可保存为JSON供后续按计划执行
+type Plan struct {
+	CreatedAt         string     `json:"created_at" yaml:"created_at" mapstructure:"created_at"`
+	MaxWorkers        int        `json:"max_workers" yaml:"max_workers" mapstructure:"max_workers"`
+	BatchSize         int        `json:"batch_size" yaml:"batch_size" mapstructure:"batch_size"`
+	Calibrated        bool       `json:"calibrated" yaml:"calibrated" mapstructure:"calibrated"` // 是否基于校准基准测试估算
+	TotalTables       int        `json:"total_tables" yaml:"total_tables" mapstructure:"total_tables"`
+	TotalRows         int64      `json:"total_rows" yaml:"total_rows" mapstructure:"total_rows"`
+	TotalDataBytes    int64      `json:"total_data_bytes" yaml:"total_data_bytes" mapstructure:"total_data_bytes"`
+	EstimatedSeconds  float64    `json:"estimated_seconds" yaml:"estimated_seconds" mapstructure:"estimated_seconds"`
+	EstimatedDuration string     `json:"estimated_duration" yaml:"estimated_duration" mapstructure:"estimated_duration"`
+	Pairs             []PlanPair `json:"pairs" yaml:"pairs" mapstructure:"pairs"`
+	MissingDatabases  []string   `json:"missing_databases,omitempty" yaml:"missing_databases,omitempty" mapstructure:"missing_databases"` // 自动发现时只在Azure中存在的数据库
+	ExtraDatabases    []string   `json:"extra_databases,omitempty" yaml:"extra_databases,omitempty" mapstructure:"extra_databases"`       // 自动发现时只在AWS中存在的数据库
+}
+
+// PlanPair 单个数据库对比对的验证计划，只记录实例名称，连接信息从配置中获取
+type PlanPair struct {
+	AzureInstance      string      `json:"azure_instance" yaml:"azure_instance" mapstructure:"azure_instance"`
+	AWSInstance        string      `json:"aws_instance" yaml:"aws_instance" mapstructure:"aws_instance"`
+	AzureDatabase      string      `json:"azure_database" yaml:"azure_database" mapstructure:"azure_database"`
+	AWSDatabase        string      `json:"aws_database" yaml:"aws_database" mapstructure:"aws_database"`
+	Tables             []PlanTable `json:"tables" yaml:"tables" mapstructure:"tables"`
+	MissingInAWS       []string    `json:"missing_in_aws,omitempty" yaml:"missing_in_aws,omitempty" mapstructure:"missing_in_aws"`
+	ExtraInAWS         []string    `json:"extra_in_aws,omitempty" yaml:"extra_in_aws,omitempty" mapstructure:"extra_in_aws"`
+	AzureRowsPerSecond float64     `json:"azure_rows_per_second" yaml:"azure_rows_per_second" mapstructure:"azure_rows_per_second"`
+	AWSRowsPerSecond   float64     `json:"aws_rows_per_second" yaml:"aws_rows_per_second" mapstructure:"aws_rows_per_second"`
+	EstimatedSeconds   float64     `json:"estimated_seconds" yaml:"estimated_seconds" mapstructure:"estimated_seconds"`
+	Error              string      `json:"error,omitempty" yaml:"error,omitempty" mapstructure:"error"`
+}
+
+// PlanTable 单个表的验证计划
+type PlanTable struct {
+	Table            string  `json:"table" yaml:"table" mapstructure:"table"`
+	EstimatedRows    int64   `json:"estimated_rows" yaml:"estimated_rows" mapstructure:"estimated_rows"` // information_schema中的估算行数
+	DataBytes        int64   `json:"data_bytes" yaml:"data_bytes" mapstructure:"data_bytes"`
+	Strategy         string  `json:"strategy" yaml:"strategy" mapstructure:"strategy"` // 校验策略
+	Chunks           int     `json:"chunks" yaml:"chunks" mapstructure:"chunks"`
+	EstimatedSeconds float64 `json:"estimated_seconds" yaml:"estimated_seconds" mapstructure:"estimated_seconds"`
+}
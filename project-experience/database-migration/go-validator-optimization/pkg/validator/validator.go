@@ -0,0 +1,642 @@
+// pkg/validator/validator.go
+// 多数据库一致性验证器
+
+// Package validator 提供可嵌入的多数据库一致性验证器
+//
+// 使用示例:
+//
+//	v := validator.New(
+//		validator.WithInstances(azure, aws),
+//		validator.WithMaxWorkers(4),
+//		validator.WithMismatchHook(func(m validator.Mismatch) { ... }),
+//	)
+//	report, err := v.Run(ctx, nil) // nil表示验证全部对比对并自动发现表
+//
+// 数据源和校验策略分别通过Source和ChecksumStrategy接口扩展。
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认并发数
+const defaultMaxWorkers = 3
+
+// Validator 多数据库一致性验证器，同一个Validator可多次调用Run
+type Validator struct {
+	azure          []DatabaseInstance
+	aws            []DatabaseInstance
+	maxWorkers     int
+	openSource     SourceOpener
+	strategies     map[string]ChecksumStrategy
+	selectStrategy StrategySelector
+	discovery      *DiscoveryConfig
+	listDatabases  DatabaseLister
+	sharding       *ShardingConfig
+	columnDiff     bool
+	objects        bool
+	grantUsers     []string
+	masking        maskRules
+	textCheck      *TextCheckConfig
+	hashName       string
+	newHash        HashFunc
+	hashErr        error
+	logger         *log.Logger
+	onProgress     func(Event)
+	onMismatch     func(Mismatch)
+	onError        func(*ValidationError)
+}
+
+// New 创建验证器
+func New(opts ...Option) *Validator {
+	v := &Validator{
+		maxWorkers:     defaultMaxWorkers,
+		openSource:     OpenSource,
+		listDatabases:  ListDatabases,
+		selectStrategy: ChooseStrategy,
+		columnDiff:     true,
+		objects:        true,
+		logger:         log.Default(),
+		strategies: map[string]ChecksumStrategy{
+			StrategyFull:     FullStrategy{},
+			StrategyBatched:  BatchedStrategy{BatchSize: defaultBatchSize},
+			StrategyMultiset: MultisetStrategy{},
+		},
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	v.applyHash()
+	return v
+}
+
+// applyHash 为未指定哈希算法的内置策略设置验证器的哈希算法
+func (v *Validator) applyHash() {
+	v.newHash, v.hashErr = NewHash(v.hashName)
+	if v.hashErr != nil {
+		return
+	}
+	for name, strategy := range v.strategies {
+		switch s := strategy.(type) {
+		case FullStrategy:
+			if s.Hash == nil {
+				s.Hash = v.newHash
+				v.strategies[name] = s
+			}
+		case BatchedStrategy:
+			if s.Hash == nil {
+				s.Hash = v.newHash
+				v.strategies[name] = s
+			}
+		case MultisetStrategy:
+			if s.Hash == nil {
+				s.Hash = v.newHash
+				v.strategies[name] = s
+			}
+		}
+	}
+}
+
+// Pairs 按下标将Azure和AWS实例组成对比对
+func (v *Validator) Pairs() ([]DatabasePair, error) {
+	if len(v.azure) != len(v.aws) {
+		return nil, fmt.Errorf("Azure实例数量(%d)与AWS实例数量(%d)不一致", len(v.azure), len(v.aws))
+	}
+	pairs := make([]DatabasePair, len(v.azure))
+	for i := range v.azure {
+		pairs[i] = DatabasePair{AzureInstance: v.azure[i], AWSInstance: v.aws[i]}
+	}
+	return pairs, nil
+}
+
+// resolvePairs 返回配置的对比对和自动发现的对比对
+func (v *Validator) resolvePairs(ctx context.Context) ([]DatabasePair, *Discovery, error) {
+	pairs, err := v.Pairs()
+	if err != nil || v.discovery == nil {
+		return pairs, nil, err
+	}
+
+	discovery, err := Discover(ctx, *v.discovery, v.listDatabases)
+	if err != nil {
+		return nil, nil, err
+	}
+	v.logf("自动发现 %d 个数据库对比对，仅Azure存在 %d 个，仅AWS存在 %d 个",
+		len(discovery.Pairs), len(discovery.MissingInAWS), len(discovery.ExtraInAWS))
+	for _, name := range discovery.MissingInAWS {
+		v.logf("数据库 %s 在AWS中不存在", name)
+	}
+	for _, name := range discovery.ExtraInAWS {
+		v.logf("数据库 %s 只在AWS中存在", name)
+	}
+	return append(pairs, discovery.Pairs...), discovery, nil
+}
+
+// Run 执行验证并返回报告
+// plan为nil时验证全部对比对并自动发现表，否则按计划中的表和校验策略验证
+// ctx取消时尚未完成的对比对记为错误，返回已生成的报告和ctx.Err()
+func (v *Validator) Run(ctx context.Context, plan *Plan) (*Report, error) {
+	if v.hashErr != nil {
+		return nil, v.hashErr
+	}
+
+	var pairs []DatabasePair
+	var discovery *Discovery
+	var err error
+	if plan == nil {
+		pairs, discovery, err = v.resolvePairs(ctx)
+	} else {
+		pairs, err = v.resolvePlan(plan)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkStrategies(pairs); err != nil {
+		return nil, err
+	}
+
+	v.emit(Event{Type: EventRunStarted, Databases: len(pairs) + v.shardedTableCount()})
+	results := v.validatePairs(ctx, pairs)
+	shards := v.validateShards(ctx)
+	grants := v.validateGrants(ctx, pairs)
+	v.logf("所有数据库验证完成")
+	v.emit(Event{Type: EventRunFinished, Databases: len(pairs) + v.shardedTableCount()})
+
+	report := buildReport(len(pairs), results)
+	report.ShardedTables = shards
+	report.Grants = grants
+	report.HashAlgorithm = v.hashName
+	if report.HashAlgorithm == "" {
+		report.HashAlgorithm = HashXXHash
+	}
+	if discovery != nil {
+		report.MissingDatabases = discovery.MissingInAWS
+		report.ExtraDatabases = discovery.ExtraInAWS
+	} else if plan != nil {
+		report.MissingDatabases = plan.MissingDatabases
+		report.ExtraDatabases = plan.ExtraDatabases
+	}
+	return report, ctx.Err()
+}
+
+// validatePairs 并行验证给定的数据库对比对
+func (v *Validator) validatePairs(ctx context.Context, databasePairs []DatabasePair) map[string]DatabaseResult {
+	v.logf("开始验证 %d 个数据库对比对，最大并发数: %d", len(databasePairs), v.maxWorkers)
+
+	// 使用goroutine和channel进行并发控制
+	semaphore := make(chan struct{}, v.maxWorkers)
+	var wg sync.WaitGroup
+	resultsChan := make(chan DatabaseResult, len(databasePairs))
+
+	// 启动验证任务
+	for _, pair := range databasePairs {
+		wg.Add(1)
+		go func(p DatabasePair) {
+			defer wg.Done()
+			semaphore <- struct{}{}        // 获取信号量
+			defer func() { <-semaphore }() // 释放信号量
+
+			resultsChan <- v.validateDatabase(ctx, p)
+		}(pair)
+	}
+
+	// 等待所有任务完成
+	go func() {
+		wg.Wait()
+		close(resultsChan)
+	}()
+
+	// 收集结果
+	results := make(map[string]DatabaseResult, len(databasePairs))
+	for result := range resultsChan {
+		results[ResultKey(result.AzureInstance, result.AWSInstance, result.Database)] = result
+		v.logf("数据库对比 %s vs %s 验证完成，状态: %s", result.AzureInstance, result.AWSInstance, result.Status)
+		v.emit(Event{
+			Type:     EventDatabaseFinished,
+			Database: result.Database,
+			Status:   result.Status,
+			Message:  strings.Join(result.Errors, "; "),
+		})
+	}
+
+	return results
+}
+
+// validateDatabase 验证单个数据库对比对的一致性
+func (v *Validator) validateDatabase(ctx context.Context, pair DatabasePair) DatabaseResult {
+	azureInstance := pair.AzureInstance
+	awsInstance := pair.AWSInstance
+	database := azureInstance.Database
+
+	v.logf("开始验证数据库对比: %s (Azure: %s) vs %s (AWS: %s)",
+		azureInstance.Database, azureInstance.Name, awsInstance.Database, awsInstance.Name)
+	v.emit(Event{Type: EventDatabaseStarted, Database: database})
+
+	// 初始化结果
+	result := DatabaseResult{
+		Database:         database,
+		AzureInstance:    azureInstance.Name,
+		AWSInstance:      awsInstance.Name,
+		TableComparisons: []TableComparison{},
+		Status:           StatusSuccess,
+		Errors:           []string{},
+		StartTime:        time.Now().Format(time.RFC3339),
+	}
+
+	// fail 记录对比对级别的错误并结束验证
+	fail := func(side string, err error) DatabaseResult {
+		result.Status = StatusError
+		result.Errors = append(result.Errors, err.Error())
+		result.EndTime = time.Now().Format(time.RFC3339)
+		v.reportError(&ValidationError{Database: database, Side: side, Err: err})
+		return result
+	}
+
+	azureSrc, err := v.openSource(ctx, azureInstance)
+	if err != nil {
+		return fail(SideAzure, fmt.Errorf("Azure数据库%v", err))
+	}
+	defer azureSrc.Close()
+	// 目标端是脱敏后的数据时，源端读取的行先做相同的变换
+	azureSrc = v.maskSource(azureSrc, azureInstance.Database)
+
+	awsSrc, err := v.openSource(ctx, awsInstance)
+	if err != nil {
+		return fail(SideAWS, fmt.Errorf("AWS数据库%v", err))
+	}
+	defer awsSrc.Close()
+
+	// 获取表列表
+	azureTables, err := azureSrc.Tables(ctx)
+	if err != nil {
+		return fail(SideAzure, fmt.Errorf("获取Azure表列表失败: %v", err))
+	}
+	awsTables, err := awsSrc.Tables(ctx)
+	if err != nil {
+		return fail(SideAWS, fmt.Errorf("获取AWS表列表失败: %v", err))
+	}
+
+	// 分片表单独与全部分片对比，不参与对比对验证
+	azureTables = withoutTables(azureTables, v.shardedTables(SideAzure, azureInstance))
+	awsTables = withoutTables(awsTables, v.shardedTables(SideAWS, awsInstance))
+
+	result.AzureTables = len(azureTables)
+	result.AWSTables = len(awsTables)
+
+	// 检查表数量一致性
+	if len(azureTables) != len(awsTables) {
+		result.Status = StatusWarning
+		errorMsg := fmt.Sprintf("表数量不一致: Azure(%d) vs AWS(%d)", len(azureTables), len(awsTables))
+		result.Errors = append(result.Errors, errorMsg)
+		v.logf("数据库 %s: %s", database, errorMsg)
+	}
+
+	// 按验证计划执行时只验证计划中的表，并使用计划选定的校验策略
+	tablesToCheck := azureTables
+	strategies := map[string]string{}
+	var plannedRows int64
+	if pair.Plan != nil {
+		tablesToCheck = make([]string, 0, len(pair.Plan.Tables))
+		for _, planned := range pair.Plan.Tables {
+			tablesToCheck = append(tablesToCheck, planned.Table)
+			strategies[planned.Table] = planned.Strategy
+			plannedRows += planned.EstimatedRows
+		}
+	}
+	v.emit(Event{Type: EventDatabaseTables, Database: database, Tables: len(tablesToCheck), Rows: plannedRows})
+
+	// 对比每个表的数据一致性
+	v.logf("开始验证数据库 %s 中的 %d 个表", database, len(tablesToCheck))
+
+	for i, table := range tablesToCheck {
+		if err := ctx.Err(); err != nil {
+			result.Status = StatusError
+			result.Errors = append(result.Errors, fmt.Sprintf("验证已取消: %v", err))
+			v.reportError(&ValidationError{Database: database, Err: err})
+			break
+		}
+
+		v.logf("验证表 %d/%d: %s", i+1, len(tablesToCheck), table)
+		v.emit(Event{Type: EventTableStarted, Database: database, Table: table})
+
+		if !contains(awsTables, table) {
+			errorMsg := fmt.Sprintf("表 %s 在AWS中不存在", table)
+			result.Errors = append(result.Errors, errorMsg)
+			result.Status = StatusInconsistent
+			v.logf("数据库 %s: %s", database, errorMsg)
+			v.tableResult(database, table, TableMissing, errorMsg, nil)
+			continue
+		}
+
+		// tableFail 记录表级别的错误
+		tableFail := func(side string, err error) {
+			result.Errors = append(result.Errors, err.Error())
+			result.Status = StatusError
+			v.logf("数据库 %s: %s", database, err)
+			v.tableResult(database, table, TableError, err.Error(), nil)
+			v.reportError(&ValidationError{Database: database, Table: table, Side: side, Err: err})
+		}
+
+		// 没有唯一键的表行顺序不确定，两端都使用与顺序无关的指纹
+		strategy := strategies[table]
+		if strategy == "" && v.keyless(ctx, database, table, azureSrc, awsSrc) {
+			strategy = StrategyMultiset
+		}
+
+		// 计算校验和，需要列级差异定位时在同一遍读取中累加每列的分块指纹
+		azureRead, awsRead := azureSrc, awsSrc
+		var azureSums, awsSums *columnSums
+		if v.columnDiff {
+			azureSums, awsSums = newColumnSums(v.batchSize()), newColumnSums(v.batchSize())
+			azureRead, awsRead = azureSums.wrap(azureSrc), awsSums.wrap(awsSrc)
+		}
+		azureChecksum, usedStrategy, err := v.tableChecksum(ctx, azureRead, table, strategy, v.chunkReporter(database, table, SideAzure))
+		if err != nil {
+			tableFail(SideAzure, fmt.Errorf("表 %s Azure校验和计算失败: %v", table, err))
+			continue
+		}
+		awsChecksum, _, err := v.tableChecksum(ctx, awsRead, table, strategy, v.chunkReporter(database, table, SideAWS))
+		if err != nil {
+			tableFail(SideAWS, fmt.Errorf("表 %s AWS校验和计算失败: %v", table, err))
+			continue
+		}
+
+		// 记录对比结果
+		tableComparison := TableComparison{
+			Table:         table,
+			AzureChecksum: azureChecksum,
+			AWSChecksum:   awsChecksum,
+			Match:         azureChecksum == awsChecksum,
+			Strategy:      usedStrategy,
+			AzureInstance: azureInstance.Name,
+			AWSInstance:   awsInstance.Name,
+			AzureDatabase: azureInstance.Database,
+			AWSDatabase:   awsInstance.Database,
+			MaskedColumns: v.masking.columns(azureInstance.Database, table),
+		}
+		// 不一致时比较两端的每列指纹，只重新读取不一致的分块定位差异行
+		if !tableComparison.Match && v.columnDiff {
+			diff, err := diffColumns(ctx, azureSrc, awsSrc, table, usedStrategy, azureSums, awsSums)
+			if err != nil {
+				v.logf("数据库 %s: 表 %s 列级差异定位失败: %v", database, table, err)
+			} else {
+				tableComparison.Diff = diff
+			}
+		}
+		// 检查文本列中字符集转换造成的问题
+		if v.textCheck != nil && v.textCheck.covers(table) {
+			issues, err := checkText(ctx, azureSrc, awsSrc, table, v.textCheck.samples())
+			if err != nil {
+				v.logf("数据库 %s: 表 %s 文本检查: %v", database, table, err)
+			}
+			tableComparison.TextIssues = issues
+			if len(issues) > 0 {
+				v.logf("数据库 %s: 表 %s 文本问题: %s", database, table, TextSummary(issues))
+			}
+			if v.textCheck.FailOnIssues && textFailed(issues) {
+				tableComparison.Match = false
+			}
+		}
+		result.TableComparisons = append(result.TableComparisons, tableComparison)
+
+		// 检查是否一致
+		if tableComparison.Match {
+			v.logf("数据一致 - Azure实例: %s 数据库: %s 表: %s vs AWS实例: %s 数据库: %s 表: %s",
+				azureInstance.Name, azureInstance.Database, table,
+				awsInstance.Name, awsInstance.Database, table)
+			v.tableResult(database, table, TableMatch, "", nil)
+		} else {
+			result.Status = StatusInconsistent
+			v.logf("数据不一致 - Azure实例: %s 数据库: %s 表: %s vs AWS实例: %s 数据库: %s 表: %s",
+				azureInstance.Name, azureInstance.Database, table,
+				awsInstance.Name, awsInstance.Database, table)
+			var details []string
+			if tableComparison.Diff != nil {
+				details = append(details, tableComparison.Diff.Summary)
+				v.logf("  差异: %s", tableComparison.Diff.Summary)
+			}
+			if len(tableComparison.TextIssues) > 0 {
+				details = append(details, TextSummary(tableComparison.TextIssues))
+			}
+			message := strings.Join(details, "；")
+			v.tableResult(database, table, TableMismatch, message, &tableComparison)
+		}
+	}
+
+	// 对比视图、存储过程、函数、触发器和事件
+	if ctx.Err() == nil {
+		v.validateObjects(ctx, &result, pair, azureSrc, awsSrc)
+	}
+
+	// 记录结束时间
+	result.EndTime = time.Now().Format(time.RFC3339)
+
+	// 统计验证结果
+	consistentTables := 0
+	for _, comparison := range result.TableComparisons {
+		if comparison.Match {
+			consistentTables++
+		}
+	}
+
+	v.logf("数据库 %s 验证完成:", database)
+	v.logf("  状态: %s", result.Status)
+	v.logf("  表数量: Azure(%d) vs AWS(%d)", result.AzureTables, result.AWSTables)
+	v.logf("  数据一致性: %d/%d", consistentTables, len(result.TableComparisons))
+	v.logf("  错误数量: %d", len(result.Errors))
+
+	return result
+}
+
+// tableChecksum 计算表的校验和，strategy为空时根据行数选择校验策略，返回实际使用的策略
+func (v *Validator) tableChecksum(ctx context.Context, src Source, table, strategy string, onChunk ChunkFunc) (string, string, error) {
+	rows, err := src.CountRows(ctx, table)
+	if err != nil {
+		return "", "", err
+	}
+
+	// 空表处理
+	if rows == 0 {
+		onChunk(1, 1, 0)
+		return emptyTableChecksum, strategy, nil
+	}
+
+	if strategy == "" {
+		strategy = v.selectStrategy(rows)
+	}
+	s, ok := v.strategies[strategy]
+	if !ok {
+		return "", "", fmt.Errorf("未知的校验策略: %s", strategy)
+	}
+	checksum, err := s.Checksum(ctx, src, table, rows, onChunk)
+	return checksum, strategy, err
+}
+
+// batchSize 分批策略的批大小，列级差异定位按相同大小分块
+func (v *Validator) batchSize() int64 {
+	if batched, ok := v.strategies[StrategyBatched].(BatchedStrategy); ok {
+		return batched.size()
+	}
+	return defaultBatchSize
+}
+
+// keyless 任一端的表没有可用于确定行顺序的唯一键时返回true
+// 数据源未实现KeyChecker或查询失败时按有唯一键处理
+func (v *Validator) keyless(ctx context.Context, database, table string, sources ...Source) bool {
+	for _, src := range sources {
+		checker, ok := src.(KeyChecker)
+		if !ok {
+			continue
+		}
+		hasKey, err := checker.HasUniqueKey(ctx, table)
+		if err != nil {
+			v.logf("数据库 %s: 检查表 %s 的唯一键失败，按有序表处理: %v", database, table, err)
+			continue
+		}
+		if !hasKey {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStrategies 检查计划中引用的校验策略均已注册
+func (v *Validator) checkStrategies(pairs []DatabasePair) error {
+	for _, pair := range pairs {
+		if pair.Plan == nil {
+			continue
+		}
+		for _, t := range pair.Plan.Tables {
+			if _, ok := v.strategies[t.Strategy]; !ok {
+				return fmt.Errorf("表 %s.%s 的校验策略无效: %q", pair.Plan.AzureDatabase, t.Table, t.Strategy)
+			}
+		}
+	}
+	return nil
+}
+
+// emit 发送验证事件
+func (v *Validator) emit(event Event) {
+	if v.onProgress == nil {
+		return
+	}
+	event.Time = time.Now().Format(time.RFC3339)
+	v.onProgress(event)
+}
+
+// tableResult 发送表验证完成事件，不一致或缺失时额外发送table_mismatch事件并调用不一致回调
+func (v *Validator) tableResult(database, table, status, message string, comparison *TableComparison) {
+	v.emit(Event{Type: EventTableChecked, Database: database, Table: table, Status: status, Message: message})
+	if status != TableMismatch && status != TableMissing {
+		return
+	}
+	v.emit(Event{Type: EventTableMismatch, Database: database, Table: table, Status: status, Message: message})
+	if v.onMismatch != nil {
+		v.onMismatch(Mismatch{Database: database, Table: table, Status: status, Comparison: comparison, Message: message})
+	}
+}
+
+// reportError 调用错误回调
+func (v *Validator) reportError(err *ValidationError) {
+	if v.onError != nil {
+		v.onError(err)
+	}
+}
+
+// chunkReporter 返回分块完成回调，用于发送chunk_done事件
+func (v *Validator) chunkReporter(database, table, side string) ChunkFunc {
+	return func(chunk, chunks int, rows int64) {
+		v.emit(Event{
+			Type:     EventChunkDone,
+			Database: database,
+			Table:    table,
+			Side:     side,
+			Chunk:    chunk,
+			Chunks:   chunks,
+			Rows:     rows,
+		})
+	}
+}
+
+// logf 输出日志
+func (v *Validator) logf(format string, args ...any) {
+	if v.logger != nil {
+		v.logger.Printf(format, args...)
+	}
+}
+
+// buildReport 汇总验证结果
+func buildReport(totalDatabases int, results map[string]DatabaseResult) *Report {
+	successfulValidations := 0
+	inconsistentDatabases := 0
+	errorDatabases := 0
+
+	for _, result := range results {
+		switch result.Status {
+		case StatusSuccess:
+			successfulValidations++
+		case StatusInconsistent:
+			inconsistentDatabases++
+		case StatusError:
+			errorDatabases++
+		}
+	}
+
+	// 只配置分片表时没有对比对
+	successRate := "-"
+	if totalDatabases > 0 {
+		successRate = fmt.Sprintf("%.2f%%", float64(successfulValidations)/float64(totalDatabases)*100)
+	}
+
+	return &Report{
+		Timestamp:             time.Now().Format(time.RFC3339),
+		TotalDatabases:        totalDatabases,
+		SuccessfulValidations: successfulValidations,
+		InconsistentDatabases: inconsistentDatabases,
+		ErrorDatabases:        errorDatabases,
+		SuccessRate:           successRate,
+		Results:               results,
+	}
+}
+
+// SaveReport 将验证报告保存为JSON文件
+func SaveReport(report *Report, filename string) error {
+	jsonData, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化报告失败: %v", err)
+	}
+	if err := os.WriteFile(filename, jsonData, 0644); err != nil {
+		return fmt.Errorf("写入报告文件失败: %v", err)
+	}
+	return nil
+}
+
+// withoutTables 去掉集合中的表
+func withoutTables(tables []string, skip map[string]bool) []string {
+	if len(skip) == 0 {
+		return tables
+	}
+	kept := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if !skip[table] {
+			kept = append(kept, table)
+		}
+	}
+	return kept
+}
+
+// contains 检查切片是否包含指定元素
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
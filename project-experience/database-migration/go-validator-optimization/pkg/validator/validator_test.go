@@ -0,0 +1,473 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// memSource 内存数据源，表数据按第一列有序
+type memSource struct {
+	tables  map[string][][]any
+	columns map[string][]string // 表的列名，未设置时为c1、c2...
+	read    int64               // 读取的行数
+}
+
+func (s *memSource) Tables(ctx context.Context) ([]string, error) {
+	var names []string
+	for name := range s.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *memSource) TableStats(ctx context.Context) ([]TableStat, error) {
+	names, _ := s.Tables(ctx)
+	stats := make([]TableStat, len(names))
+	for i, name := range names {
+		stats[i] = TableStat{Name: name, Rows: int64(len(s.tables[name]))}
+	}
+	return stats, nil
+}
+
+func (s *memSource) CountRows(ctx context.Context, table string) (int64, error) {
+	rows, ok := s.tables[table]
+	if !ok {
+		return 0, fmt.Errorf("表 %s 不存在", table)
+	}
+	return int64(len(rows)), nil
+}
+
+func (s *memSource) ReadRows(ctx context.Context, table string, offset, limit int64) (Rows, error) {
+	rows := s.tables[table]
+	end := int64(len(rows))
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	if offset > end {
+		offset = end
+	}
+	s.read += end - offset
+	return &memRows{rows: rows[offset:end], pos: -1, names: s.columns[table]}, nil
+}
+
+func (s *memSource) Ping(ctx context.Context) error { return nil }
+func (s *memSource) Close() error                   { return nil }
+
+type memRows struct {
+	rows  [][]any
+	pos   int
+	names []string
+}
+
+// Columns 未指定列名时为c1、c2...
+func (r *memRows) Columns() []string {
+	if r.names != nil {
+		return r.names
+	}
+	if len(r.rows) == 0 {
+		return nil
+	}
+	names := make([]string, len(r.rows[0]))
+	for i := range names {
+		names[i] = fmt.Sprintf("c%d", i+1)
+	}
+	return names
+}
+func (r *memRows) Next() bool    { r.pos++; return r.pos < len(r.rows) }
+func (r *memRows) Values() []any { return r.rows[r.pos] }
+func (r *memRows) Err() error    { return nil }
+func (r *memRows) Close() error  { return nil }
+
+func numberedRows(n int) [][]any {
+	rows := make([][]any, n)
+	for i := range rows {
+		rows[i] = []any{i, fmt.Sprintf("name-%d", i), nil}
+	}
+	return rows
+}
+
+func TestRun(t *testing.T) {
+	azure := &memSource{tables: map[string][][]any{
+		"users":  numberedRows(25),
+		"orders": numberedRows(3),
+		"empty":  nil,
+		"audit":  numberedRows(1),
+	}}
+	aws := &memSource{tables: map[string][][]any{
+		"users":  numberedRows(25),
+		"orders": numberedRows(4),
+		"empty":  nil,
+	}}
+	sources := map[string]Source{"azure-1": azure, "aws-1": aws}
+
+	var mu sync.Mutex
+	var mismatches []string
+	events := map[string]int{}
+
+	v := New(
+		WithInstances(
+			[]DatabaseInstance{{Name: "azure-1", Database: "db1"}},
+			[]DatabaseInstance{{Name: "aws-1", Database: "db1"}},
+		),
+		WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+			return sources[inst.Name], nil
+		}),
+		// 超过10行即分批，覆盖分批策略
+		WithChecksumStrategy(BatchedStrategy{BatchSize: 10}),
+		WithStrategySelector(func(rows int64) string {
+			if rows > 10 {
+				return StrategyBatched
+			}
+			return StrategyFull
+		}),
+		WithLogger(nil),
+		WithProgressHook(func(e Event) {
+			mu.Lock()
+			events[e.Type]++
+			mu.Unlock()
+		}),
+		WithMismatchHook(func(m Mismatch) {
+			mu.Lock()
+			mismatches = append(mismatches, m.Table+":"+m.Status)
+			mu.Unlock()
+		}),
+	)
+
+	report, err := v.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	result := report.Results[ResultKey("azure-1", "aws-1", "db1")]
+	if result.Status != StatusInconsistent {
+		t.Errorf("Status = %s, want %s", result.Status, StatusInconsistent)
+	}
+	if report.InconsistentDatabases != 1 || report.TotalDatabases != 1 {
+		t.Errorf("report counters = %+v", report)
+	}
+
+	match := map[string]bool{}
+	for _, c := range result.TableComparisons {
+		match[c.Table] = c.Match
+	}
+	want := map[string]bool{"empty": true, "orders": false, "users": true}
+	for table, m := range want {
+		if got, ok := match[table]; !ok || got != m {
+			t.Errorf("table %s match = %v (present %v), want %v", table, got, ok, m)
+		}
+	}
+
+	sort.Strings(mismatches)
+	if fmt.Sprint(mismatches) != "[audit:MISSING orders:MISMATCH]" {
+		t.Errorf("mismatches = %v", mismatches)
+	}
+	// users按10行分3批，两侧共6个分块；其余3个表各1个分块，audit缺失不计算
+	if events[EventChunkDone] != 6+2+2 {
+		t.Errorf("chunk_done events = %d, want 10", events[EventChunkDone])
+	}
+	if events[EventTableChecked] != 4 || events[EventRunFinished] != 1 {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestRunRejectsUnknownStrategy(t *testing.T) {
+	v := New(
+		WithInstances([]DatabaseInstance{{Name: "a", Database: "db"}}, []DatabaseInstance{{Name: "b", Database: "db"}}),
+		WithLogger(nil),
+	)
+	plan := &Plan{Pairs: []PlanPair{{
+		AzureInstance: "a", AWSInstance: "b", AzureDatabase: "db", AWSDatabase: "db",
+		Tables: []PlanTable{{Table: "t", Strategy: "unknown"}},
+	}}}
+	if _, err := v.Run(context.Background(), plan); err == nil {
+		t.Fatal("Run() with unknown strategy should fail")
+	}
+}
+
+func TestRunMismatchedInstances(t *testing.T) {
+	v := New(WithInstances([]DatabaseInstance{{Name: "a"}}, nil), WithLogger(nil))
+	if _, err := v.Run(context.Background(), nil); err == nil {
+		t.Fatal("Run() with unequal instance counts should fail")
+	}
+}
+
+func TestRunSameDatabaseOnSeveralInstances(t *testing.T) {
+	sources := map[string]Source{
+		"azure-1": &memSource{tables: map[string][][]any{"t": numberedRows(3)}},
+		"aws-1":   &memSource{tables: map[string][][]any{"t": numberedRows(3)}},
+		"azure-2": &memSource{tables: map[string][][]any{"t": numberedRows(3)}},
+		"aws-2":   &memSource{tables: map[string][][]any{"t": numberedRows(4)}},
+	}
+	v := New(
+		WithInstances(
+			[]DatabaseInstance{{Name: "azure-1", Database: "db1"}, {Name: "azure-2", Database: "db1"}},
+			[]DatabaseInstance{{Name: "aws-1", Database: "db1"}, {Name: "aws-2", Database: "db1"}},
+		),
+		WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+			return sources[inst.Name], nil
+		}),
+		WithLogger(nil),
+	)
+
+	report, err := v.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// 同名数据库按实例分别记录结果，汇总时都被计入
+	if len(report.Results) != 2 {
+		t.Fatalf("results = %d, want 2", len(report.Results))
+	}
+	if got := report.Results[ResultKey("azure-1", "aws-1", "db1")].Status; got != StatusSuccess {
+		t.Errorf("azure-1 status = %s, want %s", got, StatusSuccess)
+	}
+	if got := report.Results[ResultKey("azure-2", "aws-2", "db1")].Status; got != StatusInconsistent {
+		t.Errorf("azure-2 status = %s, want %s", got, StatusInconsistent)
+	}
+	if report.SuccessfulValidations != 1 || report.InconsistentDatabases != 1 || report.SuccessRate != "50.00%" {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+// keylessSource 所有表都没有唯一键的内存数据源
+type keylessSource struct{ memSource }
+
+func (s *keylessSource) HasUniqueKey(ctx context.Context, table string) (bool, error) {
+	return false, nil
+}
+
+func TestRunKeylessTableUsesMultiset(t *testing.T) {
+	row := func(v string) []any { return []any{v, nil} }
+	azure := &keylessSource{memSource{tables: map[string][][]any{
+		// 行顺序不同、内容相同
+		"events": {row("b"), row("a"), row("a"), row("c")},
+		// 行数相同，但a和b的重复次数不同
+		"logs": {row("a"), row("a"), row("b")},
+	}}}
+	aws := &memSource{tables: map[string][][]any{
+		"events": {row("a"), row("c"), row("b"), row("a")},
+		"logs":   {row("a"), row("b"), row("b")},
+	}}
+	sources := map[string]Source{"azure-1": azure, "aws-1": aws}
+
+	v := New(
+		WithInstances(
+			[]DatabaseInstance{{Name: "azure-1", Database: "db1"}},
+			[]DatabaseInstance{{Name: "aws-1", Database: "db1"}},
+		),
+		WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+			return sources[inst.Name], nil
+		}),
+		WithLogger(nil),
+	)
+
+	report, err := v.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, c := range report.Results[ResultKey("azure-1", "aws-1", "db1")].TableComparisons {
+		if c.Strategy != StrategyMultiset {
+			t.Errorf("table %s strategy = %s, want %s", c.Table, c.Strategy, StrategyMultiset)
+		}
+		if want := c.Table == "events"; c.Match != want {
+			t.Errorf("table %s match = %v, want %v", c.Table, c.Match, want)
+		}
+		// 没有唯一键时只比较每列与顺序无关的指纹
+		if c.Table == "logs" && (c.Diff == nil || len(c.Diff.Columns) != 1 || c.Diff.Columns[0].Column != "c1" || c.Diff.RowsCounted) {
+			t.Errorf("logs diff = %+v", c.Diff)
+		}
+	}
+}
+
+func TestRunColumnDiff(t *testing.T) {
+	rows := func(n int) [][]any {
+		out := make([][]any, n)
+		for i := range out {
+			out[i] = []any{int64(i), fmt.Sprintf("name-%d", i), "9.99"}
+		}
+		return out
+	}
+	azure := &memSource{tables: map[string][][]any{"items": rows(20)}}
+	awsRows := rows(20)
+	awsRows[3][2] = "9.9900"
+	awsRows[17][2] = "10.0"
+	// 第一列为8的行缺失，之后的分块整体错位，但只有c3真正不一致
+	awsRows = append(awsRows[:8], awsRows[9:]...)
+	aws := &memSource{tables: map[string][][]any{"items": awsRows}}
+	sources := map[string]Source{"azure-1": azure, "aws-1": aws}
+
+	v := New(
+		WithInstances(
+			[]DatabaseInstance{{Name: "azure-1", Database: "db1"}},
+			[]DatabaseInstance{{Name: "aws-1", Database: "db1"}},
+		),
+		WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+			return sources[inst.Name], nil
+		}),
+		WithChecksumStrategy(BatchedStrategy{BatchSize: 5}),
+		WithLogger(nil),
+	)
+
+	report, err := v.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	diff := report.Results[ResultKey("azure-1", "aws-1", "db1")].TableComparisons[0].Diff
+	if diff == nil {
+		t.Fatal("expected column diff for mismatching table")
+	}
+	if len(diff.Columns) != 1 || diff.Columns[0].Column != "c3" || diff.Columns[0].Rows != 2 {
+		t.Errorf("columns = %+v", diff.Columns)
+	}
+	if diff.AzureOnlyRows != 1 || diff.AWSOnlyRows != 0 || !diff.RowsCounted {
+		t.Errorf("diff = %+v", diff)
+	}
+	if want := "仅 c3 列不一致，涉及 2 行；Azure多 1 行"; diff.Summary != want {
+		t.Errorf("summary = %q, want %q", diff.Summary, want)
+	}
+}
+
+func TestRunColumnDiffRereadsMismatchedChunks(t *testing.T) {
+	rows := func() [][]any {
+		out := make([][]any, 20)
+		for i := range out {
+			out[i] = []any{int64(i), "9.99"}
+		}
+		return out
+	}
+	azure := &memSource{tables: map[string][][]any{"items": rows()}}
+	awsRows := rows()
+	awsRows[12][1] = "10.0"
+	aws := &memSource{tables: map[string][][]any{"items": awsRows}}
+	sources := map[string]Source{"azure-1": azure, "aws-1": aws}
+
+	v := New(
+		WithInstances(
+			[]DatabaseInstance{{Name: "azure-1", Database: "db1"}},
+			[]DatabaseInstance{{Name: "aws-1", Database: "db1"}},
+		),
+		WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+			return sources[inst.Name], nil
+		}),
+		WithChecksumStrategy(BatchedStrategy{BatchSize: 5}),
+		WithStrategySelector(func(int64) string { return StrategyBatched }),
+		WithLogger(nil),
+	)
+
+	report, err := v.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	diff := report.Results[ResultKey("azure-1", "aws-1", "db1")].TableComparisons[0].Diff
+	if diff == nil {
+		t.Fatal("expected column diff for mismatching table")
+	}
+	if fmt.Sprint(diff.MismatchedChunks) != "[3]" || len(diff.Columns) != 1 || diff.Columns[0].Column != "c2" || diff.Columns[0].Rows != 1 {
+		t.Errorf("diff = %+v", diff)
+	}
+	// 第一遍读取全表计算校验和和每列指纹，之后只重新读取不一致的第3个分块
+	if azure.read != 25 || aws.read != 25 {
+		t.Errorf("rows read azure = %d, aws = %d, want 25", azure.read, aws.read)
+	}
+}
+
+func TestRunColumnDiffMatchesByKeyName(t *testing.T) {
+	var azureRows, awsRows [][]any
+	for i := 0; i < 6; i++ {
+		azureRows = append(azureRows, []any{int64(i), fmt.Sprintf("name-%d", 5-i), "9.99"})
+	}
+	// AWS的列顺序不同，第一列为name，行按name排序
+	for i := 5; i >= 0; i-- {
+		price := "9.99"
+		if i == 2 {
+			price = "9.9900"
+		}
+		awsRows = append(awsRows, []any{fmt.Sprintf("name-%d", 5-i), int64(i), price})
+	}
+	azure := &memSource{
+		tables:  map[string][][]any{"items": azureRows},
+		columns: map[string][]string{"items": {"id", "name", "price"}},
+	}
+	aws := &memSource{
+		tables:  map[string][][]any{"items": awsRows},
+		columns: map[string][]string{"items": {"name", "id", "price"}},
+	}
+	sources := map[string]Source{"azure-1": azure, "aws-1": aws}
+
+	v := New(
+		WithInstances(
+			[]DatabaseInstance{{Name: "azure-1", Database: "db1"}},
+			[]DatabaseInstance{{Name: "aws-1", Database: "db1"}},
+		),
+		WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+			return sources[inst.Name], nil
+		}),
+		WithLogger(nil),
+	)
+
+	report, err := v.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	diff := report.Results[ResultKey("azure-1", "aws-1", "db1")].TableComparisons[0].Diff
+	if diff == nil {
+		t.Fatal("expected column diff for mismatching table")
+	}
+	// 按唯一键列id配对，只有price不一致
+	if !diff.RowsCounted || len(diff.Columns) != 1 || diff.Columns[0].Column != "price" || diff.Columns[0].Rows != 1 {
+		t.Errorf("diff = %+v", diff)
+	}
+	if diff.AzureOnlyRows != 0 || diff.AWSOnlyRows != 0 {
+		t.Errorf("only rows azure = %d, aws = %d, want 0", diff.AzureOnlyRows, diff.AWSOnlyRows)
+	}
+}
+
+func TestRunDiscovery(t *testing.T) {
+	databases := map[string][]string{
+		"azure-srv": {"mysql", "shop", "crm_old", "test_tmp", "billing"},
+		"aws-srv":   {"sys", "shop", "crm", "reporting", "test_tmp"},
+	}
+	tables := func() *memSource { return &memSource{tables: map[string][][]any{"t": numberedRows(3)}} }
+
+	v := New(
+		WithDiscovery(DiscoveryConfig{
+			Azure:   DatabaseInstance{Name: "azure-srv"},
+			AWS:     DatabaseInstance{Name: "aws-srv"},
+			Exclude: []string{"test_*"},
+			Rename:  []DatabaseRename{{Azure: "crm_old", AWS: "crm"}},
+		}),
+		WithDatabaseLister(func(ctx context.Context, inst DatabaseInstance) ([]string, error) {
+			return databases[inst.Name], nil
+		}),
+		WithSourceOpener(func(ctx context.Context, inst DatabaseInstance) (Source, error) {
+			return tables(), nil
+		}),
+		WithLogger(nil),
+	)
+
+	report, err := v.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Results) != 2 || report.Results[ResultKey("azure-srv", "aws-srv", "shop")].Status != "SUCCESS" || report.Results[ResultKey("azure-srv", "aws-srv", "crm_old")].Status != "SUCCESS" {
+		t.Errorf("results = %+v", report.Results)
+	}
+	if fmt.Sprint(report.MissingDatabases) != "[billing]" || fmt.Sprint(report.ExtraDatabases) != "[reporting]" {
+		t.Errorf("missing = %v, extra = %v", report.MissingDatabases, report.ExtraDatabases)
+	}
+
+	// 计划记录发现的数据库名，执行时按服务器还原实例
+	plan, err := v.BuildPlan(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Pairs) != 2 || plan.Pairs[0].AWSDatabase != "crm" {
+		t.Fatalf("plan pairs = %+v", plan.Pairs)
+	}
+	if report, err = v.Run(context.Background(), plan); err != nil || len(report.Results) != 2 {
+		t.Fatalf("Run(plan) = %+v, %v", report, err)
+	}
+}
@@ -31,7 +31,7 @@ make fmt
 
 # 使用开发配置运行验证
 echo "🔍 使用开发配置运行验证..."
-./bin/validator-optimization validate --config configs/dev.yaml --dry-run --verbose
+./bin/validator-optimization validate --profile dev --dry-run --verbose
 
 echo ""
 echo "✅ 开发环境启动完成！"
@@ -1,7 +1,17 @@
 module multi-database-validator
 
-go 1.21
+go 1.23.8
 
-require github.com/go-sql-driver/mysql v1.7.1
+require (
+	gopkg.in/yaml.v3 v3.0.1
+	multi-database-validator-optimization v0.0.0
+)
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/go-sql-driver/mysql v1.9.3 // indirect
+)
+
+// 验证器核心逻辑由go-validator-optimization中的公开包pkg/validator提供
+replace multi-database-validator-optimization => ../go-validator-optimization
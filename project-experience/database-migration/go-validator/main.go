@@ -4,11 +4,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 	"time"
+
+	"multi-database-validator-optimization/pkg/validator"
 )
 
 func main() {
@@ -58,28 +61,25 @@ func main() {
 		fmt.Println("使用默认配置")
 	}
 
-	// 创建数据库对比对
-	databasePairs := make([]DatabasePair, len(config.Azure))
-	for i := range config.Azure {
-		databasePairs[i] = DatabasePair{
-			AzureInstance: config.Azure[i],
-			AWSInstance:   config.AWS[i],
-		}
-	}
-
 	// 创建验证器实例
-	validator := NewMultiDatabaseValidator(databasePairs)
+	v := validator.New(
+		validator.WithInstances(config.Azure, config.AWS),
+		validator.WithMaxWorkers(config.MaxWorkers),
+	)
 
 	// 执行验证
 	startTime := time.Now()
-	validator.validateAllDatabases(config.MaxWorkers)
+	summary, err := v.Run(context.Background(), nil)
+	if err != nil {
+		log.Fatalf("验证失败: %v", err)
+	}
 	duration := time.Since(startTime)
 
 	// 生成报告
-	summary, err := validator.generateReport("consistency_report.json")
-	if err != nil {
+	if err := validator.SaveReport(summary, "consistency_report.json"); err != nil {
 		log.Fatalf("生成报告失败: %v", err)
 	}
+	log.Printf("验证报告已生成: consistency_report.json")
 
 	// 输出验证摘要
 	printSummary(summary, duration)
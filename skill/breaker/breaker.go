@@ -0,0 +1,344 @@
+// Package breaker 熔断器：依赖故障时快速失败，打开一段时间后放行少量探测请求，探测成功再恢复
+//
+// 状态转换:
+//
+//	closed --(失败率或连续失败超过阈值)--> open --(OpenTimeout后)--> half-open
+//	half-open --(探测全部成功)--> closed
+//	half-open --(任一探测失败)--> open
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed   State = iota // 正常放行，统计失败
+	StateOpen                  // 拒绝所有请求
+	StateHalfOpen              // 放行有限的探测请求
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+var (
+	// ErrOpen 熔断器打开，请求未执行
+	ErrOpen = errors.New("breaker: 熔断器已打开")
+	// ErrTooManyProbes 半开状态的探测名额已用完，请求未执行
+	ErrTooManyProbes = errors.New("breaker: 半开状态的探测请求已满")
+)
+
+// Settings 熔断器配置，零值字段使用默认值
+type Settings struct {
+	Name string
+
+	// 滚动窗口，按失败率熔断时只统计最近Window内的请求
+	Window  time.Duration // 默认10s
+	Buckets int           // 窗口分桶数，默认10，最多为Window的纳秒数
+
+	// 熔断策略，满足任一条件即打开
+	FailureRate         float64 // 窗口内失败率阈值(0,1]，默认0.5，<0表示不按失败率熔断
+	MinRequests         int     // 按失败率熔断时窗口内至少需要的请求数，默认20
+	ConsecutiveFailures int     // 连续失败次数阈值，默认5，<0表示不按连续失败熔断
+
+	OpenTimeout    time.Duration // 打开状态持续时间，默认30s
+	HalfOpenProbes int           // 半开状态允许的并发探测数，全部成功后关闭，默认1
+
+	// IsFailure 判断错误是否计为依赖故障，默认非nil且不是调用方取消(context.Canceled)的错误
+	IsFailure func(err error) bool
+	// OnStateChange 状态变化回调，在锁外调用
+	OnStateChange func(name string, from, to State)
+	// Clock 时钟，用于测试
+	Clock func() time.Time
+}
+
+// Counts 熔断器计数
+type Counts struct {
+	Requests            int64 // 累计执行的请求数
+	Successes           int64 // 累计成功数
+	Failures            int64 // 累计失败数
+	Rejected            int64 // 累计被拒绝（未执行）的请求数
+	ConsecutiveFailures int   // 当前连续失败次数
+	WindowRequests      int   // 滚动窗口内的请求数
+	WindowFailures      int   // 滚动窗口内的失败数
+}
+
+// bucket 滚动窗口中的一个时间桶
+type bucket struct {
+	index    int64
+	requests int
+	failures int
+}
+
+// Breaker 熔断器，并发安全
+type Breaker struct {
+	mu         sync.Mutex
+	settings   Settings
+	bucketSize time.Duration
+	buckets    []bucket
+	state      State
+	generation uint64 // 每次状态变化加一，旧状态下发出的请求结果被忽略
+	openUntil  time.Time
+	probes     int // 半开状态进行中的探测数
+	probeOK    int // 半开状态已成功的探测数
+	counts     Counts
+}
+
+// New 创建熔断器
+func New(s Settings) *Breaker {
+	if s.Window <= 0 {
+		s.Window = 10 * time.Second
+	}
+	if s.Buckets <= 0 {
+		s.Buckets = 10
+	}
+	// 每个桶至少1ns，否则桶长截断为0，计算桶序号时除零
+	if time.Duration(s.Buckets) > s.Window {
+		s.Buckets = int(s.Window)
+	}
+	if s.FailureRate == 0 {
+		s.FailureRate = 0.5
+	}
+	if s.MinRequests <= 0 {
+		s.MinRequests = 20
+	}
+	if s.ConsecutiveFailures == 0 {
+		s.ConsecutiveFailures = 5
+	}
+	if s.OpenTimeout <= 0 {
+		s.OpenTimeout = 30 * time.Second
+	}
+	if s.HalfOpenProbes <= 0 {
+		s.HalfOpenProbes = 1
+	}
+	if s.IsFailure == nil {
+		s.IsFailure = func(err error) bool {
+			return err != nil && !errors.Is(err, context.Canceled)
+		}
+	}
+	if s.Clock == nil {
+		s.Clock = time.Now
+	}
+	return &Breaker{
+		settings:   s,
+		bucketSize: s.Window / time.Duration(s.Buckets),
+		buckets:    make([]bucket, s.Buckets),
+	}
+}
+
+// Name 熔断器名称
+func (b *Breaker) Name() string {
+	return b.settings.Name
+}
+
+// State 当前状态，打开超时后返回half-open
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	state := b.currentState(b.settings.Clock())
+	b.mu.Unlock()
+	return state
+}
+
+// Counts 当前计数
+func (b *Breaker) Counts() Counts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	counts := b.counts
+	counts.WindowRequests, counts.WindowFailures = b.windowTotals(b.settings.Clock())
+	return counts
+}
+
+// Execute 通过熔断器执行fn，熔断时不执行并返回ErrOpen或ErrTooManyProbes
+func (b *Breaker) Execute(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			done(fmt.Errorf("panic: %v", r))
+			panic(r)
+		}
+	}()
+	err = fn()
+	done(err)
+	return err
+}
+
+// Do 通过熔断器执行带返回值的fn
+func Do[T any](b *Breaker, fn func() (T, error)) (T, error) {
+	var result T
+	err := b.Execute(func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	return result, err
+}
+
+// Allow 两阶段用法：允许时返回done，调用方执行请求后必须以结果调用done一次
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	now := b.settings.Clock()
+	var transition func()
+	state := b.currentState(now)
+	if state != b.state {
+		transition = b.setState(state, now)
+	}
+	switch {
+	case state == StateOpen:
+		err = ErrOpen
+	case state == StateHalfOpen && b.probes >= b.settings.HalfOpenProbes:
+		err = ErrTooManyProbes
+	}
+	if err != nil {
+		b.counts.Rejected++
+		b.mu.Unlock()
+		runTransition(transition)
+		return nil, err
+	}
+	if state == StateHalfOpen {
+		b.probes++
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	runTransition(transition)
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { b.record(generation, err) })
+	}, nil
+}
+
+// record 记录一次请求的结果
+func (b *Breaker) record(generation uint64, err error) {
+	b.mu.Lock()
+	now := b.settings.Clock()
+	failed := b.settings.IsFailure(err)
+	b.counts.Requests++
+	if failed {
+		b.counts.Failures++
+	} else {
+		b.counts.Successes++
+	}
+	if generation != b.generation {
+		// 状态已经变化，旧请求的结果只计入累计数
+		b.mu.Unlock()
+		return
+	}
+	if failed {
+		b.counts.ConsecutiveFailures++
+	} else {
+		b.counts.ConsecutiveFailures = 0
+	}
+
+	var transition func()
+	switch b.state {
+	case StateClosed:
+		bk := b.bucket(now)
+		bk.requests++
+		if failed {
+			bk.failures++
+		}
+		if b.shouldTrip(now) {
+			transition = b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		b.probes--
+		if failed {
+			transition = b.setState(StateOpen, now)
+			break
+		}
+		b.probeOK++
+		if b.probeOK >= b.settings.HalfOpenProbes {
+			transition = b.setState(StateClosed, now)
+		}
+	}
+	b.mu.Unlock()
+	runTransition(transition)
+}
+
+// shouldTrip 是否满足熔断条件
+func (b *Breaker) shouldTrip(now time.Time) bool {
+	s := b.settings
+	if s.ConsecutiveFailures > 0 && b.counts.ConsecutiveFailures >= s.ConsecutiveFailures {
+		return true
+	}
+	if s.FailureRate <= 0 {
+		return false
+	}
+	requests, failures := b.windowTotals(now)
+	return requests >= s.MinRequests && float64(failures)/float64(requests) >= s.FailureRate
+}
+
+// currentState 打开超时后视为半开
+func (b *Breaker) currentState(now time.Time) State {
+	if b.state == StateOpen && !now.Before(b.openUntil) {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// setState 切换状态并重置对应的统计，返回在锁外执行的回调，没有回调时为nil
+func (b *Breaker) setState(to State, now time.Time) func() {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.probes, b.probeOK = 0, 0
+	switch to {
+	case StateOpen:
+		b.openUntil = now.Add(b.settings.OpenTimeout)
+	case StateClosed:
+		b.counts.ConsecutiveFailures = 0
+		clear(b.buckets)
+	}
+	if b.settings.OnStateChange == nil {
+		return nil
+	}
+	name, fn := b.settings.Name, b.settings.OnStateChange
+	return func() { fn(name, from, to) }
+}
+
+// bucket 返回now所在的时间桶，复用环形数组中过期的桶
+func (b *Breaker) bucket(now time.Time) *bucket {
+	index := now.UnixNano() / int64(b.bucketSize)
+	bk := &b.buckets[index%int64(len(b.buckets))]
+	if bk.index != index {
+		*bk = bucket{index: index}
+	}
+	return bk
+}
+
+// windowTotals 滚动窗口内的请求数和失败数
+func (b *Breaker) windowTotals(now time.Time) (requests, failures int) {
+	current := now.UnixNano() / int64(b.bucketSize)
+	for _, bk := range b.buckets {
+		if bk.index > current-int64(len(b.buckets)) && bk.index <= current {
+			requests += bk.requests
+			failures += bk.failures
+		}
+	}
+	return requests, failures
+}
+
+// runTransition 在锁外执行状态变化回调
+func runTransition(fn func()) {
+	if fn != nil {
+		fn()
+	}
+}
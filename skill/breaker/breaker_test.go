@@ -0,0 +1,208 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kuihuar/ai/skill/internal/testutil"
+)
+
+var errBackend = errors.New("backend down")
+
+func fail() error    { return errBackend }
+func succeed() error { return nil }
+
+func TestConsecutiveFailures(t *testing.T) {
+	clock := testutil.NewClock()
+	var transitions []string
+	b := New(Settings{
+		Name:                "db",
+		ConsecutiveFailures: 3,
+		FailureRate:         -1,
+		OpenTimeout:         time.Second,
+		Clock:               clock.Now,
+		OnStateChange: func(name string, from, to State) {
+			transitions = append(transitions, fmt.Sprintf("%s:%s->%s", name, from, to))
+		},
+	})
+
+	b.Execute(fail)
+	b.Execute(fail)
+	b.Execute(succeed) // 成功后连续失败清零
+	for range 3 {
+		b.Execute(fail)
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("state = %s, want open", b.State())
+	}
+	called := false
+	if err := b.Execute(func() error { called = true; return nil }); !errors.Is(err, ErrOpen) || called {
+		t.Errorf("open breaker: err = %v, called = %v", err, called)
+	}
+
+	// 超时后半开，只放行一个探测
+	clock.Advance(time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("state = %s, want half-open", b.State())
+	}
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrTooManyProbes) {
+		t.Errorf("second probe: err = %v", err)
+	}
+	done(errBackend)
+	if b.State() != StateOpen {
+		t.Fatalf("failed probe: state = %s, want open", b.State())
+	}
+
+	clock.Advance(time.Second)
+	if err := b.Execute(succeed); err != nil || b.State() != StateClosed {
+		t.Errorf("successful probe: err = %v, state = %s", err, b.State())
+	}
+
+	want := "[db:closed->open db:open->half-open db:half-open->open db:open->half-open db:half-open->closed]"
+	if got := fmt.Sprint(transitions); got != want {
+		t.Errorf("transitions = %s\nwant %s", got, want)
+	}
+	counts := b.Counts()
+	if counts.Requests != 8 || counts.Failures != 6 || counts.Rejected != 2 || counts.ConsecutiveFailures != 0 {
+		t.Errorf("counts = %+v", counts)
+	}
+}
+
+func TestFailureRateWindow(t *testing.T) {
+	clock := testutil.NewClock()
+	b := New(Settings{
+		Window:              10 * time.Second,
+		Buckets:             10,
+		FailureRate:         0.5,
+		MinRequests:         10,
+		ConsecutiveFailures: -1,
+		Clock:               clock.Now,
+	})
+
+	// 旧失败滑出窗口后不再计入
+	for range 4 {
+		b.Execute(fail)
+	}
+	clock.Advance(10 * time.Second)
+	for i := range 9 {
+		if i%2 == 0 {
+			b.Execute(fail)
+		} else {
+			b.Execute(succeed)
+		}
+		clock.Advance(100 * time.Millisecond)
+	}
+	if counts := b.Counts(); counts.WindowRequests != 9 || counts.WindowFailures != 5 || b.State() != StateClosed {
+		t.Fatalf("below min requests: %+v, state = %s", counts, b.State())
+	}
+	b.Execute(succeed) // 10个请求中5个失败，达到阈值
+	if b.State() != StateOpen {
+		t.Errorf("state = %s, want open", b.State())
+	}
+}
+
+func TestTinyWindow(t *testing.T) {
+	clock := testutil.NewClock()
+	// 窗口的纳秒数小于桶数时减少桶数，不会因桶长为0而除零
+	b := New(Settings{Window: 5, Buckets: 10, ConsecutiveFailures: -1, MinRequests: 1, Clock: clock.Now})
+	b.Execute(succeed)
+	if counts := b.Counts(); counts.WindowRequests != 1 {
+		t.Errorf("counts = %+v", counts)
+	}
+	clock.Advance(5)
+	if counts := b.Counts(); counts.WindowRequests != 0 {
+		t.Errorf("request should slide out of a 5ns window: %+v", counts)
+	}
+}
+
+func TestIsFailureAndStaleResults(t *testing.T) {
+	clock := testutil.NewClock()
+	b := New(Settings{ConsecutiveFailures: 2, FailureRate: -1, HalfOpenProbes: 2, OpenTimeout: time.Second, Clock: clock.Now})
+
+	// 调用方取消不计为失败
+	for range 3 {
+		b.Execute(func() error { return context.Canceled })
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("canceled calls tripped breaker")
+	}
+
+	// 打开前发出的慢请求，结果在状态变化后到达时被忽略
+	slow, _ := b.Allow()
+	b.Execute(fail)
+	b.Execute(fail)
+	clock.Advance(time.Second)
+	p1, _ := b.Allow()
+	p2, _ := b.Allow()
+	slow(errBackend)
+	p1(nil)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("state = %s, want half-open until all probes succeed", b.State())
+	}
+	p2(nil)
+	p2(errBackend) // done只生效一次
+	if b.State() != StateClosed {
+		t.Errorf("state = %s, want closed", b.State())
+	}
+}
+
+func TestDoPanic(t *testing.T) {
+	b := New(Settings{ConsecutiveFailures: 1})
+	v, err := Do(b, func() (int, error) { return 42, nil })
+	if v != 42 || err != nil {
+		t.Errorf("Do = %d, %v", v, err)
+	}
+	func() {
+		defer func() { recover() }()
+		b.Execute(func() error { panic("boom") })
+	}()
+	if b.State() != StateOpen {
+		t.Errorf("panic not recorded as failure, state = %s", b.State())
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	transport := &Transport{Settings: Settings{ConsecutiveFailures: 3}}
+	client := &http.Client{Transport: transport}
+	for i := range 5 {
+		resp, err := client.Get(server.URL)
+		if i < 3 {
+			// 5xx响应照常返回给调用方
+			if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+				t.Fatalf("request %d: resp = %v, err = %v", i, resp, err)
+			}
+			resp.Body.Close()
+			continue
+		}
+		if !errors.Is(err, ErrOpen) {
+			t.Errorf("request %d: err = %v, want ErrOpen", i, err)
+		}
+	}
+	if hits != 3 {
+		t.Errorf("server hits = %d, want 3", hits)
+	}
+	host := server.Listener.Addr().String()
+	if b := transport.Breaker(host); b.Name() != host || b.State() != StateOpen {
+		t.Errorf("breaker %q state = %s", b.Name(), b.State())
+	}
+}
@@ -0,0 +1,81 @@
+package breaker
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Transport 带熔断的http.RoundTripper，按请求的Host各用一个熔断器
+//
+//	client := &http.Client{Transport: &breaker.Transport{Settings: breaker.Settings{OpenTimeout: 10 * time.Second}}}
+type Transport struct {
+	// Base 实际发送请求的RoundTripper，默认http.DefaultTransport
+	Base http.RoundTripper
+	// Settings 为每个Host创建熔断器时使用的配置，Name为空时使用Host
+	Settings Settings
+	// IsFailure 判断响应是否计为依赖故障，默认网络错误和5xx响应
+	IsFailure func(resp *http.Response, err error) bool
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// RoundTrip 熔断器打开时不发送请求，返回包装了ErrOpen或ErrTooManyProbes的错误
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.Breaker(req.URL.Host)
+	done, err := b.Allow()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", req.URL.Host, err)
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+
+	isFailure := t.IsFailure
+	if isFailure == nil {
+		isFailure = defaultHTTPFailure
+	}
+	if isFailure(resp, err) {
+		// 5xx响应照常返回给调用方，熔断器只记录这次失败
+		done(fmt.Errorf("%s: %w", req.URL.Host, failure(resp, err)))
+	} else {
+		done(nil)
+	}
+	return resp, err
+}
+
+// Breaker 返回host对应的熔断器，不存在时创建
+func (t *Transport) Breaker(host string) *Breaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.breakers == nil {
+		t.breakers = make(map[string]*Breaker)
+	}
+	b, ok := t.breakers[host]
+	if !ok {
+		settings := t.Settings
+		if settings.Name == "" {
+			settings.Name = host
+		}
+		b = New(settings)
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// defaultHTTPFailure 网络错误和5xx响应计为失败
+func defaultHTTPFailure(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// failure 失败原因，网络错误或响应状态码
+func failure(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("HTTP %d", resp.StatusCode)
+}
@@ -0,0 +1,474 @@
+// Package cache 有界泛型缓存：按条目数或成本限制容量，可选LRU、LFU、W-TinyLFU淘汰策略，
+// 支持按条目的TTL、到期前异步刷新、合并并发未命中的Loader、淘汰回调和命中统计
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/kuihuar/ai/skill/timingwheel"
+)
+
+// ErrNoLoader 未配置Loader时调用GetOrLoad
+var ErrNoLoader = errors.New("cache: 未配置Loader")
+
+// PanicError Loader panic时返回的错误
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("cache: Loader panic: %v", e.Value)
+}
+
+// Loader 未命中时加载数据
+type Loader[K comparable, V any] interface {
+	Load(ctx context.Context, key K) (V, error)
+}
+
+// LoaderFunc 函数形式的Loader
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+func (f LoaderFunc[K, V]) Load(ctx context.Context, key K) (V, error) {
+	return f(ctx, key)
+}
+
+// Policy 淘汰策略
+type Policy int
+
+const (
+	LRU     Policy = iota // 淘汰最久未访问的条目
+	LFU                   // 淘汰访问次数最少的条目，次数相同时淘汰最久未访问的
+	TinyLFU               // W-TinyLFU：小窗口LRU接收新条目，按历史频率决定能否进入主区，抗扫描
+)
+
+// EvictReason 条目被移除的原因
+type EvictReason int
+
+const (
+	ReasonCapacity EvictReason = iota // 超出容量被淘汰
+	ReasonExpired                     // TTL到期
+	ReasonDeleted                     // 调用Delete
+	ReasonReplaced                    // 被Set或刷新覆盖
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	}
+	return "unknown"
+}
+
+// Config 缓存配置，零值表示不限容量、不过期的LRU缓存
+type Config[K comparable, V any] struct {
+	// 容量：MaxCost>0时按Cost计算的总成本限制，否则MaxEntries>0时按条目数限制
+	MaxEntries int
+	MaxCost    int64
+	Cost       func(key K, value V) int64 // 默认每个条目成本为1
+
+	Policy Policy
+
+	TTL time.Duration // 默认TTL，0表示不过期
+	// ExpireAfter 按条目计算TTL，设置时代替TTL用于Set和加载，如不存在的结果使用更短的TTL
+	ExpireAfter func(key K, value V) time.Duration
+	// RefreshAfter 条目写入超过这个时间后被读取时，在后台用Loader刷新，读取方先拿到旧值；需要配置Loader
+	RefreshAfter time.Duration
+	Loader       Loader[K, V]
+
+	// OnEvict 条目被移除时的回调，在锁外调用
+	OnEvict func(key K, value V, reason EvictReason)
+
+	// Clock 时钟，用于测试；设置时不启动后台过期，过期的条目在读取时移除
+	Clock func() time.Time
+}
+
+// Stats 缓存统计
+type Stats struct {
+	Hits        int64
+	Misses      int64
+	Loads       int64 // Loader成功次数，包括后台刷新
+	LoadErrors  int64
+	Refreshes   int64 // 触发的后台刷新次数
+	Evictions   int64 // 因容量淘汰的条目数
+	Expirations int64 // 因TTL移除的条目数
+}
+
+// HitRate 命中率
+func (s Stats) HitRate() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// entry 缓存条目，同时是淘汰策略中的链表或堆节点
+type entry[K comparable, V any] struct {
+	key        K
+	value      V
+	cost       int64
+	expiresAt  time.Time // 为零表示不过期
+	writtenAt  time.Time
+	refreshing bool
+	timer      *timingwheel.Timer
+
+	// 淘汰策略使用的字段
+	prev, next *entry[K, V]
+	segment    segment
+	freq       int
+	seq        uint64
+	index      int
+}
+
+// removal 锁内记录、锁外回调的移除事件
+type removal[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+// call 进行中的加载
+type call[V any] struct {
+	done        chan struct{}
+	value       V
+	err         error
+	invalidated bool // 加载期间key被Set或Delete，结果不写入缓存
+}
+
+// Cache 有界泛型缓存，并发安全
+type Cache[K comparable, V any] struct {
+	cfg     Config[K, V]
+	now     func() time.Time
+	maxCost int64
+
+	mu      sync.Mutex
+	items   map[K]*entry[K, V]
+	policy  policy[K, V]
+	cost    int64
+	calls   map[K]*call[V]
+	stats   Stats
+	removed []removal[K, V] // 待回调的移除事件
+
+	wheel     *timingwheel.Wheel // 第一次写入有TTL的条目时创建
+	ctx       context.Context    // 后台刷新使用，Close时取消
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// New 创建缓存
+func New[K comparable, V any](cfg Config[K, V]) *Cache[K, V] {
+	c := &Cache[K, V]{
+		cfg:   cfg,
+		now:   cfg.Clock,
+		items: make(map[K]*entry[K, V]),
+		calls: make(map[K]*call[V]),
+	}
+	if c.now == nil {
+		c.now = time.Now
+	}
+	switch {
+	case cfg.MaxCost > 0:
+		c.maxCost = cfg.MaxCost
+	case cfg.MaxEntries > 0:
+		c.maxCost = int64(cfg.MaxEntries)
+		c.cfg.Cost = nil
+	}
+	switch cfg.Policy {
+	case LFU:
+		c.policy = &lfu[K, V]{}
+	case TinyLFU:
+		c.policy = newTinyLFU[K, V](c.maxCost)
+	default:
+		c.policy = &lru[K, V]{}
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c
+}
+
+// Get 读取缓存，不触发加载；命中需要刷新的条目时在后台刷新
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	e, ok := c.lookup(key)
+	var value V
+	if ok {
+		value = e.value
+	}
+	c.mu.Unlock()
+	c.notify()
+	return value, ok
+}
+
+// GetOrLoad 读取缓存，未命中时通过Loader加载并写入；同一个key的并发未命中只加载一次
+//
+// 加载使用第一个未命中的调用方的ctx，ctx取消时等待同一次加载的其他调用方也会收到错误；加载失败的结果不缓存
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K) (V, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+	if c.cfg.Loader == nil {
+		var zero V
+		return zero, ErrNoLoader
+	}
+	return c.load(ctx, key)
+}
+
+// Set 使用默认TTL写入
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl(key, value))
+}
+
+// SetWithTTL 写入并指定TTL，0表示不过期
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	if call, ok := c.calls[key]; ok {
+		call.invalidated = true
+	}
+	c.store(key, value, ttl)
+	c.mu.Unlock()
+	c.notify()
+}
+
+// Delete 删除条目
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	if call, ok := c.calls[key]; ok {
+		call.invalidated = true
+	}
+	if e, ok := c.items[key]; ok {
+		c.remove(e, ReasonDeleted)
+	}
+	c.mu.Unlock()
+	c.notify()
+}
+
+// Len 条目数，可能包含已过期还未移除的条目
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Cost 当前总成本
+func (c *Cache[K, V]) Cost() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cost
+}
+
+// Stats 统计
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Close 停止后台过期和刷新，等待进行中的刷新结束；之后缓存仍可读写，过期条目在读取时移除
+func (c *Cache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.mu.Lock()
+		wheel := c.wheel
+		c.mu.Unlock()
+		if wheel != nil {
+			wheel.Stop()
+		}
+	})
+	c.wg.Wait()
+}
+
+// timers 返回时间轮，第一次使用时创建，没有TTL的缓存不启动后台goroutine；
+// Close之后返回nil，条目只在读取时过期。调用方持有锁
+func (c *Cache[K, V]) timers() *timingwheel.Wheel {
+	if c.wheel == nil && c.ctx.Err() == nil {
+		if c.cfg.Clock != nil {
+			c.wheel = timingwheel.New(time.Second, timingwheel.WithClock(c.cfg.Clock))
+		} else {
+			c.wheel = timingwheel.New(time.Second)
+			c.wheel.Start()
+		}
+	}
+	return c.wheel
+}
+
+// ttl 条目的默认TTL
+func (c *Cache[K, V]) ttl(key K, value V) time.Duration {
+	if c.cfg.ExpireAfter != nil {
+		return c.cfg.ExpireAfter(key, value)
+	}
+	return c.cfg.TTL
+}
+
+// lookup 查找未过期的条目并记录访问，调用方持有锁
+func (c *Cache[K, V]) lookup(key K) (*entry[K, V], bool) {
+	e, ok := c.items[key]
+	now := c.now()
+	if ok && !e.expiresAt.IsZero() && !now.Before(e.expiresAt) {
+		c.remove(e, ReasonExpired)
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		c.policy.miss(key)
+		return nil, false
+	}
+	c.stats.Hits++
+	c.policy.access(e)
+	if c.cfg.RefreshAfter > 0 && c.cfg.Loader != nil && !e.refreshing &&
+		now.Sub(e.writtenAt) >= c.cfg.RefreshAfter && c.ctx.Err() == nil {
+		e.refreshing = true
+		c.stats.Refreshes++
+		c.wg.Add(1)
+		go c.refresh(key)
+	}
+	return e, true
+}
+
+// store 写入条目并按容量淘汰，调用方持有锁
+func (c *Cache[K, V]) store(key K, value V, ttl time.Duration) {
+	cost := int64(1)
+	if c.cfg.Cost != nil {
+		cost = c.cfg.Cost(key, value)
+	}
+	if old, ok := c.items[key]; ok {
+		c.remove(old, ReasonReplaced)
+	}
+	if c.maxCost > 0 && cost > c.maxCost {
+		// 单个条目超过总容量，不缓存
+		c.removed = append(c.removed, removal[K, V]{key, value, ReasonCapacity})
+		c.stats.Evictions++
+		return
+	}
+
+	now := c.now()
+	e := &entry[K, V]{key: key, value: value, cost: cost, writtenAt: now}
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+		if wheel := c.timers(); wheel != nil {
+			e.timer = wheel.AfterFunc(ttl, func() { c.expire(e) })
+		}
+	}
+	c.items[key] = e
+	c.cost += cost
+	c.policy.add(e)
+
+	for c.maxCost > 0 && c.cost > c.maxCost {
+		victim := c.policy.victim()
+		if victim == nil {
+			break
+		}
+		c.stats.Evictions++
+		c.remove(victim, ReasonCapacity)
+	}
+}
+
+// remove 移除条目，调用方持有锁
+func (c *Cache[K, V]) remove(e *entry[K, V], reason EvictReason) {
+	delete(c.items, e.key)
+	c.cost -= e.cost
+	c.policy.remove(e)
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	if reason == ReasonExpired {
+		c.stats.Expirations++
+	}
+	c.removed = append(c.removed, removal[K, V]{e.key, e.value, reason})
+}
+
+// expire 时间轮到期回调
+func (c *Cache[K, V]) expire(e *entry[K, V]) {
+	c.mu.Lock()
+	if c.items[e.key] == e {
+		c.remove(e, ReasonExpired)
+	}
+	c.mu.Unlock()
+	c.notify()
+}
+
+// notify 在锁外执行移除回调
+func (c *Cache[K, V]) notify() {
+	c.mu.Lock()
+	removed := c.removed
+	c.removed = nil
+	c.mu.Unlock()
+	if c.cfg.OnEvict == nil {
+		return
+	}
+	for _, r := range removed {
+		c.cfg.OnEvict(r.key, r.value, r.reason)
+	}
+}
+
+// load 合并同一个key的并发加载
+func (c *Cache[K, V]) load(ctx context.Context, key K) (V, error) {
+	c.mu.Lock()
+	if cl, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-cl.done:
+			return cl.value, cl.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+	cl := &call[V]{done: make(chan struct{})}
+	c.calls[key] = cl
+	c.mu.Unlock()
+	// 写入时的回调panic也要移除进行中的加载并唤醒等待方
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(cl.done)
+		c.notify()
+	}()
+
+	cl.value, cl.err = c.callLoader(ctx, key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cl.err != nil {
+		c.stats.LoadErrors++
+	} else {
+		c.stats.Loads++
+		if !cl.invalidated {
+			c.store(key, cl.value, c.ttl(key, cl.value))
+		}
+	}
+	return cl.value, cl.err
+}
+
+// callLoader 调用Loader，把panic转换为PanicError
+func (c *Cache[K, V]) callLoader(ctx context.Context, key K) (value V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return c.cfg.Loader.Load(ctx, key)
+}
+
+// refresh 后台刷新，失败或Loader panic时保留旧值
+func (c *Cache[K, V]) refresh(key K) {
+	defer c.wg.Done()
+	if _, err := c.load(c.ctx, key); err != nil {
+		c.mu.Lock()
+		if e, ok := c.items[key]; ok {
+			e.refreshing = false
+		}
+		c.mu.Unlock()
+	}
+}
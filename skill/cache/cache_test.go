@@ -0,0 +1,459 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kuihuar/ai/skill/internal/testutil"
+)
+
+// evictLog 记录OnEvict回调
+type evictLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *evictLog) record(key string, _ int, reason EvictReason) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, key+":"+reason.String())
+}
+
+func (l *evictLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return fmt.Sprint(l.events)
+}
+
+func TestLRUEviction(t *testing.T) {
+	var log evictLog
+	c := New(Config[string, int]{MaxEntries: 3, OnEvict: log.record})
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a")
+	c.Set("d", 4)
+	c.Set("c", 30)
+	c.Set("e", 5)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should be evicted")
+	}
+	for _, k := range []string{"c", "d", "e"} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("%s missing", k)
+		}
+	}
+	if got := log.String(); got != "[b:capacity c:replaced a:capacity]" {
+		t.Errorf("evictions = %s", got)
+	}
+}
+
+func TestLFUEviction(t *testing.T) {
+	c := New(Config[string, int]{MaxEntries: 3, Policy: LFU})
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	for range 3 {
+		c.Get("a")
+	}
+	c.Get("b")
+	c.Set("d", 4) // c访问次数最少
+	c.Set("e", 5) // d和e次数相同，d更旧
+
+	for k, want := range map[string]bool{"a": true, "b": true, "c": false, "d": false, "e": true} {
+		if _, ok := c.Get(k); ok != want {
+			t.Errorf("%s present = %v, want %v", k, ok, want)
+		}
+	}
+}
+
+func TestCostBound(t *testing.T) {
+	var log evictLog
+	c := New(Config[string, int]{
+		MaxCost: 10,
+		Cost:    func(_ string, v int) int64 { return int64(v) },
+		OnEvict: log.record,
+	})
+	defer c.Close()
+
+	c.Set("a", 4)
+	c.Set("b", 4)
+	c.Set("c", 4) // 12 > 10，淘汰a
+	if c.Cost() != 8 || c.Len() != 2 {
+		t.Errorf("cost = %d, len = %d", c.Cost(), c.Len())
+	}
+	c.Set("huge", 11) // 超过总容量，不缓存
+	if _, ok := c.Get("huge"); ok {
+		t.Error("oversized entry cached")
+	}
+	if c.Cost() != 8 {
+		t.Errorf("cost = %d", c.Cost())
+	}
+	if got := log.String(); got != "[a:capacity huge:capacity]" {
+		t.Errorf("evictions = %s", got)
+	}
+	if s := c.Stats(); s.Evictions != 2 {
+		t.Errorf("evictions = %d", s.Evictions)
+	}
+}
+
+// hotHitRate 80个热点key每轮都被读取，同时每轮写入50个只出现一次的key
+func hotHitRate(p Policy) float64 {
+	c := New(Config[int, int]{MaxEntries: 100, Policy: p})
+	defer c.Close()
+
+	hits, reads := 0, 0
+	next := 1000
+	for round := range 100 {
+		for k := range 80 {
+			if _, ok := c.Get(k); ok {
+				hits++
+			} else {
+				c.Set(k, k)
+			}
+			if round > 10 {
+				reads++
+			}
+		}
+		if round <= 10 {
+			hits = 0
+		}
+		for range 50 {
+			c.Set(next, next)
+			next++
+		}
+	}
+	return float64(hits) / float64(reads)
+}
+
+func TestTinyLFUScanResistance(t *testing.T) {
+	lruRate, tinyRate := hotHitRate(LRU), hotHitRate(TinyLFU)
+	t.Logf("hot hit rate: lru %.2f, tinylfu %.2f", lruRate, tinyRate)
+	if tinyRate < 0.9 {
+		t.Errorf("tinylfu hit rate %.2f, want >= 0.9", tinyRate)
+	}
+	if tinyRate <= lruRate {
+		t.Errorf("tinylfu %.2f not better than lru %.2f", tinyRate, lruRate)
+	}
+}
+
+func TestPoliciesStayBounded(t *testing.T) {
+	for _, p := range []Policy{LRU, LFU, TinyLFU} {
+		c := New(Config[int, int]{MaxEntries: 50, Policy: p})
+		for range 5000 {
+			k := rand.IntN(200)
+			switch rand.IntN(4) {
+			case 0:
+				c.Delete(k)
+			case 1:
+				c.Set(k, k)
+			default:
+				if _, ok := c.Get(k); !ok {
+					c.Set(k, k)
+				}
+			}
+			if c.Len() > 50 {
+				t.Fatalf("policy %d: len %d exceeds bound", p, c.Len())
+			}
+		}
+		c.Close()
+	}
+}
+
+func TestTTL(t *testing.T) {
+	clock := testutil.NewClock()
+	var log evictLog
+	c := New(Config[string, int]{TTL: 5 * time.Second, Clock: clock.Now, OnEvict: log.record})
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, time.Minute)
+	c.SetWithTTL("forever", 3, 0)
+
+	clock.Advance(3 * time.Second)
+	c.wheel.Advance()
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a expired early")
+	}
+	clock.Advance(3 * time.Second)
+	c.wheel.Advance() // 时间轮到期移除
+	if c.Len() != 2 {
+		t.Errorf("len = %d, want 2", c.Len())
+	}
+
+	clock.Advance(2 * time.Minute)
+	if _, ok := c.Get("b"); ok { // 时间轮还未推进，读取时检查过期
+		t.Error("b should be expired on read")
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Error("entry without TTL expired")
+	}
+	if got := log.String(); got != "[a:expired b:expired]" {
+		t.Errorf("evictions = %s", got)
+	}
+	if s := c.Stats(); s.Expirations != 2 {
+		t.Errorf("expirations = %d", s.Expirations)
+	}
+}
+
+func TestExpireAfter(t *testing.T) {
+	clock := testutil.NewClock()
+	c := New(Config[string, int]{
+		TTL:   time.Minute,
+		Clock: clock.Now,
+		ExpireAfter: func(_ string, v int) time.Duration {
+			if v < 0 {
+				return time.Second
+			}
+			return time.Minute
+		},
+		Loader: LoaderFunc[string, int](func(ctx context.Context, key string) (int, error) {
+			return -1, nil
+		}),
+	})
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.GetOrLoad(context.Background(), "missing")
+	clock.Advance(2 * time.Second)
+	if _, ok := c.Get("missing"); ok {
+		t.Error("loaded entry should use ExpireAfter")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a expired early")
+	}
+}
+
+func TestGetOrLoadSingleflight(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	c := New(Config[string, int]{
+		Loader: LoaderFunc[string, int](func(ctx context.Context, key string) (int, error) {
+			loads.Add(1)
+			<-release
+			return len(key), nil
+		}),
+	})
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.GetOrLoad(context.Background(), "hello")
+		}()
+	}
+	for !c.loading("hello") {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loader called %d times", n)
+	}
+	for i, v := range results {
+		if v != 5 {
+			t.Errorf("result %d = %d", i, v)
+		}
+	}
+	if v, ok := c.Get("hello"); !ok || v != 5 {
+		t.Errorf("Get = %d, %v", v, ok)
+	}
+}
+
+// loading key是否有进行中的加载
+func (c *Cache[K, V]) loading(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.calls[key]
+	return ok
+}
+
+func TestLoadErrorsAndInvalidation(t *testing.T) {
+	errDown := errors.New("db down")
+	fail := true
+	release := make(chan struct{}, 1)
+	c := New(Config[string, int]{
+		Loader: LoaderFunc[string, int](func(ctx context.Context, key string) (int, error) {
+			if fail {
+				return 0, errDown
+			}
+			<-release
+			return 1, nil
+		}),
+	})
+	defer c.Close()
+
+	if _, err := c.GetOrLoad(context.Background(), "k"); !errors.Is(err, errDown) {
+		t.Fatalf("err = %v", err)
+	}
+	if c.Len() != 0 {
+		t.Error("failed load was cached")
+	}
+
+	// 加载期间写入，加载结果不覆盖新值
+	fail = false
+	done := make(chan int)
+	go func() {
+		v, _ := c.GetOrLoad(context.Background(), "k")
+		done <- v
+	}()
+	for !c.loading("k") {
+		time.Sleep(time.Millisecond)
+	}
+	c.Set("k", 42)
+	release <- struct{}{}
+	if v := <-done; v != 1 {
+		t.Errorf("loader result = %d", v)
+	}
+	if v, _ := c.Get("k"); v != 42 {
+		t.Errorf("Get = %d, want 42", v)
+	}
+
+	s := c.Stats()
+	if s.Loads != 1 || s.LoadErrors != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+
+	noLoader := New(Config[string, int]{})
+	defer noLoader.Close()
+	if _, err := noLoader.GetOrLoad(context.Background(), "k"); !errors.Is(err, ErrNoLoader) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestLoaderPanic(t *testing.T) {
+	clock := testutil.NewClock()
+	var calls atomic.Int32
+	c := New(Config[string, int]{
+		RefreshAfter: 10 * time.Second,
+		Clock:        clock.Now,
+		Loader: LoaderFunc[string, int](func(ctx context.Context, key string) (int, error) {
+			if n := calls.Add(1); n != 2 {
+				panic("boom")
+			}
+			return 7, nil
+		}),
+	})
+	defer c.Close()
+
+	var pe *PanicError
+	if _, err := c.GetOrLoad(context.Background(), "k"); !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Fatalf("err = %v", err)
+	}
+	// panic后不留下进行中的加载，下一次未命中重新加载
+	if c.loading("k") {
+		t.Fatal("call left behind after panic")
+	}
+	if v, err := c.GetOrLoad(context.Background(), "k"); err != nil || v != 7 {
+		t.Fatalf("reload = %d, %v", v, err)
+	}
+
+	// 后台刷新panic不影响进程，保留旧值
+	clock.Advance(11 * time.Second)
+	c.Get("k")
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().LoadErrors != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("refresh did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if v, ok := c.Get("k"); !ok || v != 7 {
+		t.Errorf("Get after failed refresh = %d, %v", v, ok)
+	}
+}
+
+func TestRefreshAhead(t *testing.T) {
+	clock := testutil.NewClock()
+	var version atomic.Int32
+	c := New(Config[string, int32]{
+		RefreshAfter: 10 * time.Second,
+		TTL:          time.Minute,
+		Clock:        clock.Now,
+		Loader: LoaderFunc[string, int32](func(ctx context.Context, key string) (int32, error) {
+			return version.Add(1), nil
+		}),
+	})
+	defer c.Close()
+
+	if v, _ := c.GetOrLoad(context.Background(), "k"); v != 1 {
+		t.Fatalf("first load = %d", v)
+	}
+	clock.Advance(5 * time.Second)
+	if v, _ := c.Get("k"); v != 1 || c.Stats().Refreshes != 0 {
+		t.Fatal("refreshed too early")
+	}
+
+	clock.Advance(6 * time.Second)
+	if v, _ := c.Get("k"); v != 1 { // 先返回旧值，后台刷新
+		t.Errorf("stale read = %d", v)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if v, _ := c.Get("k"); v == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("refresh did not complete")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s := c.Stats(); s.Refreshes != 1 || s.Loads != 2 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestStatsAndClose(t *testing.T) {
+	var log evictLog
+	c := New(Config[string, int]{OnEvict: log.record})
+
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Delete("a")
+	c.Delete("missing")
+
+	s := c.Stats()
+	if s.Hits != 2 || s.Misses != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+	if r := s.HitRate(); r < 0.66 || r > 0.67 {
+		t.Errorf("hit rate = %v", r)
+	}
+	if got := log.String(); got != "[a:deleted]" {
+		t.Errorf("evictions = %s", got)
+	}
+
+	// 没有TTL时不创建时间轮
+	if c.wheel != nil {
+		t.Error("wheel started without TTL")
+	}
+	c.SetWithTTL("t", 1, time.Minute)
+	if c.wheel == nil {
+		t.Error("wheel not created for SetWithTTL")
+	}
+
+	c.Close()
+	c.Close()
+	c.Set("b", 2) // 关闭后仍可读写
+	if v, ok := c.Get("b"); !ok || v != 2 {
+		t.Errorf("Get after Close = %d, %v", v, ok)
+	}
+}
@@ -0,0 +1,141 @@
+package cache
+
+import "container/heap"
+
+// policy 淘汰策略，所有方法在缓存的锁内调用
+type policy[K comparable, V any] interface {
+	add(e *entry[K, V])    // 新条目写入
+	access(e *entry[K, V]) // 命中
+	remove(e *entry[K, V]) // 条目被移除
+	miss(key K)            // 未命中，TinyLFU用于统计频率
+	victim() *entry[K, V]  // 下一个淘汰的条目
+}
+
+// segment 条目所在的链表
+type segment uint8
+
+const (
+	segmentNone segment = iota
+	segmentWindow
+	segmentProbation
+	segmentProtected
+)
+
+// list 带哨兵的侵入式双向链表，头部为最近访问
+type list[K comparable, V any] struct {
+	root entry[K, V]
+	len  int
+	cost int64
+}
+
+func (l *list[K, V]) init() {
+	l.root.prev, l.root.next = &l.root, &l.root
+}
+
+func (l *list[K, V]) pushFront(e *entry[K, V]) {
+	if l.root.next == nil {
+		l.init()
+	}
+	e.prev, e.next = &l.root, l.root.next
+	l.root.next.prev = e
+	l.root.next = e
+	l.len++
+	l.cost += e.cost
+}
+
+func (l *list[K, V]) unlink(e *entry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev, e.next = nil, nil
+	l.len--
+	l.cost -= e.cost
+}
+
+func (l *list[K, V]) moveToFront(e *entry[K, V]) {
+	l.unlink(e)
+	l.pushFront(e)
+}
+
+func (l *list[K, V]) front() *entry[K, V] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.next
+}
+
+func (l *list[K, V]) back() *entry[K, V] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// lru 最近最少使用
+type lru[K comparable, V any] struct {
+	entries list[K, V]
+}
+
+func (p *lru[K, V]) add(e *entry[K, V])    { p.entries.pushFront(e) }
+func (p *lru[K, V]) access(e *entry[K, V]) { p.entries.moveToFront(e) }
+func (p *lru[K, V]) remove(e *entry[K, V]) { p.entries.unlink(e) }
+func (p *lru[K, V]) miss(K)                {}
+func (p *lru[K, V]) victim() *entry[K, V]  { return p.entries.back() }
+
+// lfu 最不经常使用，按访问次数的小顶堆，次数相同时淘汰最久未访问的
+type lfu[K comparable, V any] struct {
+	heap lfuHeap[K, V]
+	seq  uint64
+}
+
+func (p *lfu[K, V]) add(e *entry[K, V]) {
+	p.seq++
+	e.freq, e.seq = 1, p.seq
+	heap.Push(&p.heap, e)
+}
+
+func (p *lfu[K, V]) access(e *entry[K, V]) {
+	p.seq++
+	e.freq++
+	e.seq = p.seq
+	heap.Fix(&p.heap, e.index)
+}
+
+func (p *lfu[K, V]) remove(e *entry[K, V]) { heap.Remove(&p.heap, e.index) }
+func (p *lfu[K, V]) miss(K)                {}
+
+func (p *lfu[K, V]) victim() *entry[K, V] {
+	if len(p.heap) == 0 {
+		return nil
+	}
+	return p.heap[0]
+}
+
+type lfuHeap[K comparable, V any] []*entry[K, V]
+
+func (h lfuHeap[K, V]) Len() int { return len(h) }
+func (h lfuHeap[K, V]) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].seq < h[j].seq
+}
+func (h lfuHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap[K, V]) Push(x any) {
+	e := x.(*entry[K, V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap[K, V]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*h = old[:len(old)-1]
+	return e
+}
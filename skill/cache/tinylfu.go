@@ -0,0 +1,153 @@
+package cache
+
+import "hash/maphash"
+
+// tinyLFU W-TinyLFU淘汰策略
+//
+// 新条目先进入占容量1%的窗口LRU；窗口满时最旧的条目进入主区的试用段，成为候选者。
+// 需要淘汰时比较试用段中最新的候选者和最旧的条目在计数草图中的历史频率，淘汰频率低的一个，
+// 所以一次性扫描大量新key不会冲掉热点数据。试用段中再次命中的条目晋升到保护段（主区的80%）。
+type tinyLFU[K comparable, V any] struct {
+	seed      maphash.Seed
+	sketch    *sketch
+	window    list[K, V]
+	probation list[K, V]
+	protected list[K, V]
+
+	windowMax    int64
+	protectedMax int64
+}
+
+func newTinyLFU[K comparable, V any](capacity int64) *tinyLFU[K, V] {
+	windowMax := max(capacity/100, 1)
+	return &tinyLFU[K, V]{
+		seed:         maphash.MakeSeed(),
+		sketch:       newSketch(capacity),
+		windowMax:    windowMax,
+		protectedMax: max(capacity-windowMax, 0) * 8 / 10,
+	}
+}
+
+func (p *tinyLFU[K, V]) hash(key K) uint64 {
+	return maphash.Comparable(p.seed, key)
+}
+
+func (p *tinyLFU[K, V]) add(e *entry[K, V]) {
+	p.sketch.increment(p.hash(e.key))
+	e.segment = segmentWindow
+	p.window.pushFront(e)
+	for p.window.cost > p.windowMax && p.window.len > 1 {
+		// 窗口溢出的条目进入试用段头部，等待与试用段尾部比较
+		candidate := p.window.back()
+		p.window.unlink(candidate)
+		candidate.segment = segmentProbation
+		p.probation.pushFront(candidate)
+	}
+}
+
+func (p *tinyLFU[K, V]) access(e *entry[K, V]) {
+	p.sketch.increment(p.hash(e.key))
+	switch e.segment {
+	case segmentWindow:
+		p.window.moveToFront(e)
+	case segmentProtected:
+		p.protected.moveToFront(e)
+	case segmentProbation:
+		p.probation.unlink(e)
+		e.segment = segmentProtected
+		p.protected.pushFront(e)
+		for p.protected.cost > p.protectedMax && p.protected.len > 0 {
+			demoted := p.protected.back()
+			p.protected.unlink(demoted)
+			demoted.segment = segmentProbation
+			p.probation.pushFront(demoted)
+		}
+	}
+}
+
+func (p *tinyLFU[K, V]) remove(e *entry[K, V]) {
+	switch e.segment {
+	case segmentWindow:
+		p.window.unlink(e)
+	case segmentProbation:
+		p.probation.unlink(e)
+	case segmentProtected:
+		p.protected.unlink(e)
+	}
+	e.segment = segmentNone
+}
+
+// miss 未命中的key也计入频率，被淘汰后反复访问的key能重新进入主区
+func (p *tinyLFU[K, V]) miss(key K) {
+	p.sketch.increment(p.hash(key))
+}
+
+func (p *tinyLFU[K, V]) victim() *entry[K, V] {
+	candidate, victim := p.probation.front(), p.probation.back()
+	switch {
+	case candidate == nil:
+		if v := p.protected.back(); v != nil {
+			return v
+		}
+		return p.window.back()
+	case candidate == victim:
+		return victim
+	}
+	// 候选者的历史频率高于试用段最旧的条目时才被接纳，平局时拒绝候选者
+	if p.sketch.estimate(p.hash(candidate.key)) > p.sketch.estimate(p.hash(victim.key)) {
+		return victim
+	}
+	return candidate
+}
+
+// sketch 4行的Count-Min计数草图，计数上限15；累计增加到10倍宽度时所有计数减半，让频率随时间衰减
+type sketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newSketch(capacity int64) *sketch {
+	width := uint64(16)
+	for width < uint64(capacity) {
+		width <<= 1
+	}
+	s := &sketch{mask: width - 1, resetAt: int(width) * 10}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index 第i行的位置，用不同的乘数从同一个哈希值派生
+func (s *sketch) index(h uint64, i int) uint64 {
+	seeds := [4]uint64{0x9E3779B97F4A7C15, 0xC2B2AE3D27D4EB4F, 0x165667B19E3779F9, 0x27D4EB2F165667C5}
+	h *= seeds[i]
+	return (h ^ h>>32) & s.mask
+}
+
+func (s *sketch) increment(h uint64) {
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < 15 {
+			*c++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		for i := range s.rows {
+			for j := range s.rows[i] {
+				s.rows[i][j] >>= 1
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *sketch) estimate(h uint64) uint8 {
+	est := uint8(15)
+	for i := range s.rows {
+		est = min(est, s.rows[i][s.index(h, i)])
+	}
+	return est
+}
@@ -6,6 +6,8 @@ import (
 	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/kuihuar/ai/skill/workerpool"
 )
 
 // ==================== Channel 通道详解 ====================
@@ -161,73 +163,49 @@ func NonBlockingExample() {
 // 4. Channel 模式应用
 
 // 4.1 工作池模式
+// 基于泛型工作池 workerpool.Pool，结果写入输出channel
 type WorkerPoolWithChannel struct {
-	workers int
-	input   chan interface{}
-	output  chan interface{}
-	done    chan struct{}
-	wg      sync.WaitGroup
+	pool   *workerpool.Pool[interface{}, interface{}]
+	output chan interface{}
 }
 
 func NewWorkerPoolWithChannel(workers int) *WorkerPoolWithChannel {
-	return &WorkerPoolWithChannel{
-		workers: workers,
-		input:   make(chan interface{}, 100),
-		output:  make(chan interface{}, 100),
-		done:    make(chan struct{}),
-	}
-}
-
-func (wp *WorkerPoolWithChannel) Start() {
-	for i := 0; i < wp.workers; i++ {
-		wp.wg.Add(1)
-		go wp.worker(i)
-	}
+	wp := &WorkerPoolWithChannel{output: make(chan interface{}, 100)}
+	wp.pool = workerpool.New(workers, wp.processTask, workerpool.WithQueueSize(100))
+	return wp
 }
 
-func (wp *WorkerPoolWithChannel) worker(id int) {
-	defer wp.wg.Done()
+// Start worker在创建时已经启动，保留以兼容原有用法
+func (wp *WorkerPoolWithChannel) Start() {}
 
-	for {
-		select {
-		case task, ok := <-wp.input:
-			if !ok {
-				return
-			}
-			// 处理任务
-			result := wp.processTask(task, id)
-			select {
-			case wp.output <- result:
-			case <-wp.done:
-				return
-			}
-		case <-wp.done:
-			return
-		}
-	}
-}
-
-func (wp *WorkerPoolWithChannel) processTask(task interface{}, workerID int) interface{} {
+func (wp *WorkerPoolWithChannel) processTask(ctx context.Context, task interface{}) (interface{}, error) {
 	// 模拟任务处理
 	time.Sleep(time.Millisecond * 100)
-	return fmt.Sprintf("Task %v processed by worker %d", task, workerID)
+	result := fmt.Sprintf("Task %v processed by worker %d", task, workerpool.WorkerID(ctx))
+	select {
+	case wp.output <- result:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return result, nil
 }
 
 func (wp *WorkerPoolWithChannel) Submit(task interface{}) {
-	select {
-	case wp.input <- task:
-	case <-wp.done:
-	}
+	wp.pool.Submit(context.Background(), task)
 }
 
 func (wp *WorkerPoolWithChannel) Results() <-chan interface{} {
 	return wp.output
 }
 
+// Stop 放弃排队的任务并取消执行中的任务，worker全部退出后关闭输出channel
+// 不等待结果被读取，输出channel满时也不会阻塞
 func (wp *WorkerPoolWithChannel) Stop() {
-	close(wp.done)
-	close(wp.input)
-	wp.wg.Wait()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	wp.pool.Shutdown(ctx)
+	// 执行中的任务已被取消，再次调用只等待worker退出
+	wp.pool.Shutdown(context.Background())
 	close(wp.output)
 }
 
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/kuihuar/ai/skill/breaker"
 )
 
 // ==================== context.Context 上下文控制详解 ====================
@@ -195,10 +197,14 @@ func ContextChainExample() {
 // 4.1 HTTP 请求处理
 type HTTPHandler struct {
 	timeout time.Duration
+	api     *breaker.Breaker // 外部 API 的熔断器
 }
 
 func NewHTTPHandler(timeout time.Duration) *HTTPHandler {
-	return &HTTPHandler{timeout: timeout}
+	return &HTTPHandler{
+		timeout: timeout,
+		api:     breaker.New(breaker.Settings{Name: "external-api"}),
+	}
 }
 
 func (h *HTTPHandler) HandleRequest(userID string) error {
@@ -246,14 +252,17 @@ func (h *HTTPHandler) callExternalAPI(ctx context.Context) error {
 	apiCtx, cancel := context.WithTimeout(ctx, time.Second)
 	defer cancel()
 
-	// 模拟 API 调用
-	select {
-	case <-apiCtx.Done():
-		return apiCtx.Err()
-	case <-time.After(time.Millisecond * 200):
-		fmt.Println("External API call completed")
-		return nil
-	}
+	// 熔断器打开时直接返回 breaker.ErrOpen，不再等待超时
+	return h.api.Execute(func() error {
+		// 模拟 API 调用
+		select {
+		case <-apiCtx.Done():
+			return apiCtx.Err()
+		case <-time.After(time.Millisecond * 200):
+			fmt.Println("External API call completed")
+			return nil
+		}
+	})
 }
 
 // 4.2 并发任务控制
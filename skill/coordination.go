@@ -1,9 +1,12 @@
 package skill
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/kuihuar/ai/skill/workerpool"
 )
 
 // 1. 条件变量 (Cond) - 线程同步
@@ -257,74 +260,31 @@ func BarrierExample() {
 // 4. 组合使用示例
 
 // 4.1 工作池
+// 基于泛型工作池 workerpool.Pool，WaitForCompletion 等待所有已提交的工作完成
 type WorkPool struct {
-	mu      sync.Mutex
-	cond    *sync.Cond
-	wg      sync.WaitGroup
-	barrier *Barrier
-	jobs    chan int
-	workers int
-	active  int
+	pool *workerpool.Pool[int, struct{}]
 }
 
 func NewWorkPool(workers int) *WorkPool {
-	wp := &WorkPool{
-		jobs:    make(chan int, 100),
-		workers: workers,
+	return &WorkPool{
+		pool: workerpool.New(workers, func(ctx context.Context, job int) (struct{}, error) {
+			// 处理工作
+			fmt.Printf("Worker %d processing job %d\n", workerpool.WorkerID(ctx), job)
+			time.Sleep(time.Millisecond * 100)
+			return struct{}{}, nil
+		}, workerpool.WithQueueSize(100)),
 	}
-	wp.cond = sync.NewCond(&wp.mu)
-	wp.barrier = NewBarrier(workers)
-	return wp
 }
 
-func (wp *WorkPool) Start() {
-	// 启动工作协程
-	for i := 0; i < wp.workers; i++ {
-		wp.wg.Add(1)
-		go wp.worker(i)
-	}
-
-	// 等待所有工作协程准备就绪
-	wp.barrier.Await()
-}
-
-func (wp *WorkPool) worker(id int) {
-	defer wp.wg.Done()
-
-	// 等待开始信号
-	wp.barrier.Await()
-
-	for job := range wp.jobs {
-		wp.mu.Lock()
-		wp.active++
-		wp.mu.Unlock()
-
-		// 处理工作
-		fmt.Printf("Worker %d processing job %d\n", id, job)
-		time.Sleep(time.Millisecond * 100)
-
-		wp.mu.Lock()
-		wp.active--
-		if wp.active == 0 {
-			wp.cond.Signal() // 通知所有工作完成
-		}
-		wp.mu.Unlock()
-	}
-}
+// Start worker在创建时已经启动，保留以兼容原有用法
+func (wp *WorkPool) Start() {}
 
 func (wp *WorkPool) AddJob(job int) {
-	wp.jobs <- job
+	wp.pool.Submit(context.Background(), job)
 }
 
 func (wp *WorkPool) WaitForCompletion() {
-	wp.mu.Lock()
-	for wp.active > 0 {
-		wp.cond.Wait()
-	}
-	wp.mu.Unlock()
-
-	close(wp.jobs)
-	wp.wg.Wait()
+	wp.pool.Shutdown(context.Background())
 }
 
 // 5. 门闩 (Latch) - 一次性同步点
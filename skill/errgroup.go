@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/kuihuar/ai/skill/breaker"
+	"github.com/kuihuar/ai/skill/retry"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -259,10 +261,14 @@ func (fp *FileProcessorWithErrGroup) processFile(filename string) error {
 // 4.2 并行 HTTP 请求
 type HTTPClient struct {
 	timeout time.Duration
+	breaker *breaker.Breaker // 依赖故障时快速失败，不再每次等到超时
 }
 
 func NewHTTPClient(timeout time.Duration) *HTTPClient {
-	return &HTTPClient{timeout: timeout}
+	return &HTTPClient{
+		timeout: timeout,
+		breaker: breaker.New(breaker.Settings{Name: "http-client"}),
+	}
 }
 
 func (hc *HTTPClient) FetchURLs(urls []string) ([]string, error) {
@@ -291,13 +297,15 @@ func (hc *HTTPClient) FetchURLs(urls []string) ([]string, error) {
 }
 
 func (hc *HTTPClient) fetchURL(ctx context.Context, url string) (string, error) {
-	// 模拟 HTTP 请求
-	select {
-	case <-time.After(hc.timeout):
-		return fmt.Sprintf("Response from %s", url), nil
-	case <-ctx.Done():
-		return "", ctx.Err()
-	}
+	return breaker.Do(hc.breaker, func() (string, error) {
+		// 模拟 HTTP 请求
+		select {
+		case <-time.After(hc.timeout):
+			return fmt.Sprintf("Response from %s", url), nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	})
 }
 
 // 4.3 数据库批量操作
@@ -491,23 +499,19 @@ func ErrGroupWithRetryExample() {
 }
 
 func retryTask(taskID string, maxRetries int) error {
-	var lastErr error
-
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if err := processTask(taskID); err != nil {
-			lastErr = err
-			fmt.Printf("Task %s failed (attempt %d/%d): %v\n", taskID, attempt, maxRetries, err)
-
-			if attempt < maxRetries {
-				time.Sleep(time.Millisecond * time.Duration(attempt*100))
-				continue
-			}
-		} else {
-			return nil
-		}
+	r := retry.New(
+		retry.Attempts(maxRetries),
+		retry.WithBackoff(retry.Exponential(100*time.Millisecond, time.Second)),
+		retry.OnRetry(func(attempt int, err error, delay time.Duration) {
+			fmt.Printf("Task %s failed (attempt %d/%d): %v, retry in %v\n", taskID, attempt, maxRetries, err, delay)
+		}),
+	)
+	if err := r.Do(context.Background(), func(context.Context) error {
+		return processTask(taskID)
+	}); err != nil {
+		return fmt.Errorf("task %s: %w", taskID, err)
 	}
-
-	return fmt.Errorf("task %s failed after %d attempts: %v", taskID, maxRetries, lastErr)
+	return nil
 }
 
 // 7. errgroup 性能优化
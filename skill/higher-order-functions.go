@@ -1,10 +1,13 @@
 package skill
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/kuihuar/ai/skill/retry"
 )
 
 // 函数式编程三大核心操作：
@@ -159,19 +162,22 @@ func AsyncExecuteWithHiger[T any, R any](fn func(T) R) func(T) <-chan R {
 }
 
 // 14. 作为返回值 - 重试函数
-// 为函数添加重试机制
+// 为函数添加重试机制，指数退避（1s, 2s, 4s ...），完整的重试策略见 skill/retry
+// 至少调用一次fn（maxRetries小于0时按0处理），全部失败时原样返回最后一次的错误
 func WithRetryWithHiger[T any](fn func(T) error, maxRetries int) func(T) error {
+	r := retry.New(
+		retry.Attempts(max(maxRetries, 0)+1),
+		retry.WithBackoff(retry.Exponential(time.Second, 30*time.Second)),
+	)
 	return func(x T) error {
-		var err error
-		for i := 0; i <= maxRetries; i++ {
-			if err = fn(x); err == nil {
-				return nil // 成功则返回
-			}
-			if i < maxRetries {
-				time.Sleep(time.Duration(i+1) * time.Second) // 指数退避
-			}
+		var last error
+		if err := r.Do(context.Background(), func(context.Context) error {
+			last = fn(x)
+			return last
+		}); err != nil {
+			return last
 		}
-		return err
+		return nil
 	}
 }
 
@@ -0,0 +1,45 @@
+// Package testutil 各包测试共用的辅助工具：手动推进的时钟、轮询等待条件成立
+package testutil
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock 手动推进的时钟，Now可以作为被测对象的时钟函数传入
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock 创建从固定时刻开始的时钟
+func NewClock() *Clock {
+	return &Clock{now: time.Unix(1_700_000_000, 0)}
+}
+
+// Now 当前时刻
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance 把时钟向前推进d
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// WaitFor 轮询直到cond成立，2秒内不成立时测试失败
+func WaitFor(t testing.TB, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
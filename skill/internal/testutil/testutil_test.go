@@ -0,0 +1,25 @@
+package testutil
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	clock := NewClock()
+	start := clock.Now()
+	clock.Advance(time.Second)
+	if got := clock.Now().Sub(start); got != time.Second {
+		t.Errorf("advanced %v, want 1s", got)
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	var done atomic.Bool
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		done.Store(true)
+	}()
+	WaitFor(t, done.Load)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/kuihuar/ai/skill/timingwheel"
 )
 
 // ==================== sync.Map 同步原语 ====================
@@ -107,6 +109,8 @@ func (cm *CounterMap) GetCount(key string) int {
 }
 
 // 2.2 缓存映射
+// 每个条目在时间轮上挂一个过期定时器，到期即删除，不需要定期遍历整个map
+// 没有容量上限，值为interface{}；需要有界、类型安全、带Loader的缓存时使用 skill/cache
 type CacheMap struct {
 	cache sync.Map
 }
@@ -114,15 +118,18 @@ type CacheMap struct {
 type CacheEntry struct {
 	Value      interface{}
 	ExpireTime time.Time
+	timer      *timingwheel.Timer
 }
 
-func NewCacheMap() *CacheMap {
-	cm := &CacheMap{}
-
-	// 启动清理协程
-	go cm.cleanupExpired()
+// expiryWheel CacheMap 和 SessionManager 共用的时间轮，所有条目的过期只占用一个goroutine
+var expiryWheel = sync.OnceValue(func() *timingwheel.Wheel {
+	w := timingwheel.New(100 * time.Millisecond)
+	w.Start()
+	return w
+})
 
-	return cm
+func NewCacheMap() *CacheMap {
+	return &CacheMap{}
 }
 
 func (cm *CacheMap) Set(key string, value interface{}, ttl time.Duration) {
@@ -130,35 +137,34 @@ func (cm *CacheMap) Set(key string, value interface{}, ttl time.Duration) {
 		Value:      value,
 		ExpireTime: time.Now().Add(ttl),
 	}
-	cm.cache.Store(key, entry)
+	entry.timer = expiryWheel().AfterFunc(ttl, func() {
+		// 期间被覆盖的旧条目不会删除新值
+		cm.cache.CompareAndDelete(key, entry)
+	})
+	if old, loaded := cm.cache.Swap(key, entry); loaded {
+		old.(*CacheEntry).timer.Stop()
+	}
+	// ttl极短时定时器可能在写入前就已执行，定时器不会提前执行，所以此时一定已过期
+	if !time.Now().Before(entry.ExpireTime) {
+		cm.cache.CompareAndDelete(key, entry)
+	}
 }
 
 func (cm *CacheMap) Get(key string) (interface{}, bool) {
 	if value, ok := cm.cache.Load(key); ok {
 		if entry, ok := value.(*CacheEntry); ok {
+			// 时间轮的精度为一个tick，读取时仍按过期时间判断
 			if time.Now().Before(entry.ExpireTime) {
 				return entry.Value, true
-			} else {
-				cm.cache.Delete(key)
 			}
 		}
 	}
 	return nil, false
 }
 
-func (cm *CacheMap) cleanupExpired() {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		cm.cache.Range(func(key, value interface{}) bool {
-			if entry, ok := value.(*CacheEntry); ok {
-				if time.Now().After(entry.ExpireTime) {
-					cm.cache.Delete(key)
-				}
-			}
-			return true
-		})
+func (cm *CacheMap) Delete(key string) {
+	if old, loaded := cm.cache.LoadAndDelete(key); loaded {
+		old.(*CacheEntry).timer.Stop()
 	}
 }
 
@@ -241,6 +247,7 @@ func (cm *ConfigManager) GetConfigWithDefault(key string, defaultValue interface
 }
 
 // 3.2 会话管理器
+// 会话在最后一次访问24小时后过期，每次访问重置时间轮上的定时器
 type SessionManager struct {
 	sessions sync.Map
 }
@@ -251,15 +258,14 @@ type Session struct {
 	CreatedAt time.Time
 	LastSeen  time.Time
 	Data      map[string]interface{}
+	timer     *timingwheel.Timer
 }
 
-func NewSessionManager() *SessionManager {
-	sm := &SessionManager{}
-
-	// 启动会话清理协程
-	go sm.cleanupExpiredSessions()
+// sessionTTL 会话空闲过期时间
+const sessionTTL = time.Hour * 24
 
-	return sm
+func NewSessionManager() *SessionManager {
+	return &SessionManager{}
 }
 
 func (sm *SessionManager) CreateSession(userID string) *Session {
@@ -270,6 +276,9 @@ func (sm *SessionManager) CreateSession(userID string) *Session {
 		LastSeen:  time.Now(),
 		Data:      make(map[string]interface{}),
 	}
+	session.timer = expiryWheel().AfterFunc(sessionTTL, func() {
+		sm.sessions.CompareAndDelete(session.ID, session)
+	})
 
 	sm.sessions.Store(session.ID, session)
 	return session
@@ -279,6 +288,7 @@ func (sm *SessionManager) GetSession(sessionID string) (*Session, bool) {
 	if value, ok := sm.sessions.Load(sessionID); ok {
 		if session, ok := value.(*Session); ok {
 			session.LastSeen = time.Now()
+			session.timer.Reset(sessionTTL)
 			return session, true
 		}
 	}
@@ -286,24 +296,8 @@ func (sm *SessionManager) GetSession(sessionID string) (*Session, bool) {
 }
 
 func (sm *SessionManager) DeleteSession(sessionID string) {
-	sm.sessions.Delete(sessionID)
-}
-
-func (sm *SessionManager) cleanupExpiredSessions() {
-	ticker := time.NewTicker(time.Minute * 5)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		expireTime := time.Now().Add(-time.Hour * 24) // 24小时过期
-
-		sm.sessions.Range(func(key, value interface{}) bool {
-			if session, ok := value.(*Session); ok {
-				if session.LastSeen.Before(expireTime) {
-					sm.sessions.Delete(key)
-				}
-			}
-			return true
-		})
+	if value, loaded := sm.sessions.LoadAndDelete(sessionID); loaded {
+		value.(*Session).timer.Stop()
 	}
 }
 
@@ -62,7 +62,7 @@ func (rm *ResourceManagerWithOnce) GetConnection() *ConnectionWithOnce {
 			ID:   int(time.Now().UnixNano()),
 			Open: true,
 		}
-		fmt.Printf("Connection established: %s\n", rm.connection.ID)
+		fmt.Printf("Connection established: %d\n", rm.connection.ID)
 	})
 	return rm.connection
 }
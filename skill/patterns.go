@@ -260,7 +260,7 @@ func (d logDecorator) Do(ctx context.Context, in string) (string, error) {
 	return "log->" + out, err
 }
 
-// 装饰器：增加缓存（简化）
+// 装饰器：增加缓存（简化），生产中存储前的读写缓存层见 skill/storecache
 type cacheDecorator struct {
 	next Service
 	mu   sync.Mutex
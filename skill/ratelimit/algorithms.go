@@ -0,0 +1,382 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ==================== 令牌桶 ====================
+
+// NewTokenBucket 令牌桶：以rate的速率补充令牌，最多积攒burst个，允许突发
+func NewTokenBucket(rate float64, burst int, opts ...Option) Limiter {
+	return newLimiter(&tokenBucket{rate: rate, size: burst, tokens: float64(burst)}, opts)
+}
+
+type tokenBucket struct {
+	rate   float64
+	size   int
+	tokens float64 // 可以为负数，表示已被预留的未来令牌
+	last   time.Time
+}
+
+// advance 补充从上次计算到now之间产生的令牌
+func (b *tokenBucket) advance(now time.Time) {
+	if b.last.IsZero() {
+		b.last = now
+		return
+	}
+	if !now.After(b.last) {
+		return
+	}
+	b.tokens = math.Min(float64(b.size), b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+func (b *tokenBucket) reserve(now time.Time, n int, maxWait time.Duration) (time.Time, bool) {
+	if n > b.size {
+		return time.Time{}, false
+	}
+	b.advance(now)
+	tokens := b.tokens - float64(n)
+	var wait time.Duration
+	if tokens < 0 {
+		if b.rate <= 0 {
+			return time.Time{}, false
+		}
+		wait = seconds(-tokens / b.rate)
+	}
+	if wait > maxWait {
+		return time.Time{}, false
+	}
+	b.tokens = tokens
+	return now.Add(wait), true
+}
+
+func (b *tokenBucket) cancel(now time.Time, n int, at time.Time) {
+	b.advance(now)
+	b.tokens = math.Min(float64(b.size), b.tokens+float64(n))
+}
+
+func (b *tokenBucket) setRate(now time.Time, rate float64) {
+	b.advance(now)
+	b.rate = rate
+}
+
+func (b *tokenBucket) setBurst(now time.Time, burst int) {
+	b.advance(now)
+	b.size = burst
+	b.tokens = math.Min(b.tokens, float64(burst))
+}
+
+func (b *tokenBucket) burst() int {
+	return b.size
+}
+
+func (b *tokenBucket) status(now time.Time) (int, time.Duration) {
+	b.advance(now)
+	var reset time.Duration
+	if missing := float64(b.size) - b.tokens; missing > 0 && b.rate > 0 {
+		reset = seconds(missing / b.rate)
+	}
+	return int(b.tokens), reset
+}
+
+// ==================== 漏桶 ====================
+
+// NewLeakyBucket 漏桶：请求以rate的速率匀速流出，不允许突发；
+// burst是队列容量，排队中的事件超过burst时预留失败
+func NewLeakyBucket(rate float64, burst int, opts ...Option) Limiter {
+	return newLimiter(&leakyBucket{rate: rate, size: burst}, opts)
+}
+
+type leakyBucket struct {
+	rate float64
+	size int
+	tat  time.Time // 队列排空的时刻
+}
+
+func (b *leakyBucket) reserve(now time.Time, n int, maxWait time.Duration) (time.Time, bool) {
+	if n > b.size || b.rate <= 0 {
+		return time.Time{}, false
+	}
+	start := now
+	if b.tat.After(now) {
+		start = b.tat
+	}
+	wait := start.Sub(now)
+	if queued := wait.Seconds() * b.rate; queued+float64(n) > float64(b.size) {
+		return time.Time{}, false
+	}
+	if wait > maxWait {
+		return time.Time{}, false
+	}
+	b.tat = start.Add(seconds(float64(n) / b.rate))
+	return start, true
+}
+
+func (b *leakyBucket) cancel(now time.Time, n int, at time.Time) {
+	// 速率为0时无法换算归还的时长，与reserve一样不改变队列
+	if b.rate <= 0 {
+		return
+	}
+	b.tat = b.tat.Add(-seconds(float64(n) / b.rate))
+	if b.tat.Before(now) {
+		b.tat = now
+	}
+}
+
+func (b *leakyBucket) setRate(now time.Time, rate float64) {
+	b.rate = rate
+}
+
+func (b *leakyBucket) setBurst(now time.Time, burst int) {
+	b.size = burst
+}
+
+func (b *leakyBucket) burst() int {
+	return b.size
+}
+
+func (b *leakyBucket) status(now time.Time) (int, time.Duration) {
+	if !b.tat.After(now) {
+		return b.size, 0
+	}
+	// Allow只在队列为空时成功，排队中时没有可立即通过的额度
+	return 0, b.tat.Sub(now)
+}
+
+// ==================== 窗口算法 ====================
+
+// 窗口算法的突发容量即每个窗口的事件上限；
+// SetRate保持窗口长度不变，将上限改为 rate*window
+
+// windowLimit 根据速率计算窗口上限，至少为1
+func windowLimit(rate float64, window time.Duration) int {
+	return max(1, int(rate*window.Seconds()))
+}
+
+// checkWindow 窗口算法按窗口长度计算窗口序号，window必须大于0
+func checkWindow(name string, window time.Duration) {
+	if window <= 0 {
+		panic(fmt.Sprintf("ratelimit: %s 的window必须大于0，实际为 %v", name, window))
+	}
+}
+
+// NewFixedWindow 固定窗口：每个长度为window的窗口内最多limit个事件，窗口边界处可能出现两倍突发；
+// window不大于0时panic
+func NewFixedWindow(limit int, window time.Duration, opts ...Option) Limiter {
+	checkWindow("NewFixedWindow", window)
+	return newLimiter(&fixedWindow{limit: limit, window: window, counts: make(map[int64]int)}, opts)
+}
+
+type fixedWindow struct {
+	limit  int
+	window time.Duration
+	counts map[int64]int // 窗口序号 -> 已预留的事件数，包括未来的窗口
+}
+
+func (w *fixedWindow) index(t time.Time) int64 {
+	return t.UnixNano() / int64(w.window)
+}
+
+func (w *fixedWindow) start(i int64) time.Time {
+	return time.Unix(0, i*int64(w.window))
+}
+
+func (w *fixedWindow) reserve(now time.Time, n int, maxWait time.Duration) (time.Time, bool) {
+	if n > w.limit {
+		return time.Time{}, false
+	}
+	current := w.index(now)
+	for i := range w.counts {
+		if i < current {
+			delete(w.counts, i)
+		}
+	}
+	// 找到第一个还有余量的窗口，未来的窗口只会被依次占满，循环次数有限
+	for i := current; ; i++ {
+		if w.counts[i]+n > w.limit {
+			continue
+		}
+		at := now
+		if i > current {
+			at = w.start(i)
+		}
+		if at.Sub(now) > maxWait {
+			return time.Time{}, false
+		}
+		w.counts[i] += n
+		return at, true
+	}
+}
+
+func (w *fixedWindow) cancel(now time.Time, n int, at time.Time) {
+	i := w.index(at)
+	if w.counts[i] -= n; w.counts[i] <= 0 {
+		delete(w.counts, i)
+	}
+}
+
+func (w *fixedWindow) setRate(now time.Time, rate float64) {
+	w.limit = windowLimit(rate, w.window)
+}
+
+func (w *fixedWindow) setBurst(now time.Time, burst int) {
+	w.limit = burst
+}
+
+func (w *fixedWindow) burst() int {
+	return w.limit
+}
+
+func (w *fixedWindow) status(now time.Time) (int, time.Duration) {
+	i := w.index(now)
+	if w.counts[i] == 0 {
+		return w.limit, 0
+	}
+	return w.limit - w.counts[i], w.start(i + 1).Sub(now)
+}
+
+// NewSlidingWindowLog 滑动窗口日志：记录每个事件的时间，任意长度为window的区间内最多limit个事件；
+// 精确但内存与limit成正比
+func NewSlidingWindowLog(limit int, window time.Duration, opts ...Option) Limiter {
+	return newLimiter(&slidingLog{limit: limit, window: window}, opts)
+}
+
+type slidingLog struct {
+	limit  int
+	window time.Duration
+	log    []time.Time // 按时间排序，包括未来的预留
+}
+
+func (w *slidingLog) reserve(now time.Time, n int, maxWait time.Duration) (time.Time, bool) {
+	if n > w.limit {
+		return time.Time{}, false
+	}
+	// 清理已经滑出窗口的记录
+	expired := sort.Search(len(w.log), func(i int) bool {
+		return w.log[i].Add(w.window).After(now)
+	})
+	w.log = w.log[expired:]
+
+	at := now
+	if over := len(w.log) + n - w.limit; over > 0 {
+		// 需要最早的over个记录滑出窗口
+		at = w.log[over-1].Add(w.window)
+	}
+	// 按先来后到排在已有预留之后，保证之后的每个窗口都不超过上限
+	if last := len(w.log) - 1; last >= 0 && w.log[last].After(at) {
+		at = w.log[last]
+	}
+	if at.Sub(now) > maxWait {
+		return time.Time{}, false
+	}
+	for range n {
+		w.log = append(w.log, at)
+	}
+	return at, true
+}
+
+func (w *slidingLog) cancel(now time.Time, n int, at time.Time) {
+	for i := len(w.log) - 1; i >= 0 && n > 0; i-- {
+		if w.log[i].Equal(at) {
+			w.log = append(w.log[:i], w.log[i+1:]...)
+			n--
+		}
+	}
+}
+
+func (w *slidingLog) setRate(now time.Time, rate float64) {
+	w.limit = windowLimit(rate, w.window)
+}
+
+func (w *slidingLog) setBurst(now time.Time, burst int) {
+	w.limit = burst
+}
+
+func (w *slidingLog) burst() int {
+	return w.limit
+}
+
+func (w *slidingLog) status(now time.Time) (int, time.Duration) {
+	// 未滑出窗口的记录（包括未来的预留）都占用额度
+	active := 0
+	for _, t := range w.log {
+		if t.Add(w.window).After(now) {
+			active++
+		}
+	}
+	if active == 0 {
+		return w.limit, 0
+	}
+	return w.limit - active, w.log[len(w.log)-1].Add(w.window).Sub(now)
+}
+
+// NewSlidingWindowCounter 滑动窗口计数器：用上一个窗口的计数按重叠比例加权估算当前窗口，
+// 内存固定，是固定窗口和滑动日志之间的折中；window不大于0时panic
+func NewSlidingWindowCounter(limit int, window time.Duration, opts ...Option) Limiter {
+	checkWindow("NewSlidingWindowCounter", window)
+	return newLimiter(&slidingCounter{fixedWindow{limit: limit, window: window, counts: make(map[int64]int)}}, opts)
+}
+
+type slidingCounter struct {
+	fixedWindow
+}
+
+func (w *slidingCounter) reserve(now time.Time, n int, maxWait time.Duration) (time.Time, bool) {
+	if n > w.limit {
+		return time.Time{}, false
+	}
+	current := w.index(now)
+	for i := range w.counts {
+		if i < current-1 {
+			delete(w.counts, i)
+		}
+	}
+
+	for i := current; ; i++ {
+		count := w.counts[i]
+		if count+n > w.limit {
+			continue
+		}
+		// 估算值 prev*(1-f) + count，f为在窗口i中经过的比例
+		f := 0.0
+		if i == current {
+			f = float64(now.Sub(w.start(i))) / float64(w.window)
+		}
+		prev := float64(w.counts[i-1])
+		room := float64(w.limit - count - n)
+		if prev*(1-f) > room {
+			f = 1 - room/prev
+		}
+		if f >= 1 {
+			continue
+		}
+		at := w.start(i).Add(time.Duration(f * float64(w.window)))
+		if at.Before(now) {
+			at = now
+		}
+		if at.Sub(now) > maxWait {
+			return time.Time{}, false
+		}
+		w.counts[i] += n
+		return at, true
+	}
+}
+
+func (w *slidingCounter) status(now time.Time) (int, time.Duration) {
+	i := w.index(now)
+	f := float64(now.Sub(w.start(i))) / float64(w.window)
+	estimated := float64(w.counts[i-1])*(1-f) + float64(w.counts[i])
+	var reset time.Duration
+	switch {
+	case w.counts[i] > 0:
+		// 当前窗口的计数在下一个窗口结束时完全滑出
+		reset = w.start(i + 2).Sub(now)
+	case w.counts[i-1] > 0:
+		reset = w.start(i + 1).Sub(now)
+	}
+	return w.limit - int(math.Ceil(estimated)), reset
+}
@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Registry 按key（用户、租户、IP等）惰性创建限流器，
+// 超过容量时淘汰最久未使用的key，空闲超过TTL的key在访问时顺带淘汰，不启动后台goroutine
+//
+// 被淘汰的key再次出现时会得到一个新的限流器，因此TTL应大于限流器补满额度所需的时间
+type Registry struct {
+	mu         sync.Mutex
+	newLimiter func(key string) Limiter
+	maxKeys    int
+	idleTTL    time.Duration
+	now        func() time.Time
+	items      map[string]*list.Element
+	lru        *list.List // 队首为最近使用
+	onEvict    func(key string)
+}
+
+// registryEntry LRU链表中的条目
+type registryEntry struct {
+	key      string
+	limiter  Limiter
+	lastUsed time.Time
+}
+
+// RegistryOption Registry选项
+type RegistryOption func(*Registry)
+
+// WithMaxKeys 最多保留的key数量，默认10000，<=0表示不限制
+func WithMaxKeys(n int) RegistryOption {
+	return func(r *Registry) {
+		r.maxKeys = n
+	}
+}
+
+// WithIdleTTL 空闲超过ttl的key被淘汰，默认10分钟，<=0表示不按时间淘汰
+func WithIdleTTL(ttl time.Duration) RegistryOption {
+	return func(r *Registry) {
+		r.idleTTL = ttl
+	}
+}
+
+// WithRegistryClock 替换时钟，用于测试
+func WithRegistryClock(now func() time.Time) RegistryOption {
+	return func(r *Registry) {
+		r.now = now
+	}
+}
+
+// WithEvictCallback key被淘汰时回调，调用时持有Registry的锁，回调中不能再访问Registry
+func WithEvictCallback(fn func(key string)) RegistryOption {
+	return func(r *Registry) {
+		r.onEvict = fn
+	}
+}
+
+// NewRegistry 创建按key限流的注册表，newLimiter在key第一次出现时调用
+func NewRegistry(newLimiter func(key string) Limiter, opts ...RegistryOption) *Registry {
+	r := &Registry{
+		newLimiter: newLimiter,
+		maxKeys:    10000,
+		idleTTL:    10 * time.Minute,
+		now:        time.Now,
+		items:      make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Get 返回key对应的限流器，不存在时创建
+func (r *Registry) Get(key string) Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.evictIdle(now)
+	if elem, ok := r.items[key]; ok {
+		entry := elem.Value.(*registryEntry)
+		entry.lastUsed = now
+		r.lru.MoveToFront(elem)
+		return entry.limiter
+	}
+
+	entry := &registryEntry{key: key, limiter: r.newLimiter(key), lastUsed: now}
+	r.items[key] = r.lru.PushFront(entry)
+	for r.maxKeys > 0 && r.lru.Len() > r.maxKeys {
+		r.remove(r.lru.Back())
+	}
+	return entry.limiter
+}
+
+// Allow 等价于 Get(key).Allow()
+func (r *Registry) Allow(key string) bool {
+	return r.Get(key).Allow()
+}
+
+// Remove 删除key，下次访问时重新创建限流器
+func (r *Registry) Remove(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if elem, ok := r.items[key]; ok {
+		r.remove(elem)
+	}
+}
+
+// Len 当前保留的key数量
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lru.Len()
+}
+
+// Sweep 立即淘汰所有空闲超时的key，长时间没有访问时可由调用方定期调用以释放内存
+func (r *Registry) Sweep() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.evictIdle(r.now())
+}
+
+// evictIdle 从链表尾部淘汰空闲超时的key，尾部最久未使用，遇到未超时的即可停止
+func (r *Registry) evictIdle(now time.Time) {
+	if r.idleTTL <= 0 {
+		return
+	}
+	for elem := r.lru.Back(); elem != nil; elem = r.lru.Back() {
+		if now.Sub(elem.Value.(*registryEntry).lastUsed) < r.idleTTL {
+			return
+		}
+		r.remove(elem)
+	}
+}
+
+func (r *Registry) remove(elem *list.Element) {
+	entry := r.lru.Remove(elem).(*registryEntry)
+	delete(r.items, entry.key)
+	if r.onEvict != nil {
+		r.onEvict(entry.key)
+	}
+}
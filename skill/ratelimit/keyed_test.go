@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kuihuar/ai/skill/internal/testutil"
+)
+
+func TestRegistryEviction(t *testing.T) {
+	clock := testutil.NewClock()
+	var created, evicted []string
+	r := NewRegistry(func(key string) Limiter {
+		created = append(created, key)
+		return NewTokenBucket(1, 1, WithClock(clock.Now))
+	}, WithMaxKeys(2), WithIdleTTL(time.Minute), WithRegistryClock(clock.Now), WithEvictCallback(func(key string) {
+		evicted = append(evicted, key)
+	}))
+
+	if !r.Allow("a") || r.Allow("a") {
+		t.Error("per-key limiter not reused")
+	}
+	r.Get("b")
+	r.Get("a") // a最近使用，超出容量时淘汰b
+	r.Get("c")
+	if r.Len() != 2 || fmt.Sprint(evicted) != "[b]" {
+		t.Errorf("len = %d, evicted = %v", r.Len(), evicted)
+	}
+
+	// 空闲超时的key在下次访问时淘汰
+	clock.Advance(30 * time.Second)
+	r.Get("c")
+	clock.Advance(45 * time.Second)
+	r.Get("d")
+	if fmt.Sprint(evicted) != "[b a]" || r.Len() != 2 {
+		t.Errorf("len = %d, evicted = %v", r.Len(), evicted)
+	}
+	clock.Advance(2 * time.Minute)
+	r.Sweep()
+	if r.Len() != 0 {
+		t.Errorf("len after sweep = %d", r.Len())
+	}
+	if fmt.Sprint(created) != "[a b c d]" {
+		t.Errorf("created = %v", created)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	clock := testutil.NewClock()
+	perIP := NewRegistry(func(string) Limiter { return NewTokenBucket(1, 3, WithClock(clock.Now)) }, WithRegistryClock(clock.Now))
+	perUser := NewRegistry(func(string) Limiter { return NewFixedWindow(1, time.Minute, WithClock(clock.Now)) }, WithRegistryClock(clock.Now))
+	handler := RateLimitMiddleware(
+		Policy{Name: "ip", Key: KeyByIP, Registry: perIP},
+		Policy{Name: "user-write", Match: PathPrefix("/orders", http.MethodPost), Key: KeyByHeader("X-User-ID"), Registry: perUser},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	do := func(method, path, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		if user != "" {
+			req.Header.Set("X-User-ID", user)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/orders", "u1")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Policy") != "user-write" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("first write: %d %v", rec.Code, rec.Header())
+	}
+
+	// 用户策略拒绝时返回429，并归还IP策略已预留的令牌
+	rec = do(http.MethodPost, "/orders", "u1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("second write: %d %v", rec.Code, rec.Header())
+	}
+	if got := perIP.Get("10.0.0.1").(StatusReporter).Status().Remaining; got != 2 {
+		t.Errorf("ip remaining = %d, want 2", got)
+	}
+
+	// 读请求不匹配用户策略，只受IP策略限制
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		rec := do(http.MethodGet, "/orders", "u1")
+		if rec.Code != want {
+			t.Errorf("read %d: code = %d, want %d", i, rec.Code, want)
+		}
+	}
+	if rec := do(http.MethodGet, "/orders", ""); rec.Header().Get("Retry-After") != "1" || rec.Header().Get("RateLimit-Limit") != "3" {
+		t.Errorf("headers = %v", rec.Header())
+	}
+
+	clock.Advance(time.Second)
+	if rec := do(http.MethodGet, "/", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("after refill: code = %d", rec.Code)
+	}
+}
+
+func TestRateLimitMiddlewareQueueFull(t *testing.T) {
+	// 漏桶队列已满时预留失败，Retry-After取队列排空所需的时间
+	clock := testutil.NewClock()
+	registry := NewRegistry(func(string) Limiter { return NewLeakyBucket(0.5, 1, WithClock(clock.Now)) }, WithRegistryClock(clock.Now))
+	handler := RateLimitMiddleware(Policy{Name: "ip", Key: KeyByIP, Registry: registry})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(); rec.Code != http.StatusNoContent {
+		t.Fatalf("first: code = %d", rec.Code)
+	}
+	if res := registry.Get("10.0.0.1").Reserve(); res.OK() {
+		t.Fatalf("queue should be full, delay = %v", res.Delay())
+	}
+	rec := do()
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("queue full: %d Retry-After=%q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
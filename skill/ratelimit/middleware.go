@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Middleware HTTP中间件，与 closure.LoggingMiddleware 的形式相同
+type Middleware func(http.Handler) http.Handler
+
+// KeyFunc 从请求中提取限流key，返回空字符串时该策略不限制这个请求
+type KeyFunc func(r *http.Request) string
+
+// Policy 限流策略
+type Policy struct {
+	Name     string                   // 策略名，出现在 RateLimit-Policy 响应头
+	Match    func(*http.Request) bool // 策略适用的请求，nil表示所有请求
+	Key      KeyFunc                  // 限流维度，如按IP、用户或租户
+	Registry *Registry                // 每个key的限流器
+}
+
+// KeyByIP 按客户端IP限流，使用连接的远端地址；在反向代理之后应使用KeyByHeader读取代理设置的头
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader 按请求头限流，如 X-User-ID、X-Tenant-ID
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// PathPrefix 匹配路径前缀，可选限定请求方法
+func PathPrefix(prefix string, methods ...string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			return false
+		}
+		if len(methods) == 0 {
+			return true
+		}
+		for _, m := range methods {
+			if r.Method == m {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RateLimitMiddleware 按策略限流，一个请求可以同时匹配多个策略（如全局按IP、写接口按用户），
+// 任一策略拒绝时返回429并归还其他策略已预留的额度
+//
+// 响应头:
+//   - RateLimit-Limit / RateLimit-Remaining / RateLimit-Reset: 剩余额度最少的策略的状态
+//   - RateLimit-Policy: 该策略的名称
+//   - Retry-After: 被拒绝时需要等待的秒数，预留失败（如队列已满）时取额度恢复所需的时间
+func RateLimitMiddleware(policies ...Policy) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var reservations []*Reservation
+			var retryAfter time.Duration
+			var tightest *Status
+			var tightestName, rejectedBy string
+
+			for _, p := range policies {
+				if p.Match != nil && !p.Match(r) {
+					continue
+				}
+				key := p.Key(r)
+				if key == "" {
+					continue
+				}
+				limiter := p.Registry.Get(key)
+				res := limiter.Reserve()
+				delay := res.Delay()
+				if !res.OK() || delay > 0 {
+					res.Cancel()
+					if sr, ok := limiter.(StatusReporter); ok && delay == InfDuration {
+						// 预留失败（如漏桶队列已满）时没有等待时间，改用额度恢复所需的时间
+						if reset := sr.Status().Reset; reset > 0 {
+							delay = reset
+						}
+					}
+					if delay > retryAfter {
+						retryAfter, rejectedBy = delay, p.Name
+					}
+				} else {
+					reservations = append(reservations, res)
+				}
+				if sr, ok := limiter.(StatusReporter); ok {
+					if status := sr.Status(); tightest == nil || status.Remaining < tightest.Remaining {
+						tightest, tightestName = &status, p.Name
+					}
+				}
+			}
+
+			if tightest != nil {
+				w.Header().Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+				w.Header().Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+				w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(tightest.Reset)))
+				if tightestName != "" {
+					w.Header().Set("RateLimit-Policy", tightestName)
+				}
+			}
+			if retryAfter > 0 {
+				for _, res := range reservations {
+					res.cancel(true)
+				}
+				if retryAfter != InfDuration {
+					w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+				}
+				message := "Too Many Requests"
+				if rejectedBy != "" {
+					message = fmt.Sprintf("%s (%s)", message, rejectedBy)
+				}
+				http.Error(w, message, http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds 向上取整到秒，至少为1秒，避免客户端立即重试
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return max(1, int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,245 @@
+// Package ratelimit 限流器：令牌桶、漏桶、固定窗口、滑动窗口日志和滑动窗口计数器
+//
+// 所有算法都在调用时根据时钟惰性计算可用额度，不启动ticker goroutine，
+// 空闲的限流器不占用任何后台资源。
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrLimitExceeded 请求的数量超过容量，或等待时间超过context的截止时间
+var ErrLimitExceeded = errors.New("ratelimit: 超出限流器容量")
+
+// InfDuration 不限制等待时间
+const InfDuration = time.Duration(math.MaxInt64)
+
+// Limiter 限流器
+type Limiter interface {
+	// Allow 等价于 AllowN(1)
+	Allow() bool
+	// AllowN 现在是否允许n个事件，允许时立即消耗额度
+	AllowN(n int) bool
+	// Wait 等价于 WaitN(ctx, 1)
+	Wait(ctx context.Context) error
+	// WaitN 阻塞直到允许n个事件；n超过容量或等待会超过ctx截止时间时立即返回错误
+	WaitN(ctx context.Context, n int) error
+	// Reserve 等价于 ReserveN(1)
+	Reserve() *Reservation
+	// ReserveN 预留n个事件的额度，调用方需等待Delay()后再执行，不执行时调用Cancel归还
+	ReserveN(n int) *Reservation
+	// SetRate 运行时修改速率（每秒事件数）
+	SetRate(rate float64)
+	// SetBurst 运行时修改突发容量
+	SetBurst(burst int)
+}
+
+// Every 将事件间隔转换为速率，如 Every(100*time.Millisecond) 为每秒10个
+func Every(interval time.Duration) float64 {
+	if interval <= 0 {
+		return math.Inf(1)
+	}
+	return 1 / interval.Seconds()
+}
+
+// algorithm 限流算法，调用方持有锁
+type algorithm interface {
+	// reserve 预留n个事件，返回可以执行的时刻；等待超过maxWait时不预留
+	reserve(now time.Time, n int, maxWait time.Duration) (time.Time, bool)
+	// cancel 归还尚未到期的预留
+	cancel(now time.Time, n int, at time.Time)
+	setRate(now time.Time, rate float64)
+	setBurst(now time.Time, burst int)
+	// burst 单次请求允许的最大数量
+	burst() int
+	// status 当前剩余额度和恢复到满额度所需的时间
+	status(now time.Time) (remaining int, reset time.Duration)
+}
+
+// Status 限流器的当前状态，用于生成 RateLimit-* 响应头
+type Status struct {
+	Limit     int           // 突发容量或每窗口上限
+	Remaining int           // 现在还能立即通过的事件数
+	Reset     time.Duration // 额度恢复到Limit还需的时间
+}
+
+// StatusReporter 能报告当前状态的限流器，本包的所有算法都实现了该接口
+type StatusReporter interface {
+	Status() Status
+}
+
+// Option 限流器选项
+type Option func(*limiter)
+
+// WithClock 替换时钟，用于测试
+func WithClock(now func() time.Time) Option {
+	return func(l *limiter) {
+		l.now = now
+	}
+}
+
+// limiter 在算法外层实现Limiter接口的公共部分
+type limiter struct {
+	mu  sync.Mutex
+	alg algorithm
+	now func() time.Time
+}
+
+func newLimiter(alg algorithm, opts []Option) *limiter {
+	l := &limiter{alg: alg, now: time.Now}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *limiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+func (l *limiter) AllowN(n int) bool {
+	return l.reserveN(l.now(), n, 0).ok
+}
+
+func (l *limiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+func (l *limiter) WaitN(ctx context.Context, n int) error {
+	l.mu.Lock()
+	burst := l.alg.burst()
+	l.mu.Unlock()
+	if n > burst {
+		return fmt.Errorf("%w: n=%d, burst=%d", ErrLimitExceeded, n, burst)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := l.now()
+	maxWait := InfDuration
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = deadline.Sub(now)
+	}
+	r := l.reserveN(now, n, maxWait)
+	if !r.ok {
+		return fmt.Errorf("%w: 等待 %d 个事件的额度会超过截止时间", ErrLimitExceeded, n)
+	}
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) Reserve() *Reservation {
+	return l.ReserveN(1)
+}
+
+func (l *limiter) ReserveN(n int) *Reservation {
+	return l.reserveN(l.now(), n, InfDuration)
+}
+
+func (l *limiter) reserveN(now time.Time, n int, maxWait time.Duration) *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n <= 0 {
+		return &Reservation{ok: true, lim: l, at: now}
+	}
+	at, ok := l.alg.reserve(now, n, maxWait)
+	return &Reservation{ok: ok, lim: l, n: n, at: at}
+}
+
+func (l *limiter) SetRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.alg.setRate(l.now(), rate)
+}
+
+func (l *limiter) SetBurst(burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.alg.setBurst(l.now(), burst)
+}
+
+func (l *limiter) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	remaining, reset := l.alg.status(l.now())
+	return Status{Limit: l.alg.burst(), Remaining: max(0, remaining), Reset: reset}
+}
+
+// Reservation 一次预留
+type Reservation struct {
+	ok       bool
+	lim      *limiter
+	n        int
+	at       time.Time
+	canceled bool
+}
+
+// OK 是否预留成功，n超过容量或漏桶队列已满时为false
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay 距离可以执行还需等待的时间
+func (r *Reservation) Delay() time.Duration {
+	return r.DelayFrom(r.lim.now())
+}
+
+// DelayFrom 从now开始还需等待的时间，预留失败时返回InfDuration
+func (r *Reservation) DelayFrom(now time.Time) time.Duration {
+	if !r.ok {
+		return InfDuration
+	}
+	if d := r.at.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel 放弃预留并尽量归还额度，已经到期的预留不归还
+func (r *Reservation) Cancel() {
+	r.cancel(false)
+}
+
+// cancel 归还额度，force为true时即使已经到期也归还，用于同一请求中撤销刚刚做出的预留
+func (r *Reservation) cancel(force bool) {
+	if !r.ok || r.n == 0 {
+		return
+	}
+	r.lim.mu.Lock()
+	defer r.lim.mu.Unlock()
+	if r.canceled {
+		return
+	}
+	r.canceled = true
+	now := r.lim.now()
+	if force || r.at.After(now) {
+		r.lim.alg.cancel(now, r.n, r.at)
+	}
+}
+
+// seconds 将秒数转换为时间间隔，溢出时返回InfDuration
+func seconds(s float64) time.Duration {
+	d := s * float64(time.Second)
+	if d >= float64(InfDuration) {
+		return InfDuration
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,212 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kuihuar/ai/skill/internal/testutil"
+)
+
+// allowed 返回连续调用Allow的结果，每次调用后推进step
+func allowed(l Limiter, clock *testutil.Clock, calls int, step time.Duration) []bool {
+	var got []bool
+	for range calls {
+		got = append(got, l.Allow())
+		clock.Advance(step)
+	}
+	return got
+}
+
+func count(results []bool) int {
+	n := 0
+	for _, ok := range results {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+func TestAlgorithms(t *testing.T) {
+	tests := []struct {
+		name  string
+		new   func(clock *testutil.Clock) Limiter
+		calls int
+		step  time.Duration
+		want  int
+	}{
+		// 10/s，突发5：先用完5个，之后每100ms补充1个
+		{"token bucket burst", func(c *testutil.Clock) Limiter { return NewTokenBucket(10, 5, WithClock(c.Now)) }, 20, 10 * time.Millisecond, 6},
+		{"token bucket steady", func(c *testutil.Clock) Limiter { return NewTokenBucket(10, 5, WithClock(c.Now)) }, 20, 100 * time.Millisecond, 20},
+		// 漏桶没有突发，每100ms只放行1个
+		{"leaky bucket", func(c *testutil.Clock) Limiter { return NewLeakyBucket(10, 5, WithClock(c.Now)) }, 20, 10 * time.Millisecond, 2},
+		{"fixed window", func(c *testutil.Clock) Limiter { return NewFixedWindow(5, time.Second, WithClock(c.Now)) }, 30, 100 * time.Millisecond, 15},
+		{"sliding log", func(c *testutil.Clock) Limiter { return NewSlidingWindowLog(5, time.Second, WithClock(c.Now)) }, 30, 100 * time.Millisecond, 15},
+		// 上一个窗口用满时，按重叠比例估算，后续窗口各只放行4个
+		{"sliding counter", func(c *testutil.Clock) Limiter { return NewSlidingWindowCounter(5, time.Second, WithClock(c.Now)) }, 30, 100 * time.Millisecond, 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := testutil.NewClock()
+			if got := count(allowed(tt.new(clock), clock, tt.calls, tt.step)); got != tt.want {
+				t.Errorf("allowed = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowBoundary(t *testing.T) {
+	// 固定窗口在边界两侧各放行一次上限，滑动窗口不会
+	clock := testutil.NewClock()
+	clock.Advance(900 * time.Millisecond)
+	fixed := NewFixedWindow(5, time.Second, WithClock(clock.Now))
+	log := NewSlidingWindowLog(5, time.Second, WithClock(clock.Now))
+	counter := NewSlidingWindowCounter(5, time.Second, WithClock(clock.Now))
+	var results [3][]bool
+	for range 2 {
+		for range 5 {
+			results[0] = append(results[0], fixed.Allow())
+			results[1] = append(results[1], log.Allow())
+			results[2] = append(results[2], counter.Allow())
+		}
+		clock.Advance(200 * time.Millisecond)
+	}
+	if got := []int{count(results[0]), count(results[1]), count(results[2])}; got[0] != 10 || got[1] != 5 || got[2] != 5 {
+		t.Errorf("fixed/log/counter = %v, want [10 5 5]", got)
+	}
+}
+
+func TestWindowMustBePositive(t *testing.T) {
+	for name, newLimiter := range map[string]func(int, time.Duration, ...Option) Limiter{
+		"fixed":   NewFixedWindow,
+		"counter": NewSlidingWindowCounter,
+	} {
+		for _, window := range []time.Duration{0, -time.Second} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("%s window=%v: 没有panic", name, window)
+					}
+				}()
+				newLimiter(5, window)
+			}()
+		}
+	}
+}
+
+func TestReserve(t *testing.T) {
+	clock := testutil.NewClock()
+	l := NewTokenBucket(10, 2, WithClock(clock.Now))
+	l.AllowN(2)
+
+	r := l.ReserveN(2)
+	if !r.OK() || r.Delay() != 200*time.Millisecond {
+		t.Fatalf("delay = %v", r.Delay())
+	}
+	// 取消后归还额度
+	r.Cancel()
+	clock.Advance(100 * time.Millisecond)
+	if !l.Allow() || l.Allow() {
+		t.Error("tokens not returned after cancel")
+	}
+
+	if r := l.ReserveN(3); r.OK() {
+		t.Error("reservation larger than burst should fail")
+	}
+
+	// 漏桶队列满时预留失败
+	leaky := NewLeakyBucket(10, 3, WithClock(clock.Now))
+	for i := range 3 {
+		if r := leaky.Reserve(); !r.OK() || r.Delay() != time.Duration(i)*100*time.Millisecond {
+			t.Errorf("reservation %d delay = %v", i, r.Delay())
+		}
+	}
+	if leaky.Reserve().OK() {
+		t.Error("leaky bucket queue should be full")
+	}
+
+	// 滑动日志的预留排在已有预留之后
+	log := NewSlidingWindowLog(2, time.Second, WithClock(clock.Now))
+	log.AllowN(2)
+	if r := log.Reserve(); r.Delay() != time.Second {
+		t.Errorf("sliding log delay = %v", r.Delay())
+	}
+}
+
+func TestSetRateAndBurst(t *testing.T) {
+	clock := testutil.NewClock()
+	l := NewTokenBucket(1, 1, WithClock(clock.Now))
+	l.Allow()
+	l.SetRate(10)
+	clock.Advance(100 * time.Millisecond)
+	if !l.Allow() {
+		t.Error("SetRate not applied")
+	}
+	l.SetBurst(3)
+	clock.Advance(time.Second)
+	if !l.AllowN(3) {
+		t.Error("SetBurst not applied")
+	}
+
+	// 速率降为0后取消预留不改变漏桶队列，恢复速率后按原队列继续排空
+	leaky := NewLeakyBucket(10, 2, WithClock(clock.Now))
+	leaky.Allow()
+	r := leaky.Reserve()
+	leaky.SetRate(0)
+	r.Cancel()
+	if leaky.Allow() {
+		t.Error("leaky bucket with zero rate should reject")
+	}
+	leaky.SetRate(10)
+	if leaky.Allow() {
+		t.Error("cancel at zero rate should not reset the leaky bucket queue")
+	}
+	clock.Advance(200 * time.Millisecond)
+	if !leaky.Allow() {
+		t.Error("leaky bucket should drain after rate is restored")
+	}
+
+	w := NewFixedWindow(1, time.Second, WithClock(clock.Now))
+	w.SetRate(3)
+	if !w.AllowN(3) || w.Allow() {
+		t.Error("fixed window SetRate should set limit to rate*window")
+	}
+}
+
+func TestWait(t *testing.T) {
+	l := NewTokenBucket(Every(20*time.Millisecond), 1)
+	start := time.Now()
+	for range 3 {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("3 waits took %v, want >= 40ms", elapsed)
+	}
+
+	if err := l.WaitN(context.Background(), 2); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("WaitN above burst err = %v", err)
+	}
+
+	// 截止时间之前拿不到额度时立即返回，不占用额度
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Wait with short deadline err = %v", err)
+	}
+
+	// 等待中取消时归还额度
+	slow := NewTokenBucket(Every(time.Hour), 1)
+	slow.Allow()
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := slow.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait canceled err = %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff 退避策略，根据重试次数和上一次的等待时间计算下一次等待时间
+//
+// attempt 从1开始，表示第几次重试；prev 为上一次的等待时间，第一次重试时为0
+type Backoff interface {
+	Next(attempt int, prev time.Duration) time.Duration
+}
+
+// BackoffFunc 函数形式的退避策略
+type BackoffFunc func(attempt int, prev time.Duration) time.Duration
+
+func (f BackoffFunc) Next(attempt int, prev time.Duration) time.Duration {
+	return f(attempt, prev)
+}
+
+// Constant 固定间隔
+func Constant(d time.Duration) Backoff {
+	return BackoffFunc(func(int, time.Duration) time.Duration {
+		return d
+	})
+}
+
+// Exponential 指数退避：base, 2*base, 4*base ... 不超过maxDelay
+func Exponential(base, maxDelay time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		d := base
+		for i := 1; i < attempt; i++ {
+			d *= 2
+			if d >= maxDelay || d <= 0 {
+				return maxDelay
+			}
+		}
+		return min(d, maxDelay)
+	})
+}
+
+// DecorrelatedJitter 去相关抖动：在 [base, 3*prev] 之间随机，不超过maxDelay
+//
+// 多个客户端同时失败时，等待时间相互错开，避免一起重试形成新的流量尖峰
+func DecorrelatedJitter(base, maxDelay time.Duration) Backoff {
+	return BackoffFunc(func(_ int, prev time.Duration) time.Duration {
+		upper := max(prev*3, base)
+		d := base
+		if upper > base {
+			d += rand.N(upper - base)
+		}
+		return min(d, maxDelay)
+	})
+}
@@ -0,0 +1,54 @@
+package retry
+
+import "sync"
+
+// Budget 重试预算，多个调用方共享，限制依赖整体故障时的重试总量
+//
+// 采用令牌方式（与gRPC的重试限流相同）：初始为满额，每次失败扣1个令牌，
+// 每次成功补回ratio个令牌；令牌低于一半时不再重试，只执行第一次请求。
+// 依赖恢复后成功请求会逐渐补回令牌，重试自动恢复。
+type Budget struct {
+	mu        sync.Mutex
+	maxTokens float64
+	ratio     float64
+	tokens    float64
+}
+
+// NewBudget 创建重试预算，maxTokens为令牌上限，ratio为每次成功补回的令牌数
+//
+// 例如 NewBudget(100, 0.1)：持续失败时大约50次失败后停止重试，之后每10次成功恢复1个令牌
+func NewBudget(maxTokens, ratio float64) *Budget {
+	if maxTokens <= 0 {
+		maxTokens = 100
+	}
+	if ratio <= 0 {
+		ratio = 0.1
+	}
+	return &Budget{maxTokens: maxTokens, ratio: ratio, tokens: maxTokens}
+}
+
+// Tokens 当前令牌数
+func (b *Budget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+// allowRetry 令牌高于一半时允许重试
+func (b *Budget) allowRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens > b.maxTokens/2
+}
+
+func (b *Budget) onSuccess() {
+	b.mu.Lock()
+	b.tokens = min(b.tokens+b.ratio, b.maxTokens)
+	b.mu.Unlock()
+}
+
+func (b *Budget) onFailure() {
+	b.mu.Lock()
+	b.tokens = max(b.tokens-1, 0)
+	b.mu.Unlock()
+}
@@ -0,0 +1,205 @@
+// Package retry 带退避的重试：可配置退避策略、最大次数和总时长，感知context，
+// 区分可重试错误，遵循错误携带的 RetryAfter 提示，并可用共享的重试预算避免重试风暴
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrBudgetExhausted 重试预算耗尽，放弃重试
+	ErrBudgetExhausted = errors.New("retry: 重试预算已耗尽")
+)
+
+// config 重试配置
+type config struct {
+	maxAttempts int
+	maxElapsed  time.Duration
+	backoff     Backoff
+	retryable   func(error) bool
+	budget      *Budget
+	onRetry     func(attempt int, err error, delay time.Duration)
+	now         func() time.Time
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+// Option 重试选项
+type Option func(*config)
+
+// Attempts 最多执行次数（包括第一次），默认3，<=0表示不限次数，此时应配合MaxElapsed或context使用
+func Attempts(n int) Option {
+	return func(c *config) { c.maxAttempts = n }
+}
+
+// MaxElapsed 从第一次执行开始的最长总时长，下一次等待会超过时不再重试，默认不限
+func MaxElapsed(d time.Duration) Option {
+	return func(c *config) { c.maxElapsed = d }
+}
+
+// WithBackoff 退避策略，默认 Exponential(100ms, 10s)
+func WithBackoff(b Backoff) Option {
+	return func(c *config) { c.backoff = b }
+}
+
+// WithRetryable 判断错误是否可重试，默认除 context.Canceled 外都重试；Permanent 包装的错误总是不重试
+func WithRetryable(fn func(error) bool) Option {
+	return func(c *config) { c.retryable = fn }
+}
+
+// WithBudget 共享的重试预算，多个调用方使用同一个预算时，依赖整体故障会限制总的重试量
+func WithBudget(b *Budget) Option {
+	return func(c *config) { c.budget = b }
+}
+
+// OnRetry 每次重试等待前的回调，可以打日志或上报指标
+func OnRetry(fn func(attempt int, err error, delay time.Duration)) Option {
+	return func(c *config) { c.onRetry = fn }
+}
+
+// Retrier 重试器，创建后可在多个goroutine中复用
+type Retrier struct {
+	cfg config
+}
+
+// New 创建重试器
+func New(opts ...Option) *Retrier {
+	cfg := config{
+		maxAttempts: 3,
+		backoff:     Exponential(100*time.Millisecond, 10*time.Second),
+		retryable: func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		},
+		now:   time.Now,
+		sleep: sleep,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Retrier{cfg: cfg}
+}
+
+// Do 执行fn直到成功、遇到不可重试的错误、次数或时长用完、预算耗尽或ctx结束
+//
+// 放弃时返回的错误包装了最后一次的错误，可以用 errors.Is/As 判断
+func (r *Retrier) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	c := r.cfg
+	start := c.now()
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if c.budget != nil {
+				c.budget.onSuccess()
+			}
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if !c.retryable(err) {
+			return err
+		}
+		// 只有可重试的失败说明依赖可能故障，扣减预算
+		if c.budget != nil {
+			c.budget.onFailure()
+		}
+		if c.maxAttempts > 0 && attempt >= c.maxAttempts {
+			return fmt.Errorf("retry: %d次尝试后失败: %w", attempt, err)
+		}
+
+		delay = c.backoff.Next(attempt, delay)
+		if hint, ok := retryAfterHint(err); ok {
+			delay = hint
+		}
+		if c.maxElapsed > 0 && c.now().Add(delay).Sub(start) > c.maxElapsed {
+			return fmt.Errorf("retry: 超过最长重试时间%v: %w", c.maxElapsed, err)
+		}
+		if c.budget != nil && !c.budget.allowRetry() {
+			return fmt.Errorf("%w: %w", ErrBudgetExhausted, err)
+		}
+		if c.onRetry != nil {
+			c.onRetry(attempt, err, delay)
+		}
+		if serr := c.sleep(ctx, delay); serr != nil {
+			return fmt.Errorf("retry: 等待重试时 %w, 上次错误: %w", serr, err)
+		}
+	}
+}
+
+// Do 带返回值的重试
+func Do[T any](ctx context.Context, r *Retrier, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := r.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+// permanentError 不可重试的错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记错误不可重试，Do 直接返回原错误
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// retryAfterError 携带重试等待时间提示的错误
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string             { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error             { return e.err }
+func (e *retryAfterError) RetryAfter() time.Duration { return e.after }
+
+// RetryAfter 为错误附加等待时间提示（如HTTP 429/503的Retry-After头），下一次重试用它代替退避策略的结果
+//
+// 实现了 RetryAfter() time.Duration 方法的错误类型同样生效
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, after: d}
+}
+
+// retryAfterHint 从错误链中取出等待时间提示
+func retryAfterHint(err error) (time.Duration, bool) {
+	var hinted interface{ RetryAfter() time.Duration }
+	if errors.As(err, &hinted) && hinted.RetryAfter() > 0 {
+		return hinted.RetryAfter(), true
+	}
+	return 0, false
+}
+
+// sleep 等待d，ctx结束时提前返回
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,164 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errTemporary = errors.New("temporary")
+
+// fakeSleep 记录等待时间并推进时钟，不真正等待
+type fakeSleep struct {
+	now    time.Time
+	delays []time.Duration
+}
+
+func (f *fakeSleep) option() Option {
+	f.now = time.Unix(1_700_000_000, 0)
+	return func(c *config) {
+		c.now = func() time.Time { return f.now }
+		c.sleep = func(ctx context.Context, d time.Duration) error {
+			f.delays = append(f.delays, d)
+			f.now = f.now.Add(d)
+			return ctx.Err()
+		}
+	}
+}
+
+// failing 前n次返回err，之后成功
+func failing(n int, err error) func(context.Context) error {
+	calls := 0
+	return func(context.Context) error {
+		calls++
+		if calls <= n {
+			return err
+		}
+		return nil
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	exp := Exponential(100*time.Millisecond, time.Second)
+	var got []time.Duration
+	for attempt := 1; attempt <= 6; attempt++ {
+		got = append(got, exp.Next(attempt, 0))
+	}
+	if fmt.Sprint(got) != "[100ms 200ms 400ms 800ms 1s 1s]" {
+		t.Errorf("exponential = %v", got)
+	}
+
+	jitter := DecorrelatedJitter(100*time.Millisecond, time.Second)
+	var prev time.Duration
+	for attempt := 1; attempt <= 50; attempt++ {
+		d := jitter.Next(attempt, prev)
+		if d < 100*time.Millisecond || d > time.Second || (prev > 0 && d > max(3*prev, 100*time.Millisecond)) {
+			t.Fatalf("attempt %d: delay %v out of range (prev %v)", attempt, d, prev)
+		}
+		prev = d
+	}
+}
+
+func TestDo(t *testing.T) {
+	fs := &fakeSleep{}
+	var retries []int
+	r := New(Attempts(4), WithBackoff(Exponential(10*time.Millisecond, time.Second)), fs.option(),
+		OnRetry(func(attempt int, err error, delay time.Duration) { retries = append(retries, attempt) }))
+
+	if err := r.Do(context.Background(), failing(2, errTemporary)); err != nil {
+		t.Fatalf("Do = %v", err)
+	}
+	if fmt.Sprint(fs.delays) != "[10ms 20ms]" || fmt.Sprint(retries) != "[1 2]" {
+		t.Errorf("delays = %v, retries = %v", fs.delays, retries)
+	}
+
+	err := r.Do(context.Background(), failing(10, errTemporary))
+	if !errors.Is(err, errTemporary) || len(fs.delays) != 5 {
+		t.Errorf("exhausted: err = %v, delays = %v", err, fs.delays)
+	}
+
+	// 不可重试的错误直接返回
+	calls := 0
+	err = r.Do(context.Background(), func(context.Context) error {
+		calls++
+		return Permanent(errTemporary)
+	})
+	if err != errTemporary || calls != 1 {
+		t.Errorf("permanent: err = %v, calls = %d", err, calls)
+	}
+	classified := New(WithRetryable(func(err error) bool { return !errors.Is(err, errTemporary) }), fs.option())
+	if err := classified.Do(context.Background(), failing(1, errTemporary)); err != errTemporary {
+		t.Errorf("classifier: err = %v", err)
+	}
+
+	v, err := Do(context.Background(), r, func(context.Context) (string, error) { return "ok", nil })
+	if v != "ok" || err != nil {
+		t.Errorf("generic Do = %q, %v", v, err)
+	}
+}
+
+func TestRetryAfterAndMaxElapsed(t *testing.T) {
+	fs := &fakeSleep{}
+	r := New(Attempts(0), MaxElapsed(5*time.Second), WithBackoff(Constant(time.Second)), fs.option())
+
+	err := r.Do(context.Background(), failing(1, RetryAfter(errTemporary, 3*time.Second)))
+	if err != nil || fmt.Sprint(fs.delays) != "[3s]" {
+		t.Errorf("retry-after: err = %v, delays = %v", err, fs.delays)
+	}
+
+	// 不限次数时由总时长限制：等待1s*5后再等待会超过5s
+	fs.delays = nil
+	err = r.Do(context.Background(), failing(100, errTemporary))
+	if !errors.Is(err, errTemporary) || len(fs.delays) != 5 {
+		t.Errorf("max elapsed: err = %v, delays = %v", err, fs.delays)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := New(Attempts(0), WithBackoff(Constant(time.Hour)))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := r.Do(ctx, failing(100, errTemporary))
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errTemporary) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	fs := &fakeSleep{}
+	budget := NewBudget(10, 1)
+	r := New(Attempts(3), WithBackoff(Constant(time.Millisecond)), WithBudget(budget), fs.option())
+
+	// 依赖持续失败：每次调用扣3个令牌，令牌不超过一半后只执行第一次
+	var attempts []int
+	for range 4 {
+		calls := 0
+		err := r.Do(context.Background(), func(context.Context) error {
+			calls++
+			return errTemporary
+		})
+		if !errors.Is(err, errTemporary) {
+			t.Fatalf("err = %v", err)
+		}
+		attempts = append(attempts, calls)
+	}
+	if fmt.Sprint(attempts) != "[3 2 1 1]" {
+		t.Errorf("attempts = %v, tokens = %v", attempts, budget.Tokens())
+	}
+	if err := r.Do(context.Background(), failing(1, errTemporary)); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("err = %v, want ErrBudgetExhausted", err)
+	}
+
+	// 成功请求补回令牌后恢复重试
+	for range 10 {
+		r.Do(context.Background(), failing(0, nil))
+	}
+	if budget.Tokens() != 10 {
+		t.Errorf("tokens = %v", budget.Tokens())
+	}
+}
@@ -0,0 +1,363 @@
+// Package scheduler 优先级任务调度器：按优先级和截止时间排序，等待时间长的任务逐步提升优先级避免饥饿，
+// 按类别限制并发，排队中的任务可以按ID取消，截止时间已过的任务不会执行
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCanceled 任务在排队时被Cancel取消
+	ErrCanceled = errors.New("scheduler: 任务已取消")
+	// ErrClosed 调度器已关闭
+	ErrClosed = errors.New("scheduler: 调度器已关闭")
+	// ErrDuplicateID 排队或执行中已有相同ID的任务
+	ErrDuplicateID = errors.New("scheduler: 任务ID重复")
+)
+
+// Task 调度的任务
+type Task struct {
+	ID       string    // 任务ID，用于取消，为空时自动生成
+	Class    string    // 类别，用于按类别限制并发，如 "report"、"email"
+	Priority int       // 优先级，越大越先执行
+	Deadline time.Time // 截止时间，为零时只使用Submit的ctx的截止时间
+	Run      func(ctx context.Context) error
+}
+
+// Option 调度器选项
+type Option func(*Scheduler)
+
+// WithClassLimit 限制某一类别同时执行的任务数
+func WithClassLimit(class string, n int) Option {
+	return func(s *Scheduler) { s.limits[class] = max(n, 1) }
+}
+
+// WithAging 每排队interval优先级相当于加1，避免低优先级任务饥饿，默认不提升
+func WithAging(interval time.Duration) Option {
+	return func(s *Scheduler) { s.aging = interval }
+}
+
+// Handle 已提交任务的句柄
+type Handle struct {
+	id   string
+	done chan struct{}
+	err  error
+}
+
+// ID 任务ID
+func (h *Handle) ID() string { return h.id }
+
+// Done 任务执行完成或被丢弃时关闭
+func (h *Handle) Done() <-chan struct{} { return h.done }
+
+// Wait 等待任务结束，返回任务的错误或被丢弃的原因
+func (h *Handle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// Err 任务结束后的错误，未结束时为nil
+func (h *Handle) Err() error {
+	select {
+	case <-h.done:
+		return h.err
+	default:
+		return nil
+	}
+}
+
+func (h *Handle) finish(err error) {
+	h.err = err
+	close(h.done)
+}
+
+// Scheduler 优先级任务调度器，并发安全
+type Scheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limits  map[string]int
+	aging   time.Duration
+	classes map[string]*class
+	tasks   map[string]*item // 排队和执行中的任务
+	seq     uint64
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// class 一个类别的排队任务和执行数
+type class struct {
+	queue   queue
+	running int
+}
+
+// New 创建调度器并启动workers个worker
+func New(workers int, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		limits:  make(map[string]int),
+		classes: make(map[string]*class),
+		tasks:   make(map[string]*item),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	for _, opt := range opts {
+		opt(s)
+	}
+	for range max(workers, 1) {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// Submit 提交任务
+//
+// 任务的截止时间取 t.Deadline 与 ctx 截止时间中较早的一个；开始执行前截止时间已过或ctx被取消的任务
+// 从队列中移除，Handle返回ctx的错误；执行时传给 t.Run 的ctx带有同样的截止时间
+func (s *Scheduler) Submit(ctx context.Context, t Task) (*Handle, error) {
+	if t.Run == nil {
+		return nil, errors.New("scheduler: 任务缺少Run")
+	}
+	cancel := context.CancelFunc(func() {})
+	if !t.Deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(ctx, t.Deadline)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		cancel()
+		return nil, ErrClosed
+	}
+	s.seq++
+	if t.ID == "" {
+		t.ID = fmt.Sprintf("task-%d", s.seq)
+	}
+	if _, ok := s.tasks[t.ID]; ok {
+		s.mu.Unlock()
+		cancel()
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateID, t.ID)
+	}
+	it := &item{
+		task:     t,
+		ctx:      ctx,
+		cancel:   cancel,
+		handle:   &Handle{id: t.ID, done: make(chan struct{})},
+		level:    s.level(t.Priority, time.Now()),
+		deadline: t.Deadline,
+		seq:      s.seq,
+	}
+	if d, ok := ctx.Deadline(); ok {
+		it.deadline = d
+	}
+	c := s.classes[t.Class]
+	if c == nil {
+		c = &class{}
+		s.classes[t.Class] = c
+	}
+	heap.Push(&c.queue, it)
+	s.tasks[t.ID] = it
+	// 排队时截止时间到达或ctx取消，立即移出队列
+	it.stopExpire = context.AfterFunc(ctx, func() {
+		s.remove(it, ctx.Err())
+	})
+	s.mu.Unlock()
+	s.cond.Signal()
+	return it.handle, nil
+}
+
+// Cancel 取消排队中的任务，任务已开始执行或不存在时返回false
+func (s *Scheduler) Cancel(id string) bool {
+	s.mu.Lock()
+	it := s.tasks[id]
+	s.mu.Unlock()
+	return it != nil && s.remove(it, ErrCanceled)
+}
+
+// Len 排队中的任务数
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, c := range s.classes {
+		n += c.queue.Len()
+	}
+	return n
+}
+
+// Close 停止调度，排队中的任务以ErrClosed结束，等待执行中的任务完成
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.wg.Wait()
+		return
+	}
+	s.closed = true
+	var dropped []*item
+	for _, c := range s.classes {
+		for c.queue.Len() > 0 {
+			it := heap.Pop(&c.queue).(*item)
+			delete(s.tasks, it.task.ID)
+			dropped = append(dropped, it)
+		}
+	}
+	s.mu.Unlock()
+	s.cond.Broadcast()
+	for _, it := range dropped {
+		it.drop(ErrClosed)
+	}
+	s.wg.Wait()
+}
+
+// remove 把排队中的任务移出队列并以err结束，任务已出队时返回false
+func (s *Scheduler) remove(it *item, err error) bool {
+	s.mu.Lock()
+	if s.tasks[it.task.ID] != it || it.index < 0 {
+		s.mu.Unlock()
+		return false
+	}
+	heap.Remove(&s.classes[it.task.Class].queue, it.index)
+	delete(s.tasks, it.task.ID)
+	s.mu.Unlock()
+	it.drop(err)
+	return true
+}
+
+// worker 循环取出可执行的最高优先级任务
+func (s *Scheduler) worker() {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		var it *item
+		var c *class
+		for {
+			if s.closed {
+				s.mu.Unlock()
+				return
+			}
+			if it, c = s.next(); it != nil {
+				break
+			}
+			s.cond.Wait()
+		}
+		if err := it.ctx.Err(); err != nil {
+			// 截止时间恰好在出队前到达，过期回调还没来得及移除
+			delete(s.tasks, it.task.ID)
+			s.mu.Unlock()
+			it.drop(err)
+			continue
+		}
+		c.running++
+		s.mu.Unlock()
+
+		it.stopExpire()
+		err := it.task.Run(it.ctx)
+		it.cancel()
+
+		s.mu.Lock()
+		c.running--
+		delete(s.tasks, it.task.ID)
+		s.mu.Unlock()
+		// 类别的并发名额释放后，可能有其他worker等待的任务可以执行
+		s.cond.Broadcast()
+		it.handle.finish(err)
+	}
+}
+
+// next 在未达到并发上限的类别中取出优先级最高的任务
+func (s *Scheduler) next() (*item, *class) {
+	var best *class
+	for name, c := range s.classes {
+		if c.queue.Len() == 0 {
+			continue
+		}
+		if limit, ok := s.limits[name]; ok && c.running >= limit {
+			continue
+		}
+		if best == nil || c.queue.less(c.queue[0], best.queue[0]) {
+			best = c
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return heap.Pop(&best.queue).(*item), best
+}
+
+// level 排序用的优先级
+//
+// 启用aging时，入队时间按aging向上取整分段，每晚一段优先级减1；所有任务随等待时间同步提升，
+// 所以相对顺序不随时间变化，可以直接用堆排序：先入队的任务每多等待一个aging相当于优先级加1
+func (s *Scheduler) level(priority int, now time.Time) int64 {
+	if s.aging <= 0 {
+		return int64(priority)
+	}
+	step := int64(s.aging)
+	return int64(priority) - (now.UnixNano()+step-1)/step
+}
+
+// item 队列中的任务
+type item struct {
+	task       Task
+	ctx        context.Context
+	cancel     context.CancelFunc
+	stopExpire func() bool
+	handle     *Handle
+	level      int64
+	deadline   time.Time
+	seq        uint64
+	index      int // 在堆中的位置，-1表示已出队
+}
+
+// drop 任务未执行就结束
+func (it *item) drop(err error) {
+	it.stopExpire()
+	it.cancel()
+	if !errors.Is(err, ErrCanceled) && !errors.Is(err, ErrClosed) {
+		err = fmt.Errorf("scheduler: 任务 %s 未执行: %w", it.task.ID, err)
+	}
+	it.handle.finish(err)
+}
+
+// queue 按 level 降序、截止时间升序（无截止时间的排最后）、提交顺序排列的堆
+type queue []*item
+
+func (q queue) less(a, b *item) bool {
+	if a.level != b.level {
+		return a.level > b.level
+	}
+	if !a.deadline.Equal(b.deadline) {
+		if a.deadline.IsZero() || b.deadline.IsZero() {
+			return b.deadline.IsZero()
+		}
+		return a.deadline.Before(b.deadline)
+	}
+	return a.seq < b.seq
+}
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q.less(q[i], q[j]) }
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x any) {
+	it := x.(*item)
+	it.index = len(*q)
+	*q = append(*q, it)
+}
+
+func (q *queue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	old[len(old)-1] = nil
+	it.index = -1
+	*q = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,195 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recorder 按执行顺序记录任务ID
+type recorder struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *recorder) task(id, class string, priority int) Task {
+	return Task{ID: id, Class: class, Priority: priority, Run: func(context.Context) error {
+		r.mu.Lock()
+		r.ids = append(r.ids, id)
+		r.mu.Unlock()
+		return nil
+	}}
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fmt.Sprint(r.ids)
+}
+
+// block 占住一个worker，返回释放函数
+func block(t *testing.T, s *Scheduler, class string) func() {
+	t.Helper()
+	started, release := make(chan struct{}), make(chan struct{})
+	if _, err := s.Submit(context.Background(), Task{Class: class, Priority: 1 << 20, Run: func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	return func() { close(release) }
+}
+
+func TestPriorityDeadlineOrder(t *testing.T) {
+	s := New(1)
+	defer s.Close()
+	release := block(t, s, "")
+
+	var r recorder
+	now := time.Now()
+	var handles []*Handle
+	for _, task := range []Task{
+		r.task("low", "", 1),
+		r.task("high", "", 5),
+		r.task("mid-late", "", 3),
+		r.task("mid-early", "", 3),
+		r.task("mid-none", "", 3),
+	} {
+		switch task.ID {
+		case "mid-late":
+			task.Deadline = now.Add(time.Hour)
+		case "mid-early":
+			task.Deadline = now.Add(time.Minute)
+		}
+		h, err := s.Submit(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handles = append(handles, h)
+	}
+	if _, err := s.Submit(context.Background(), r.task("low", "", 0)); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("duplicate id: err = %v", err)
+	}
+	if !s.Cancel("low") || s.Cancel("low") {
+		t.Error("cancel queued task")
+	}
+	release()
+	for _, h := range handles {
+		h.Wait()
+	}
+	if got := r.String(); got != "[high mid-early mid-late mid-none]" {
+		t.Errorf("order = %s", got)
+	}
+	if !errors.Is(handles[0].Err(), ErrCanceled) {
+		t.Errorf("canceled handle: err = %v", handles[0].Err())
+	}
+}
+
+func TestExpiredTasksNeverRun(t *testing.T) {
+	s := New(1)
+	defer s.Close()
+	release := block(t, s, "")
+
+	var ran atomic.Bool
+	run := func(context.Context) error { ran.Store(true); return nil }
+	expired, _ := s.Submit(context.Background(), Task{Priority: 10, Deadline: time.Now().Add(10 * time.Millisecond), Run: run})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ctxExpired, _ := s.Submit(ctx, Task{Priority: 10, Run: run})
+
+	// 排队中过期的任务立即结束，不需要等worker空闲
+	if err := expired.Wait(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("deadline: err = %v", err)
+	}
+	if err := ctxExpired.Wait(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ctx deadline: err = %v", err)
+	}
+	if s.Len() != 0 {
+		t.Errorf("len = %d", s.Len())
+	}
+	release()
+
+	// 执行中的任务能看到截止时间
+	h, _ := s.Submit(context.Background(), Task{Deadline: time.Now().Add(time.Hour), Run: func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("no deadline")
+		}
+		return nil
+	}})
+	if err := h.Wait(); err != nil || ran.Load() {
+		t.Errorf("err = %v, expired task ran = %v", err, ran.Load())
+	}
+}
+
+func TestClassLimit(t *testing.T) {
+	s := New(4, WithClassLimit("report", 1))
+	defer s.Close()
+
+	var running, peak atomic.Int32
+	var handles []*Handle
+	for range 6 {
+		h, _ := s.Submit(context.Background(), Task{Class: "report", Run: func(context.Context) error {
+			if n := running.Add(1); n > peak.Load() {
+				peak.Store(n)
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		}})
+		handles = append(handles, h)
+	}
+	// 其他类别不受report并发限制影响
+	other, _ := s.Submit(context.Background(), Task{Class: "email", Run: func(context.Context) error { return nil }})
+	select {
+	case <-other.Done():
+	case <-time.After(time.Second):
+		t.Fatal("email task blocked by report limit")
+	}
+	for _, h := range handles {
+		h.Wait()
+	}
+	if peak.Load() != 1 {
+		t.Errorf("report peak concurrency = %d", peak.Load())
+	}
+}
+
+func TestAging(t *testing.T) {
+	s := New(1, WithAging(20*time.Millisecond))
+	defer s.Close()
+	release := block(t, s, "")
+
+	var r recorder
+	s.Submit(context.Background(), r.task("old-low", "", 1))
+	time.Sleep(70 * time.Millisecond) // 至少等待3个aging周期
+	s.Submit(context.Background(), r.task("new-mid", "", 2))
+	h, _ := s.Submit(context.Background(), r.task("new-high", "", 10))
+	release()
+	h.Wait()
+	s.Close()
+	if got := r.String(); got != "[new-high old-low new-mid]" {
+		t.Errorf("order = %s", got)
+	}
+}
+
+func TestClose(t *testing.T) {
+	s := New(1)
+	release := block(t, s, "")
+	h, _ := s.Submit(context.Background(), Task{Run: func(context.Context) error { return nil }})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	s.Close()
+	if err := h.Wait(); !errors.Is(err, ErrClosed) {
+		t.Errorf("queued task: err = %v", err)
+	}
+	if _, err := s.Submit(context.Background(), Task{Run: func(context.Context) error { return nil }}); !errors.Is(err, ErrClosed) {
+		t.Errorf("submit after close: err = %v", err)
+	}
+}
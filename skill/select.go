@@ -6,8 +6,10 @@ import (
 	"math/rand"
 	"os"
 	"reflect"
-	"sync"
 	"time"
+
+	"github.com/kuihuar/ai/skill/scheduler"
+	"github.com/kuihuar/ai/skill/workerpool"
 )
 
 // ==================== select 多路复用详解 ====================
@@ -235,87 +237,55 @@ func FanOutExample() {
 }
 
 // 4.3 工作池模式
+// 基于泛型工作池 workerpool.Pool，结果由输出协程打印
 type WorkerPool struct {
-	workers int
-	input   chan interface{}
-	output  chan interface{}
-	done    chan struct{}
-	wg      sync.WaitGroup
+	pool   *workerpool.Pool[interface{}, interface{}]
+	output chan interface{}
+	done   chan struct{}
 }
 
 func NewWorkerPool(workers int) *WorkerPool {
-	return &WorkerPool{
-		workers: workers,
-		input:   make(chan interface{}, 100),
-		output:  make(chan interface{}, 100),
-		done:    make(chan struct{}),
+	wp := &WorkerPool{
+		output: make(chan interface{}, 100),
+		done:   make(chan struct{}),
 	}
+	wp.pool = workerpool.New(workers, wp.processTask, workerpool.WithQueueSize(100))
+	return wp
 }
 
 func (wp *WorkerPool) Start() {
-	for i := 0; i < wp.workers; i++ {
-		wp.wg.Add(1)
-		go wp.worker(i)
-	}
-
 	// 启动输出处理
 	go wp.outputHandler()
 }
 
-func (wp *WorkerPool) worker(id int) {
-	defer wp.wg.Done()
-
-	for {
-		select {
-		case task, ok := <-wp.input:
-			if !ok {
-				return
-			}
-			// 处理任务
-			result := wp.processTask(task)
-			select {
-			case wp.output <- result:
-			case <-wp.done:
-				return
-			}
-		case <-wp.done:
-			return
-		}
-	}
-}
-
-func (wp *WorkerPool) processTask(task interface{}) interface{} {
+func (wp *WorkerPool) processTask(ctx context.Context, task interface{}) (interface{}, error) {
 	// 模拟任务处理
 	time.Sleep(time.Millisecond * 50)
-	return fmt.Sprintf("Processed: %v", task)
+	result := fmt.Sprintf("Processed: %v", task)
+	select {
+	case wp.output <- result:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return result, nil
 }
 
 func (wp *WorkerPool) outputHandler() {
-	for {
-		select {
-		case result, ok := <-wp.output:
-			if !ok {
-				return
-			}
-			fmt.Printf("Output: %v\n", result)
-		case <-wp.done:
-			return
-		}
+	defer close(wp.done)
+	for result := range wp.output {
+		fmt.Printf("Output: %v\n", result)
 	}
 }
 
 func (wp *WorkerPool) Submit(task interface{}) {
-	select {
-	case wp.input <- task:
-	case <-wp.done:
-	}
+	wp.pool.Submit(context.Background(), task)
 }
 
+// Stop 等待已提交的任务完成、输出全部打印后返回
 func (wp *WorkerPool) Stop() {
-	close(wp.done)
-	close(wp.input)
-	wp.wg.Wait()
+	wp.pool.Shutdown(context.Background())
 	close(wp.output)
+	<-wp.done
 }
 
 // 5. select 与错误处理
@@ -464,6 +434,39 @@ func PrioritySelectExample() {
 	}
 }
 
+// 7.1.1 多级优先级调度
+// 嵌套 select 只能表达两级优先级，多级优先级、截止时间和按类别限流使用 scheduler 包
+func PrioritySchedulerExample() {
+	s := scheduler.New(2, scheduler.WithClassLimit("report", 1), scheduler.WithAging(time.Second))
+	defer s.Close()
+
+	ctx := context.Background()
+	var handles []*scheduler.Handle
+	for i, priority := range []int{1, 5, 3, 5, 0} {
+		h, err := s.Submit(ctx, scheduler.Task{
+			Class:    "report",
+			Priority: priority,
+			Deadline: time.Now().Add(time.Millisecond * 300), // 300ms内未开始的任务不再执行
+			Run: func(ctx context.Context) error {
+				fmt.Printf("Processing report %d (priority %d)\n", i, priority)
+				time.Sleep(time.Millisecond * 100)
+				return nil
+			},
+		})
+		if err != nil {
+			fmt.Printf("Submit failed: %v\n", err)
+			continue
+		}
+		handles = append(handles, h)
+	}
+
+	for _, h := range handles {
+		if err := h.Wait(); err != nil {
+			fmt.Printf("Task %s: %v\n", h.ID(), err)
+		}
+	}
+}
+
 // 7.2 条件选择
 func ConditionalSelectExample() {
 	ch1 := make(chan int)
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/kuihuar/ai/skill/ratelimit"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -237,53 +238,23 @@ func (cp *ConnectionPool) Close() {
 }
 
 // 4.2 限流器实现
+// 信号量不适合做限流：按固定间隔Release会在令牌未被取走时panic或超过突发容量，
+// 这里改用ratelimit包的令牌桶，令牌在调用时惰性计算，不需要后台goroutine
 type RateLimiter struct {
-	sem    *semaphore.Weighted
-	rate   int64
-	burst  int64
-	ticker *time.Ticker
-	ctx    context.Context
-	cancel context.CancelFunc
+	limiter ratelimit.Limiter
 }
 
 func NewRateLimiter(rate int64, burst int64) *RateLimiter {
-	ctx, cancel := context.WithCancel(context.Background())
-
-	limiter := &RateLimiter{
-		sem:    semaphore.NewWeighted(burst),
-		rate:   rate,
-		burst:  burst,
-		ctx:    ctx,
-		cancel: cancel,
-	}
-
-	// 启动令牌桶填充
-	limiter.ticker = time.NewTicker(time.Second / time.Duration(rate))
-	go limiter.fillTokens()
-
-	return limiter
-}
-
-func (rl *RateLimiter) fillTokens() {
-	for {
-		select {
-		case <-rl.ticker.C:
-			// 释放一个令牌
-			rl.sem.Release(1)
-		case <-rl.ctx.Done():
-			return
-		}
-	}
+	return &RateLimiter{limiter: ratelimit.NewTokenBucket(float64(rate), int(burst))}
 }
 
+// Allow 阻塞直到获得一个令牌或ctx结束
 func (rl *RateLimiter) Allow(ctx context.Context) error {
-	return rl.sem.Acquire(ctx, 1)
+	return rl.limiter.Wait(ctx)
 }
 
-func (rl *RateLimiter) Close() {
-	rl.cancel()
-	rl.ticker.Stop()
-}
+// Close 保留以兼容旧的调用方，令牌桶没有需要释放的资源
+func (rl *RateLimiter) Close() {}
 
 // 4.3 资源管理器
 type ResourceManager struct {
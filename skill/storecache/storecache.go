@@ -0,0 +1,535 @@
+// Package storecache 存储前的缓存层：读穿透、写穿透或写回（合并后批量落盘），支持负缓存和落盘失败重试
+package storecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/kuihuar/ai/skill/cache"
+	"github.com/kuihuar/ai/skill/retry"
+)
+
+var (
+	// ErrNotFound key不存在，Store 在 Get 时也应返回它（可包装）
+	ErrNotFound = errors.New("storecache: not found")
+	// ErrClosed 缓存层已关闭
+	ErrClosed = errors.New("storecache: closed")
+	// ErrTransient 写入失败但可以重试，如连接断开、锁等待超时；Store 返回包装它的错误时默认的重试才会重试
+	ErrTransient = errors.New("storecache: transient")
+	// ErrDropped 写回时遇到不可重试的错误，条目单独重写仍失败后被丢弃，OnFlushError收到包装它的错误
+	ErrDropped = errors.New("storecache: 写回失败，条目已丢弃")
+)
+
+// IsTransient 错误是否可以重试：包装了 ErrTransient，或实现了 Temporary() 且返回true（如net.Error）
+func IsTransient(err error) bool {
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// Store 后端存储，如MySQL仓库
+type Store[K comparable, V any] interface {
+	Get(ctx context.Context, key K) (V, error)
+	// GetMany 不存在的key不出现在结果中
+	GetMany(ctx context.Context, keys []K) (map[K]V, error)
+	Put(ctx context.Context, key K, value V) error
+	PutMany(ctx context.Context, items map[K]V) error
+	Delete(ctx context.Context, key K) error
+	DeleteMany(ctx context.Context, keys []K) error
+}
+
+// Mode 写入模式
+type Mode int
+
+const (
+	// WriteThrough 先写存储，成功后更新缓存
+	WriteThrough Mode = iota
+	// WriteBehind 只写缓存和待写队列，同一个key的多次写入合并，按数量或间隔批量写入存储
+	WriteBehind
+)
+
+// Config 缓存层配置
+type Config[K comparable, V any] struct {
+	Mode Mode
+
+	MaxEntries  int // 缓存条目上限，0表示不限
+	Policy      cache.Policy
+	TTL         time.Duration // 0表示不过期
+	NegativeTTL time.Duration // 不存在的key缓存多久，0表示不缓存
+
+	BatchSize     int            // 写回时每批的条目数，待写条目达到这个数量时立即写回，默认100
+	FlushInterval time.Duration  // 定期写回的间隔，默认1s
+	Retrier       *retry.Retrier // 写存储失败时的重试，默认重试3次、指数退避
+	// Retryable 判断写存储的错误是否可重试，默认 IsTransient；默认的Retrier只重试这类错误，
+	// 写回时这类错误的批次放回队列，其他错误的批次拆分重写，单独写入仍失败的条目被丢弃
+	Retryable func(error) bool
+	// OnFlushError 写回重试后仍失败：可重试的错误条目留在队列中等待下次写回，
+	// 被丢弃的条目收到包装 ErrDropped 的错误，可以在这里记入死信
+	OnFlushError func(keys []K, err error)
+}
+
+// lookup 缓存的查询结果，found为false表示负缓存
+type lookup[V any] struct {
+	value V
+	found bool
+}
+
+// writeState 同一个key并发写存储时，完成顺序不确定，不能用任何一次的值更新缓存
+type writeState struct {
+	n        int
+	conflict bool
+}
+
+// op 待写入存储的操作
+type op[V any] struct {
+	value   V
+	deleted bool
+}
+
+// Layer 存储前的缓存层，并发安全
+type Layer[K comparable, V any] struct {
+	cfg       Config[K, V]
+	store     Store[K, V]
+	cache     *cache.Cache[K, lookup[V]]
+	retrier   *retry.Retrier
+	retryable func(error) bool
+
+	mu       sync.Mutex
+	pending  map[K]op[V]       // 等待写回
+	inflight map[K]op[V]       // 正在写回的批次
+	writes   uint64            // 写入计数，批量读取用来判断读取期间是否有写入
+	writing  map[K]*writeState // 写穿透模式下正在写存储的key
+	closed   bool
+
+	flushMu sync.Mutex // 保证批次按顺序写回
+	kick    chan struct{}
+	ctx     context.Context // 后台写回使用，Close时取消
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// New 创建缓存层，WriteBehind模式启动后台写回
+func New[K comparable, V any](store Store[K, V], cfg Config[K, V]) *Layer[K, V] {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	l := &Layer[K, V]{
+		cfg:       cfg,
+		store:     store,
+		retrier:   cfg.Retrier,
+		retryable: cfg.Retryable,
+		pending:   make(map[K]op[V]),
+		inflight:  make(map[K]op[V]),
+		writing:   make(map[K]*writeState),
+		kick:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	if l.retryable == nil {
+		l.retryable = IsTransient
+	}
+	if l.retrier == nil {
+		// 主键冲突、数据过长等永久错误重试也不会成功，只重试存储标记为临时的错误
+		l.retrier = retry.New(retry.Attempts(3), retry.WithRetryable(l.retryable))
+	}
+	l.cache = cache.New(cache.Config[K, lookup[V]]{
+		MaxEntries: cfg.MaxEntries,
+		Policy:     cfg.Policy,
+		ExpireAfter: func(_ K, r lookup[V]) time.Duration {
+			if !r.found {
+				return cfg.NegativeTTL
+			}
+			return cfg.TTL
+		},
+		Loader: cache.LoaderFunc[K, lookup[V]](l.load),
+	})
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	if cfg.Mode == WriteBehind {
+		go l.run()
+	} else {
+		close(l.done)
+	}
+	return l
+}
+
+// Get 读取，未命中时从存储加载；不存在时返回ErrNotFound
+func (l *Layer[K, V]) Get(ctx context.Context, key K) (V, error) {
+	r, err := l.cache.GetOrLoad(ctx, key)
+	if err == nil && !r.found {
+		err = ErrNotFound
+	}
+	return r.value, err
+}
+
+// GetMany 批量读取，未命中的key合并为一次存储查询；不存在的key不出现在结果中
+func (l *Layer[K, V]) GetMany(ctx context.Context, keys []K) (map[K]V, error) {
+	result := make(map[K]V, len(keys))
+	var misses []K
+	l.mu.Lock()
+	for _, key := range keys {
+		if r, ok := l.cache.Get(key); ok {
+			if r.found {
+				result[key] = r.value
+			}
+		} else if o, ok := l.unflushed(key); ok {
+			if !o.deleted {
+				result[key] = o.value
+			}
+		} else {
+			misses = append(misses, key)
+		}
+	}
+	writes := l.writes
+	l.mu.Unlock()
+	if len(misses) == 0 {
+		return result, nil
+	}
+
+	loaded, err := l.store.GetMany(ctx, misses)
+	if err != nil {
+		return nil, fmt.Errorf("storecache: 批量读取: %w", err)
+	}
+	l.mu.Lock()
+	// 读取期间有写入时不知道哪些结果已过时，只返回不缓存
+	fill := l.writes == writes
+	for _, key := range misses {
+		v, ok := loaded[key]
+		if ok {
+			result[key] = v
+		}
+		if fill && (ok || l.cfg.NegativeTTL > 0) {
+			l.cache.Set(key, lookup[V]{v, ok})
+		}
+	}
+	l.mu.Unlock()
+	return result, nil
+}
+
+// Put 写入
+func (l *Layer[K, V]) Put(ctx context.Context, key K, value V) error {
+	return l.PutMany(ctx, map[K]V{key: value})
+}
+
+// PutMany 批量写入
+func (l *Layer[K, V]) PutMany(ctx context.Context, items map[K]V) error {
+	ops := make(map[K]op[V], len(items))
+	for k, v := range items {
+		ops[k] = op[V]{value: v}
+	}
+	return l.write(ctx, ops)
+}
+
+// Delete 删除
+func (l *Layer[K, V]) Delete(ctx context.Context, key K) error {
+	return l.DeleteMany(ctx, []K{key})
+}
+
+// DeleteMany 批量删除
+func (l *Layer[K, V]) DeleteMany(ctx context.Context, keys []K) error {
+	ops := make(map[K]op[V], len(keys))
+	for _, k := range keys {
+		ops[k] = op[V]{deleted: true}
+	}
+	return l.write(ctx, ops)
+}
+
+// Flush 立即写回所有待写条目
+func (l *Layer[K, V]) Flush(ctx context.Context) error {
+	return l.flush(ctx)
+}
+
+// Pending 等待写回的条目数，包括正在写回的
+func (l *Layer[K, V]) Pending() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.pending) + len(l.inflight)
+}
+
+// Stats 缓存统计
+func (l *Layer[K, V]) Stats() cache.Stats {
+	return l.cache.Stats()
+}
+
+// Close 停止接受写入，写回所有待写条目；可重试的写回失败持续重试直到成功或ctx结束
+func (l *Layer[K, V]) Close(ctx context.Context) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.mu.Unlock()
+
+	l.cancel()
+	<-l.done
+	defer l.cache.Close()
+	for {
+		err := l.flush(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("storecache: 关闭时有 %d 条写入未落盘: %w", l.Pending(), err)
+		case <-time.After(l.cfg.FlushInterval):
+		}
+	}
+}
+
+// write 按模式写入存储或待写队列，并更新缓存
+func (l *Layer[K, V]) write(ctx context.Context, ops map[K]op[V]) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ErrClosed
+	}
+	if l.cfg.Mode == WriteBehind {
+		for k, o := range ops {
+			l.pending[k] = o
+			l.setCache(k, o)
+		}
+		l.writes++
+		if len(l.pending) >= l.cfg.BatchSize {
+			select {
+			case l.kick <- struct{}{}:
+			default:
+			}
+		}
+		l.mu.Unlock()
+		return nil
+	}
+	for k := range ops {
+		ws, ok := l.writing[k]
+		if !ok {
+			ws = &writeState{}
+			l.writing[k] = ws
+		}
+		ws.n++
+		ws.conflict = ws.conflict || ws.n > 1
+	}
+	l.mu.Unlock()
+
+	err := l.writeStore(ctx, ops)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writes++
+	for k, o := range ops {
+		ws := l.writing[k]
+		if ws.n--; ws.n == 0 {
+			delete(l.writing, k)
+		}
+		if err != nil || ws.conflict {
+			// 写入失败时是否部分生效未知，并发写入时最终值未知，都让下次读取回源
+			l.cache.Delete(k)
+		} else {
+			l.setCache(k, o)
+		}
+	}
+	return err
+}
+
+// setCache 写入后更新缓存，调用方持有锁
+func (l *Layer[K, V]) setCache(key K, o op[V]) {
+	if o.deleted && l.cfg.NegativeTTL <= 0 {
+		l.cache.Delete(key)
+		return
+	}
+	l.cache.Set(key, lookup[V]{o.value, !o.deleted})
+}
+
+// unflushed 还未写入存储的操作，调用方持有锁
+func (l *Layer[K, V]) unflushed(key K) (op[V], bool) {
+	if o, ok := l.pending[key]; ok {
+		return o, true
+	}
+	o, ok := l.inflight[key]
+	return o, ok
+}
+
+// load 缓存未命中时的加载，写回模式下先查未落盘的写入
+func (l *Layer[K, V]) load(ctx context.Context, key K) (lookup[V], error) {
+	l.mu.Lock()
+	o, ok := l.unflushed(key)
+	l.mu.Unlock()
+	if ok {
+		if o.deleted && l.cfg.NegativeTTL <= 0 {
+			// 与存储返回不存在时一致，不缓存
+			return lookup[V]{}, ErrNotFound
+		}
+		return lookup[V]{o.value, !o.deleted}, nil
+	}
+
+	v, err := l.store.Get(ctx, key)
+	switch {
+	case errors.Is(err, ErrNotFound) && l.cfg.NegativeTTL > 0:
+		return lookup[V]{}, nil
+	case err != nil:
+		return lookup[V]{}, err
+	}
+	return lookup[V]{v, true}, nil
+}
+
+// writeStore 带重试地写入存储
+func (l *Layer[K, V]) writeStore(ctx context.Context, ops map[K]op[V]) error {
+	puts := make(map[K]V, len(ops))
+	var deletes []K
+	for k, o := range ops {
+		if o.deleted {
+			deletes = append(deletes, k)
+		} else {
+			puts[k] = o.value
+		}
+	}
+	err := l.retrier.Do(ctx, func(ctx context.Context) error {
+		if err := l.putStore(ctx, puts); err != nil {
+			return err
+		}
+		clear(puts) // 重试时只写删除
+		return l.deleteStore(ctx, deletes)
+	})
+	if err != nil {
+		return fmt.Errorf("storecache: 写入存储: %w", err)
+	}
+	return nil
+}
+
+// putStore 单个条目用Put，多个用PutMany
+func (l *Layer[K, V]) putStore(ctx context.Context, puts map[K]V) error {
+	switch len(puts) {
+	case 0:
+		return nil
+	case 1:
+		for k, v := range puts {
+			return l.store.Put(ctx, k, v)
+		}
+	}
+	return l.store.PutMany(ctx, puts)
+}
+
+// deleteStore 单个key用Delete，多个用DeleteMany
+func (l *Layer[K, V]) deleteStore(ctx context.Context, keys []K) error {
+	switch len(keys) {
+	case 0:
+		return nil
+	case 1:
+		return l.store.Delete(ctx, keys[0])
+	}
+	return l.store.DeleteMany(ctx, keys)
+}
+
+// run 后台写回，按间隔或待写数量触发
+func (l *Layer[K, V]) run() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-l.kick:
+		}
+		l.flush(l.ctx)
+	}
+}
+
+// flush 分批写回所有待写条目，可重试的失败把批次放回队列并结束本次写回
+func (l *Layer[K, V]) flush(ctx context.Context) error {
+	l.flushMu.Lock()
+	defer l.flushMu.Unlock()
+	for {
+		l.mu.Lock()
+		for k, o := range l.pending {
+			if len(l.inflight) == l.cfg.BatchSize {
+				break
+			}
+			l.inflight[k] = o
+			delete(l.pending, k)
+		}
+		batch := maps.Clone(l.inflight)
+		l.mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		requeue, err := l.writeBatch(ctx, batch)
+
+		l.mu.Lock()
+		for k, o := range requeue {
+			if _, newer := l.pending[k]; !newer {
+				l.pending[k] = o
+			}
+		}
+		l.inflight = make(map[K]op[V])
+		l.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeBatch 写回一个批次，返回需要放回队列的条目
+// 不可重试的错误（如某一行数据过长）可能只由个别条目引起，批次对半拆分后分别重写，
+// 单独写入仍失败的条目被丢弃，不阻塞同批的其他条目；可重试的错误返回err，结束本次写回
+func (l *Layer[K, V]) writeBatch(ctx context.Context, batch map[K]op[V]) (map[K]op[V], error) {
+	err := l.writeStore(ctx, batch)
+	switch {
+	case err == nil:
+		return nil, nil
+	case ctx.Err() != nil || l.retryable(err):
+		l.flushFailed(ctx, batch, err)
+		return batch, err
+	case len(batch) == 1:
+		l.drop(batch)
+		l.flushFailed(ctx, batch, fmt.Errorf("%w: %w", ErrDropped, err))
+		return nil, nil
+	}
+
+	first, second := make(map[K]op[V]), make(map[K]op[V])
+	for k, o := range batch {
+		if len(first) < len(batch)/2 {
+			first[k] = o
+		} else {
+			second[k] = o
+		}
+	}
+	requeue, err := l.writeBatch(ctx, first)
+	if err != nil {
+		maps.Copy(requeue, second)
+		return requeue, err
+	}
+	return l.writeBatch(ctx, second)
+}
+
+// drop 丢弃无法写入的条目，缓存中的值没有落盘，失效后让下次读取回源
+func (l *Layer[K, V]) drop(batch map[K]op[V]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k := range batch {
+		delete(l.inflight, k)
+		if _, newer := l.pending[k]; !newer {
+			l.cache.Delete(k)
+		}
+	}
+}
+
+// flushFailed 通知写回失败的条目，Close取消后台写回导致的失败不通知
+func (l *Layer[K, V]) flushFailed(ctx context.Context, batch map[K]op[V], err error) {
+	if l.cfg.OnFlushError == nil || ctx.Err() != nil {
+		return
+	}
+	keys := make([]K, 0, len(batch))
+	for k := range batch {
+		keys = append(keys, k)
+	}
+	l.cfg.OnFlushError(keys, err)
+}
@@ -0,0 +1,509 @@
+package storecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kuihuar/ai/skill/internal/testutil"
+	"github.com/kuihuar/ai/skill/retry"
+)
+
+var (
+	errStore   = fmt.Errorf("mysql: connection refused: %w", ErrTransient)
+	errTooLong = errors.New("mysql: data too long for column 'name'")
+)
+
+// memStore 内存存储，记录调用次数，可注入写入失败
+type memStore struct {
+	mu       sync.Mutex
+	data     map[string]int
+	calls    map[string]int
+	batches  []int // 每次PutMany的条目数
+	getKeys  [][]string
+	failures int    // 接下来失败的写入次数，负数表示一直失败
+	failErr  error  // 写入失败时返回的错误，默认errStore
+	reject   string // 写入包含这个key时永久失败，如数据过长
+}
+
+func newMemStore(data map[string]int) *memStore {
+	if data == nil {
+		data = map[string]int{}
+	}
+	return &memStore{data: data, calls: map[string]int{}}
+}
+
+func (s *memStore) fail() error {
+	if s.failures == 0 {
+		return nil
+	}
+	if s.failures > 0 {
+		s.failures--
+	}
+	if s.failErr != nil {
+		return s.failErr
+	}
+	return errStore
+}
+
+func (s *memStore) Get(_ context.Context, key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["Get"]++
+	v, ok := s.data[key]
+	if !ok {
+		return 0, fmt.Errorf("user %s: %w", key, ErrNotFound)
+	}
+	return v, nil
+}
+
+func (s *memStore) GetMany(_ context.Context, keys []string) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["GetMany"]++
+	s.getKeys = append(s.getKeys, slices.Sorted(slices.Values(keys)))
+	out := map[string]int{}
+	for _, k := range keys {
+		if v, ok := s.data[k]; ok {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) Put(_ context.Context, key string, value int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["Put"]++
+	if err := s.fail(); err != nil {
+		return err
+	}
+	if key == s.reject {
+		return errTooLong
+	}
+	s.data[key] = value
+	return nil
+}
+
+func (s *memStore) PutMany(_ context.Context, items map[string]int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["PutMany"]++
+	if err := s.fail(); err != nil {
+		return err
+	}
+	if _, ok := items[s.reject]; ok {
+		return errTooLong
+	}
+	s.batches = append(s.batches, len(items))
+	for k, v := range items {
+		s.data[k] = v
+	}
+	return nil
+}
+
+func (s *memStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["Delete"]++
+	if err := s.fail(); err != nil {
+		return err
+	}
+	delete(s.data, key)
+	return nil
+}
+
+func (s *memStore) DeleteMany(_ context.Context, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls["DeleteMany"]++
+	if err := s.fail(); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		delete(s.data, k)
+	}
+	return nil
+}
+
+func (s *memStore) value(key string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+func (s *memStore) count(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+func (s *memStore) setFailures(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+func fastRetrier() *retry.Retrier {
+	return retry.New(retry.Attempts(3), retry.WithBackoff(retry.Constant(time.Millisecond)))
+}
+
+func TestDefaultRetrierOnlyRetriesTransient(t *testing.T) {
+	ctx := context.Background()
+	errDup := errors.New("mysql: duplicate entry")
+	for _, tc := range []struct {
+		name      string
+		err       error
+		retryable func(error) bool
+		calls     int
+	}{
+		{"永久错误不重试", errDup, nil, 1},
+		{"临时错误重试", fmt.Errorf("mysql: lock wait timeout: %w", ErrTransient), nil, 3},
+		{"自定义判断", errDup, func(error) bool { return true }, 3},
+	} {
+		store := newMemStore(nil)
+		store.failErr = tc.err
+		store.setFailures(-1)
+		l := New[string, int](store, Config[string, int]{Retryable: tc.retryable})
+		if err := l.Put(ctx, "alice", 1); !errors.Is(err, tc.err) {
+			t.Fatalf("%s: err = %v", tc.name, err)
+		}
+		if n := store.count("Put"); n != tc.calls {
+			t.Errorf("%s: Put calls = %d, want %d", tc.name, n, tc.calls)
+		}
+		l.Close(ctx)
+	}
+
+	if IsTransient(errDup) || !IsTransient(fmt.Errorf("put: %w", ErrTransient)) {
+		t.Error("IsTransient")
+	}
+}
+
+func TestReadThroughNegativeCache(t *testing.T) {
+	ctx := context.Background()
+	for _, negative := range []time.Duration{0, time.Minute} {
+		store := newMemStore(map[string]int{"alice": 1})
+		l := New[string, int](store, Config[string, int]{NegativeTTL: negative})
+
+		for range 2 {
+			if v, err := l.Get(ctx, "alice"); err != nil || v != 1 {
+				t.Fatalf("Get = %d, %v", v, err)
+			}
+			if _, err := l.Get(ctx, "bob"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("err = %v", err)
+			}
+		}
+		want := 3 // alice一次，bob不缓存时两次
+		if negative > 0 {
+			want = 2
+		}
+		if n := store.count("Get"); n != want {
+			t.Errorf("negativeTTL %v: store Get called %d times, want %d", negative, n, want)
+		}
+		l.Close(ctx)
+	}
+}
+
+func TestWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore(nil)
+	l := New[string, int](store, Config[string, int]{NegativeTTL: time.Minute, Retrier: fastRetrier()})
+	defer l.Close(ctx)
+
+	if _, err := l.Get(ctx, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v", err)
+	}
+	if err := l.Put(ctx, "alice", 1); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := store.value("alice"); v != 1 {
+		t.Errorf("store = %d", v)
+	}
+	if v, err := l.Get(ctx, "alice"); err != nil || v != 1 { // 覆盖负缓存
+		t.Errorf("Get = %d, %v", v, err)
+	}
+
+	// 失败次数超过重试次数，返回错误并让缓存失效
+	store.setFailures(3)
+	if err := l.Put(ctx, "alice", 2); !errors.Is(err, errStore) {
+		t.Fatalf("err = %v", err)
+	}
+	if v, _ := l.Get(ctx, "alice"); v != 1 {
+		t.Errorf("Get after failed write = %d, want store value 1", v)
+	}
+	store.setFailures(2) // 重试后成功
+	if err := l.Delete(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(ctx, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v", err)
+	}
+	if n := store.count("Get"); n != 2 {
+		t.Errorf("store Get called %d times, want 2", n)
+	}
+}
+
+func TestWriteThroughConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore(nil)
+	l := New[string, int](store, Config[string, int]{})
+	defer l.Close(ctx)
+
+	for round := range 20 {
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				l.Put(ctx, "k", round*10+i)
+			}()
+		}
+		wg.Wait()
+		got, _ := l.Get(ctx, "k")
+		if want, _ := store.value("k"); got != want {
+			t.Fatalf("round %d: cache %d, store %d", round, got, want)
+		}
+	}
+}
+
+func TestWriteBehindCoalesces(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore(map[string]int{"carol": 7})
+	l := New[string, int](store, Config[string, int]{Mode: WriteBehind, FlushInterval: time.Hour})
+	defer l.Close(ctx)
+
+	for i := range 5 {
+		l.Put(ctx, "alice", i)
+	}
+	l.PutMany(ctx, map[string]int{"bob": 1, "dave": 2})
+	l.Delete(ctx, "carol")
+
+	if n := store.count("PutMany") + store.count("Put") + store.count("Delete"); n != 0 {
+		t.Fatalf("store written %d times before flush", n)
+	}
+	if v, _ := l.Get(ctx, "alice"); v != 4 {
+		t.Errorf("Get = %d", v)
+	}
+	if _, err := l.Get(ctx, "carol"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted key: err = %v", err)
+	}
+	if l.Pending() != 4 {
+		t.Errorf("pending = %d", l.Pending())
+	}
+
+	if err := l.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if store.count("PutMany") != 1 || store.count("Delete") != 1 || store.count("Put") != 0 {
+		t.Errorf("calls = %v", store.calls)
+	}
+	for k, want := range map[string]int{"alice": 4, "bob": 1, "dave": 2} {
+		if v, _ := store.value(k); v != want {
+			t.Errorf("store %s = %d, want %d", k, v, want)
+		}
+	}
+	if _, ok := store.value("carol"); ok {
+		t.Error("carol not deleted")
+	}
+	if l.Pending() != 0 {
+		t.Errorf("pending = %d", l.Pending())
+	}
+}
+
+func TestWriteBehindTriggers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("size", func(t *testing.T) {
+		store := newMemStore(nil)
+		l := New[string, int](store, Config[string, int]{Mode: WriteBehind, BatchSize: 10, FlushInterval: time.Hour})
+		for i := range 35 {
+			l.Put(ctx, fmt.Sprint(i), i)
+		}
+		testutil.WaitFor(t, func() bool { return l.Pending() < 10 })
+		if err := l.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range store.batches {
+			if n > 10 {
+				t.Errorf("batch of %d exceeds BatchSize", n)
+			}
+		}
+		if len(store.data) != 35 {
+			t.Errorf("store has %d entries", len(store.data))
+		}
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		store := newMemStore(nil)
+		l := New[string, int](store, Config[string, int]{Mode: WriteBehind, FlushInterval: 5 * time.Millisecond})
+		defer l.Close(ctx)
+		l.Put(ctx, "alice", 1)
+		testutil.WaitFor(t, func() bool { _, ok := store.value("alice"); return ok })
+	})
+}
+
+func TestPendingDeleteNotCached(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore(map[string]int{"alice": 1})
+	l := New[string, int](store, Config[string, int]{Mode: WriteBehind, FlushInterval: time.Hour})
+	defer l.Close(ctx)
+
+	l.Delete(ctx, "alice")
+	if _, err := l.Get(ctx, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get pending delete: %v", err)
+	}
+	if err := l.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// NegativeTTL为0时不存在的结果不缓存，其他进程写入后能读到
+	store.Put(ctx, "alice", 2)
+	if v, err := l.Get(ctx, "alice"); err != nil || v != 2 {
+		t.Errorf("Get = %d, %v", v, err)
+	}
+}
+
+func TestFlushFailureRequeues(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore(nil)
+	var failed []string
+	l := New[string, int](store, Config[string, int]{
+		Mode:          WriteBehind,
+		FlushInterval: time.Hour,
+		Retrier:       fastRetrier(),
+		OnFlushError:  func(keys []string, err error) { failed = append(failed, keys...) },
+	})
+	defer l.Close(ctx)
+
+	l.Put(ctx, "alice", 1)
+	store.setFailures(3)
+	if err := l.Flush(ctx); !errors.Is(err, errStore) {
+		t.Fatalf("err = %v", err)
+	}
+	if fmt.Sprint(failed) != "[alice]" || l.Pending() != 1 {
+		t.Fatalf("failed = %v, pending = %d", failed, l.Pending())
+	}
+	if v, _ := l.Get(ctx, "alice"); v != 1 {
+		t.Errorf("Get = %d", v)
+	}
+
+	l.Put(ctx, "alice", 2) // 新值不被失败的旧批次覆盖
+	store.setFailures(2)
+	if err := l.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := store.value("alice"); v != 2 {
+		t.Errorf("store = %d", v)
+	}
+}
+
+func TestFlushDropsPermanentFailures(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore(nil)
+	store.reject = "bob"
+	var dropped []string
+	var dropErr error
+	l := New[string, int](store, Config[string, int]{
+		Mode:          WriteBehind,
+		FlushInterval: time.Hour,
+		OnFlushError: func(keys []string, err error) {
+			dropped, dropErr = append(dropped, keys...), err
+		},
+	})
+
+	l.PutMany(ctx, map[string]int{"alice": 1, "bob": 2, "carol": 3, "dave": 4})
+	if err := l.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 批次拆分重写，只有bob被丢弃，其他条目正常落盘
+	if fmt.Sprint(dropped) != "[bob]" || !errors.Is(dropErr, ErrDropped) || !errors.Is(dropErr, errTooLong) {
+		t.Fatalf("dropped = %v, err = %v", dropped, dropErr)
+	}
+	if len(store.data) != 3 || l.Pending() != 0 {
+		t.Errorf("store = %v, pending = %d", store.data, l.Pending())
+	}
+	// 没有落盘的值不留在缓存中
+	if _, err := l.Get(ctx, "bob"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get dropped key: %v", err)
+	}
+
+	l.Put(ctx, "bob", 5)
+	done := make(chan error, 1)
+	go func() { done <- l.Close(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return with a permanently failing key")
+	}
+}
+
+func TestCloseFlushes(t *testing.T) {
+	store := newMemStore(nil)
+	l := New[string, int](store, Config[string, int]{
+		Mode:          WriteBehind,
+		FlushInterval: time.Millisecond,
+		Retrier:       fastRetrier(),
+	})
+	store.setFailures(5) // 第一轮3次都失败，关闭时的第二轮成功
+	l.PutMany(context.Background(), map[string]int{"alice": 1, "bob": 2})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := l.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.data) != 2 {
+		t.Errorf("store = %v", store.data)
+	}
+	if err := l.Put(ctx, "carol", 3); !errors.Is(err, ErrClosed) {
+		t.Errorf("Put after Close: %v", err)
+	}
+
+	down := newMemStore(nil)
+	down.setFailures(-1)
+	l = New[string, int](down, Config[string, int]{Mode: WriteBehind, FlushInterval: time.Hour, Retrier: fastRetrier()})
+	l.Put(context.Background(), "alice", 1)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.Close(ctx); !errors.Is(err, errStore) {
+		t.Errorf("Close with store down: %v", err)
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore(map[string]int{"alice": 1, "bob": 2})
+	l := New[string, int](store, Config[string, int]{
+		Mode:          WriteBehind,
+		FlushInterval: time.Hour,
+		NegativeTTL:   time.Minute,
+	})
+	defer l.Close(ctx)
+
+	l.Get(ctx, "alice")
+	l.Put(ctx, "carol", 3)
+
+	keys := []string{"alice", "bob", "carol", "dave"}
+	got, err := l.GetMany(ctx, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "map[alice:1 bob:2 carol:3]" {
+		t.Errorf("GetMany = %v", got)
+	}
+	if fmt.Sprint(store.getKeys) != "[[bob dave]]" {
+		t.Errorf("store queried %v", store.getKeys)
+	}
+
+	// bob和dave（负缓存）都已缓存
+	if got, _ := l.GetMany(ctx, keys); len(got) != 3 || store.count("GetMany") != 1 {
+		t.Errorf("second GetMany = %v, store calls %d", got, store.count("GetMany"))
+	}
+}
@@ -0,0 +1,266 @@
+// Package timingwheel 分层时间轮：大量定时器共用一个驱动goroutine，添加、取消、重置都是O(1)
+//
+// 第0层每个槽对应一个tick，第l层每个槽对应 slots^l 个tick。定时器按到期时间放入能容纳它的最低层，
+// 高层的槽在时间走到时整体下沉（cascade）到低层，最终在第0层到期执行。
+package timingwheel
+
+import (
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// Option 时间轮选项
+type Option func(*Wheel)
+
+// WithSlots 每层的槽数，向上取整为2的幂，默认64
+func WithSlots(n int) Option {
+	return func(w *Wheel) {
+		w.bits = uint(bits.Len(uint(max(n, 2) - 1)))
+	}
+}
+
+// WithLevels 层数，默认5；超出最高层范围的定时器先放在最高层，下沉时重新计算
+func WithLevels(n int) Option {
+	return func(w *Wheel) { w.levels = max(n, 1) }
+}
+
+// WithClock 注入时钟，用于测试；配合 Advance 手动推进，不调用 Start
+func WithClock(now func() time.Time) Option {
+	return func(w *Wheel) { w.now = now }
+}
+
+// Wheel 分层时间轮，并发安全
+//
+// 到期的回调在推进时间轮的goroutine中依次同步执行，回调应尽快返回，耗时操作应自行启动goroutine
+type Wheel struct {
+	tick   time.Duration
+	bits   uint
+	levels int
+	now    func() time.Time
+
+	mu      sync.Mutex
+	start   time.Time
+	current uint64     // 已处理到的tick
+	slots   [][]bucket // [level][slot]
+	count   int
+
+	// advanceMu 保证同一时刻只有一个goroutine推进并执行回调，回调按到期顺序执行
+	advanceMu sync.Mutex
+	stopOnce  sync.Once
+	stop      chan struct{}
+}
+
+// bucket 一个槽中的定时器，带哨兵的双向链表
+type bucket struct {
+	head Timer
+}
+
+// New 创建时间轮，tick为精度；调用 Start 后开始按真实时间推进
+func New(tick time.Duration, opts ...Option) *Wheel {
+	w := &Wheel{
+		tick:   max(tick, time.Millisecond),
+		bits:   6,
+		levels: 5,
+		now:    time.Now,
+		stop:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.start = w.now()
+	size := 1 << w.bits
+	w.slots = make([][]bucket, w.levels)
+	for l := range w.slots {
+		w.slots[l] = make([]bucket, size)
+		for s := range w.slots[l] {
+			b := &w.slots[l][s]
+			b.head.prev, b.head.next = &b.head, &b.head
+		}
+	}
+	return w
+}
+
+// Start 启动驱动goroutine，每个tick推进一次
+func (w *Wheel) Start() {
+	go func() {
+		ticker := time.NewTicker(w.tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.Advance()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止驱动goroutine，未到期的定时器不再执行
+func (w *Wheel) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+// Len 未到期的定时器数
+func (w *Wheel) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// AfterFunc d之后执行f，精度为一个tick，不会提前执行
+func (w *Wheel) AfterFunc(d time.Duration, f func()) *Timer {
+	t := &Timer{w: w, fn: f}
+	w.mu.Lock()
+	t.expire = w.expireAt(d)
+	w.add(t)
+	w.mu.Unlock()
+	return t
+}
+
+// Schedule 每隔every执行一次f，第一次在every之后；执行时间按固定频率计算，不受回调耗时影响
+func (w *Wheel) Schedule(every time.Duration, f func()) *Timer {
+	t := &Timer{w: w, fn: f, period: max(w.ticks(every), 1)}
+	w.mu.Lock()
+	t.expire = w.expireAt(every)
+	w.add(t)
+	w.mu.Unlock()
+	return t
+}
+
+// Advance 按时钟推进到当前时间，执行到期的回调；Start 后由驱动goroutine调用，手动推进时在测试中调用
+func (w *Wheel) Advance() {
+	w.advanceMu.Lock()
+	defer w.advanceMu.Unlock()
+	for {
+		w.mu.Lock()
+		target := uint64(w.now().Sub(w.start) / w.tick)
+		if w.current >= target {
+			w.mu.Unlock()
+			return
+		}
+		w.current++
+		w.cascade()
+		expired := w.expire()
+		w.mu.Unlock()
+
+		for _, f := range expired {
+			f()
+		}
+	}
+}
+
+// ticks d对应的tick数，向上取整
+func (w *Wheel) ticks(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	return uint64((d + w.tick - 1) / w.tick)
+}
+
+// expireAt 现在起d之后的到期tick，至少为下一个tick
+func (w *Wheel) expireAt(d time.Duration) uint64 {
+	elapsed := w.now().Sub(w.start) + max(d, 0)
+	return max(w.ticks(elapsed), w.current+1)
+}
+
+// add 按到期时间放入能容纳它的最低层
+func (w *Wheel) add(t *Timer) {
+	delta := t.expire - w.current
+	level := 0
+	for level < w.levels-1 && delta >= 1<<(w.bits*uint(level+1)) {
+		level++
+	}
+	expire := t.expire
+	if limit := w.current + 1<<(w.bits*uint(w.levels)) - 1; expire > limit {
+		// 超出最高层范围，先放在最高层最远的槽，下沉时重新计算
+		expire = limit
+	}
+	slot := (expire >> (w.bits * uint(level))) & (1<<w.bits - 1)
+	b := &w.slots[level][slot]
+	t.bucket = b
+	t.prev, t.next = b.head.prev, &b.head
+	b.head.prev.next = t
+	b.head.prev = t
+	w.count++
+}
+
+// remove 从所在的槽中移除
+func (w *Wheel) remove(t *Timer) {
+	t.prev.next = t.next
+	t.next.prev = t.prev
+	t.prev, t.next, t.bucket = nil, nil, nil
+	w.count--
+}
+
+// cascade current的低位全为0时，把高层对应槽中的定时器下沉到低层
+func (w *Wheel) cascade() {
+	mask := uint64(1)<<w.bits - 1
+	for level := 1; level < w.levels; level++ {
+		shift := w.bits * uint(level)
+		if w.current&(1<<shift-1) != 0 {
+			return
+		}
+		b := &w.slots[level][(w.current>>shift)&mask]
+		for t := b.head.next; t != &b.head; {
+			next := t.next
+			w.remove(t)
+			w.add(t)
+			t = next
+		}
+	}
+}
+
+// expire 取出第0层当前槽中的定时器，周期定时器重新加入
+func (w *Wheel) expire() []func() {
+	b := &w.slots[0][w.current&(1<<w.bits-1)]
+	var fns []func()
+	for t := b.head.next; t != &b.head; {
+		next := t.next
+		w.remove(t)
+		fns = append(fns, t.fn)
+		if t.period > 0 {
+			t.expire = max(t.expire+t.period, w.current+1)
+			w.add(t)
+		}
+		t = next
+	}
+	return fns
+}
+
+// Timer 时间轮中的定时器
+type Timer struct {
+	w      *Wheel
+	fn     func()
+	expire uint64 // 到期tick
+	period uint64 // 周期tick数，0表示一次性
+
+	bucket     *bucket
+	prev, next *Timer
+}
+
+// Stop 取消定时器，定时器还未到期时返回true；回调已取出正在执行时返回false
+func (t *Timer) Stop() bool {
+	t.w.mu.Lock()
+	defer t.w.mu.Unlock()
+	if t.bucket == nil {
+		return false
+	}
+	t.w.remove(t)
+	return true
+}
+
+// Reset 改为d之后到期，周期定时器之后仍按原周期执行；返回重置前是否未到期
+func (t *Timer) Reset(d time.Duration) bool {
+	w := t.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pending := t.bucket != nil
+	if pending {
+		w.remove(t)
+	}
+	t.expire = w.expireAt(d)
+	w.add(t)
+	return pending
+}
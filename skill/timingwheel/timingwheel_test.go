@@ -0,0 +1,123 @@
+package timingwheel
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/kuihuar/ai/skill/internal/testutil"
+)
+
+// step 每次推进一个tick并驱动时间轮
+func step(w *Wheel, clock *testutil.Clock, ticks int) {
+	for range ticks {
+		clock.Advance(w.tick)
+		w.Advance()
+	}
+}
+
+func TestFiresOnTimeAcrossLevels(t *testing.T) {
+	clock := testutil.NewClock()
+	// 8个槽、3层，范围512个tick，更远的定时器需要多次下沉
+	w := New(time.Second, WithSlots(8), WithLevels(3), WithClock(clock.Now))
+
+	start := clock.Now()
+	delays := []time.Duration{1, 7, 8, 9, 63, 64, 65, 511, 512, 513, 2000}
+	for range 200 {
+		delays = append(delays, time.Duration(rand.IntN(3000)+1))
+	}
+	fired := make(map[int]time.Duration)
+	for i, d := range delays {
+		w.AfterFunc(d*time.Second, func() { fired[i] = clock.Now().Sub(start) })
+	}
+	clock.Advance(300 * time.Millisecond) // 不在tick边界上添加
+	w.Advance()
+	w.AfterFunc(1500*time.Millisecond, func() { fired[-1] = clock.Now().Sub(start) })
+	clock.Advance(700 * time.Millisecond) // 回到tick边界
+	w.Advance()
+
+	step(w, clock, 3100)
+	for i, d := range delays {
+		if fired[i] != d*time.Second {
+			t.Errorf("timer %d (%ds) fired at %v", i, d, fired[i])
+		}
+	}
+	// 0.3s时添加的1.5s定时器在第一个不早于1.8s的tick执行
+	if fired[-1] != 2*time.Second {
+		t.Errorf("mid-tick timer fired at %v", fired[-1])
+	}
+	if w.Len() != 0 {
+		t.Errorf("len = %d", w.Len())
+	}
+}
+
+func TestStopResetSchedule(t *testing.T) {
+	clock := testutil.NewClock()
+	w := New(time.Second, WithSlots(4), WithClock(clock.Now))
+
+	var events []string
+	stopped := w.AfterFunc(10*time.Second, func() { events = append(events, "stopped") })
+	reset := w.AfterFunc(2*time.Second, func() { events = append(events, "reset") })
+	var periodic *Timer
+	n := 0
+	periodic = w.Schedule(3*time.Second, func() {
+		n++
+		events = append(events, "tick")
+		if n == 3 {
+			periodic.Stop()
+		}
+	})
+
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("Stop should report pending only once")
+	}
+	step(w, clock, 1)
+	if !reset.Reset(20 * time.Second) {
+		t.Error("Reset of pending timer returned false")
+	}
+	step(w, clock, 10)
+	if w.Len() != 1 {
+		t.Errorf("len = %d, want 1", w.Len())
+	}
+	step(w, clock, 10)
+	want := "[tick tick tick reset]"
+	if got := fmt.Sprint(events); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+	if reset.Stop() {
+		t.Error("Stop after firing returned true")
+	}
+	if reset.Reset(time.Second) {
+		t.Error("Reset after firing returned true")
+	}
+	step(w, clock, 1)
+	if got := fmt.Sprint(events); got != "[tick tick tick reset reset]" {
+		t.Errorf("events = %s", got)
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	w := New(5 * time.Millisecond)
+	w.Start()
+	defer w.Stop()
+
+	done := make(chan time.Duration, 1)
+	start := time.Now()
+	w.AfterFunc(30*time.Millisecond, func() { done <- time.Since(start) })
+	select {
+	case elapsed := <-done:
+		if elapsed < 30*time.Millisecond {
+			t.Errorf("fired early after %v", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire")
+	}
+}
+
+func BenchmarkAfterFuncStop(b *testing.B) {
+	w := New(time.Millisecond)
+	for i := 0; b.Loop(); i++ {
+		w.AfterFunc(time.Duration(i%100000)*time.Millisecond, func() {}).Stop()
+	}
+}
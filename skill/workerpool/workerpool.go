@@ -0,0 +1,316 @@
+// Package workerpool 泛型工作池：固定数量的worker从有界队列中取任务执行，
+// 每个任务返回 Future 获取结果，支持队列满时的策略、运行时调整worker数、panic恢复、指标和优雅关闭
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrRejected 队列已满，任务被拒绝（Reject策略）
+	ErrRejected = errors.New("workerpool: 队列已满")
+	// ErrClosed 工作池已关闭，不再接受任务
+	ErrClosed = errors.New("workerpool: 工作池已关闭")
+	// ErrAbandoned Shutdown超时，排队中的任务被放弃
+	ErrAbandoned = errors.New("workerpool: 任务在关闭时被放弃")
+)
+
+// PanicError 任务panic时返回的错误
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("workerpool: 任务panic: %v", e.Value)
+}
+
+// FullPolicy 队列满时的处理策略
+type FullPolicy int
+
+const (
+	Block      FullPolicy = iota // 阻塞等待队列有空位，直到ctx结束
+	Reject                       // 立即返回ErrRejected
+	CallerRuns                   // 在调用Submit的goroutine中直接执行，自然地降低提交速度
+)
+
+// Option 工作池选项
+type Option func(*options)
+
+type options struct {
+	queueSize int
+	policy    FullPolicy
+}
+
+// WithQueueSize 队列容量，默认100，0表示没有空闲worker时即视为队列满
+func WithQueueSize(n int) Option {
+	return func(o *options) { o.queueSize = max(n, 0) }
+}
+
+// WithFullPolicy 队列满时的策略，默认Block
+func WithFullPolicy(p FullPolicy) Option {
+	return func(o *options) { o.policy = p }
+}
+
+// Metrics 工作池指标
+type Metrics struct {
+	Workers    int           // 当前运行的worker数
+	Queued     int           // 排队中的任务数
+	Active     int           // 正在执行的任务数
+	Submitted  int64         // 累计提交数，包括被拒绝的
+	Completed  int64         // 累计执行完成数，包括返回错误和panic的
+	Failed     int64         // 累计执行失败数（返回错误或panic）
+	Panics     int64         // 累计panic数
+	Rejected   int64         // 累计因队列满被拒绝数
+	AvgWait    time.Duration // 平均排队时间
+	AvgLatency time.Duration // 平均执行时间
+}
+
+// task 队列中的任务
+type task[In, Out any] struct {
+	ctx      context.Context
+	in       In
+	future   *Future[Out]
+	enqueued time.Time
+}
+
+// Pool 泛型工作池，并发安全
+type Pool[In, Out any] struct {
+	fn     func(ctx context.Context, in In) (Out, error)
+	policy FullPolicy
+	tasks  chan *task[In, Out]
+	shrink chan struct{}
+
+	// mu 保证Shutdown关闭tasks后不会再有Submit发送，也不会再启动worker
+	mu     sync.RWMutex
+	closed bool
+
+	sizeMu sync.Mutex
+	size   int
+	nextID int
+
+	closing  chan struct{} // Shutdown开始时关闭，唤醒阻塞的Submit
+	drained  chan struct{} // 所有worker退出后关闭
+	shutdown sync.Once
+	wg       sync.WaitGroup
+
+	// base 取消时放弃排队的任务并取消执行中任务的ctx
+	base    context.Context
+	abandon context.CancelFunc
+
+	workers, active                                atomic.Int64
+	submitted, completed, failed, panics, rejected atomic.Int64
+	waitNanos, runNanos                            atomic.Int64
+}
+
+// New 创建工作池并启动workers个worker，fn处理每个任务
+func New[In, Out any](workers int, fn func(ctx context.Context, in In) (Out, error), opts ...Option) *Pool[In, Out] {
+	o := options{queueSize: 100}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	base, abandon := context.WithCancel(context.Background())
+	p := &Pool[In, Out]{
+		fn:      fn,
+		policy:  o.policy,
+		tasks:   make(chan *task[In, Out], o.queueSize),
+		shrink:  make(chan struct{}),
+		closing: make(chan struct{}),
+		drained: make(chan struct{}),
+		base:    base,
+		abandon: abandon,
+	}
+	p.Resize(workers)
+	return p
+}
+
+// Submit 提交任务，返回获取结果的Future
+//
+// 工作池关闭、队列满被拒绝、或等待入队时ctx结束，Future立即以对应的错误完成；
+// 任务开始执行前ctx已结束时不执行，执行时传给fn的ctx在Submit的ctx结束或Shutdown放弃任务时取消
+func (p *Pool[In, Out]) Submit(ctx context.Context, in In) *Future[Out] {
+	f := newFuture[Out]()
+	t := &task[In, Out]{ctx: ctx, in: in, future: f, enqueued: time.Now()}
+
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		f.fail(ErrClosed)
+		return f
+	}
+	p.submitted.Add(1)
+	switch p.policy {
+	case Reject, CallerRuns:
+		select {
+		case p.tasks <- t:
+		default:
+			p.mu.RUnlock()
+			if p.policy == Reject {
+				p.rejected.Add(1)
+				f.fail(ErrRejected)
+			} else {
+				p.run(-1, t)
+			}
+			return f
+		}
+	default:
+		select {
+		case p.tasks <- t:
+		case <-ctx.Done():
+			f.fail(ctx.Err())
+		case <-p.closing:
+			f.fail(ErrClosed)
+		}
+	}
+	p.mu.RUnlock()
+	return f
+}
+
+// Resize 调整worker数，至少为1；缩容时多余的worker执行完当前任务后退出
+func (p *Pool[In, Out]) Resize(n int) {
+	n = max(n, 1)
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+	p.sizeMu.Lock()
+	defer p.sizeMu.Unlock()
+	diff := n - p.size
+	p.size = n
+	for range diff {
+		p.wg.Add(1)
+		p.workers.Add(1)
+		go p.worker(p.nextID)
+		p.nextID++
+	}
+	if diff < 0 {
+		go func() {
+			for range -diff {
+				select {
+				case p.shrink <- struct{}{}:
+				case <-p.closing:
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Shutdown 停止接受新任务，等待排队和执行中的任务完成
+//
+// ctx结束时放弃仍在排队的任务（Future返回ErrAbandoned），取消执行中任务的ctx，并返回ctx.Err()；
+// 可以重复调用，例如先等待一段时间，超时后再以已取消的ctx调用立即放弃
+func (p *Pool[In, Out]) Shutdown(ctx context.Context) error {
+	p.shutdown.Do(func() {
+		close(p.closing)
+		p.mu.Lock()
+		p.closed = true
+		close(p.tasks)
+		p.mu.Unlock()
+		go func() {
+			p.wg.Wait()
+			close(p.drained)
+		}()
+	})
+	select {
+	case <-p.drained:
+		return nil
+	case <-ctx.Done():
+		p.abandon()
+		return ctx.Err()
+	}
+}
+
+// Metrics 当前指标
+func (p *Pool[In, Out]) Metrics() Metrics {
+	m := Metrics{
+		Workers:   int(p.workers.Load()),
+		Queued:    len(p.tasks),
+		Active:    int(p.active.Load()),
+		Submitted: p.submitted.Load(),
+		Completed: p.completed.Load(),
+		Failed:    p.failed.Load(),
+		Panics:    p.panics.Load(),
+		Rejected:  p.rejected.Load(),
+	}
+	if m.Completed > 0 {
+		m.AvgWait = time.Duration(p.waitNanos.Load() / m.Completed)
+		m.AvgLatency = time.Duration(p.runNanos.Load() / m.Completed)
+	}
+	return m
+}
+
+// worker 从队列中取任务执行，队列关闭或收到缩容信号时退出
+func (p *Pool[In, Out]) worker(id int) {
+	defer p.wg.Done()
+	defer p.workers.Add(-1)
+	for {
+		select {
+		case t, ok := <-p.tasks:
+			if !ok {
+				return
+			}
+			p.run(id, t)
+		case <-p.shrink:
+			return
+		}
+	}
+}
+
+// run 执行一个任务，id为-1表示在调用方goroutine中执行
+func (p *Pool[In, Out]) run(id int, t *task[In, Out]) {
+	if p.base.Err() != nil {
+		t.future.fail(ErrAbandoned)
+		return
+	}
+	if err := t.ctx.Err(); err != nil {
+		t.future.fail(err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(t.ctx, workerIDKey{}, id))
+	stop := context.AfterFunc(p.base, cancel)
+	defer stop()
+	defer cancel()
+
+	start := time.Now()
+	p.active.Add(1)
+	out, err := p.call(ctx, t.in)
+	p.active.Add(-1)
+
+	p.waitNanos.Add(int64(start.Sub(t.enqueued)))
+	p.runNanos.Add(int64(time.Since(start)))
+	p.completed.Add(1)
+	if err != nil {
+		p.failed.Add(1)
+	}
+	t.future.complete(out, err)
+}
+
+// call 调用fn，把panic转换为PanicError
+func (p *Pool[In, Out]) call(ctx context.Context, in In) (out Out, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.panics.Add(1)
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return p.fn(ctx, in)
+}
+
+type workerIDKey struct{}
+
+// WorkerID 返回执行当前任务的worker编号，CallerRuns策略下在调用方执行时为-1
+func WorkerID(ctx context.Context) int {
+	if id, ok := ctx.Value(workerIDKey{}).(int); ok {
+		return id
+	}
+	return -1
+}
@@ -0,0 +1,174 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kuihuar/ai/skill/internal/testutil"
+)
+
+func TestSubmitResults(t *testing.T) {
+	p := New(4, func(ctx context.Context, n int) (string, error) {
+		if n < 0 {
+			return "", errors.New("negative")
+		}
+		if n == 13 {
+			panic("unlucky")
+		}
+		return strconv.Itoa(n * n), nil
+	})
+
+	futures := make([]*Future[string], 20)
+	for i := range futures {
+		futures[i] = p.Submit(context.Background(), i)
+	}
+	for i, f := range futures {
+		got, err := f.Wait()
+		if i == 13 {
+			var pe *PanicError
+			if !errors.As(err, &pe) || pe.Value != "unlucky" || len(pe.Stack) == 0 {
+				t.Errorf("panic task: err = %v", err)
+			}
+			continue
+		}
+		if err != nil || got != strconv.Itoa(i*i) {
+			t.Errorf("task %d = %q, %v", i, got, err)
+		}
+	}
+	if _, err := p.Submit(context.Background(), -1).Wait(); err == nil {
+		t.Error("error not returned")
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	m := p.Metrics()
+	if m.Submitted != 21 || m.Completed != 21 || m.Failed != 2 || m.Panics != 1 || m.Workers != 0 {
+		t.Errorf("metrics = %+v", m)
+	}
+	if _, err := p.Submit(context.Background(), 1).Wait(); !errors.Is(err, ErrClosed) {
+		t.Errorf("submit after shutdown: err = %v", err)
+	}
+}
+
+// blockingPool 返回一个worker阻塞在release上的工作池
+func blockingPool(workers, queue int, policy FullPolicy) (*Pool[int, int], chan struct{}, chan int) {
+	release := make(chan struct{})
+	started := make(chan int, 100)
+	p := New(workers, func(ctx context.Context, n int) (int, error) {
+		started <- WorkerID(ctx)
+		select {
+		case <-release:
+			return n, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}, WithQueueSize(queue), WithFullPolicy(policy))
+	return p, release, started
+}
+
+func TestFullPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	p, release, started := blockingPool(1, 1, Reject)
+	p.Submit(ctx, 1)
+	<-started
+	p.Submit(ctx, 2) // 进入队列
+	if _, err := p.Submit(ctx, 3).Wait(); !errors.Is(err, ErrRejected) {
+		t.Errorf("reject: err = %v", err)
+	}
+	close(release)
+	p.Shutdown(ctx)
+	if m := p.Metrics(); m.Rejected != 1 || m.Completed != 2 {
+		t.Errorf("metrics = %+v", m)
+	}
+
+	p, release, started = blockingPool(1, 1, CallerRuns)
+	p.Submit(ctx, 1)
+	<-started
+	p.Submit(ctx, 2)
+	third := make(chan *Future[int])
+	go func() { third <- p.Submit(ctx, 3) }()
+	if id := <-started; id != -1 {
+		t.Errorf("caller runs in worker %d", id)
+	}
+	close(release)
+	if v, err := (<-third).Wait(); v != 3 || err != nil {
+		t.Errorf("caller runs = %d, %v", v, err)
+	}
+	p.Shutdown(ctx)
+
+	p, release, started = blockingPool(1, 1, Block)
+	p.Submit(ctx, 1)
+	<-started
+	p.Submit(ctx, 2)
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Submit(timeout, 3).Wait(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("block: err = %v", err)
+	}
+	close(release)
+	p.Shutdown(ctx)
+}
+
+func TestResize(t *testing.T) {
+	var running, peak atomic.Int64
+	release := make(chan struct{})
+	p := New(2, func(ctx context.Context, n int) (int, error) {
+		cur := running.Add(1)
+		for {
+			old := peak.Load()
+			if cur <= old || peak.CompareAndSwap(old, cur) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return n, nil
+	})
+
+	var futures []*Future[int]
+	for i := range 8 {
+		futures = append(futures, p.Submit(context.Background(), i))
+	}
+	p.Resize(6)
+	testutil.WaitFor(t, func() bool { return running.Load() == 6 })
+
+	p.Resize(1)
+	close(release)
+	for _, f := range futures {
+		f.Wait()
+	}
+	testutil.WaitFor(t, func() bool { return p.Metrics().Workers == 1 })
+	if peak.Load() != 6 {
+		t.Errorf("peak = %d", peak.Load())
+	}
+	p.Shutdown(context.Background())
+}
+
+func TestShutdownAbandon(t *testing.T) {
+	p, release, started := blockingPool(1, 10, Block)
+	defer close(release)
+	running := p.Submit(context.Background(), 1)
+	<-started
+	queued := p.Submit(context.Background(), 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown = %v", err)
+	}
+	if _, err := running.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("running task: err = %v", err)
+	}
+	if _, err := queued.Wait(); !errors.Is(err, ErrAbandoned) {
+		t.Errorf("queued task: err = %v", err)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Errorf("second shutdown = %v", err)
+	}
+}
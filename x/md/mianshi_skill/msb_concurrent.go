@@ -1,8 +1,10 @@
 package mianshiskill
 
 import (
+	"context"
 	"fmt"
-	"sync"
+
+	"github.com/kuihuar/ai/skill/workerpool"
 )
 
 // 任务调度器, 工作池模式
@@ -27,36 +29,28 @@ func (t DemoTask) PrintTask() string {
 }
 
 type SchedulerWorkerPool struct {
-	tasks        chan Task
-	wg           sync.WaitGroup
+	pool         *workerpool.Pool[Task, struct{}]
 	maxWorkerNum int
+	maxTaskNum   int
 }
 
 func NewSchedulerWorkerPool(maxWorkerNum, maxTaskNum int) *SchedulerWorkerPool {
 	return &SchedulerWorkerPool{
-		tasks:        make(chan Task, maxTaskNum),
 		maxWorkerNum: maxWorkerNum,
+		maxTaskNum:   maxTaskNum,
 	}
 }
 
 func (swp *SchedulerWorkerPool) Run() {
-	for i := 0; i < swp.maxWorkerNum; i++ {
-		swp.wg.Add(1)
-		workerId := i
-		go func(int) {
-			defer swp.wg.Done()
-			for task := range swp.tasks {
-				task.Execute(workerId)
-			}
-		}(workerId)
-	}
+	swp.pool = workerpool.New(swp.maxWorkerNum, func(ctx context.Context, task Task) (struct{}, error) {
+		return struct{}{}, task.Execute(workerpool.WorkerID(ctx))
+	}, workerpool.WithQueueSize(swp.maxTaskNum))
 }
 func (swp *SchedulerWorkerPool) AddTask(task Task) {
-	swp.tasks <- task
+	swp.pool.Submit(context.Background(), task)
 }
 func (swp *SchedulerWorkerPool) WaitAndClose() {
-	close(swp.tasks)
-	swp.wg.Wait()
+	swp.pool.Shutdown(context.Background())
 }
 func UseTaskWithWorkerPool() {
 	const numWorkers = 5
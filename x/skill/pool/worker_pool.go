@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"sync"
+
+	"github.com/kuihuar/ai/skill/workerpool"
 )
 
 func Foo() {
@@ -21,26 +24,23 @@ type handleFunc func()
 
 type WorkPool struct {
 	WorkNum int
-	tasks   chan handleFunc
+	pool    *workerpool.Pool[handleFunc, struct{}]
 }
 
 func NewWorkPool(workNum int) *WorkPool {
-	w := &WorkPool{WorkNum: workNum, tasks: make(chan handleFunc)}
+	w := &WorkPool{WorkNum: workNum}
 	w.Start()
 	return w
 }
 func (w *WorkPool) Start() {
-	for i := 0; i < w.WorkNum; i++ {
-		go func() {
-			for task := range w.tasks {
-				task()
-			}
-		}()
-	}
+	w.pool = workerpool.New(w.WorkNum, func(_ context.Context, task handleFunc) (struct{}, error) {
+		task()
+		return struct{}{}, nil
+	}, workerpool.WithQueueSize(0))
 }
 
 func (w *WorkPool) addTask(task handleFunc) {
-	w.tasks <- task
+	w.pool.Submit(context.Background(), task)
 }
 
 func useWorkPool() {